@@ -0,0 +1,65 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic writes all records from src to the named file.
+// Records are first written to a temporary file in the same directory which is synced and then renamed,
+// so that a partially-written file is never visible under the final name.
+// The permissions of an existing file are preserved.
+func WriteFileAtomic(path string, src RecordSource, d Dialect) error {
+	return writeFileAtomic(path, func(f *os.File) error {
+		_, err := Copy(d.NewWriter(f), src)
+		return err
+	})
+}
+
+// writeFileAtomic writes the named file content with write (see WriteFileAtomic).
+func writeFileAtomic(path string, write func(f *os.File) error) (err error) {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := ioutil.TempFile(dir, "."+base+".tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+		}
+	}()
+	var perm os.FileMode = 0644
+	if fi, err := os.Stat(path); err == nil {
+		perm = fi.Mode().Perm()
+	}
+	if err = f.Chmod(perm); err != nil {
+		return err
+	}
+	if err = write(f); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(f.Name(), path); err != nil {
+		return err
+	}
+	// Best effort: make the rename durable (not supported on all platforms).
+	if df, err := os.Open(dir); err == nil {
+		_ = df.Sync()
+		_ = df.Close()
+	}
+	return nil
+}
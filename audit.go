@@ -0,0 +1,64 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+// QuotingStats reports how many fields were quoted by a Writer and why (see Writer.Stats),
+// which is useful to tune dialects and to spot unexpected embedded newlines in upstream data.
+// A field is counted once for each reason that applies.
+type QuotingStats struct {
+	Fields    int // fields written
+	Quoted    int // fields quoted (in quoted mode)
+	Forced    int // fields quoted only because it was requested (see ForceQuotes and WriteQuoted)
+	Separator int // fields containing the separator
+	Quote     int // fields containing a double quote
+	Newline   int // fields containing a newline (\n or \r)
+	Space     int // fields with a leading or trailing space (not quoted unless forced but trimmed by some readers)
+
+	Newlines map[int]int // number of fields containing a newline by column index (first is 1)
+}
+
+// audit updates the statistics with the value written in the current column.
+func (st *QuotingStats) audit(value []byte, sep byte, col int, quoted, forced bool) {
+	st.Fields++
+	var hasSep, hasQuote, hasNewline bool
+	for _, c := range value {
+		switch c {
+		case sep:
+			hasSep = true
+		case '"':
+			hasQuote = true
+		case '\n', '\r':
+			hasNewline = true
+		}
+	}
+	if hasSep {
+		st.Separator++
+	}
+	if hasQuote {
+		st.Quote++
+	}
+	if hasNewline {
+		st.Newline++
+	}
+	if n := len(value); n > 0 && (isBlank(value[0]) || isBlank(value[n-1])) {
+		st.Space++
+	}
+	if quoted {
+		st.Quoted++
+		if forced && !hasSep && !hasQuote && !hasNewline {
+			st.Forced++
+		}
+	}
+	if hasNewline {
+		if st.Newlines == nil {
+			st.Newlines = make(map[int]int)
+		}
+		st.Newlines[col]++
+	}
+}
+
+func isBlank(c byte) bool {
+	return c == ' ' || c == '\t'
+}
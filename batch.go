@@ -0,0 +1,165 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// ColType is the type of a column decoded by ScanBatch.
+type ColType int
+
+// Column types
+const (
+	StringCol ColType = iota
+	Int64Col
+	Float64Col
+)
+
+// Col is one decoded column of a ColBatch.
+// Only the slice matching Type is filled.
+// Null (empty or missing) values are decoded as zero values and flagged in the validity bitmap.
+type Col struct {
+	Type    ColType
+	Int64   []int64
+	Float64 []float64
+	String  []string
+	Valid   []uint64 // validity bitmap: bit i is set when the i-th value is not null
+}
+
+// IsValid tells if the i-th value is not null.
+func (c *Col) IsValid(i int) bool {
+	return c.Valid[i/64]&(1<<uint(i%64)) != 0
+}
+
+func (c *Col) setValid(i int, valid bool) {
+	if i%64 == 0 {
+		c.Valid = append(c.Valid, 0)
+	}
+	if valid {
+		c.Valid[i/64] |= 1 << uint(i%64)
+	}
+}
+
+// ColBatch is a batch of records decoded in column-major order.
+type ColBatch struct {
+	Len  int   // number of records
+	Cols []Col // decoded columns (see Reader.ColTypes)
+}
+
+// ScanBatch decodes up to n records into typed columns (see ColTypes).
+// Extra fields are ignored and missing fields are null.
+// Empty lines are ignored/skipped.
+// Returns (nil, io.EOF) when there is no more record, a *FieldError when a value cannot be decoded
+// and an error when n < 1.
+// Fields are scanned directly (like Scan): the features of ReadRecord (Transformers, Quarantine,
+// MaxColumns, MissingFields and ExtraFields) are not applied.
+func (s *Reader) ScanBatch(n int) (*ColBatch, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("invalid batch size: %d", n)
+	}
+	b := &ColBatch{Cols: make([]Col, len(s.ColTypes))}
+	for j, t := range s.ColTypes {
+		c := &b.Cols[j]
+		c.Type = t
+		c.Valid = make([]uint64, 0, (n+63)/64)
+		switch t {
+		case Int64Col:
+			c.Int64 = make([]int64, 0, n)
+		case Float64Col:
+			c.Float64 = make([]float64, 0, n)
+		default:
+			c.String = make([]string, 0, n)
+		}
+	}
+	for b.Len < n {
+		j := 0
+		for s.Scan() {
+			if j == 0 && s.skippable() { // skip empty line (or line comment)
+				continue
+			}
+			if j < len(b.Cols) {
+				if err := b.Cols[j].decode(b.Len, s.Bytes()); err != nil {
+					return nil, &FieldError{Line: s.recordLine(), Column: j + 1, Name: s.headerName(j + 1), Err: err}
+				}
+			}
+			j++
+			if s.eor {
+				break
+			}
+		}
+		if err := s.Err(); err != nil {
+			return nil, err
+		} else if j == 0 { // EOF
+			break
+		}
+		for ; j < len(b.Cols); j++ { // missing fields
+			b.Cols[j].decode(b.Len, nil)
+		}
+		b.Len++
+	}
+	if b.Len == 0 {
+		return nil, io.EOF
+	}
+	return b, nil
+}
+
+// decode appends the i-th value.
+func (c *Col) decode(i int, v []byte) error {
+	c.setValid(i, len(v) > 0)
+	switch c.Type {
+	case Int64Col:
+		var n int64
+		if len(v) > 0 {
+			var ok bool
+			if n, ok = parseInt64(v); !ok {
+				return fmt.Errorf("invalid int64 %q", v)
+			}
+		}
+		c.Int64 = append(c.Int64, n)
+	case Float64Col:
+		var f float64
+		if len(v) > 0 {
+			var err error
+			if f, err = strconv.ParseFloat(string(v), 64); err != nil {
+				return fmt.Errorf("invalid float64 %q", v)
+			}
+		}
+		c.Float64 = append(c.Float64, f)
+	default:
+		c.String = append(c.String, string(v))
+	}
+	return nil
+}
+
+// parseInt64 parses a decimal integer without allocating.
+func parseInt64(v []byte) (int64, bool) {
+	neg := false
+	if v[0] == '-' || v[0] == '+' {
+		neg = v[0] == '-'
+		v = v[1:]
+	}
+	if len(v) == 0 || len(v) > 19 {
+		return 0, false
+	}
+	var n uint64
+	for _, c := range v {
+		if !isDigit(c) {
+			return 0, false
+		}
+		n = n*10 + uint64(c-'0')
+	}
+	if neg {
+		if n > 1<<63 {
+			return 0, false
+		}
+		return -int64(n), true
+	} else if n > 1<<63-1 {
+		return 0, false
+	}
+	return int64(n), true
+}
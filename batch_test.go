@@ -0,0 +1,60 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+func TestScanBatch(t *testing.T) {
+	r := DefaultReader(strings.NewReader("1,1.5,a\n\n-9223372036854775808,,\"b\nc\"\n3,2e3\n"))
+	r.ColTypes = []ColType{Int64Col, Float64Col, StringCol}
+	for _, n := range []int{0, -1} {
+		if _, err := r.ScanBatch(n); err == nil || err == io.EOF {
+			t.Errorf("got %v; want an error for batch size %d", err, n)
+		}
+	}
+	b, err := r.ScanBatch(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Len != 2 || !reflect.DeepEqual(b.Cols[0].Int64, []int64{1, -9223372036854775808}) ||
+		!reflect.DeepEqual(b.Cols[1].Float64, []float64{1.5, 0}) || !reflect.DeepEqual(b.Cols[2].String, []string{"a", "b\nc"}) {
+		t.Errorf("unexpected batch: %+v", b)
+	}
+	if !b.Cols[1].IsValid(0) || b.Cols[1].IsValid(1) {
+		t.Errorf("unexpected validity: %b", b.Cols[1].Valid)
+	}
+	if b, err = r.ScanBatch(2); err != nil {
+		t.Fatal(err)
+	}
+	if b.Len != 1 || b.Cols[1].Float64[0] != 2000 || b.Cols[2].IsValid(0) {
+		t.Errorf("unexpected batch: %+v", b)
+	}
+	if _, err = r.ScanBatch(2); err != io.EOF {
+		t.Errorf("got %v; want EOF", err)
+	}
+
+	r = DefaultReader(strings.NewReader("1\n9223372036854775808\n"))
+	r.ColTypes = []ColType{Int64Col}
+	var ferr *FieldError
+	if _, err = r.ScanBatch(10); !errors.As(err, &ferr) || ferr.Line != 2 || ferr.Column != 1 {
+		t.Errorf("overflow error expected: %v", err)
+	} else if err.Error() != `invalid int64 "9223372036854775808" at line 2, column 1` {
+		t.Errorf("got %q", err)
+	}
+	r = DefaultReader(strings.NewReader("x\n"))
+	r.ColTypes = []ColType{Float64Col}
+	r.Headers = map[string]int{"price": 1}
+	if _, err = r.ScanBatch(1); err == nil || err.Error() != `price: invalid float64 "x" at line 1, column 1` {
+		t.Errorf("got %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+const benchSize = 1 << 20
+
+func benchmarkWorkloads(b *testing.B, parse func(io.Reader) (int, error)) {
+	for _, wl := range workloads {
+		var buf bytes.Buffer
+		rows, err := generate(&buf, wl, benchSize, 1)
+		if err != nil {
+			b.Fatal(err)
+		}
+		data := buf.Bytes()
+		b.Run(wl.name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				n, err := parse(bytes.NewReader(data))
+				if err != nil {
+					b.Fatal(err)
+				} else if n != rows {
+					b.Fatalf("wrong # rows: %d; want %d", n, rows)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkYacr(b *testing.B) {
+	benchmarkWorkloads(b, parseYacr)
+}
+
+func BenchmarkEncodingCSV(b *testing.B) {
+	benchmarkWorkloads(b, parseCSV)
+}
+
+func TestGenerate(t *testing.T) {
+	for _, wl := range workloads {
+		var b1, b2 bytes.Buffer
+		rows, err := generate(&b1, wl, 64*1024, 42)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = generate(&b2, wl, 64*1024, 42); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b1.Bytes(), b2.Bytes()) {
+			t.Errorf("%s: generator not reproducible", wl.name)
+		}
+		for _, parse := range []func(io.Reader) (int, error){parseYacr, parseCSV} {
+			if n, err := parse(bytes.NewReader(b1.Bytes())); err != nil || n != rows {
+				t.Errorf("%s: got %d rows, %v; want %d", wl.name, n, err, rows)
+			}
+		}
+	}
+}
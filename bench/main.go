@@ -0,0 +1,170 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Command bench measures the parsing throughput of yacr (and encoding/csv for comparison)
+// on reproducible synthetic files:
+//
+//	go run ./bench -workload=all -size=1073741824 -cpuprofile=cpu.out
+//
+// Workloads are: narrow (few short fields), wide (100 fields), quoted (heavy quoting)
+// and multiline (quoted fields with embedded newlines).
+// Generated files are kept in -dir and reused by subsequent runs.
+// The same workloads are available as Go benchmarks (go test -bench . ./bench).
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"time"
+
+	"github.com/gwenn/yacr"
+)
+
+func main() {
+	name := flag.String("workload", "all", "workload name (narrow, wide, quoted, multiline or all)")
+	size := flag.Int64("size", 1<<30, "approximate size in bytes of the generated files")
+	seed := flag.Int64("seed", 1, "seed of the generator")
+	dir := flag.String("dir", os.TempDir(), "directory of the generated files")
+	parser := flag.String("parser", "all", "parser (yacr, csv or all)")
+	cpuprofile := flag.String("cpuprofile", "", "write cpu profile to file")
+	memprofile := flag.String("memprofile", "", "write memory profile to file")
+	flag.Parse()
+
+	var selected []workload
+	if *name == "all" {
+		selected = workloads
+	} else if wl, ok := findWorkload(*name); ok {
+		selected = []workload{wl}
+	} else {
+		log.Fatalf("unknown workload: %s", *name)
+	}
+	var names []string
+	switch *parser {
+	case "all":
+		names = []string{"yacr", "csv"}
+	case "yacr", "csv":
+		names = []string{*parser}
+	default:
+		log.Fatalf("unknown parser: %s", *parser)
+	}
+
+	if *cpuprofile != "" {
+		f, err := os.Create(*cpuprofile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err = pprof.StartCPUProfile(f); err != nil {
+			log.Fatal(err)
+		}
+	}
+	err := run(selected, names, *dir, *size, *seed, *memprofile)
+	if *cpuprofile != "" { // not deferred: log.Fatal would skip it
+		pprof.StopCPUProfile()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+var parsers = map[string]func(io.Reader) (int, error){"yacr": parseYacr, "csv": parseCSV}
+
+// run measures the parsers on the workloads and writes the memory profile (when memprofile is specified).
+func run(selected []workload, names []string, dir string, size, seed int64, memprofile string) error {
+	for _, wl := range selected {
+		path, err := generated(dir, wl, size, seed)
+		if err != nil {
+			return err
+		}
+		for _, p := range names {
+			d, n, rows, err := measure(path, parsers[p])
+			if err != nil {
+				return fmt.Errorf("%s/%s: %s", p, wl.name, err)
+			}
+			fmt.Printf("%-5s %-10s %12d bytes %10d rows %10v %8.1f MB/s\n", p, wl.name, n, rows, d.Round(time.Millisecond), float64(n)/d.Seconds()/1e6)
+		}
+	}
+	if memprofile != "" {
+		f, err := os.Create(memprofile)
+		if err != nil {
+			return err
+		}
+		runtime.GC()
+		if err = pprof.WriteHeapProfile(f); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}
+	return nil
+}
+
+// generated returns the path of the generated file (created when missing).
+func generated(dir string, wl workload, size, seed int64) (string, error) {
+	path := filepath.Join(dir, fmt.Sprintf("yacr-bench-%s-%d-%d.csv", wl.name, size, seed))
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+	f, err := os.Create(path + ".tmp")
+	if err != nil {
+		return "", err
+	}
+	if _, err = generate(f, wl, size, seed); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err = f.Close(); err != nil {
+		return "", err
+	}
+	return path, os.Rename(path+".tmp", path)
+}
+
+func measure(path string, parse func(io.Reader) (int, error)) (d time.Duration, size int64, rows int, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return
+	}
+	start := time.Now()
+	rows, err = parse(f)
+	return time.Since(start), fi.Size(), rows, err
+}
+
+func parseYacr(rd io.Reader) (int, error) {
+	r := yacr.DefaultReader(rd)
+	rows := 0
+	for r.Scan() {
+		if r.EndOfRecord() {
+			rows++
+		}
+	}
+	return rows, r.Err()
+}
+
+func parseCSV(rd io.Reader) (int, error) {
+	r := csv.NewReader(bufio.NewReader(rd))
+	r.FieldsPerRecord = -1
+	r.ReuseRecord = true
+	rows := 0
+	for {
+		_, err := r.Read()
+		if err == io.EOF {
+			return rows, nil
+		} else if err != nil {
+			return rows, err
+		}
+		rows++
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/gwenn/yacr"
+)
+
+// workload describes the shape of a synthetic CSV file.
+type workload struct {
+	name string
+	cols int
+	row  func(rnd *rand.Rand, record []string) // fills one record
+}
+
+var words = strings.Fields("lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod tempor incididunt ut labore et dolore magna aliqua")
+
+func word(rnd *rand.Rand) string {
+	return words[rnd.Intn(len(words))]
+}
+
+func sentence(rnd *rand.Rand, n int, sep string) string {
+	s := make([]string, n)
+	for i := range s {
+		s[i] = word(rnd)
+	}
+	return strings.Join(s, sep)
+}
+
+var workloads = []workload{
+	{"narrow", 4, func(rnd *rand.Rand, record []string) {
+		record[0] = strconv.Itoa(rnd.Intn(1000000))
+		record[1] = word(rnd)
+		record[2] = strconv.FormatFloat(rnd.Float64()*1000, 'f', 2, 64)
+		record[3] = strconv.FormatBool(rnd.Intn(2) == 0)
+	}},
+	{"wide", 100, func(rnd *rand.Rand, record []string) {
+		for i := range record {
+			if i%2 == 0 {
+				record[i] = strconv.Itoa(rnd.Int())
+			} else {
+				record[i] = word(rnd)
+			}
+		}
+	}},
+	{"quoted", 6, func(rnd *rand.Rand, record []string) {
+		for i := range record {
+			record[i] = `"` + sentence(rnd, 1+rnd.Intn(4), ", ") + `", he said`
+		}
+	}},
+	{"multiline", 4, func(rnd *rand.Rand, record []string) {
+		record[0] = strconv.Itoa(rnd.Intn(1000000))
+		record[1] = sentence(rnd, 1+rnd.Intn(10), "\n")
+		record[2] = sentence(rnd, 1+rnd.Intn(5), " ")
+		record[3] = sentence(rnd, 1+rnd.Intn(3), "\r\n")
+	}},
+}
+
+func findWorkload(name string) (workload, bool) {
+	for _, wl := range workloads {
+		if wl.name == name {
+			return wl, true
+		}
+	}
+	return workload{}, false
+}
+
+// generate writes (approximately) size bytes of records of the workload to w.
+// The output is reproducible for a given seed.
+func generate(w io.Writer, wl workload, size int64, seed int64) (rows int, err error) {
+	c := &countingWriter{w: w}
+	cw := yacr.DefaultWriter(c)
+	rnd := rand.New(rand.NewSource(seed))
+	record := make([]string, wl.cols)
+	for c.n < size {
+		wl.row(rnd, record)
+		for _, value := range record {
+			cw.WriteString(value)
+		}
+		cw.EndOfRecord()
+		if err = cw.Err(); err != nil {
+			return
+		}
+		rows++
+	}
+	cw.Flush()
+	return rows, cw.Err()
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	return n, err
+}
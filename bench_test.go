@@ -10,6 +10,7 @@ import (
 	"io"
 	"strings"
 	"testing"
+	"time"
 
 	. "github.com/gwenn/yacr"
 )
@@ -93,6 +94,80 @@ func BenchmarkYacrParser(b *testing.B) {
 	}
 }
 
+func BenchmarkScanBatch(b *testing.B) {
+	s := strings.Repeat("123456,3.14159,label\n", 2000)
+	b.SetBytes(int64(len(s)))
+	for i := 0; i < b.N; i++ {
+		r := DefaultReader(strings.NewReader(s))
+		r.ColTypes = []ColType{Int64Col, Float64Col, StringCol}
+		for {
+			if _, err := r.ScanBatch(512); err == io.EOF {
+				break
+			} else if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+type benchUser struct {
+	ID     int64   `yacr:"id"`
+	Name   string  `yacr:"name"`
+	Email  string  `yacr:"email"`
+	Score  float64 `yacr:"score"`
+	Active bool    `yacr:"active"`
+}
+
+// generateUsers returns 2000 random benchUser records (with header).
+func generateUsers(b *testing.B) []byte {
+	schema := Schema{Columns: []Column{
+		{Name: "id", Type: IntegerType},
+		{Name: "name", Format: "name"},
+		{Name: "email", Format: "email", NullRate: 0.1},
+		{Name: "score", Type: NumberType},
+		{Name: "active", Type: BooleanType},
+	}}
+	data := &bytes.Buffer{}
+	if err := Generate(DefaultWriter(data), schema, 2000, 1); err != nil {
+		b.Fatal(err)
+	}
+	return data.Bytes()
+}
+
+func BenchmarkScanStruct(b *testing.B) {
+	data := generateUsers(b)
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		r := DefaultReader(bytes.NewReader(data))
+		if err := r.ScanHeaders(); err != nil {
+			b.Fatal(err)
+		}
+		var u benchUser
+		for {
+			if err := r.ScanStruct(&u); err == io.EOF {
+				break
+			} else if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkTypedReader(b *testing.B) {
+	data := generateUsers(b)
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		r := NewTypedReader[benchUser](bytes.NewReader(data), DialectDefault)
+		for {
+			if _, err := r.Read(); err == io.EOF {
+				break
+			} else if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
 func BenchmarkYacrWriter(b *testing.B) {
 	b.StopTimer()
 	s := strings.Repeat("valu,e1 value2\" value3 valu\ne4 value5", 25)
@@ -129,3 +204,16 @@ func BenchmarkStdWriter(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkRecordBuilder(b *testing.B) {
+	ts := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	w := DefaultWriter(io.Discard)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		w.Record().Str("value").Int(i).Float(3.14159, 2).Time(ts, time.RFC3339).Null().End()
+	}
+	w.Flush()
+	if err := w.Err(); err != nil {
+		b.Fatal(err)
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// bloomMagic starts the binary form of a Bloom filter.
+const bloomMagic = "YBF1"
+
+// maxBloomHashes bounds the number of hash functions (a false positive rate of about 2^-32).
+const maxBloomHashes = 32
+
+// Bloom is a Bloom filter: a compact set of values answering "possibly present" or "definitely absent",
+// handy for pre-joins on huge files (keep only records whose id appears in another file) without loading
+// all the values in memory.
+// It can be saved and loaded with MarshalBinary and UnmarshalBinary.
+type Bloom struct {
+	bits []uint64
+	m    uint64 // number of bits
+	k    uint32 // number of hash functions
+}
+
+// NewBloom returns an empty filter sized for n values with a false positive rate fpRate (between 0 and 1 excluded).
+func NewBloom(n int, fpRate float64) (*Bloom, error) {
+	if !(fpRate > 0 && fpRate < 1) {
+		return nil, fmt.Errorf("invalid false positive rate: %g", fpRate)
+	}
+	if n < 1 {
+		n = 1
+	}
+	m := math.Ceil(-float64(n) * math.Log(fpRate) / (math.Ln2 * math.Ln2))
+	k := math.Round(m / float64(n) * math.Ln2)
+	if k < 1 {
+		k = 1
+	} else if k > maxBloomHashes {
+		k = maxBloomHashes
+	}
+	b := &Bloom{m: uint64(m), k: uint32(k)}
+	b.bits = make([]uint64, (b.m+63)/64)
+	return b, nil
+}
+
+// BuildBloom reads all remaining records of r and returns a filter of the values of column col (first is 1)
+// sized for n values (the expected number of records, see NewBloom) with a false positive rate fpRate
+// (between 0 and 1 excluded): the actual rate is greater when there are more values.
+// Records without such a column are ignored.
+// The header, if any, should be read before.
+func BuildBloom(r *Reader, col, n int, fpRate float64) (*Bloom, error) {
+	if col < 1 {
+		return nil, fmt.Errorf("invalid column index: %d", col)
+	}
+	b, err := NewBloom(n, fpRate)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		fields, err := r.ReadRecord()
+		if err == io.EOF {
+			return b, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if col <= len(fields) {
+			b.Add(fields[col-1])
+		}
+	}
+}
+
+// Add adds value to the filter.
+func (b *Bloom) Add(value []byte) {
+	b.add(bloomHash(value))
+}
+
+// Contains tells if value may have been added to the filter (false means it has not).
+func (b *Bloom) Contains(value []byte) bool {
+	h1 := bloomHash(value)
+	h2 := bloomMix(h1)
+	for i := uint32(0); i < b.k; i++ {
+		if bit := (h1 + uint64(i)*h2) % b.m; b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *Bloom) add(h1 uint64) {
+	h2 := bloomMix(h1)
+	for i := uint32(0); i < b.k; i++ {
+		bit := (h1 + uint64(i)*h2) % b.m
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface.
+func (b *Bloom) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 0, len(bloomMagic)+4+8+8*len(b.bits))
+	data = append(data, bloomMagic...)
+	data = binary.BigEndian.AppendUint32(data, b.k)
+	data = binary.BigEndian.AppendUint64(data, b.m)
+	for _, w := range b.bits {
+		data = binary.BigEndian.AppendUint64(data, w)
+	}
+	return data, nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+func (b *Bloom) UnmarshalBinary(data []byte) error {
+	header := len(bloomMagic) + 4 + 8
+	if len(data) < header || string(data[:len(bloomMagic)]) != bloomMagic {
+		return errors.New("invalid bloom filter")
+	}
+	k := binary.BigEndian.Uint32(data[len(bloomMagic):])
+	m := binary.BigEndian.Uint64(data[len(bloomMagic)+4:])
+	size := len(data) - header
+	if k == 0 || k > maxBloomHashes || m == 0 || size%8 != 0 || (m-1)/64+1 != uint64(size/8) {
+		return errors.New("invalid bloom filter")
+	}
+	bits := make([]uint64, size/8)
+	for i := range bits {
+		bits[i] = binary.BigEndian.Uint64(data[header+8*i:])
+	}
+	b.bits, b.m, b.k = bits, m, k
+	return nil
+}
+
+// FilterByBloom returns the records of src whose value of column col (first is 1) may be in b
+// (see Bloom.Contains). Records without such a column are skipped.
+func FilterByBloom(src RecordSource, b *Bloom, col int) RecordSource {
+	return &bloomFilter{src, b, col}
+}
+
+type bloomFilter struct {
+	src RecordSource
+	b   *Bloom
+	col int
+}
+
+func (f *bloomFilter) ReadRecord() ([][]byte, error) {
+	if f.col < 1 {
+		return nil, fmt.Errorf("invalid column index: %d", f.col)
+	}
+	for {
+		fields, err := f.src.ReadRecord()
+		if err != nil {
+			return nil, err
+		}
+		if f.col <= len(fields) && f.b.Contains(fields[f.col-1]) {
+			return fields, nil
+		}
+	}
+}
+
+// bloomHash returns the FNV-1a hash of value
+// (stable across processes, so that filters can be saved and loaded).
+func bloomHash(value []byte) uint64 {
+	h := uint64(14695981039346656037)
+	for _, c := range value {
+		h ^= uint64(c)
+		h *= 1099511628211
+	}
+	return h
+}
+
+// bloomMix derives from h1 the (odd) step of the double hashing giving the k positions.
+func bloomMix(h1 uint64) uint64 {
+	h := h1 ^ h1>>33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	return h | 1
+}
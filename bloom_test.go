@@ -0,0 +1,73 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+func TestBloom(t *testing.T) {
+	var b strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&b, "%d,id%d\n", i, 2*i)
+	}
+	bloom, err := BuildBloom(DefaultReader(strings.NewReader(b.String())), 2, 1000, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := bloom.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := new(Bloom)
+	if err = loaded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	falsePositives := 0
+	for i := 0; i < 2000; i++ {
+		id := []byte(fmt.Sprintf("id%d", i))
+		if i%2 == 0 && !loaded.Contains(id) {
+			t.Fatalf("false negative: %s", id)
+		} else if i%2 == 1 && loaded.Contains(id) {
+			falsePositives++
+		}
+	}
+	if falsePositives > 30 {
+		t.Errorf("got %d false positives out of 1000; want about 10", falsePositives)
+	}
+
+	r := DefaultReader(strings.NewReader("a,id0\nb,id1\nc\nd,id4\n"))
+	var ids []string
+	src := FilterByBloom(r, loaded, 2)
+	for {
+		fields, err := src.ReadRecord()
+		if err != nil {
+			break
+		}
+		ids = append(ids, string(fields[0]))
+	}
+	if got := strings.Join(ids, ","); got != "a,d" {
+		t.Errorf("got %q; want %q", got, "a,d")
+	}
+
+	if _, err = BuildBloom(DefaultReader(strings.NewReader("a\n")), 1, 1, 1); err == nil {
+		t.Error("error expected for invalid false positive rate")
+	}
+	if err = loaded.UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Error("error expected for truncated filter")
+	}
+	for _, header := range []string{
+		"YBF1\x00\x00\x00\x01\xff\xff\xff\xff\xff\xff\xff\xff", // overflowing number of bits
+		"YBF1\xff\xff\xff\xff\x00\x00\x00\x00\x00\x00\x00\x40", // too many hash functions
+	} {
+		if err = loaded.UnmarshalBinary(append([]byte(header), make([]byte, 8)...)); err == nil {
+			t.Errorf("error expected for %q", header)
+		}
+	}
+}
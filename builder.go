@@ -0,0 +1,90 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"strconv"
+	"time"
+)
+
+// RecordBuilder writes one record value by value with a fluent API,
+// numbers and times being formatted into a buffer of the Writer (without intermediate strings nor allocations):
+//
+//	w.Record().Str("x").Int(42).Float(3.14, 2).Time(t, time.RFC3339).Null().End()
+//
+// Values are quoted when needed, like with Write. Errors are sticky (see Writer.Err).
+type RecordBuilder struct {
+	w *Writer
+}
+
+// Record returns a builder of the next record (terminated by End).
+func (w *Writer) Record() RecordBuilder {
+	return RecordBuilder{w}
+}
+
+// Str writes a string value.
+func (b RecordBuilder) Str(v string) RecordBuilder {
+	b.w.WriteString(v)
+	return b
+}
+
+// Bytes writes a raw value.
+func (b RecordBuilder) Bytes(v []byte) RecordBuilder {
+	b.w.Write(v)
+	return b
+}
+
+// Int writes an integer value.
+func (b RecordBuilder) Int(v int) RecordBuilder {
+	return b.Int64(int64(v))
+}
+
+// Int64 writes an integer value.
+func (b RecordBuilder) Int64(v int64) RecordBuilder {
+	b.w.fb = strconv.AppendInt(b.w.fb[:0], v, 10)
+	b.w.Write(b.w.fb)
+	return b
+}
+
+// Uint64 writes an unsigned integer value.
+func (b RecordBuilder) Uint64(v uint64) RecordBuilder {
+	b.w.fb = strconv.AppendUint(b.w.fb[:0], v, 10)
+	b.w.Write(b.w.fb)
+	return b
+}
+
+// Float writes a float value with prec digits after the decimal point
+// (-1 for the smallest number of digits representing the value exactly, like WriteValue).
+func (b RecordBuilder) Float(v float64, prec int) RecordBuilder {
+	b.w.fb = strconv.AppendFloat(b.w.fb[:0], v, 'f', prec, 64)
+	b.w.Write(b.w.fb)
+	return b
+}
+
+// Bool writes a boolean value ("true" or "false").
+func (b RecordBuilder) Bool(v bool) RecordBuilder {
+	b.w.fb = strconv.AppendBool(b.w.fb[:0], v)
+	b.w.Write(b.w.fb)
+	return b
+}
+
+// Time writes a time value formatted with layout (see time.Time.Format).
+func (b RecordBuilder) Time(t time.Time, layout string) RecordBuilder {
+	b.w.fb = t.AppendFormat(b.w.fb[:0], layout)
+	b.w.Write(b.w.fb)
+	return b
+}
+
+// Null writes an empty value.
+func (b RecordBuilder) Null() RecordBuilder {
+	b.w.Write(nil)
+	return b
+}
+
+// End terminates the record (see Writer.EndOfRecord) and tells if no error occurred.
+func (b RecordBuilder) End() bool {
+	b.w.EndOfRecord()
+	return b.w.err == nil
+}
@@ -0,0 +1,41 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	. "github.com/gwenn/yacr"
+)
+
+func TestRecordBuilder(t *testing.T) {
+	var b bytes.Buffer
+	w := NewWriter(&b, ';', true)
+	ts := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	if !w.Record().Str("x;y").Int(42).Float(3.14159, 2).Time(ts, time.RFC3339).Null().End() {
+		t.Fatal(w.Err())
+	}
+	if !w.Record().Bytes([]byte("a\"b")).Int64(-7).Uint64(7).Float(0.1, -1).Bool(true).Time(ts, "2006-01-02;15:04").End() {
+		t.Fatal(w.Err())
+	}
+	w.Flush()
+	if expected := "\"x;y\";42;3.14;2021-03-04T05:06:07Z;\n\"a\"\"b\";-7;7;0.1;true;\"2021-03-04;05:06\"\n"; b.String() != expected {
+		t.Errorf("got %q; want %q", b.String(), expected)
+	}
+}
+
+func TestRecordBuilderAllocs(t *testing.T) {
+	w := NewWriter(io.Discard, ',', true)
+	ts := time.Now()
+	allocs := testing.AllocsPerRun(100, func() {
+		w.Record().Str("x").Int(42).Float(3.14, 2).Time(ts, time.RFC3339).Null().End()
+	})
+	if allocs != 0 {
+		t.Errorf("got %v allocations per record; want 0", allocs)
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"bytes"
+	"io"
+)
+
+// Canonicalize writes all records from r to w in a byte-stable canonical form,
+// so that CSV files stored in a version control system produce minimal diffs
+// (two inputs with the same records always give the same output):
+//   - values are separated by a comma and records are terminated by \n (including the last one),
+//   - values are quoted only when they contain a comma, a double quote, \r or \n
+//     (or when the record is made of a single empty value, which would otherwise be an empty line, see BlankLineAsRecord),
+//   - double quotes are escaped by doubling them (backslash escapes of the input are decoded),
+//   - line breaks embedded in values (\r\n or \r) are normalized to \n,
+//   - there is no byte order mark, empty lines and comments (see Reader.Comment) are dropped,
+//   - values are otherwise kept as is (no trimming, no number reformatting and the header is not sorted).
+//
+// The dialect of the input is specified by configuring r.
+func Canonicalize(r *Reader, w io.Writer) error {
+	wr := NewWriter(w, ',', true)
+	wr.NormalizeNewlines = true
+	for first := true; ; first = false {
+		fields, err := r.ReadRecord()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if first && len(fields) > 0 {
+			fields[0] = bytes.TrimPrefix(fields[0], []byte("\uFEFF"))
+		}
+		if len(fields) == 1 && len(fields[0]) == 0 {
+			wr.WriteQuoted(fields[0], true)
+			wr.EndOfRecord()
+		} else {
+			wr.WriteFields(fields)
+		}
+		if err = wr.Err(); err != nil {
+			return err
+		}
+	}
+	wr.Flush()
+	return wr.Err()
+}
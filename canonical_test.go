@@ -0,0 +1,57 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+var canonicalTests = []struct {
+	Name   string
+	Input  string
+	Sep    byte
+	Escape byte
+	Blank  BlankLinePolicy
+	Output string
+}{
+	{Name: "Canonical", Input: "a,b\n1,\"x,y\"\n", Output: "a,b\n1,\"x,y\"\n"},
+	{Name: "TrailingNewline", Input: "a,b\r\n1,2", Output: "a,b\n1,2\n"},
+	{Name: "UselessQuotes", Input: "\"a\",\"b\"\n\"\",\" c \"\n", Output: "a,b\n, c \n"},
+	{Name: "EmbeddedNewlines", Input: "a\r\n\"x\r\ny\rz\"\r\n", Output: "a\n\"x\ny\nz\"\n"},
+	{Name: "Semicolon", Input: "a;b,c\n", Sep: ';', Output: "a,\"b,c\"\n"},
+	{Name: "Escape", Input: "a|b\\|c|d\\\"\n", Sep: '|', Escape: '\\', Output: "a,b|c,\"d\"\"\"\n"},
+	{Name: "BOM", Input: "\uFEFFa,b\n\n1,2\n", Output: "a,b\n1,2\n"},
+	{Name: "EmptyRecord", Input: "a\n\nb\n", Blank: BlankLineAsRecord, Output: "a\n\"\"\nb\n"},
+}
+
+func TestCanonicalize(t *testing.T) {
+	for _, tt := range canonicalTests {
+		sep := tt.Sep
+		if sep == 0 {
+			sep = ','
+		}
+		r := NewReader(strings.NewReader(tt.Input), sep, tt.Escape == 0, false)
+		r.Escape = tt.Escape
+		r.BlankLines = tt.Blank
+		var b bytes.Buffer
+		if err := Canonicalize(r, &b); err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.Name, err)
+			continue
+		}
+		if b.String() != tt.Output {
+			t.Errorf("%s: got %q; want %q", tt.Name, b.String(), tt.Output)
+		}
+		var again bytes.Buffer
+		r = DefaultReader(bytes.NewReader(b.Bytes()))
+		r.BlankLines = tt.Blank
+		if err := Canonicalize(r, &again); err != nil || again.String() != b.String() {
+			t.Errorf("%s: canonical form is not stable: %q", tt.Name, again.String())
+		}
+	}
+}
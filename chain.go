@@ -0,0 +1,165 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+)
+
+// ErrChainBroken is the error reported by VerifyChain when a record of an audit log
+// has been altered, removed, inserted or reordered.
+var ErrChainBroken = errors.New("yacr: broken audit chain")
+
+// chainError details ErrChainBroken.
+type chainError struct {
+	seq uint64 // sequence number of the offending record
+	msg string
+}
+
+func (e *chainError) Error() string {
+	return fmt.Sprintf("%s at record %d: %s", ErrChainBroken, e.seq, e.msg)
+}
+
+func (e *chainError) Is(target error) bool {
+	return target == ErrChainBroken
+}
+
+// ChainWriter writes an append-only audit log: each record is prefixed by its sequence number (first is 1)
+// and followed by its hash (hex-encoded SHA-256 of the record chained with the hash of the previous one),
+// so that any alteration, removal, insertion or reordering of records is detected by VerifyChain:
+//
+//	seq,values...,hash
+//
+// The removal of the last records can only be detected by comparing the hash of the last record (see Head)
+// with a copy kept elsewhere.
+// The Writer must not alter values (see Writer.SanitizeFormulas and Writer.TextProtection).
+type ChainWriter struct {
+	w      *Writer
+	h      hash.Hash
+	seq    uint64   // sequence number of the last record
+	prev   []byte   // hash of the last record (empty before the first one)
+	rec    [][]byte // sequence number and values of the record being written
+	num    []byte   // sequence number being written
+	sum    []byte   // hash being written
+	sumHex []byte   // hex-encoded hash being written
+	buf    []byte
+}
+
+// NewChainWriter returns a writer of a new audit log to w (see Resume to append to an existing one).
+func NewChainWriter(w *Writer) *ChainWriter {
+	return &ChainWriter{w: w, h: sha256.New()}
+}
+
+// WriteHeader writes the header of the log (names being the names of the values).
+// It must be called before the first record (and not when the log is resumed).
+func (cw *ChainWriter) WriteHeader(names ...string) bool {
+	cw.w.WriteString("seq")
+	for _, name := range names {
+		cw.w.WriteString(name)
+	}
+	cw.w.WriteString("hash")
+	cw.w.EndOfRecord()
+	return cw.w.err == nil
+}
+
+// WriteFields appends one record made of values.
+// The chain is not advanced when the record cannot be written.
+// Returns false when an error occurred (see Writer.Err).
+func (cw *ChainWriter) WriteFields(values [][]byte) bool {
+	cw.num = strconv.AppendUint(cw.num[:0], cw.seq+1, 10)
+	cw.rec = append(append(cw.rec[:0], cw.num), values...)
+	cw.sum, cw.buf = chainHash(cw.h, cw.prev, cw.rec, cw.buf, cw.sum[:0])
+	cw.sumHex = hex.AppendEncode(cw.sumHex[:0], cw.sum)
+	for _, field := range cw.rec {
+		cw.w.Write(field)
+	}
+	cw.w.Write(cw.sumHex)
+	cw.w.EndOfRecord()
+	if cw.w.err != nil {
+		return false
+	}
+	cw.prev = append(cw.prev[:0], cw.sum...)
+	cw.seq++
+	return true
+}
+
+// Resume verifies the existing log read by src (see VerifyChain) and continues it:
+// new records are chained to its last record.
+func (cw *ChainWriter) Resume(src RecordSource, header bool) error {
+	seq, head, err := verifyChain(src, header)
+	if err != nil {
+		return err
+	}
+	cw.seq, cw.prev = seq, head
+	return nil
+}
+
+// Seq returns the sequence number of the last record.
+func (cw *ChainWriter) Seq() uint64 {
+	return cw.seq
+}
+
+// Head returns the hash (hex-encoded) of the last record (empty when there is none).
+func (cw *ChainWriter) Head() string {
+	return hex.EncodeToString(cw.prev)
+}
+
+// VerifyChain checks the integrity of the audit log read by src (see ChainWriter)
+// and returns the sequence number and the hash (hex-encoded) of its last record.
+// The first record is skipped when header is true.
+// Broken chains are reported as ErrChainBroken (with the sequence number of the offending record).
+func VerifyChain(src RecordSource, header bool) (uint64, string, error) {
+	seq, head, err := verifyChain(src, header)
+	return seq, hex.EncodeToString(head), err
+}
+
+func verifyChain(src RecordSource, header bool) (uint64, []byte, error) {
+	h := sha256.New()
+	var seq uint64
+	var prev, sum, buf, expected []byte
+	for first := true; ; first = false {
+		fields, err := src.ReadRecord()
+		if err == io.EOF {
+			return seq, prev, nil
+		} else if err != nil {
+			return seq, prev, err
+		}
+		if first && header {
+			continue
+		}
+		if len(fields) < 2 {
+			return seq, prev, &chainError{seq + 1, "missing sequence number or hash"}
+		}
+		if n, err := strconv.ParseUint(string(fields[0]), 10, 64); err != nil || n != seq+1 {
+			return seq, prev, &chainError{seq + 1, fmt.Sprintf("unexpected sequence number %q", fields[0])}
+		}
+		last := len(fields) - 1
+		if expected, err = hex.AppendDecode(expected[:0], fields[last]); err != nil {
+			return seq, prev, &chainError{seq + 1, fmt.Sprintf("invalid hash %q", fields[last])}
+		}
+		sum, buf = chainHash(h, prev, fields[:last], buf, sum[:0])
+		if !bytes.Equal(sum, expected) {
+			return seq, prev, &chainError{seq + 1, "hash mismatch"}
+		}
+		prev = append(prev[:0], sum...)
+		seq++
+	}
+}
+
+// chainHash appends to dst the hash of the exact bytes of the record chained with prev (the hash of the previous record,
+// which can be overwritten) and returns it with buf (a reusable scratch buffer).
+func chainHash(h hash.Hash, prev []byte, fields [][]byte, buf, dst []byte) ([]byte, []byte) {
+	h.Reset()
+	h.Write(prev)
+	buf = writeRecordHash(h, fields, false, buf)
+	return h.Sum(dst), buf
+}
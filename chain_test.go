@@ -0,0 +1,94 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+func writeAuditLog(t *testing.T, records [][]string) (string, string) {
+	var b bytes.Buffer
+	w := DefaultWriter(&b)
+	cw := NewChainWriter(w)
+	cw.WriteHeader("user", "action")
+	for _, record := range records {
+		fields := make([][]byte, len(record))
+		for i, value := range record {
+			fields[i] = []byte(value)
+		}
+		if !cw.WriteFields(fields) {
+			t.Fatal(w.Err())
+		}
+	}
+	w.Flush()
+	return b.String(), cw.Head()
+}
+
+func TestChainWriter(t *testing.T) {
+	log, head := writeAuditLog(t, [][]string{{"alice", "login"}, {"bob", "delete,all"}, {"alice", "logout"}})
+	lines := strings.Split(log, "\n")
+	if len(lines) != 5 || lines[0] != "seq,user,action,hash" || !strings.HasPrefix(lines[2], "2,bob,\"delete,all\",") {
+		t.Fatalf("unexpected log: %q", log)
+	}
+	seq, h, err := VerifyChain(DefaultReader(strings.NewReader(log)), true)
+	if err != nil || seq != 3 || h != head {
+		t.Errorf("got (%d, %s, %v); want (3, %s, nil)", seq, h, err, head)
+	}
+
+	var tamperTests = []struct {
+		Name string
+		Log  string
+		Seq  uint64
+	}{
+		{"Altered", strings.Replace(log, "bob", "eve", 1), 1},
+		{"Removed", strings.Join(append(lines[:2:2], lines[3:]...), "\n"), 1},
+		{"Reordered", strings.Join([]string{lines[0], lines[2], lines[1], lines[3]}, "\n"), 0},
+		{"Rehashed", strings.Replace(log, lines[3], "3,alice,logout,"+strings.Repeat("0", 64), 1), 2},
+	}
+	for _, test := range tamperTests {
+		seq, _, err := VerifyChain(DefaultReader(strings.NewReader(test.Log)), true)
+		if !errors.Is(err, ErrChainBroken) || seq != test.Seq {
+			t.Errorf("%s: got (%d, %v); want (%d, %v)", test.Name, seq, err, test.Seq, ErrChainBroken)
+		}
+	}
+
+	var b bytes.Buffer
+	b.WriteString(log)
+	w := DefaultWriter(&b)
+	cw := NewChainWriter(w)
+	if err := cw.Resume(DefaultReader(strings.NewReader(log)), true); err != nil {
+		t.Fatal(err)
+	}
+	cw.WriteFields([][]byte{[]byte("carol"), []byte("login")})
+	w.Flush()
+	if seq, h, err := VerifyChain(DefaultReader(&b), true); err != nil || seq != 4 || h != cw.Head() {
+		t.Errorf("got (%d, %s, %v) after resume; want (4, %s, nil)", seq, h, err, cw.Head())
+	}
+}
+
+func TestChainWriterExactBytes(t *testing.T) {
+	log, _ := writeAuditLog(t, [][]string{{"alice", "note\r\nline"}})
+	if _, _, err := VerifyChain(DefaultReader(strings.NewReader(log)), true); err != nil {
+		t.Fatal(err)
+	}
+	altered := strings.Replace(log, "note\r\nline", "note\nline", 1)
+	if _, _, err := VerifyChain(DefaultReader(strings.NewReader(altered)), true); !errors.Is(err, ErrChainBroken) {
+		t.Errorf("got %v; want %v", err, ErrChainBroken)
+	}
+
+	var b bytes.Buffer
+	cw := NewChainWriter(NewWriter(&b, ',', false))
+	if cw.WriteFields([][]byte{[]byte("a,b")}) {
+		t.Fatal("error expected for separator in unquoted value")
+	}
+	if cw.Seq() != 0 || cw.Head() != "" {
+		t.Errorf("got (%d, %s); want the chain not advanced", cw.Seq(), cw.Head())
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"context"
+	"io"
+)
+
+// ReadChan reads the records of src in a new goroutine and sends them (copied) on the returned channel,
+// buffered with size records: the reading is paused when the consumer does not keep up (back-pressure).
+// The records channel is closed at the end of the input, on error or when ctx is done;
+// then the error (nil at the end of the input, ctx.Err() when canceled) is sent on the (buffered) error channel.
+func ReadChan(ctx context.Context, src RecordSource, size int) (<-chan []string, <-chan error) {
+	records := make(chan []string, size)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(errc)
+		err := readChan(ctx, src, records)
+		close(records)
+		errc <- err
+	}()
+	return records, errc
+}
+
+func readChan(ctx context.Context, src RecordSource, records chan<- []string) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		fields, err := src.ReadRecord()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		select {
+		case records <- copyStrings(fields):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// WriteChan writes the records received on records to w until the channel is closed or ctx is done, and flushes w.
+// Returns the number of records written and the first error (ctx.Err() when canceled).
+// On error, the remaining records are not drained: the producer should also watch ctx.
+func WriteChan(ctx context.Context, w *Writer, records <-chan []string) (int, error) {
+	n := 0
+	for {
+		select {
+		case record, ok := <-records:
+			if !ok {
+				w.Flush()
+				return n, w.Err()
+			}
+			for _, value := range record {
+				if !w.WriteString(value) {
+					return n, w.Err()
+				}
+			}
+			w.EndOfRecord()
+			if err := w.Err(); err != nil {
+				return n, err
+			}
+			n++
+		case <-ctx.Done():
+			w.Flush()
+			return n, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+func TestChanPipeline(t *testing.T) {
+	input := "a,b\n\"c,d\",e\nf,\n"
+	records, errc := ReadChan(context.Background(), DefaultReader(strings.NewReader(input)), 1)
+	b := &bytes.Buffer{}
+	n, err := WriteChan(context.Background(), DefaultWriter(b), records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 || b.String() != input {
+		t.Errorf("got %d, %q; want 3, %q", n, b.String(), input)
+	}
+
+	records, errc = ReadChan(context.Background(), DefaultReader(strings.NewReader("a\n\"b")), 0)
+	for range records {
+	}
+	if err = <-errc; err == nil {
+		t.Error("error expected")
+	}
+}
+
+func TestChanCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	records, errc := ReadChan(ctx, DefaultReader(strings.NewReader(strings.Repeat("a,b\n", 100))), 0)
+	<-records
+	cancel()
+	for range records { // drain records sent before cancellation
+	}
+	if err := <-errc; err != context.Canceled {
+		t.Errorf("got %v; want %v", err, context.Canceled)
+	}
+
+	in := make(chan []string)
+	if _, err := WriteChan(ctx, DefaultWriter(&bytes.Buffer{}), in); err != context.Canceled {
+		t.Errorf("got %v; want %v", err, context.Canceled)
+	}
+}
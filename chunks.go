@@ -0,0 +1,49 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"iter"
+	"unsafe"
+)
+
+// ReadChunks reads all the records like a ReadAll but yields them in batches whose (approximate) memory footprint
+// (content plus string and slice headers) is bounded by maxBytes, so that big files can be processed with bounded memory.
+// A batch contains at least one record (even when it exceeds the budget alone).
+// Batches are not reused: they can be retained by the caller.
+func ReadChunks(r *Reader, maxBytes int) iter.Seq2[[][]string, error] {
+	const (
+		recordOverhead = int(unsafe.Sizeof([]string(nil)))
+		fieldOverhead  = int(unsafe.Sizeof(""))
+	)
+	return func(yield func([][]string, error) bool) {
+		var chunk [][]string
+		size := 0
+		for {
+			record, err := readStrings(r)
+			if err != nil {
+				yield(nil, err)
+				return
+			} else if record == nil {
+				break
+			}
+			n := recordOverhead
+			for _, field := range record {
+				n += fieldOverhead + len(field)
+			}
+			if len(chunk) > 0 && size+n > maxBytes {
+				if !yield(chunk, nil) {
+					return
+				}
+				chunk, size = nil, 0
+			}
+			chunk = append(chunk, record)
+			size += n
+		}
+		if len(chunk) > 0 {
+			yield(chunk, nil)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+func TestReadChunks(t *testing.T) {
+	input := "a,b\nc,d\n\"" + strings.Repeat("x", 200) + "\"\ne,f\n"
+	var sizes []int
+	var records [][]string
+	for chunk, err := range ReadChunks(DefaultReader(strings.NewReader(input)), 150) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		sizes = append(sizes, len(chunk))
+		records = append(records, chunk...)
+	}
+	if want := []int{2, 1, 1}; !reflect.DeepEqual(sizes, want) {
+		t.Errorf("got %v; want %v", sizes, want)
+	}
+	if len(records) != 4 || records[3][1] != "f" {
+		t.Errorf("unexpected records: %q", records)
+	}
+
+	for _, err := range ReadChunks(DefaultReader(strings.NewReader("\"a")), 100) {
+		if err == nil {
+			t.Error("error expected")
+		}
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import "io"
+
+// Open opens the named file (compressed or not, see Zopen) and returns a Reader configured with the dialect d.
+// The file is closed by the Reader's Close method.
+func Open(path string, d Dialect) (*Reader, error) {
+	f, err := Zopen(path)
+	if err != nil {
+		return nil, err
+	}
+	s := d.NewReader(f)
+	s.Source = path
+	s.OnClose(f)
+	return s, nil
+}
+
+// OnClose registers c to be closed by Close.
+// Closers are closed in the reverse order of their registration
+// (so the outermost layer, like a decompressor, is registered last and closed first).
+func (s *Reader) OnClose(c io.Closer) {
+	s.closers = append(s.closers, c)
+}
+
+// Close closes the resources owned by the Reader (see OnClose).
+// All closers are called, even when one fails, and the first error is returned.
+// Subsequent calls do nothing.
+func (s *Reader) Close() error {
+	var err error
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		if cerr := s.closers[i].Close(); err == nil {
+			err = cerr
+		}
+	}
+	s.closers = nil
+	return err
+}
+
+// closerFunc adapts a function to the io.Closer interface.
+type closerFunc func() error
+
+func (f closerFunc) Close() error {
+	return f()
+}
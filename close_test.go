@@ -0,0 +1,92 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+type closer struct {
+	name   string
+	err    error
+	closed *[]string
+}
+
+func (c closer) Close() error {
+	*c.closed = append(*c.closed, c.name)
+	return c.err
+}
+
+func TestClose(t *testing.T) {
+	var closed []string
+	r := DefaultReader(strings.NewReader("a,b\n"))
+	errFile := errors.New("file")
+	r.OnClose(closer{"file", errFile, &closed})
+	r.OnClose(closer{"decompressor", nil, &closed})
+	r.OnClose(closer{"transcoder", errors.New("transcoder"), &closed})
+	if err := r.Close(); err == nil || err.Error() != "transcoder" {
+		t.Errorf("got %v; want transcoder error", err)
+	}
+	if want := []string{"transcoder", "decompressor", "file"}; !reflect.DeepEqual(closed, want) {
+		t.Errorf("got %q; want %q", closed, want)
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("unexpected error on second Close: %v", err)
+	}
+	if len(closed) != 3 {
+		t.Errorf("closers called more than once: %q", closed)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.csv.gz")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := gzip.NewWriter(f)
+	if _, err = io.WriteString(zw, "a,b\nc,d\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err = zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Open(path, DialectDefault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records [][]string
+	for {
+		fields, err := r.ReadRecord()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, toStrings(fields))
+	}
+	if want := [][]string{{"a", "b"}, {"c", "d"}}; !reflect.DeepEqual(records, want) {
+		t.Errorf("got %q; want %q", records, want)
+	}
+	if err = r.Close(); err != nil {
+		t.Error(err)
+	}
+	if _, err = Open(filepath.Join(t.TempDir(), "missing.csv"), DialectDefault); err == nil {
+		t.Error("error expected")
+	}
+}
@@ -0,0 +1,480 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Command yacr-gen generates the code decoding and encoding struct types as CSV records without reflection:
+// (pointers to) the generated types implement yacr.RecordUnmarshaler and yacr.RecordMarshaler,
+// which are used by yacr.TypedReader and yacr.TypedWriter instead of the reflection-based codec.
+//
+//	//go:generate yacr-gen -type User,Order [-output user_yacr.go]
+//
+// The package of the current directory (or of the directory specified as argument) is loaded
+// and the code is written to <type>_yacr.go (the first type in lower case) unless -output is specified.
+//
+// Fields are bound to columns like with yacr.Reader.ScanStruct, `yacr:"name,options"` tags included
+// ("-", sep, omitempty and default options).
+// Supported field types are strings, []byte, booleans, integers, floats, []string (list values)
+// and the types implementing yacr.FieldUnmarshaler/yacr.FieldMarshaler or encoding.TextUnmarshaler/encoding.TextMarshaler.
+// Embedded structs, inline and rest options and map fields are not supported.
+// Unlike ScanStruct, the generated code ignores the settings of the Reader (NullValues, UseDefaults, Coercions...)
+// and default values also apply to missing columns.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+func main() {
+	typeNames := flag.String("type", "", "comma-separated list of struct type names (mandatory)")
+	output := flag.String("output", "", "output file name (default is <type>_yacr.go in the package directory)")
+	flag.Parse()
+	if *typeNames == "" || flag.NArg() > 1 {
+		fmt.Fprintln(os.Stderr, "usage: yacr-gen -type T[,T...] [-output file] [dir]")
+		os.Exit(2)
+	}
+	dir := "."
+	if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+	names := strings.Split(*typeNames, ",")
+	if *output == "" {
+		*output = filepath.Join(dir, strings.ToLower(names[0])+"_yacr.go")
+	}
+	src, err := generate(dir, filepath.Base(*output), names)
+	if err == nil {
+		err = os.WriteFile(*output, src, 0644)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "yacr-gen:", err)
+		os.Exit(1)
+	}
+}
+
+// generate returns the (formatted) code of the types named names in the package of dir,
+// the file named output (previously generated) being ignored.
+func generate(dir, output string, names []string) ([]byte, error) {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range bp.GoFiles {
+		if name == output {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	var typeErr error // the package may not compile until the code is generated
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		Error: func(err error) {
+			if typeErr == nil {
+				typeErr = err
+			}
+		},
+	}
+	pkg, _ := conf.Check(bp.ImportPath, fset, files, nil)
+
+	g := &generator{pkg: pkg, imports: map[string]bool{yacrPath: true}}
+	for _, name := range names {
+		obj, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+		if !ok {
+			return nil, fmt.Errorf("type %s not found in %s", name, dir)
+		}
+		st, ok := obj.Type().Underlying().(*types.Struct)
+		if !ok {
+			return nil, fmt.Errorf("invalid type %s: %s (struct expected)", name, obj.Type().Underlying())
+		}
+		fields, err := structFields(name, st, typeErr)
+		if err != nil {
+			return nil, err
+		}
+		g.generate(name, fields)
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by yacr-gen; DO NOT EDIT.\n\npackage %s\n\nimport (\n", pkg.Name())
+	paths := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, j int) bool { // standard packages first
+		if std, other := isStd(paths[i]), isStd(paths[j]); std != other {
+			return std
+		}
+		return paths[i] < paths[j]
+	})
+	for i, path := range paths {
+		if i > 0 && isStd(paths[i-1]) && !isStd(path) {
+			src.WriteByte('\n')
+		}
+		fmt.Fprintf(&src, "%q\n", path)
+	}
+	src.WriteString(")\n")
+	src.Write(g.buf.Bytes())
+	return format.Source(src.Bytes())
+}
+
+const yacrPath = "github.com/gwenn/yacr"
+
+// isStd tells if the package path denotes a package of the standard library.
+func isStd(path string) bool {
+	return !strings.Contains(strings.SplitN(path, "/", 2)[0], ".")
+}
+
+// codec denotes how a field is decoded or encoded.
+type codec int
+
+const (
+	unsupported codec = iota
+	stringCodec
+	bytesCodec
+	boolCodec
+	intCodec
+	uintCodec
+	floatCodec
+	listCodec  // []string
+	fieldCodec // yacr.FieldUnmarshaler or yacr.FieldMarshaler
+	textCodec  // encoding.TextUnmarshaler or encoding.TextMarshaler
+)
+
+// field is a struct field bound to a column.
+type field struct {
+	name   string // Go name
+	column string
+	typ    types.Type
+	bits   int // size of numbers (0 for int and uint)
+
+	decode, encode codec
+
+	sep       byte
+	omitEmpty bool
+	def       string // value decoded when the field is empty or missing (see hasDef)
+	hasDef    bool
+}
+
+var (
+	stringType = types.Typ[types.String]
+	byteType   = types.Typ[types.Byte]
+)
+
+// basicCodec returns the codec of values of type t (ignoring their methods) and their size (for numbers).
+func basicCodec(t types.Type) (codec, int) {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		info := u.Info()
+		switch {
+		case info&types.IsString != 0:
+			return stringCodec, 0
+		case info&types.IsBoolean != 0:
+			return boolCodec, 0
+		case info&types.IsUnsigned != 0:
+			return uintCodec, bitSizes[u.Kind()]
+		case info&types.IsInteger != 0:
+			return intCodec, bitSizes[u.Kind()]
+		case info&types.IsFloat != 0:
+			return floatCodec, bitSizes[u.Kind()]
+		}
+	case *types.Slice:
+		if types.Identical(u.Elem(), byteType) {
+			return bytesCodec, 0
+		} else if types.Identical(u.Elem(), stringType) {
+			return listCodec, 0
+		}
+	}
+	return unsupported, 0
+}
+
+var bitSizes = map[types.BasicKind]int{
+	types.Int8: 8, types.Int16: 16, types.Int32: 32, types.Int64: 64,
+	types.Uint8: 8, types.Uint16: 16, types.Uint32: 32, types.Uint64: 64,
+	types.Float32: 32, types.Float64: 64,
+}
+
+// hasMethod tells if the method named name is in the method set of *t.
+func hasMethod(t types.Type, name string) bool {
+	obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(t), false, nil, name)
+	_, ok := obj.(*types.Func)
+	return ok
+}
+
+// structFields returns the fields of the struct st (named name) bound to columns.
+// typeErr is the first type-checking error (reported when a field type is invalid).
+func structFields(name string, st *types.Struct, typeErr error) ([]*field, error) {
+	var fields []*field
+	for i := 0; i < st.NumFields(); i++ {
+		sf := st.Field(i)
+		raw := reflect.StructTag(st.Tag(i)).Get("yacr")
+		if raw == "-" || (!sf.Exported() && !sf.Embedded()) {
+			continue
+		}
+		if sf.Type() == types.Typ[types.Invalid] {
+			return nil, fmt.Errorf("invalid type of field %s.%s: %v", name, sf.Name(), typeErr)
+		}
+		f := &field{name: sf.Name(), column: sf.Name(), typ: sf.Type(), sep: '|'}
+		f.decode, f.bits = basicCodec(f.typ)
+		f.encode = f.decode
+		if hasMethod(f.typ, "UnmarshalCSVField") {
+			f.decode = fieldCodec
+		} else if hasMethod(f.typ, "UnmarshalText") {
+			f.decode = textCodec
+		}
+		if hasMethod(f.typ, "MarshalCSVField") {
+			f.encode = fieldCodec
+		} else if hasMethod(f.typ, "MarshalText") {
+			f.encode = textCodec
+		}
+		if sf.Embedded() {
+			if _, ok := f.typ.Underlying().(*types.Struct); ok && f.decode != fieldCodec && f.decode != textCodec {
+				return nil, fmt.Errorf("unsupported embedded struct %s.%s (use a named field)", name, sf.Name())
+			} else if !sf.Exported() {
+				continue
+			}
+		}
+		if f.decode == unsupported || f.encode == unsupported {
+			return nil, fmt.Errorf("unsupported type of field %s.%s: %s", name, sf.Name(), f.typ)
+		}
+		if i := strings.Index(raw, ",default="); i >= 0 {
+			f.def, f.hasDef = raw[i+len(",default="):], true
+			raw = raw[:i]
+		}
+		tag := strings.Split(raw, ",")
+		if tag[0] != "" {
+			f.column = tag[0]
+		}
+		for _, opt := range tag[1:] {
+			switch {
+			case strings.HasPrefix(opt, "sep="):
+				if len(opt) != len("sep=")+1 {
+					return nil, fmt.Errorf("invalid separator in tag of field %s.%s: %q", name, sf.Name(), opt)
+				}
+				f.sep = opt[len("sep=")]
+			case opt == "omitempty":
+				if _, ok := zero(f.typ, "x", nil); !ok {
+					return nil, fmt.Errorf("unsupported omitempty option for field %s.%s: %s", name, sf.Name(), f.typ)
+				}
+				f.omitEmpty = true
+			default:
+				return nil, fmt.Errorf("unsupported option in tag of field %s.%s: %q", name, sf.Name(), opt)
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// zero returns the expression testing if the expression x of type t is the zero value.
+func zero(t types.Type, x string, qf types.Qualifier) (string, bool) {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsString != 0:
+			return x + ` == ""`, true
+		case u.Info()&types.IsBoolean != 0:
+			return "!" + x, true
+		case u.Info()&types.IsNumeric != 0:
+			return x + " == 0", true
+		}
+	case *types.Slice, *types.Map:
+		return "len(" + x + ") == 0", true
+	case *types.Pointer, *types.Interface, *types.Chan, *types.Signature:
+		return x + " == nil", true
+	case *types.Struct, *types.Array:
+		if types.Comparable(t) {
+			return x + " == (" + types.TypeString(t, qf) + "{})", true
+		}
+	}
+	return "", false
+}
+
+// zeroValue returns the zero value of the type t in the generated code.
+func (g *generator) zeroValue(t types.Type) string {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsString != 0:
+			return `""`
+		case u.Info()&types.IsBoolean != 0:
+			return "false"
+		case u.Info()&types.IsNumeric != 0:
+			return "0"
+		}
+	case *types.Struct, *types.Array:
+		return g.typeName(t) + "{}"
+	}
+	return "nil"
+}
+
+// generator accumulates the generated code.
+type generator struct {
+	buf     bytes.Buffer
+	pkg     *types.Package
+	imports map[string]bool // paths of the imported packages
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// qualifier qualifies the types of other packages (which are imported).
+func (g *generator) qualifier(pkg *types.Package) string {
+	if pkg == g.pkg {
+		return ""
+	}
+	g.imports[pkg.Path()] = true
+	return pkg.Name()
+}
+
+// typeName returns the name of t in the generated code.
+func (g *generator) typeName(t types.Type) string {
+	return types.TypeString(t, g.qualifier)
+}
+
+// convert returns the conversion of the expression x of type t to the type to (unless they are identical).
+func (g *generator) convert(to types.Type, t types.Type, x string) string {
+	if types.Identical(to, t) {
+		return x
+	}
+	return g.typeName(to) + "(" + x + ")"
+}
+
+// generate generates the methods of the struct named name.
+func (g *generator) generate(name string, fields []*field) {
+	g.printf("\n// CSVColumns implements yacr.RecordUnmarshaler and yacr.RecordMarshaler.\n")
+	g.printf("func (*%s) CSVColumns() []string {\nreturn []string{", name)
+	for i, f := range fields {
+		if i > 0 {
+			g.printf(", ")
+		}
+		g.printf("%q", f.column)
+	}
+	g.printf("}\n}\n")
+
+	g.printf("\n// UnmarshalCSVRecord implements yacr.RecordUnmarshaler.\n")
+	g.printf("func (v *%s) UnmarshalCSVRecord(fields [][]byte, cols []int) error {\n", name)
+	for j, f := range fields {
+		if f.hasDef {
+			g.printf("{\ni, field := cols[%d], []byte(%q)\n", j, f.def)
+			g.printf("if i >= 0 && i < len(fields) && len(fields[i]) > 0 {\nfield = fields[i]\n}\n")
+		} else {
+			g.printf("if i := cols[%d]; i >= 0 && i < len(fields) {\nfield := fields[i]\n", j)
+		}
+		if f.omitEmpty && f.decode != stringCodec && f.decode != bytesCodec && f.decode != listCodec {
+			// zero value written as an empty field
+			g.printf("if len(field) == 0 {\n%s = %s\n} else {\n", "v."+f.name, g.zeroValue(f.typ))
+			g.decode(f)
+			g.printf("}\n")
+		} else {
+			g.decode(f)
+		}
+		g.printf("}\n")
+	}
+	g.printf("return nil\n}\n")
+
+	g.printf("\n// MarshalCSVRecord implements yacr.RecordMarshaler.\n")
+	g.printf("func (v *%s) MarshalCSVRecord(w *yacr.Writer) error {\n", name)
+	for _, f := range fields {
+		if f.omitEmpty {
+			cond, _ := zero(f.typ, "v."+f.name, g.qualifier)
+			g.printf("if %s {\nw.Write(nil)\n} else {\n", cond)
+			g.encode(f)
+			g.printf("}\n")
+		} else if f.encode == fieldCodec || f.encode == textCodec { // scope of b and err
+			g.printf("{\n")
+			g.encode(f)
+			g.printf("}\n")
+		} else {
+			g.encode(f)
+		}
+	}
+	g.printf("w.EndOfRecord()\nreturn w.Err()\n}\n")
+}
+
+// decode generates the code decoding the bytes of field into the field f
+// (the column index being i).
+func (g *generator) decode(f *field) {
+	x := "v." + f.name
+	fieldErr := fmt.Sprintf("return &yacr.FieldError{Column: i + 1, Name: %q, Err: err}", f.column)
+	switch f.decode {
+	case stringCodec:
+		g.printf("%s = %s(field)\n", x, g.typeName(f.typ))
+	case bytesCodec:
+		g.printf("%s = append(%s(nil), field...)\n", x, g.typeName(f.typ))
+	case listCodec:
+		g.printf("if len(field) == 0 {\n%s = %s{}\n} else {\n", x, g.typeName(f.typ))
+		g.imports["strings"] = true
+		g.printf("%s = strings.Split(string(field), %q)\n}\n", x, string(f.sep))
+	case boolCodec, intCodec, uintCodec, floatCodec:
+		g.imports["strconv"] = true
+		var t types.Type
+		switch f.decode {
+		case boolCodec:
+			g.printf("n, err := strconv.ParseBool(string(field))\n")
+			t = types.Typ[types.Bool]
+		case intCodec:
+			g.printf("n, err := strconv.ParseInt(string(field), 10, %d)\n", f.bits)
+			t = types.Typ[types.Int64]
+		case uintCodec:
+			g.printf("n, err := strconv.ParseUint(string(field), 10, %d)\n", f.bits)
+			t = types.Typ[types.Uint64]
+		case floatCodec:
+			g.printf("n, err := strconv.ParseFloat(string(field), %d)\n", f.bits)
+			t = types.Typ[types.Float64]
+		}
+		g.printf("if err != nil {\n%s\n}\n", fieldErr)
+		g.printf("%s = %s\n", x, g.convert(f.typ, t, "n"))
+	case fieldCodec:
+		g.printf("if err := %s.UnmarshalCSVField(field); err != nil {\n%s\n}\n", x, fieldErr)
+	case textCodec:
+		g.printf("if err := %s.UnmarshalText(field); err != nil {\n%s\n}\n", x, fieldErr)
+	}
+}
+
+// encode generates the code writing the field f.
+func (g *generator) encode(f *field) {
+	x := "v." + f.name
+	switch f.encode {
+	case stringCodec:
+		g.printf("w.WriteString(%s)\n", g.convert(stringType, f.typ, x))
+	case bytesCodec:
+		g.printf("w.Write(%s)\n", g.convert(types.NewSlice(byteType), f.typ, x))
+	case listCodec:
+		g.imports["strings"] = true
+		g.printf("w.WriteString(strings.Join(%s, %q))\n", g.convert(types.NewSlice(stringType), f.typ, x), string(f.sep))
+	case boolCodec:
+		g.imports["strconv"] = true
+		g.printf("w.WriteString(strconv.FormatBool(%s))\n", g.convert(types.Typ[types.Bool], f.typ, x))
+	case intCodec:
+		g.imports["strconv"] = true
+		g.printf("w.WriteString(strconv.FormatInt(%s, 10))\n", g.convert(types.Typ[types.Int64], f.typ, x))
+	case uintCodec:
+		g.imports["strconv"] = true
+		g.printf("w.WriteString(strconv.FormatUint(%s, 10))\n", g.convert(types.Typ[types.Uint64], f.typ, x))
+	case floatCodec:
+		g.imports["strconv"] = true
+		g.printf("w.WriteString(strconv.FormatFloat(%s, 'f', -1, %d))\n", g.convert(types.Typ[types.Float64], f.typ, x), f.bits)
+	case fieldCodec:
+		g.printf("b, err := %s.MarshalCSVField()\nif err != nil {\nreturn err\n}\nw.Write(b)\n", x)
+	case textCodec:
+		g.printf("b, err := %s.MarshalText()\nif err != nil {\nreturn err\n}\nw.Write(b)\n", x)
+	}
+}
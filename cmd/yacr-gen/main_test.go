@@ -0,0 +1,67 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestGenerate(t *testing.T) {
+	src, err := generate("testdata", "user_yacr.go", []string{"User", "Order"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := filepath.Join("testdata", "user_yacr.go.golden")
+	if *update {
+		if err = os.WriteFile(golden, src, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, expected) {
+		t.Errorf("generated code differs from %s (run with -update to regenerate it):\n%s", golden, src)
+	}
+}
+
+var generateErrorTests = []struct {
+	Name  string
+	Src   string
+	Error string
+}{
+	{"NotFound", "type T struct{}", "type U not found"},
+	{"NotStruct", "type U int", "invalid type U: int (struct expected)"},
+	{"Map", "type U struct{ M map[string]string }", "unsupported type of field U.M: map[string]string"},
+	{"Pointer", "type U struct{ P *int }", "unsupported type of field U.P: *int"},
+	{"Embedded", "type T struct{ A int }\ntype U struct{ T }", "unsupported embedded struct U.T"},
+	{"Inline", "type T struct{ A int }\ntype U struct{ T T `yacr:\"t_,inline\"` }", "unsupported type of field U.T"},
+	{"Rest", "type U struct{ R []string `yacr:\",rest\"` }", `unsupported option in tag of field U.R: "rest"`},
+	{"Separator", "type U struct{ L []string `yacr:\",sep=||\"` }", `invalid separator in tag of field U.L: "sep=||"`},
+}
+
+func TestGenerateErrors(t *testing.T) {
+	for _, test := range generateErrorTests {
+		dir := t.TempDir()
+		src := "package p\n\n" + test.Src + "\n"
+		if err := os.WriteFile(filepath.Join(dir, "p.go"), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := generate(dir, "u_yacr.go", []string{"U"})
+		if err == nil {
+			t.Errorf("%s: error expected", test.Name)
+		} else if !strings.Contains(err.Error(), test.Error) {
+			t.Errorf("%s: got error %q; want %q", test.Name, err, test.Error)
+		}
+	}
+}
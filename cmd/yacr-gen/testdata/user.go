@@ -0,0 +1,51 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package users
+
+import (
+	"strconv"
+	"time"
+)
+
+//go:generate yacr-gen -type User,Order
+
+type Status string
+
+type Level int8
+
+type Cents int64
+
+func (c *Cents) UnmarshalCSVField(b []byte) error {
+	f, err := strconv.ParseFloat(string(b), 64)
+	*c = Cents(f * 100)
+	return err
+}
+
+func (c Cents) MarshalCSVField() ([]byte, error) {
+	return strconv.AppendFloat(nil, float64(c)/100, 'f', 2, 64), nil
+}
+
+type User struct {
+	ID      int64         `yacr:"id"`
+	Name    string        `yacr:"name"`
+	Email   []byte        `yacr:"email,omitempty"`
+	Admin   bool          `yacr:"admin"`
+	Level   Level         `yacr:"level,default=1"`
+	Score   float32       `yacr:"score"`
+	Visits  uint          `yacr:"visits,omitempty"`
+	Tags    []string      `yacr:"tags,sep=;"`
+	Status  Status        `yacr:"status,default=active"`
+	Balance Cents         `yacr:"balance"`
+	Created time.Time     `yacr:"created,omitempty"`
+	Timeout time.Duration `yacr:"timeout"`
+	Comment string        `yacr:"-"`
+	secret  string
+}
+
+type Order struct {
+	ID     int
+	UserID int64 `yacr:"user_id"`
+	Total  float64
+}
@@ -0,0 +1,335 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Command yacr-git helps versioning CSV files with git.
+//
+// As a textconv filter, it renders a CSV file as an aligned table (for diff, log -p, show...):
+//
+//	yacr-git textconv file.csv
+//
+// As a merge driver, it merges CSV files record by record, records being identified by key columns
+// (the first column by default): concurrent changes of different records, or of different fields
+// of the same record, do not conflict.
+// The result is written to the current version (%A) and the exit status is 1 when there are conflicts
+// (marked like git does around the conflicting records):
+//
+//	yacr-git merge [-key id,...] [-sep ,] ancestor current other
+//
+// Configuration:
+//
+//	git config diff.csv.textconv "yacr-git textconv"
+//	git config merge.csv.name "CSV record merge"
+//	git config merge.csv.driver "yacr-git merge -key id %O %A %B"
+//	echo "*.csv diff=csv merge=csv" >> .gitattributes
+//
+// The separator is guessed when not specified.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/gwenn/yacr"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	var err error
+	conflicts := false
+	switch os.Args[1] {
+	case "textconv":
+		fs := flag.NewFlagSet("textconv", flag.ExitOnError)
+		sep := fs.String("sep", "", "values separator (guessed when empty)")
+		fs.Parse(os.Args[2:])
+		if fs.NArg() != 1 {
+			usage()
+		}
+		err = textconv(fs.Arg(0), separator(*sep), os.Stdout)
+	case "merge":
+		fs := flag.NewFlagSet("merge", flag.ExitOnError)
+		sep := fs.String("sep", "", "values separator (guessed when empty)")
+		key := fs.String("key", "", "comma-separated names of the key columns (first column when empty)")
+		fs.Parse(os.Args[2:])
+		if fs.NArg() != 3 {
+			usage()
+		}
+		var keys []string
+		if *key != "" {
+			keys = strings.Split(*key, ",")
+		}
+		conflicts, err = mergeFiles(fs.Arg(0), fs.Arg(1), fs.Arg(2), separator(*sep), keys)
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "yacr-git:", err)
+		os.Exit(2)
+	} else if conflicts {
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: yacr-git textconv [-sep ,] file")
+	fmt.Fprintln(os.Stderr, "       yacr-git merge [-key id,...] [-sep ,] ancestor current other")
+	os.Exit(2)
+}
+
+func separator(sep string) byte {
+	if sep == "" {
+		return 0
+	} else if sep == `\t` {
+		return '\t'
+	}
+	return sep[0]
+}
+
+func newReader(r io.Reader, sep byte) *yacr.Reader {
+	return yacr.NewReader(r, sep, true, sep == 0)
+}
+
+func textconv(path string, sep byte, w io.Writer) error {
+	f, err := yacr.Zopen(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return yacr.ToTable(newReader(f, sep), w, yacr.TableOptions{Header: true})
+}
+
+// table is the content of one version of a file.
+type table struct {
+	header  []string
+	keys    []string            // in order
+	records map[string][]string // by key
+	d       yacr.Dialect
+}
+
+func readTable(r io.Reader, sep byte, keyNames []string) (*table, error) {
+	yr := newReader(r, sep)
+	t := &table{records: make(map[string][]string)}
+	var keyIndexes []int
+	for {
+		fields, err := yr.ReadRecord()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		record := make([]string, len(fields))
+		for i, field := range fields {
+			record[i] = string(field)
+		}
+		if t.header == nil {
+			t.header = record
+			if keyIndexes, err = indexes(record, keyNames); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		key := recordKey(record, keyIndexes)
+		if _, ok := t.records[key]; ok {
+			return nil, fmt.Errorf("duplicate key: %q", strings.Replace(key, "\x00", ",", -1))
+		}
+		t.keys = append(t.keys, key)
+		t.records[key] = record
+	}
+	t.d = yr.Dialect()
+	return t, nil
+}
+
+func indexes(header, names []string) ([]int, error) {
+	if len(names) == 0 {
+		return []int{0}, nil
+	}
+	var indexes []int
+	for _, name := range names {
+		index := -1
+		for i, h := range header {
+			if h == name {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("unknown key column: %s", name)
+		}
+		indexes = append(indexes, index)
+	}
+	return indexes, nil
+}
+
+func recordKey(record []string, indexes []int) string {
+	values := make([]string, len(indexes))
+	for i, index := range indexes {
+		if index < len(record) {
+			values[i] = record[index]
+		}
+	}
+	return strings.Join(values, "\x00")
+}
+
+// merged is one record of the result: either resolved (record, nil when deleted) or conflicting.
+type merged struct {
+	record         []string
+	conflict       bool
+	ours, theirs   []string // nil when deleted
+	ourOK, theirOK bool
+}
+
+// merge merges the changes made from base to ours and theirs (three-way merge by key).
+func merge(base, ours, theirs *table) ([]string, []merged, error) {
+	if !equal(ours.header, theirs.header) {
+		// a change of the header can only be merged when there is no other change on the other side
+		if equal(ours.header, base.header) && sameRecords(ours, base) {
+			return theirs.header, resolved(theirs), nil
+		} else if equal(theirs.header, base.header) && sameRecords(theirs, base) {
+			return ours.header, resolved(ours), nil
+		}
+		return nil, nil, fmt.Errorf("headers differ (%q and %q)", ours.header, theirs.header)
+	}
+	var result []merged
+	seen := make(map[string]bool)
+	keys := append(append([]string(nil), ours.keys...), theirs.keys...)
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		o, oOK := base.records[key]
+		a, aOK := ours.records[key]
+		b, bOK := theirs.records[key]
+		switch {
+		case aOK == bOK && equal(a, b), aOK == oOK && equal(a, o): // same change or no change on our side
+			if bOK {
+				result = append(result, merged{record: b})
+			}
+		case bOK == oOK && equal(b, o): // no change on their side
+			if aOK {
+				result = append(result, merged{record: a})
+			}
+		case aOK && bOK && oOK && len(a) == len(o) && len(b) == len(o):
+			if record, ok := mergeFields(o, a, b); ok {
+				result = append(result, merged{record: record})
+				continue
+			}
+			fallthrough
+		default:
+			result = append(result, merged{conflict: true, ours: a, theirs: b, ourOK: aOK, theirOK: bOK})
+		}
+	}
+	return ours.header, result, nil
+}
+
+// mergeFields merges the changes of different fields of a record.
+func mergeFields(o, a, b []string) ([]string, bool) {
+	record := make([]string, len(o))
+	for i := range o {
+		switch {
+		case a[i] == b[i], a[i] == o[i]:
+			record[i] = b[i]
+		case b[i] == o[i]:
+			record[i] = a[i]
+		default:
+			return nil, false
+		}
+	}
+	return record, true
+}
+
+func sameRecords(t, base *table) bool {
+	if len(t.records) != len(base.records) {
+		return false
+	}
+	for key, record := range t.records {
+		if !equal(record, base.records[key]) {
+			return false
+		}
+	}
+	return true
+}
+
+func resolved(t *table) []merged {
+	result := make([]merged, len(t.keys))
+	for i, key := range t.keys {
+		result[i] = merged{record: t.records[key]}
+	}
+	return result
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// writeMerged writes the result, conflicts being surrounded by git-like markers.
+func writeMerged(b *bytes.Buffer, d yacr.Dialect, header []string, result []merged) (conflicts bool, err error) {
+	w := d.NewWriter(b)
+	write := func(record []string) {
+		for _, value := range record {
+			w.WriteString(value)
+		}
+		w.EndOfRecord()
+	}
+	marker := func(m string) {
+		w.Flush()
+		b.WriteString(m + "\n")
+	}
+	write(header)
+	for _, m := range result {
+		if !m.conflict {
+			write(m.record)
+			continue
+		}
+		conflicts = true
+		marker("<<<<<<< ours")
+		if m.ourOK {
+			write(m.ours)
+		}
+		marker("=======")
+		if m.theirOK {
+			write(m.theirs)
+		}
+		marker(">>>>>>> theirs")
+	}
+	w.Flush()
+	return conflicts, w.Err()
+}
+
+// mergeFiles merges ancestor, current and other into current.
+func mergeFiles(ancestor, current, other string, sep byte, keys []string) (bool, error) {
+	var tables [3]*table
+	for i, path := range []string{ancestor, current, other} {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return false, err
+		}
+		if tables[i], err = readTable(bytes.NewReader(content), sep, keys); err != nil {
+			return false, fmt.Errorf("%s: %s", path, err)
+		}
+	}
+	header, result, err := merge(tables[0], tables[1], tables[2])
+	if err != nil {
+		return false, err
+	}
+	var b bytes.Buffer
+	conflicts, err := writeMerged(&b, tables[1].d, header, result)
+	if err != nil {
+		return false, err
+	}
+	return conflicts, os.WriteFile(current, b.Bytes(), 0644)
+}
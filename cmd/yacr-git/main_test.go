@@ -0,0 +1,133 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var mergeTests = []struct {
+	Name               string
+	Base, Ours, Theirs string
+	Keys               []string
+	Output             string
+	Conflicts          bool
+	Error              string
+}{
+	{
+		Name:   "DifferentRecords",
+		Base:   "id,name,qty\n1,a,1\n2,b,2\n3,c,3\n",
+		Ours:   "id,name,qty\n1,A,1\n2,b,2\n3,c,3\n4,d,4\n",
+		Theirs: "id,name,qty\n1,a,1\n3,c,30\n5,e,5\n",
+		Output: "id,name,qty\n1,A,1\n3,c,30\n4,d,4\n5,e,5\n",
+	},
+	{
+		Name:   "DifferentFields",
+		Base:   "id,name,qty\n1,a,1\n",
+		Ours:   "id,name,qty\n1,A,1\n",
+		Theirs: "id,name,qty\n1,a,10\n",
+		Output: "id,name,qty\n1,A,10\n",
+	},
+	{
+		Name:      "Conflict",
+		Base:      "id,name\n1,a\n2,b\n",
+		Ours:      "id,name\n1,A\n2,b\n",
+		Theirs:    "id,name\n1,\"x,y\"\n",
+		Output:    "id,name\n<<<<<<< ours\n1,A\n=======\n1,\"x,y\"\n>>>>>>> theirs\n",
+		Conflicts: true,
+	},
+	{
+		Name:      "DeleteModify",
+		Base:      "id,name\n1,a\n",
+		Ours:      "id,name\n",
+		Theirs:    "id,name\n1,b\n",
+		Output:    "id,name\n<<<<<<< ours\n=======\n1,b\n>>>>>>> theirs\n",
+		Conflicts: true,
+	},
+	{
+		Name:   "CompositeKey",
+		Base:   "k1;k2;v\r\na;1;x\r\na;2;y\r\n",
+		Ours:   "k1;k2;v\r\na;1;X\r\na;2;y\r\n",
+		Theirs: "k1;k2;v\r\na;1;x\r\na;2;Y\r\n",
+		Keys:   []string{"k1", "k2"},
+		Output: "k1;k2;v\r\na;1;X\r\na;2;Y\r\n",
+	},
+	{
+		Name:   "AddAdd",
+		Base:   "",
+		Ours:   "id,name\n1,a\n",
+		Theirs: "id,name\n2,b\n",
+		Output: "id,name\n1,a\n2,b\n",
+	},
+	{
+		Name:   "HeaderChange",
+		Base:   "id,name\n1,a\n",
+		Ours:   "id,name\n1,a\n",
+		Theirs: "id,name,qty\n1,a,1\n",
+		Output: "id,name,qty\n1,a,1\n",
+	},
+	{
+		Name:   "HeadersDiffer",
+		Base:   "id,name\n1,a\n",
+		Ours:   "id,name\n1,b\n",
+		Theirs: "id,name,qty\n1,a,1\n",
+		Error:  "headers differ",
+	},
+	{
+		Name:   "DuplicateKey",
+		Base:   "id,name\n1,a\n1,b\n",
+		Ours:   "id,name\n",
+		Theirs: "id,name\n",
+		Error:  "duplicate key: \"1\"",
+	},
+}
+
+func TestMerge(t *testing.T) {
+	for _, tt := range mergeTests {
+		dir := t.TempDir()
+		var paths []string
+		for _, v := range []struct{ name, content string }{{"base", tt.Base}, {"ours", tt.Ours}, {"theirs", tt.Theirs}} {
+			path := filepath.Join(dir, v.name)
+			if err := os.WriteFile(path, []byte(v.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			paths = append(paths, path)
+		}
+		conflicts, err := mergeFiles(paths[0], paths[1], paths[2], 0, tt.Keys)
+		if tt.Error != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.Error) {
+				t.Errorf("%s: got %v; want %q", tt.Name, err, tt.Error)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.Name, err)
+			continue
+		}
+		if conflicts != tt.Conflicts {
+			t.Errorf("%s: got conflicts %t; want %t", tt.Name, conflicts, tt.Conflicts)
+		}
+		if content, _ := os.ReadFile(paths[1]); string(content) != tt.Output {
+			t.Errorf("%s: got %q; want %q", tt.Name, content, tt.Output)
+		}
+	}
+}
+
+func TestTextconv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "t.csv")
+	if err := os.WriteFile(path, []byte("id;name\n1;abc\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := textconv(path, 0, &b); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(b.String(), "id  name\n") || !strings.Contains(b.String(), "1   abc") {
+		t.Errorf("unexpected table: %q", b.String())
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Command yacr provides record-oriented utilities for CSV files
+// (multiline quoted records are never split, unlike with line-oriented tools):
+//
+//	yacr sample [-n 1000] [-seed 42] [-sep ,] [file]
+//	yacr freq [-n 10] [-col 1] [-sep ,] [file]
+//
+// sample writes the header and n records chosen uniformly at random (in input order).
+// freq writes the n most common values of a column (specified by name or index, first is 1)
+// with their number of occurrences (see yacr.ValueCounts).
+//
+// The input is read from the standard input when no file is specified
+// (gzip/bzip2 files are supported, see yacr.Zopen).
+// The separator is guessed when not specified and the output uses the dialect of the input.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gwenn/yacr"
+)
+
+// command runs a sub-command with its arguments.
+type command func(args []string, stdin io.Reader, stdout io.Writer) error
+
+var commands = map[string]command{
+	"freq":   freq,
+	"sample": sample,
+}
+
+func main() {
+	if len(os.Args) < 2 || commands[os.Args[1]] == nil {
+		usage()
+	}
+	if err := commands[os.Args[1]](os.Args[2:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "yacr:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintf(os.Stderr, "usage: yacr command [options] [file]\ncommands: %v\n", names)
+	os.Exit(2)
+}
+
+// input opens the file named by the single argument of fs (stdin when there is none or "-").
+func input(fs *flag.FlagSet, stdin io.Reader, sep string) (*yacr.Reader, error) {
+	if fs.NArg() > 1 {
+		return nil, fmt.Errorf("%s: too many arguments", fs.Name())
+	}
+	var s byte
+	if sep == `\t` {
+		s = '\t'
+	} else if sep != "" {
+		s = sep[0]
+	}
+	if fs.NArg() == 0 || fs.Arg(0) == "-" {
+		return yacr.NewReader(stdin, s, true, s == 0), nil
+	}
+	f, err := yacr.Zopen(fs.Arg(0))
+	if err != nil {
+		return nil, err
+	}
+	r := yacr.NewReader(f, s, true, s == 0)
+	r.OnClose(f)
+	return r, nil
+}
+
+// output writes the records with the dialect of r.
+func output(w io.Writer, r *yacr.Reader, header []string, records [][]string) error {
+	wr := r.Dialect().NewWriter(w)
+	for _, record := range append([][]string{header}, records...) {
+		for _, value := range record {
+			wr.WriteString(value)
+		}
+		wr.EndOfRecord()
+	}
+	wr.Flush()
+	return wr.Err()
+}
+
+func sample(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("sample", flag.ContinueOnError)
+	n := fs.Int("n", 1000, "number of records")
+	seed := fs.Int64("seed", 0, "seed of the random generator (current time when 0)")
+	sep := fs.String("sep", "", "values separator (guessed when empty)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	r, err := input(fs, stdin, *sep)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	header, records, err := yacr.Sample(r, *n, rand.New(rand.NewSource(*seed)))
+	if err != nil || header == nil {
+		return err
+	}
+	return output(stdout, r, header, records)
+}
+
+func freq(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("freq", flag.ContinueOnError)
+	n := fs.Int("n", 10, "number of values")
+	col := fs.String("col", "1", "name or index (first is 1) of the column")
+	sep := fs.String("sep", "", "values separator (guessed when empty)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	r, err := input(fs, stdin, *sep)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if err = r.ScanHeaders(); err != nil {
+		return err
+	}
+	index, ok := r.Headers[*col]
+	if !ok {
+		if index, err = strconv.Atoi(*col); err != nil {
+			return fmt.Errorf("unknown column: %s", *col)
+		}
+	}
+	counts, err := yacr.ValueCounts(r, index, *n)
+	if err != nil {
+		return err
+	}
+	name := *col
+	for h, i := range r.Headers {
+		if i == index {
+			name = h
+		}
+	}
+	records := make([][]string, len(counts))
+	for i, c := range counts {
+		records[i] = []string{c.Value, strconv.Itoa(c.Count)}
+	}
+	return output(stdout, r, []string{name, "count"}, records)
+}
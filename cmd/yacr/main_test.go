@@ -0,0 +1,48 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSample(t *testing.T) {
+	input := "id;text\r\n" + strings.Repeat("1;\"a\r\nb\"\r\n2;c\r\n", 50)
+	var b bytes.Buffer
+	if err := sample([]string{"-n", "3", "-seed", "42"}, strings.NewReader(input), &b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	if !strings.HasPrefix(out, "id;text\r\n") || strings.Count(out, "\r\n") < 4 {
+		t.Errorf("unexpected sample: %q", out)
+	}
+	var again bytes.Buffer
+	if err := sample([]string{"-n", "3", "-seed", "42", "-"}, strings.NewReader(input), &again); err != nil {
+		t.Fatal(err)
+	} else if again.String() != out {
+		t.Errorf("sample must be reproducible: %q; %q", again.String(), out)
+	}
+	if err := sample([]string{"a.csv", "b.csv"}, nil, &b); err == nil {
+		t.Error("error expected")
+	}
+}
+
+func TestFreq(t *testing.T) {
+	input := "id,color\n1,red\n2,blue\n3,red\n"
+	for _, col := range []string{"color", "2"} {
+		var b bytes.Buffer
+		if err := freq([]string{"-col", col, "-sep", ","}, strings.NewReader(input), &b); err != nil {
+			t.Fatal(err)
+		}
+		if expected := "color,count\nred,2\nblue,1\n"; b.String() != expected {
+			t.Errorf("got %q; want %q", b.String(), expected)
+		}
+	}
+	if err := freq([]string{"-col", "size"}, strings.NewReader(input), new(bytes.Buffer)); err == nil {
+		t.Error("error expected")
+	}
+}
@@ -0,0 +1,592 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"bytes"
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// structField describes how one struct field is bound to a column.
+type structField struct {
+	index []int  // see reflect.Value.FieldByIndex
+	name  string // column name (tag name or field name)
+	sep   byte   // separator of list values ([]string fields) or of pairs (map[string]string fields)
+	kvSep byte   // separator between key and value (map[string]string fields)
+	rest  bool   // true when the field collects extra fields
+	self  bool   // true when the field decodes itself (see FieldUnmarshaler)
+
+	omitEmpty bool   // true when zero values are written as empty fields
+	def       string // value decoded when the field is empty or missing (see hasDef)
+	hasDef    bool
+}
+
+// structFields caches the (parsed) fields of each struct type.
+var structFields sync.Map // map[reflect.Type][]*structField
+
+// typeFields returns the fields of the struct type t.
+// The `yacr:"name,options"` tag specifies the column name (default is the field name) and options:
+//
+//	sep=X	separator of list values for []string fields (default is '|')
+//		or of pairs for map[string]string fields (default is ';')
+//	kvsep=X	separator between key and value for map[string]string fields (default is '=')
+//	rest	[]string field collecting the extra fields (see ExtraFields)
+//	inline	struct field whose fields are flattened, the name being the prefix of their column names
+//		(e.g. `yacr:"address_,inline"`)
+//	omitempty	zero values are written as empty fields (and empty fields decoded as zero values)
+//	default=X	value decoded when the field is empty or missing (see FillMissingFields)
+//		(it must be the last option: X is the rest of the tag, commas included)
+//
+// The field is skipped when the tag is "-".
+// Embedded structs and exported embedded struct pointers (except the ones implementing FieldUnmarshaler
+// or encoding.TextUnmarshaler) are flattened without prefix unless they are tagged with a name but not inlined.
+// Struct pointers (embedded or inlined) are allocated when decoding and their fields written as empty fields when nil.
+func typeFields(t reflect.Type) ([]*structField, error) {
+	if fields, ok := structFields.Load(t); ok {
+		return fields.([]*structField), nil
+	}
+	fields, err := appendFields(nil, t, nil, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	structFields.Store(t, fields)
+	return fields, nil
+}
+
+var (
+	textUnmarshalerType  = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	fieldUnmarshalerType = reflect.TypeOf((*FieldUnmarshaler)(nil)).Elem()
+)
+
+// isUnmarshaler tells if values of type t decode themselves (see FieldUnmarshaler).
+func isUnmarshaler(t reflect.Type) bool {
+	pt := reflect.PtrTo(t)
+	return pt.Implements(fieldUnmarshalerType) || pt.Implements(textUnmarshalerType)
+}
+
+// appendFields appends the fields of the struct type t (at index of the outermost struct, flattened in parents)
+// whose column names are prefixed by prefix.
+func appendFields(fields []*structField, t reflect.Type, index []int, prefix string, parents []reflect.Type) ([]*structField, error) {
+	parents = append(parents, t)
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		st := sf.Type // flattened struct type
+		if st.Kind() == reflect.Ptr {
+			st = st.Elem()
+		}
+		embedded := sf.Anonymous && st.Kind() == reflect.Struct && !isUnmarshaler(st)
+		if sf.PkgPath != "" && (!embedded || st != sf.Type) { // unexported (embedded pointers cannot be allocated)
+			continue
+		}
+		f := &structField{index: append(index[:len(index):len(index)], sf.Index...), name: sf.Name, sep: '|', kvSep: '=', self: isUnmarshaler(sf.Type)}
+		if sf.Type.Kind() == reflect.Map {
+			f.sep = ';'
+		}
+		raw := sf.Tag.Get("yacr")
+		if i := strings.Index(raw, ",default="); i >= 0 {
+			f.def, f.hasDef = raw[i+len(",default="):], true
+			raw = raw[:i]
+		}
+		tag := strings.Split(raw, ",")
+		if len(tag) == 1 && tag[0] == "-" {
+			continue
+		} else if tag[0] != "" {
+			f.name = tag[0]
+			embedded = false
+		}
+		inline := embedded
+		for _, opt := range tag[1:] {
+			switch {
+			case strings.HasPrefix(opt, "sep="):
+				if len(opt) != len("sep=")+1 {
+					return nil, fmt.Errorf("invalid separator in tag of field %s.%s: %q", t, sf.Name, opt)
+				}
+				f.sep = opt[len("sep=")]
+			case strings.HasPrefix(opt, "kvsep="):
+				if len(opt) != len("kvsep=")+1 {
+					return nil, fmt.Errorf("invalid separator in tag of field %s.%s: %q", t, sf.Name, opt)
+				}
+				f.kvSep = opt[len("kvsep=")]
+			case opt == "rest":
+				if sf.Type != reflect.TypeOf([]string(nil)) {
+					return nil, fmt.Errorf("invalid type of rest field %s.%s: %s ([]string expected)", t, sf.Name, sf.Type)
+				}
+				f.rest = true
+			case opt == "omitempty":
+				f.omitEmpty = true
+			case opt == "inline":
+				if st.Kind() != reflect.Struct {
+					return nil, fmt.Errorf("invalid type of inline field %s.%s: %s (struct or struct pointer expected)", t, sf.Name, sf.Type)
+				}
+				inline = true
+			default:
+				return nil, fmt.Errorf("unknown option in tag of field %s.%s: %q", t, sf.Name, opt)
+			}
+		}
+		if inline {
+			for _, parent := range parents {
+				if parent == st {
+					return nil, fmt.Errorf("recursive inline field %s.%s: %s", t, sf.Name, sf.Type)
+				}
+			}
+			var err error
+			if fields, err = appendFields(fields, st, f.index, prefix+tag[0], parents); err != nil {
+				return nil, err
+			}
+			continue
+		} else if sf.PkgPath != "" { // unexported embedded struct
+			continue
+		}
+		f.name = prefix + f.name
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// fieldByIndex returns the nested field of the struct v at index (see reflect.Value.FieldByIndex),
+// allocating the nil struct pointers on the way.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// fieldByIndexOK returns the nested field of the struct v at index or false when a struct pointer on the way is nil.
+func fieldByIndexOK(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// structBinding caches the binding of a struct type to the columns of a Reader.
+type structBinding struct {
+	typ     reflect.Type
+	headers map[string]int
+	cols    []*structField // struct field by column index (nil when the column is not bound)
+	rest    *structField   // struct field collecting extra fields
+}
+
+func (s *Reader) bind(t reflect.Type) (*structBinding, error) {
+	if b := s.binding; b != nil && b.typ == t && sameHeaders(b.headers, s.Headers) {
+		return b, nil
+	}
+	fields, err := typeFields(t)
+	if err != nil {
+		return nil, err
+	}
+	b := &structBinding{typ: t, headers: s.Headers}
+	for _, f := range fields {
+		if f.rest {
+			b.rest = f
+		} else if s.Headers == nil { // by position
+			b.cols = append(b.cols, f)
+		} else { // by name
+			index, ok := s.HeaderIndex(f.name)
+			if !ok {
+				continue
+			}
+			for len(b.cols) < index {
+				b.cols = append(b.cols, nil)
+			}
+			b.cols[index-1] = f
+		}
+	}
+	s.binding = b
+	return b, nil
+}
+
+// sameHeaders tells if both maps are the same instance.
+func sameHeaders(m1, m2 map[string]int) bool {
+	return reflect.ValueOf(m1).Pointer() == reflect.ValueOf(m2).Pointer()
+}
+
+// ScanStruct decodes one record into the struct pointed to by v.
+// When Headers are loaded (see ScanHeaders), struct fields are bound to columns by name
+// (the `yacr:"name"` tag or the field name) and unknown columns are ignored;
+// otherwise fields are bound by position.
+// Missing fields are handled according to MissingFields and extra fields according to ExtraFields
+// (they are collected by the `rest` tag option when specified).
+// []string fields are decoded from list values (see List and the `sep` tag option),
+// map[string]string fields from key=value pairs (see Pairs and the `sep`/`kvsep` tag options)
+// and fields implementing FieldUnmarshaler or encoding.TextUnmarshaler (like time.Time or net.IP) by their method.
+// Null values (see NullValues and ColumnNullValues) are decoded as zero values
+// and empty values as the default of the field when specified (see the `default` tag option).
+// Fields tagged "-" are skipped.
+// Empty lines are ignored/skipped.
+// Returns io.EOF when there is no more record.
+func (s *Reader) ScanStruct(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("unsupported type %T (pointer to struct expected)", v)
+	}
+	rv = rv.Elem()
+	b, err := s.bind(rv.Type())
+	if err != nil {
+		return err
+	}
+	fields, err := s.ReadRecord()
+	if err != nil {
+		return err
+	}
+	for i, field := range fields {
+		if i >= len(b.cols) {
+			break
+		}
+		f := b.cols[i]
+		if f == nil {
+			continue
+		} else if len(field) == 0 && f.hasDef {
+			field = []byte(f.def)
+		}
+		dv := fieldByIndex(rv, f.index)
+		if s.isNull(i+1, field) || len(field) == 0 && f.omitEmpty && !decodesEmpty(dv, f) { // zero value written as an empty field
+			dv.Set(reflect.Zero(dv.Type()))
+			continue
+		}
+		if err = s.decodeField(dv, field, f); err != nil {
+			return &FieldError{Line: s.recordLine(), Column: i + 1, Name: f.name, Err: err}
+		}
+		if s.Coercions != nil {
+			s.checkCoercion(field, dv, i+1, f.name)
+		}
+	}
+	expected := len(b.cols)
+	if s.Headers != nil {
+		expected = len(s.Headers)
+	}
+	if len(fields) > expected && s.ExtraFields == RejectExtraFields {
+		return s.extraFieldsErr(len(fields), expected)
+	} else if b.rest != nil {
+		var rest []string
+		if len(fields) > expected {
+			rest = make([]string, len(fields)-expected)
+			for i, field := range fields[expected:] {
+				rest[i] = string(field)
+			}
+		}
+		fieldByIndex(rv, b.rest.index).Set(reflect.ValueOf(rest))
+	}
+	if len(fields) >= expected || s.MissingFields == LeaveMissingFields {
+		return nil
+	} else if s.MissingFields == RejectMissingFields {
+		return s.missingFieldsErr(len(fields), expected)
+	}
+	for i := len(fields); i < len(b.cols); i++ {
+		f := b.cols[i]
+		if f == nil {
+			continue
+		}
+		dv := fieldByIndex(rv, f.index)
+		def, ok := s.Defaults[f.name]
+		if !ok && f.hasDef {
+			def, ok = f.def, true
+		}
+		if !ok {
+			dv.Set(reflect.Zero(dv.Type()))
+		} else if err = s.decodeField(dv, []byte(def), f); err != nil {
+			return fmt.Errorf("%s: %s (default value)", f.name, err)
+		}
+	}
+	return nil
+}
+
+// decodesEmpty tells if the struct field dv decodes an empty value by itself (strings, lists, maps).
+func decodesEmpty(dv reflect.Value, f *structField) bool {
+	switch dv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		return !f.self
+	}
+	return false
+}
+
+// isNull tells if the field value of the column col (first is 1) is one of its null values
+// (see NullValues and ColumnNullValues).
+func (s *Reader) isNull(col int, field []byte) bool {
+	nulls, ok := s.ColumnNullValues[col]
+	if !ok {
+		nulls = s.NullValues
+	}
+	for _, null := range nulls {
+		if string(field) == null {
+			return true
+		}
+	}
+	return false
+}
+
+// recordLine returns the line number of the last record read (assuming it is not multiline).
+func (s *Reader) recordLine() int {
+	if s.eor { // newline already consumed
+		return s.lineno - 1
+	}
+	return s.lineno
+}
+
+// decodeField decodes the raw value b into the struct field dv.
+func (s *Reader) decodeField(dv reflect.Value, b []byte, f *structField) (err error) {
+	if f.self && dv.CanAddr() {
+		switch u := dv.Addr().Interface().(type) {
+		case FieldUnmarshaler:
+			return u.UnmarshalCSVField(b)
+		case encoding.TextUnmarshaler:
+			return u.UnmarshalText(b)
+		}
+	}
+	switch dv.Kind() {
+	case reflect.String:
+		dv.SetString(string(b))
+		return nil
+	case reflect.Slice:
+		switch dv.Type().Elem().Kind() {
+		case reflect.Uint8: // []byte
+			dv.SetBytes(append([]byte(nil), b...))
+			return nil
+		case reflect.String: // list
+			dv.Set(reflect.ValueOf(splitList(b, f.sep)))
+			return nil
+		}
+		return fmt.Errorf("unsupported type: %s", dv.Type())
+	case reflect.Map:
+		if dv.Type().Key().Kind() != reflect.String || dv.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type: %s", dv.Type())
+		}
+		var m map[string]string
+		if m, err = splitPairs(b, f.sep, f.kvSep); err == nil {
+			dv.Set(reflect.ValueOf(m).Convert(dv.Type()))
+		}
+		return
+	}
+	v := string(b)
+	if s.UseDefaults && v == "" {
+		dv.Set(reflect.Zero(dv.Type()))
+		return nil
+	}
+	switch dv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		if i, err = strconv.ParseInt(v, 10, dv.Type().Bits()); err == nil {
+			dv.SetInt(i)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var i uint64
+		if i, err = strconv.ParseUint(v, 10, dv.Type().Bits()); err == nil {
+			dv.SetUint(i)
+		}
+	case reflect.Bool:
+		var b bool
+		if b, err = strconv.ParseBool(v); err == nil {
+			dv.SetBool(b)
+		}
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		if f, err = strconv.ParseFloat(v, dv.Type().Bits()); err == nil {
+			dv.SetFloat(f)
+		}
+	default:
+		return fmt.Errorf("unsupported type: %s", dv.Type())
+	}
+	return
+}
+
+// List splits the current field into a list of values separated by sep (e.g. "a|b|c").
+// An empty field gives an empty list.
+func (s *Reader) List(sep byte) []string {
+	return splitList(s.Bytes(), sep)
+}
+
+func splitList(b []byte, sep byte) []string {
+	if len(b) == 0 {
+		return []string{}
+	}
+	list := make([]string, 0, bytes.Count(b, []byte{sep})+1)
+	for {
+		i := bytes.IndexByte(b, sep)
+		if i < 0 {
+			return append(list, string(b))
+		}
+		list = append(list, string(b[:i]))
+		b = b[i+1:]
+	}
+}
+
+// Pairs parses the current field as a list of key/value pairs (e.g. "k1=v1;k2=v2"
+// with sep ';' and kvSep '=').
+// An empty field gives an empty map.
+// An error is returned when a pair has no kvSep.
+func (s *Reader) Pairs(sep, kvSep byte) (map[string]string, error) {
+	return splitPairs(s.Bytes(), sep, kvSep)
+}
+
+func splitPairs(b []byte, sep, kvSep byte) (map[string]string, error) {
+	m := make(map[string]string)
+	if len(b) == 0 {
+		return m, nil
+	}
+	for _, pair := range splitList(b, sep) {
+		i := strings.IndexByte(pair, kvSep)
+		if i < 0 {
+			return nil, fmt.Errorf("missing %q in pair: %q", kvSep, pair)
+		}
+		m[pair[:i]] = pair[i+1:]
+	}
+	return m, nil
+}
+
+// structValue returns the struct pointed to by v (or v itself).
+func structValue(v interface{}) (reflect.Value, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return rv, fmt.Errorf("unsupported type %T (struct expected)", v)
+	}
+	return rv, nil
+}
+
+// WriteStructHeader writes the column names of the struct type of v (a struct or a pointer to a struct)
+// in field order (see ScanStruct for the `yacr` tag).
+func (w *Writer) WriteStructHeader(v interface{}) bool {
+	rv, err := structValue(v)
+	if err != nil {
+		w.setErr(err)
+		return false
+	}
+	fields, err := typeFields(rv.Type())
+	if err != nil {
+		w.setErr(err)
+		return false
+	}
+	for _, f := range fields {
+		if !f.rest && !w.WriteString(f.name) {
+			return false
+		}
+	}
+	w.EndOfRecord()
+	return w.err == nil
+}
+
+// WriteStruct writes the fields of v (a struct or a pointer to a struct) as one record in field order,
+// so that it can be decoded by ScanStruct:
+// []string fields are encoded as list values (see the `sep` tag option),
+// map[string]string fields as key=value pairs sorted by key (see the `sep`/`kvsep` tag options)
+// and the values of the `rest` field are appended.
+// Zero values of fields with the `omitempty` tag option and nil pointers are written as empty fields
+// (non-nil pointers are dereferenced).
+// Fields implementing FieldMarshaler or encoding.TextMarshaler (with a value or pointer receiver)
+// are encoded by their method (so that time.Time, net.IP or UUID types work as is).
+// Other values are encoded like WriteValue does.
+func (w *Writer) WriteStruct(v interface{}) bool {
+	rv, err := structValue(v)
+	if err != nil {
+		w.setErr(err)
+		return false
+	}
+	fields, err := typeFields(rv.Type())
+	if err != nil {
+		w.setErr(err)
+		return false
+	}
+	var rest *structField
+	for _, f := range fields {
+		if f.rest {
+			rest = f
+		} else if dv, ok := fieldByIndexOK(rv, f.index); !ok { // nil struct pointer
+			if !w.Write(nil) {
+				return false
+			}
+		} else if !w.encodeField(dv, f) {
+			return false
+		}
+	}
+	if rest != nil {
+		if dv, ok := fieldByIndexOK(rv, rest.index); ok {
+			for _, value := range dv.Interface().([]string) {
+				if !w.WriteString(value) {
+					return false
+				}
+			}
+		}
+	}
+	w.EndOfRecord()
+	return w.err == nil
+}
+
+// marshaler returns the FieldMarshaler or encoding.TextMarshaler implemented by dv
+// (or by its address for pointer receivers) or nil.
+func marshaler(dv reflect.Value) interface{} {
+	candidates := []reflect.Value{dv}
+	if dv.CanAddr() {
+		candidates = append(candidates, dv.Addr())
+	}
+	for _, v := range candidates {
+		if m, ok := v.Interface().(FieldMarshaler); ok {
+			return m
+		}
+	}
+	for _, v := range candidates {
+		if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+			return m
+		}
+	}
+	return nil
+}
+
+// encodeField writes the struct field dv.
+func (w *Writer) encodeField(dv reflect.Value, f *structField) bool {
+	if f.omitEmpty && dv.IsZero() {
+		return w.Write(nil)
+	}
+	if dv.Kind() == reflect.Ptr {
+		if dv.IsNil() {
+			return w.Write(nil)
+		}
+		dv = dv.Elem()
+	}
+	if m := marshaler(dv); m != nil {
+		return w.WriteValue(m)
+	}
+	switch dv.Kind() {
+	case reflect.Slice:
+		switch dv.Type().Elem().Kind() {
+		case reflect.Uint8: // []byte
+			return w.Write(dv.Bytes())
+		case reflect.String: // list
+			list := make([]string, dv.Len())
+			for i := range list {
+				list[i] = dv.Index(i).String()
+			}
+			return w.WriteString(strings.Join(list, string(f.sep)))
+		}
+	case reflect.Map:
+		if dv.Type().Key().Kind() == reflect.String && dv.Type().Elem().Kind() == reflect.String {
+			keys := dv.MapKeys()
+			sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+			pairs := make([]string, len(keys))
+			for i, k := range keys {
+				pairs[i] = k.String() + string(f.kvSep) + dv.MapIndex(k).String()
+			}
+			return w.WriteString(strings.Join(pairs, string(f.sep)))
+		}
+	}
+	return w.WriteValue(dv.Interface())
+}
@@ -0,0 +1,335 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/gwenn/yacr"
+)
+
+type product struct {
+	ID    int      `yacr:"id"`
+	Name  string   `yacr:"name"`
+	Price float64  `yacr:"price"`
+	Tags  []string `yacr:"tags"`
+	Sizes []string `yacr:"sizes,sep=/"`
+	Stock uint8
+	note  string
+}
+
+func TestScanStruct(t *testing.T) {
+	r := DefaultReader(strings.NewReader("name,id,tags,sizes,Stock,ignored\nshirt,1,a|b,S/M,3,x\n\nhat,2,,L,0\n"))
+	if err := r.ScanHeaders(); err != nil {
+		t.Fatal(err)
+	}
+	var products []product
+	for {
+		var p product
+		if err := r.ScanStruct(&p); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		products = append(products, p)
+	}
+	want := []product{
+		{ID: 1, Name: "shirt", Tags: []string{"a", "b"}, Sizes: []string{"S", "M"}, Stock: 3},
+		{ID: 2, Name: "hat", Tags: []string{}, Sizes: []string{"L"}},
+	}
+	if !reflect.DeepEqual(products, want) {
+		t.Errorf("got %+v; want %+v", products, want)
+	}
+}
+
+func TestScanStructByPosition(t *testing.T) {
+	r := DefaultReader(strings.NewReader("1,shirt,9.99,a|b|c\n2,hat,x\n"))
+	var p product
+	if err := r.ScanStruct(&p); err != nil {
+		t.Fatal(err)
+	}
+	if want := (product{ID: 1, Name: "shirt", Price: 9.99, Tags: []string{"a", "b", "c"}}); !reflect.DeepEqual(p, want) {
+		t.Errorf("got %+v; want %+v", p, want)
+	}
+	if err := r.ScanStruct(&p); err == nil || !strings.Contains(err.Error(), "price") || !strings.Contains(err.Error(), "line 2, column 3") {
+		t.Errorf("error expected: %v", err)
+	}
+	if err := r.ScanStruct(p); err == nil {
+		t.Error("error expected for non-pointer")
+	}
+}
+
+func TestList(t *testing.T) {
+	r := DefaultReader(strings.NewReader("\"a;b;;c\",\n"))
+	var lists [][]string
+	for r.Scan() {
+		lists = append(lists, r.List(';'))
+	}
+	if want := [][]string{{"a", "b", "", "c"}, {}}; !reflect.DeepEqual(lists, want) {
+		t.Errorf("got %q; want %q", lists, want)
+	}
+}
+
+type cdr struct {
+	Caller string            `yacr:"caller"`
+	Attrs  map[string]string `yacr:"attrs"`
+	Extra  map[string]string `yacr:"extra,sep=|,kvsep=:"`
+}
+
+func TestScanStructPairs(t *testing.T) {
+	r := DefaultReader(strings.NewReader("caller,attrs,extra\n+331,cell=A1;dur=12,a:1|b:\n+332,,\n+333,x,\n"))
+	if err := r.ScanHeaders(); err != nil {
+		t.Fatal(err)
+	}
+	var c cdr
+	if err := r.ScanStruct(&c); err != nil {
+		t.Fatal(err)
+	}
+	if want := (cdr{Caller: "+331", Attrs: map[string]string{"cell": "A1", "dur": "12"}, Extra: map[string]string{"a": "1", "b": ""}}); !reflect.DeepEqual(c, want) {
+		t.Errorf("got %+v; want %+v", c, want)
+	}
+	if err := r.ScanStruct(&c); err != nil {
+		t.Fatal(err)
+	}
+	if want := (cdr{Caller: "+332", Attrs: map[string]string{}, Extra: map[string]string{}}); !reflect.DeepEqual(c, want) {
+		t.Errorf("got %+v; want %+v", c, want)
+	}
+	if err := r.ScanStruct(&c); err == nil || !strings.Contains(err.Error(), "attrs") {
+		t.Errorf("error expected: %v", err)
+	}
+}
+
+func TestPairs(t *testing.T) {
+	r := DefaultReader(strings.NewReader("k1=v1;k2=v=2\n"))
+	if !r.Scan() {
+		t.Fatal(r.Err())
+	}
+	m, err := r.Pairs(';', '=')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"k1": "v1", "k2": "v=2"}; !reflect.DeepEqual(m, want) {
+		t.Errorf("got %v; want %v", m, want)
+	}
+}
+
+func TestScanStructMissingFields(t *testing.T) {
+	r := DefaultReader(strings.NewReader("id,name,price,tags\n1,shirt\n2\n"))
+	r.MissingFields = FillMissingFields
+	r.Defaults = map[string]string{"price": "9.5", "tags": "new|sale"}
+	if err := r.ScanHeaders(); err != nil {
+		t.Fatal(err)
+	}
+	var p product
+	if err := r.ScanStruct(&p); err != nil {
+		t.Fatal(err)
+	}
+	if want := (product{ID: 1, Name: "shirt", Price: 9.5, Tags: []string{"new", "sale"}}); !reflect.DeepEqual(p, want) {
+		t.Errorf("got %+v; want %+v", p, want)
+	}
+	r.MissingFields = RejectMissingFields
+	if err := r.ScanStruct(&p); err == nil || !strings.Contains(err.Error(), "got 1, want 4 at line 3") {
+		t.Errorf("error expected: %v", err)
+	}
+}
+
+type line struct {
+	ID   int      `yacr:"id"`
+	Name string   `yacr:"name"`
+	Rest []string `yacr:",rest"`
+}
+
+func TestScanStructExtraFields(t *testing.T) {
+	r := DefaultReader(strings.NewReader("id,name\n1,a,x,y\n2,b\n3,c,z\n"))
+	if err := r.ScanHeaders(); err != nil {
+		t.Fatal(err)
+	}
+	var l line
+	if err := r.ScanStruct(&l); err != nil {
+		t.Fatal(err)
+	}
+	if want := (line{ID: 1, Name: "a", Rest: []string{"x", "y"}}); !reflect.DeepEqual(l, want) {
+		t.Errorf("got %+v; want %+v", l, want)
+	}
+	if err := r.ScanStruct(&l); err != nil {
+		t.Fatal(err)
+	}
+	if want := (line{ID: 2, Name: "b"}); !reflect.DeepEqual(l, want) {
+		t.Errorf("got %+v; want %+v", l, want)
+	}
+	r.ExtraFields = RejectExtraFields
+	if err := r.ScanStruct(&l); err == nil || !strings.Contains(err.Error(), "extra field(s): got 3, want 2 at line 4") {
+		t.Errorf("error expected: %v", err)
+	}
+	type invalid struct {
+		Rest string `yacr:",rest"`
+	}
+	if err := DefaultReader(strings.NewReader("a\n")).ScanStruct(&invalid{}); err == nil {
+		t.Error("error expected for non []string rest field")
+	}
+}
+
+type address struct {
+	Street string `yacr:"street"`
+	City   string `yacr:"city"`
+}
+
+type audit struct {
+	Created string `yacr:"created"`
+}
+
+type customer struct {
+	audit
+	Name string  `yacr:"name"`
+	Home address `yacr:"home_,inline"`
+	Work address `yacr:"work_,inline"`
+}
+
+func TestScanStructInline(t *testing.T) {
+	r := DefaultReader(strings.NewReader("name,work_city,home_street,home_city,created\nann,Paris,1 main st,Lyon,2020\n"))
+	if err := r.ScanHeaders(); err != nil {
+		t.Fatal(err)
+	}
+	var c customer
+	if err := r.ScanStruct(&c); err != nil {
+		t.Fatal(err)
+	}
+	want := customer{audit: audit{"2020"}, Name: "ann", Home: address{"1 main st", "Lyon"}, Work: address{City: "Paris"}}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("got %+v; want %+v", c, want)
+	}
+
+	data, err := Marshal([]customer{want})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "created,name,home_street,home_city,work_street,work_city\n2020,ann,1 main st,Lyon,,Paris\n"; string(data) != expected {
+		t.Errorf("got %q; want %q", data, expected)
+	}
+	var decoded []customer
+	if err = Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	} else if len(decoded) != 1 || !reflect.DeepEqual(decoded[0], want) {
+		t.Errorf("got %+v; want %+v", decoded, want)
+	}
+
+	type invalid struct {
+		Name string `yacr:"name,inline"`
+	}
+	if _, err = Marshal([]invalid{}); err == nil {
+		t.Error("error expected for inline non-struct field")
+	}
+}
+
+type Base struct {
+	ID int `yacr:"id"`
+}
+
+type shipment struct {
+	*Base
+	Name string   `yacr:"name"`
+	Ship *address `yacr:"ship_,inline"`
+}
+
+func TestScanStructPointerInline(t *testing.T) {
+	r := DefaultReader(strings.NewReader("id,name,ship_city\n1,a,Rome\n"))
+	if err := r.ScanHeaders(); err != nil {
+		t.Fatal(err)
+	}
+	var s shipment
+	if err := r.ScanStruct(&s); err != nil {
+		t.Fatal(err)
+	}
+	if want := (shipment{Base: &Base{1}, Name: "a", Ship: &address{City: "Rome"}}); !reflect.DeepEqual(s, want) {
+		t.Errorf("got %+v; want %+v", s, want)
+	}
+	data, err := Marshal([]shipment{s, {Name: "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "id,name,ship_street,ship_city\n1,a,,Rome\n,b,,\n"; string(data) != want {
+		t.Errorf("got %q; want %q", data, want)
+	}
+
+	type node struct {
+		Name string `yacr:"name"`
+		Next *node  `yacr:"next_,inline"`
+	}
+	if _, err = Marshal([]node{}); err == nil {
+		t.Error("error expected for recursive inline field")
+	}
+}
+
+type order struct {
+	ID       int      `yacr:"id"`
+	Status   string   `yacr:"status,default=new"`
+	Qty      int      `yacr:"qty,omitempty,default=1"`
+	Tags     []string `yacr:"tags,omitempty"`
+	Currency string   `yacr:"currency,default=EUR,USD"` // commas in default
+	Internal string   `yacr:"-"`
+}
+
+func TestStructTagOptions(t *testing.T) {
+	r := DefaultReader(strings.NewReader("id,status,qty,tags,currency,Internal\n1,,,,,x\n2,paid,3,a\n"))
+	r.MissingFields = FillMissingFields
+	if err := r.ScanHeaders(); err != nil {
+		t.Fatal(err)
+	}
+	var orders []order
+	for {
+		var o order
+		if err := r.ScanStruct(&o); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		orders = append(orders, o)
+	}
+	want := []order{
+		{ID: 1, Status: "new", Qty: 1, Tags: []string{}, Currency: "EUR,USD"},
+		{ID: 2, Status: "paid", Qty: 3, Tags: []string{"a"}, Currency: "EUR,USD"},
+	}
+	if !reflect.DeepEqual(orders, want) {
+		t.Errorf("got %+v; want %+v", orders, want)
+	}
+
+	data, err := Marshal([]order{{ID: 3, Internal: "secret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "id,status,qty,tags,currency\n3,,,,\n"; string(data) != expected {
+		t.Errorf("got %q; want %q", data, expected)
+	}
+}
+
+type sparse struct {
+	Name  string    `yacr:"name"`
+	Count int       `yacr:"count,omitempty"`
+	Ratio float64   `yacr:"ratio,omitempty"`
+	OK    bool      `yacr:"ok,omitempty"`
+	At    time.Time `yacr:"at,omitempty"`
+}
+
+func TestOmitEmptyRoundTrip(t *testing.T) {
+	values := []sparse{{Name: "zero"}, {"set", 2, 0.5, true, time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC)}}
+	data, err := Marshal(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "name,count,ratio,ok,at\nzero,,,,\nset,2,0.5,true,2021-03-04T00:00:00Z\n"; string(data) != expected {
+		t.Errorf("got %q; want %q", data, expected)
+	}
+	var decoded []sparse
+	if err = Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, values) {
+		t.Errorf("got %+v; want %+v", decoded, values)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+)
+
+// Coercion is a value whose typed decoding does not give back the raw text when formatted
+// (e.g. "007" decoded as 7, "1.0" as 1 or "T" as true).
+type Coercion struct {
+	Line    int    // line of the record
+	Column  int    // index (first is 1)
+	Name    string // column name (empty when headers are not loaded)
+	Value   string // raw value
+	Decoded string // decoded value (formatted)
+	Lossy   bool   // true when the decoded value differs from the raw one (e.g. float rounded to 1.6777216e+07)
+}
+
+func (c Coercion) String() string {
+	col := strconv.Itoa(c.Column)
+	if c.Name != "" {
+		col += " (" + c.Name + ")"
+	}
+	verb := "coerced"
+	if c.Lossy {
+		verb = "rounded"
+	}
+	return fmt.Sprintf("line %d, column %s: %q %s to %s", c.Line, col, c.Value, verb, c.Decoded)
+}
+
+// CoercionReport reports the values converted by typed decoding (see Reader.Coercions),
+// so that the lossiness of a load can be quantified instead of being silent.
+// Null values and strings are not counted.
+type CoercionReport struct {
+	Values  int // typed values decoded
+	Coerced int // values whose representation changed (lossy ones included)
+	Lossy   int // values whose decoded value differs from the raw one
+
+	Columns     map[int]int // number of coerced values by column index (first is 1)
+	Warnings    []Coercion  // coerced values (in input order)
+	MaxWarnings int         // maximum number of Warnings kept (0 means no limit)
+}
+
+// check updates the report with the raw value decoded to dv in column col (first is 1).
+func (cr *CoercionReport) check(raw string, dv reflect.Value, line, col int, name string) {
+	var decoded string
+	lossy := false
+	switch dv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		decoded = strconv.FormatInt(dv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		decoded = strconv.FormatUint(dv.Uint(), 10)
+	case reflect.Bool:
+		decoded = strconv.FormatBool(dv.Bool())
+	case reflect.Float32, reflect.Float64:
+		decoded = strconv.FormatFloat(dv.Float(), 'g', -1, dv.Type().Bits())
+		lossy = !sameNumber(raw, decoded)
+	default: // strings, lists...
+		return
+	}
+	cr.Values++
+	if decoded == raw {
+		return
+	}
+	cr.Coerced++
+	if lossy {
+		cr.Lossy++
+	}
+	if cr.Columns == nil {
+		cr.Columns = make(map[int]int)
+	}
+	cr.Columns[col]++
+	if cr.MaxWarnings <= 0 || len(cr.Warnings) < cr.MaxWarnings {
+		cr.Warnings = append(cr.Warnings, Coercion{line, col, name, raw, decoded, lossy})
+	}
+}
+
+// sameNumber tells if both decimal representations denote the same number
+// (infinities and NaN, which are not rational, are never rounded).
+func sameNumber(a, b string) bool {
+	ra, ok := new(big.Rat).SetString(a)
+	if !ok {
+		return true
+	}
+	rb, ok := new(big.Rat).SetString(b)
+	return !ok || ra.Cmp(rb) == 0
+}
+
+// checkCoercion updates Coercions with the field of column col (first is 1) decoded to dv.
+func (s *Reader) checkCoercion(raw []byte, dv reflect.Value, col int, name string) {
+	if len(raw) == 0 || s.isNull(col, raw) { // null values (or defaults, see UseDefaults) are not counted
+		return
+	}
+	if name == "" {
+		name = s.headerName(col)
+	}
+	s.Coercions.check(string(raw), dv, s.recordLine(), col, name)
+}
@@ -0,0 +1,78 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+func TestCoercionReportScanStruct(t *testing.T) {
+	type item struct {
+		Code   int
+		Price  float64
+		Weight float32
+		Active bool
+		Name   string
+	}
+	r := DefaultReader(strings.NewReader("Code,Price,Weight,Active,Name\n007,1.0,16777217,T,007\n8,2.5,0.5,true,x\n"))
+	r.Coercions = &CoercionReport{}
+	if err := r.ScanHeaders(); err != nil {
+		t.Fatal(err)
+	}
+	var v item
+	for {
+		if err := r.ScanStruct(&v); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+	cr := r.Coercions
+	if cr.Values != 8 || cr.Coerced != 4 || cr.Lossy != 1 {
+		t.Errorf("got %d value(s), %d coerced, %d lossy; want 8, 4, 1", cr.Values, cr.Coerced, cr.Lossy)
+	}
+	expected := []Coercion{
+		{Line: 2, Column: 1, Name: "Code", Value: "007", Decoded: "7"},
+		{Line: 2, Column: 2, Name: "Price", Value: "1.0", Decoded: "1"},
+		{Line: 2, Column: 3, Name: "Weight", Value: "16777217", Decoded: "1.6777216e+07", Lossy: true},
+		{Line: 2, Column: 4, Name: "Active", Value: "T", Decoded: "true"},
+	}
+	if !reflect.DeepEqual(expected, cr.Warnings) {
+		t.Errorf("got %v; want %v", cr.Warnings, expected)
+	}
+	if s := cr.Warnings[2].String(); s != `line 2, column 3 (Weight): "16777217" rounded to 1.6777216e+07` {
+		t.Errorf("unexpected message: %s", s)
+	}
+}
+
+func TestCoercionReportScanRecord(t *testing.T) {
+	r := DefaultReader(strings.NewReader("+1,a,2\n1,b,02\n3,c,\n"))
+	r.UseDefaults = true
+	r.Coercions = &CoercionReport{MaxWarnings: 1}
+	var i, j int64
+	var s string
+	for {
+		if n, err := r.ScanRecord(&i, &s, &j); err != nil {
+			t.Fatal(err)
+		} else if n == 0 {
+			break
+		}
+	}
+	cr := r.Coercions
+	if cr.Values != 5 || cr.Coerced != 2 || len(cr.Warnings) != 1 {
+		t.Fatalf("unexpected report: %+v", cr)
+	}
+	if expected := (Coercion{Line: 1, Column: 1, Value: "+1", Decoded: "1"}); cr.Warnings[0] != expected {
+		t.Errorf("got %v; want %v", cr.Warnings[0], expected)
+	}
+	if expected := map[int]int{1: 1, 3: 1}; !reflect.DeepEqual(expected, cr.Columns) {
+		t.Errorf("got %v; want %v", cr.Columns, expected)
+	}
+}
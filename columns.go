@@ -0,0 +1,118 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"fmt"
+	"io"
+)
+
+// ColumnExtractor reads selected columns in column-major order (one vector per column),
+// batch by batch.
+type ColumnExtractor struct {
+	r       *Reader
+	indices []int
+}
+
+// NewColumnExtractor returns an extractor of the columns at indices (first is 1).
+// Missing fields (in short records) are extracted as empty strings.
+func NewColumnExtractor(r *Reader, indices []int) (*ColumnExtractor, error) {
+	for _, index := range indices {
+		if index < 1 {
+			return nil, fmt.Errorf("invalid column index: %d", index)
+		}
+	}
+	return &ColumnExtractor{r, indices}, nil
+}
+
+// Next reads up to n records (all remaining ones when n <= 0) and
+// returns the selected columns: columns[i][j] is the value of column indices[i] in the j-th record.
+// Returns io.EOF when there is no more record.
+func (e *ColumnExtractor) Next(n int) (columns [][]string, err error) {
+	columns = make([][]string, len(e.indices))
+	for rows := 0; n <= 0 || rows < n; rows++ {
+		fields, err := e.r.ReadRecord()
+		if err == io.EOF {
+			if rows == 0 {
+				return nil, io.EOF
+			}
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		for i, index := range e.indices {
+			var value string
+			if index <= len(fields) {
+				value = string(fields[index-1])
+			}
+			columns[i] = append(columns[i], value)
+		}
+	}
+	return columns, nil
+}
+
+// ReadColumns reads all remaining records and returns the columns at indices (first is 1)
+// in column-major order: columns[i][j] is the value of column indices[i] in the j-th record.
+// It's handy for code expecting vectors (statistics, plotting) rather than rows.
+func ReadColumns(r *Reader, indices []int) ([][]string, error) {
+	e, err := NewColumnExtractor(r, indices)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := e.Next(0)
+	if err == io.EOF {
+		return make([][]string, len(indices)), nil
+	}
+	return columns, err
+}
+
+// ColumnReader returns a reader of the values of the column col (first is 1) of the remaining records of r
+// as a newline-delimited stream (one line per record), so that tools expecting simple line input
+// (sort, uniq, bloom filter builders...) can consume a column directly.
+// Missing fields (in short records) are read as empty lines
+// and line breaks embedded in values are replaced by spaces.
+// Errors of r are returned by Read.
+func ColumnReader(r *Reader, col int) io.Reader {
+	cr := &columnReader{r: r, col: col}
+	if col < 1 {
+		cr.err = fmt.Errorf("invalid column index: %d", col)
+	}
+	return cr
+}
+
+type columnReader struct {
+	r       *Reader
+	col     int
+	line    []byte // buffer of the current line
+	pending []byte // part of the line not read yet
+	err     error
+}
+
+func (cr *columnReader) Read(p []byte) (int, error) {
+	for len(cr.pending) == 0 {
+		if cr.err != nil {
+			return 0, cr.err
+		}
+		fields, err := cr.r.ReadRecord()
+		if err != nil {
+			cr.err = err
+			continue
+		}
+		cr.line = cr.line[:0]
+		if cr.col <= len(fields) {
+			for _, c := range fields[cr.col-1] {
+				if c == '\n' || c == '\r' {
+					c = ' '
+				}
+				cr.line = append(cr.line, c)
+			}
+		}
+		cr.line = append(cr.line, '\n')
+		cr.pending = cr.line
+	}
+	n := copy(p, cr.pending)
+	cr.pending = cr.pending[n:]
+	return n, nil
+}
@@ -0,0 +1,66 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+func TestReadColumns(t *testing.T) {
+	columns, err := ReadColumns(DefaultReader(strings.NewReader("1,a,x\n2,b\n3,\"c\nd\",z\n")), []int{3, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]string{{"x", "", "z"}, {"1", "2", "3"}}; !reflect.DeepEqual(columns, want) {
+		t.Errorf("got %q; want %q", columns, want)
+	}
+}
+
+func TestColumnExtractor(t *testing.T) {
+	e, err := NewColumnExtractor(DefaultReader(strings.NewReader("1,a\n2,b\n3,c\n")), []int{2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var batches [][][]string
+	for {
+		columns, err := e.Next(2)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		batches = append(batches, columns)
+	}
+	if want := [][][]string{{{"a", "b"}}, {{"c"}}}; !reflect.DeepEqual(batches, want) {
+		t.Errorf("got %q; want %q", batches, want)
+	}
+	if _, err = NewColumnExtractor(nil, []int{0}); err == nil {
+		t.Error("error expected for invalid index")
+	}
+}
+
+func TestColumnReader(t *testing.T) {
+	r := DefaultReader(strings.NewReader("1,a\n2\n3,\"b\r\nc\"\n4,a\n"))
+	b, err := io.ReadAll(ColumnReader(r, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "a\n\nb  c\na\n"; string(b) != expected {
+		t.Errorf("got %q; want %q", b, expected)
+	}
+
+	r = DefaultReader(strings.NewReader("1,\"a\n2,b\n"))
+	if _, err = io.ReadAll(ColumnReader(r, 1)); err == nil {
+		t.Error("error expected for malformed input")
+	}
+	if _, err = io.ReadAll(ColumnReader(r, 0)); err == nil {
+		t.Error("error expected for invalid column index")
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+)
+
+// Record is the fields of one record (see ReadRecord).
+type Record [][]byte
+
+// String returns the i-th (first is 0) field of the record ("" when missing).
+func (r Record) String(i int) string {
+	if i < 0 || i >= len(r) {
+		return ""
+	}
+	return string(r[i])
+}
+
+// ComputedColumn is a column whose value is computed from the record
+// (row hash, load timestamp, source file name...).
+type ComputedColumn struct {
+	Name    string
+	Compute func(record Record) (string, error)
+}
+
+// ConstantValue computes the same value for every record (load timestamp, source file name...).
+func ConstantValue(value string) func(Record) (string, error) {
+	return func(Record) (string, error) {
+		return value, nil
+	}
+}
+
+// RecordHash computes the (hex-encoded) SHA-256 of the record fields
+// (fields are length-prefixed so that ("ab", "c") and ("a", "bc") give different hashes).
+func RecordHash() func(Record) (string, error) {
+	return func(record Record) (string, error) {
+		var size [binary.MaxVarintLen64]byte
+		h := sha256.New()
+		for _, field := range record {
+			h.Write(size[:binary.PutUvarint(size[:], uint64(len(field)))])
+			h.Write(field)
+		}
+		var sum [sha256.Size]byte
+		return hex.EncodeToString(h.Sum(sum[:0])), nil
+	}
+}
+
+// Computer appends computed columns to records.
+type Computer struct {
+	columns []ComputedColumn
+	buf     []byte // computed values
+	ends    []int  // end of each computed value in buf
+	out     [][]byte
+}
+
+// NewComputer returns a Computer appending the columns (in order).
+func NewComputer(columns ...ComputedColumn) *Computer {
+	return &Computer{columns: columns}
+}
+
+// Headers returns the names of the computed columns.
+func (c *Computer) Headers() []string {
+	names := make([]string, len(c.columns))
+	for i, col := range c.columns {
+		names[i] = col.Name
+	}
+	return names
+}
+
+// Transform returns the record followed by the computed values.
+// Computations only see the original fields (not the values computed before them).
+// The returned fields may be overwritten by a subsequent call.
+func (c *Computer) Transform(fields [][]byte) ([][]byte, error) {
+	c.buf = c.buf[:0]
+	c.ends = c.ends[:0]
+	for _, col := range c.columns {
+		v, err := col.Compute(fields)
+		if err != nil {
+			return nil, fmt.Errorf("computed column %s: %s", col.Name, err)
+		}
+		c.buf = append(c.buf, v...)
+		c.ends = append(c.ends, len(c.buf))
+	}
+	c.out = append(c.out[:0], fields...)
+	start := 0
+	for _, end := range c.ends {
+		c.out = append(c.out, c.buf[start:end:end])
+		start = end
+	}
+	return c.out, nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+func TestComputer(t *testing.T) {
+	c := NewComputer(
+		ComputedColumn{Name: "source", Compute: ConstantValue("a.csv")},
+		ComputedColumn{Name: "hash", Compute: RecordHash()},
+		ComputedColumn{Name: "name_len", Compute: func(r Record) (string, error) {
+			return strings.Repeat("*", len(r.String(1))), nil
+		}},
+	)
+	if want := []string{"source", "hash", "name_len"}; !reflect.DeepEqual(c.Headers(), want) {
+		t.Errorf("got %q; want %q", c.Headers(), want)
+	}
+	b := &bytes.Buffer{}
+	w := DefaultWriter(b)
+	r := DefaultReader(strings.NewReader("1,ab,c\n1,a,bc\n"))
+	var hashes []string
+	for {
+		fields, err := r.ReadRecord()
+		if err != nil {
+			break
+		}
+		if fields, err = c.Transform(fields); err != nil {
+			t.Fatal(err)
+		}
+		if len(fields) != 6 || string(fields[3]) != "a.csv" || len(fields[4]) != 64 {
+			t.Errorf("unexpected record: %q", fields)
+		}
+		hashes = append(hashes, string(fields[4]))
+		w.WriteFields(fields)
+	}
+	if len(hashes) != 2 || hashes[0] == hashes[1] {
+		t.Errorf("hashes must differ: %q", hashes)
+	}
+	w.Flush()
+	if !strings.HasSuffix(b.String(), ",*\n") {
+		t.Errorf("unexpected output: %q", b.String())
+	}
+
+	c = NewComputer(ComputedColumn{Name: "fail", Compute: func(Record) (string, error) {
+		return "", errors.New("boom")
+	}})
+	if _, err := c.Transform(nil); err == nil || err.Error() != "computed column fail: boom" {
+		t.Errorf("got %v", err)
+	}
+}
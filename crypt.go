@@ -0,0 +1,143 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Keyring maps key identifiers to AEAD ciphers (AES-GCM, see NewAESGCM, or any other cipher.AEAD).
+type Keyring map[string]cipher.AEAD
+
+// NewAESGCM returns an AES-GCM cipher for the given key (16, 24 or 32 bytes).
+func NewAESGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// ErrDecrypt is the error returned when an encrypted field cannot be decrypted (unknown key, corrupted or forged content).
+var ErrDecrypt = errors.New("yacr: cannot decrypt field")
+
+var b64 = base64.RawURLEncoding
+
+// selectColumns returns the selected columns by index (see Reader.Headers).
+func selectColumns(headers map[string]int, names []string) ([]string, error) {
+	var cols []string
+	for _, name := range names {
+		index, ok := headers[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown field name: %s", name)
+		}
+		for len(cols) < index {
+			cols = append(cols, "")
+		}
+		cols[index-1] = name
+	}
+	return cols, nil
+}
+
+// Encrypter encrypts the selected columns of records.
+// Each non-empty value is replaced by "<key id>:<base64url(nonce|ciphertext)>",
+// so that values encrypted with different keys (rotation) can be decrypted by a Decrypter.
+// The column name is authenticated with the value, so that encrypted values cannot be swapped between columns.
+// Empty values are kept empty.
+type Encrypter struct {
+	keyID string
+	aead  cipher.AEAD
+	cols  []string // selected column names by index
+	rw    recordRewriter
+	nonce []byte
+	seal  []byte
+}
+
+// NewEncrypter binds the named columns described by headers (see Reader.Headers) to the key.
+// The key id must not contain a colon.
+func NewEncrypter(headers map[string]int, columns []string, keyID string, aead cipher.AEAD) (*Encrypter, error) {
+	if strings.IndexByte(keyID, ':') >= 0 {
+		return nil, fmt.Errorf("invalid key id: %q", keyID)
+	}
+	cols, err := selectColumns(headers, columns)
+	if err != nil {
+		return nil, err
+	}
+	return &Encrypter{keyID: keyID, aead: aead, cols: cols, nonce: make([]byte, aead.NonceSize())}, nil
+}
+
+// Transform returns the record with the selected columns encrypted.
+// The returned fields may be overwritten by a subsequent call.
+func (e *Encrypter) Transform(fields [][]byte) ([][]byte, error) {
+	return e.rw.rewrite(fields, func(i int, dst, field []byte) ([]byte, bool, error) {
+		if i >= len(e.cols) || e.cols[i] == "" || len(field) == 0 {
+			return dst, false, nil
+		}
+		if _, err := io.ReadFull(rand.Reader, e.nonce); err != nil {
+			return dst, false, err
+		}
+		e.seal = append(e.seal[:0], e.nonce...)
+		e.seal = e.aead.Seal(e.seal, e.nonce, field, []byte(e.cols[i]))
+		dst = append(dst, e.keyID...)
+		dst = append(dst, ':')
+		n := len(dst)
+		dst = append(dst, make([]byte, b64.EncodedLen(len(e.seal)))...)
+		b64.Encode(dst[n:], e.seal)
+		return dst, true, nil
+	})
+}
+
+// Decrypter decrypts the selected columns of records encrypted by an Encrypter.
+type Decrypter struct {
+	keys Keyring
+	cols []string // selected column names by index
+	rw   recordRewriter
+	raw  []byte
+}
+
+// NewDecrypter binds the named columns described by headers (see Reader.Headers) to the keyring.
+func NewDecrypter(headers map[string]int, columns []string, keys Keyring) (*Decrypter, error) {
+	cols, err := selectColumns(headers, columns)
+	if err != nil {
+		return nil, err
+	}
+	return &Decrypter{keys: keys, cols: cols}, nil
+}
+
+// Transform returns the record with the selected columns decrypted.
+// The returned fields may be overwritten by a subsequent call.
+func (d *Decrypter) Transform(fields [][]byte) ([][]byte, error) {
+	return d.rw.rewrite(fields, func(i int, dst, field []byte) ([]byte, bool, error) {
+		if i >= len(d.cols) || d.cols[i] == "" || len(field) == 0 {
+			return dst, false, nil
+		}
+		sep := bytes.IndexByte(field, ':')
+		if sep < 0 {
+			return dst, false, ErrDecrypt
+		}
+		aead, ok := d.keys[string(field[:sep])]
+		if !ok {
+			return dst, false, ErrDecrypt
+		}
+		d.raw = append(d.raw[:0], make([]byte, b64.DecodedLen(len(field)-sep-1))...)
+		n, err := b64.Decode(d.raw, field[sep+1:])
+		if err != nil || n < aead.NonceSize() {
+			return dst, false, ErrDecrypt
+		}
+		nonce, sealed := d.raw[:aead.NonceSize()], d.raw[aead.NonceSize():n]
+		if dst, err = aead.Open(dst, nonce, sealed, []byte(d.cols[i])); err != nil {
+			return dst, false, ErrDecrypt
+		}
+		return dst, true, nil
+	})
+}
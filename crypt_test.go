@@ -0,0 +1,70 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	headers := map[string]int{"id": 1, "ssn": 2, "card": 3}
+	k1, err := NewAESGCM(bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	k2, err := NewAESGCM(bytes.Repeat([]byte{2}, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e1, err := NewEncrypter(headers, []string{"ssn", "card"}, "k1", k1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e2, err := NewEncrypter(headers, []string{"ssn", "card"}, "k2", k2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := NewDecrypter(headers, []string{"ssn", "card"}, Keyring{"k1": k1, "k2": k2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := &bytes.Buffer{}
+	w := DefaultWriter(b)
+	for i, e := range []*Encrypter{e1, e2} {
+		fields, err := e.Transform([][]byte{[]byte("1"), []byte("123-45-6789"), nil})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(fields[0]) != "1" || !strings.HasPrefix(string(fields[1]), []string{"k1:", "k2:"}[i]) || len(fields[2]) != 0 {
+			t.Errorf("unexpected encrypted record: %q", fields)
+		}
+		w.WriteFields(fields)
+	}
+	w.Flush()
+
+	r := DefaultReader(b)
+	for i := 0; i < 2; i++ {
+		fields, err := r.ReadRecord()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fields, err = d.Transform(fields); err != nil {
+			t.Fatal(err)
+		}
+		if string(fields[1]) != "123-45-6789" {
+			t.Errorf("unexpected decrypted record: %q", fields)
+		}
+	}
+
+	fields, _ := e1.Transform([][]byte{[]byte("1"), []byte("secret"), []byte("card")})
+	if _, err = d.Transform([][]byte{fields[0], fields[2], fields[1]}); err != ErrDecrypt {
+		t.Errorf("swapped columns must not be decrypted: %v", err)
+	}
+}
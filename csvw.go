@@ -0,0 +1,190 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// CSVWContext is the JSON-LD context of CSVW metadata.
+const CSVWContext = "http://www.w3.org/ns/csvw"
+
+// CSVW is the (supported subset of) W3C "CSV on the Web" table metadata (csv-metadata.json):
+// dialect, column names, datatypes and null values.
+type CSVW struct {
+	Context     string       `json:"@context"`
+	URL         string       `json:"url"`
+	CSVDialect  *CSVWDialect `json:"dialect,omitempty"`
+	TableSchema CSVWSchema   `json:"tableSchema"`
+}
+
+// CSVWDialect describes the CSV dialect (missing values use the CSVW defaults).
+type CSVWDialect struct {
+	Delimiter     string `json:"delimiter,omitempty"`     // default is ","
+	Header        *bool  `json:"header,omitempty"`        // default is true
+	CommentPrefix string `json:"commentPrefix,omitempty"` // only one character is supported
+}
+
+// CSVWSchema describes the columns of a table.
+type CSVWSchema struct {
+	Columns []CSVWColumn `json:"columns"`
+}
+
+// CSVWColumn describes one column.
+type CSVWColumn struct {
+	Name     string       `json:"name,omitempty"`
+	Titles   csvwStrings  `json:"titles,omitempty"`
+	Datatype csvwDatatype `json:"datatype,omitempty"`
+	Null     csvwStrings  `json:"null,omitempty"` // default is the empty string
+}
+
+// csvwStrings is a string or an array of strings (natural language properties are not supported).
+type csvwStrings []string
+
+func (s *csvwStrings) UnmarshalJSON(b []byte) error {
+	var one string
+	if err := json.Unmarshal(b, &one); err == nil {
+		*s = csvwStrings{one}
+		return nil
+	}
+	return json.Unmarshal(b, (*[]string)(s))
+}
+
+func (s csvwStrings) MarshalJSON() ([]byte, error) {
+	if len(s) == 1 {
+		return json.Marshal(s[0])
+	}
+	return json.Marshal([]string(s))
+}
+
+// csvwDatatype is a datatype name or a datatype object (only its base is used).
+type csvwDatatype string
+
+func (d *csvwDatatype) UnmarshalJSON(b []byte) error {
+	var name string
+	if err := json.Unmarshal(b, &name); err == nil {
+		*d = csvwDatatype(name)
+		return nil
+	}
+	var obj struct {
+		Base string `json:"base"`
+	}
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return err
+	}
+	*d = csvwDatatype(obj.Base)
+	return nil
+}
+
+// ReadCSVW decodes CSVW table metadata.
+func ReadCSVW(r io.Reader) (*CSVW, error) {
+	m := &CSVW{}
+	if err := json.NewDecoder(r).Decode(m); err != nil {
+		return nil, err
+	}
+	if d := m.CSVDialect; d != nil && (len(d.Delimiter) > 1 || len(d.CommentPrefix) > 1) {
+		return nil, fmt.Errorf("unsupported CSVW dialect: delimiter %q, comment prefix %q", d.Delimiter, d.CommentPrefix)
+	}
+	return m, nil
+}
+
+// NewCSVW returns the metadata describing the file at url written with the dialect d and the columns of schema.
+func NewCSVW(url string, d Dialect, schema Schema) *CSVW {
+	header := true
+	m := &CSVW{Context: CSVWContext, URL: url, CSVDialect: &CSVWDialect{Delimiter: string(d.Sep), Header: &header}}
+	if d.Comment != 0 {
+		m.CSVDialect.CommentPrefix = string(d.Comment)
+	}
+	for _, c := range schema.Columns {
+		col := CSVWColumn{Name: c.Name, Titles: csvwStrings{c.Name}, Datatype: csvwDatatype(c.Type), Null: c.Nulls}
+		switch c.Type {
+		case "":
+			col.Datatype = csvwDatatype(StringType)
+		case NumberType:
+			col.Datatype = "double"
+		}
+		m.TableSchema.Columns = append(m.TableSchema.Columns, col)
+	}
+	return m
+}
+
+// Write encodes the metadata (as indented JSON).
+func (m *CSVW) Write(w io.Writer) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(m)
+}
+
+// Dialect returns the dialect of the described file.
+func (m *CSVW) Dialect() Dialect {
+	d := Dialect{Sep: ',', Quoted: true}
+	if m.CSVDialect != nil {
+		if m.CSVDialect.Delimiter != "" {
+			d.Sep = m.CSVDialect.Delimiter[0]
+		}
+		if m.CSVDialect.CommentPrefix != "" {
+			d.Comment = m.CSVDialect.CommentPrefix[0]
+		}
+	}
+	return d
+}
+
+// Schema returns the columns of the described file.
+// A column is named after its name or else its first title.
+func (m *CSVW) Schema() Schema {
+	var schema Schema
+	for _, c := range m.TableSchema.Columns {
+		col := Column{Name: c.Name, Type: csvwType(string(c.Datatype)), Nulls: c.Null}
+		if col.Name == "" && len(c.Titles) > 0 {
+			col.Name = c.Titles[0]
+		}
+		schema.Columns = append(schema.Columns, col)
+	}
+	return schema
+}
+
+func csvwType(datatype string) FieldType {
+	switch datatype {
+	case "integer", "int", "long", "short", "byte", "nonNegativeInteger", "positiveInteger", "nonPositiveInteger", "negativeInteger",
+		"unsignedLong", "unsignedInt", "unsignedShort", "unsignedByte":
+		return IntegerType
+	case "number", "decimal", "double", "float":
+		return NumberType
+	case "boolean":
+		return BooleanType
+	case "json":
+		return JSONType
+	}
+	return StringType
+}
+
+// NewReader returns a Reader of the described file configured with its dialect, its columns
+// (the header line is consumed when present) and the null values of each column (see Reader.ColumnNullValues).
+func (m *CSVW) NewReader(r io.Reader) (*Reader, error) {
+	s := m.Dialect().NewReader(r)
+	schema := m.Schema()
+	s.ColumnNullValues = make(map[int][]string, len(schema.Columns))
+	for i, c := range schema.Columns {
+		if c.Nulls == nil {
+			s.ColumnNullValues[i+1] = []string{""}
+		} else {
+			s.ColumnNullValues[i+1] = c.Nulls
+		}
+	}
+	if m.CSVDialect == nil || m.CSVDialect.Header == nil || *m.CSVDialect.Header {
+		if err := s.ScanHeaders(); err != nil {
+			return nil, err
+		}
+	}
+	if len(schema.Columns) > 0 { // metadata names take precedence over titles
+		s.Headers = make(map[string]int, len(schema.Columns))
+		for i, c := range schema.Columns {
+			s.Headers[c.Name] = i + 1
+		}
+	}
+	return s, nil
+}
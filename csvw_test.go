@@ -0,0 +1,80 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+const csvwMetadata = `{
+  "@context": "http://www.w3.org/ns/csvw",
+  "url": "countries.csv",
+  "dialect": {"delimiter": ";", "commentPrefix": "#"},
+  "tableSchema": {
+    "columns": [
+      {"name": "code", "titles": "Country Code"},
+      {"name": "population", "titles": ["Population", "Pop."], "datatype": {"base": "integer"}, "null": ["", "NA"]},
+      {"titles": "area", "datatype": "decimal"}
+    ]
+  }
+}`
+
+type country struct {
+	Code       string  `yacr:"code"`
+	Population int64   `yacr:"population"`
+	Area       float64 `yacr:"area"`
+}
+
+func TestCSVW(t *testing.T) {
+	m, err := ReadCSVW(strings.NewReader(csvwMetadata))
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := m.Schema()
+	if names := schema.Names(); !reflect.DeepEqual(names, []string{"code", "population", "area"}) {
+		t.Errorf("got %q", names)
+	}
+	if types := []FieldType{schema.Columns[0].Type, schema.Columns[1].Type, schema.Columns[2].Type}; !reflect.DeepEqual(types, []FieldType{StringType, IntegerType, NumberType}) {
+		t.Errorf("got %q", types)
+	}
+	r, err := m.NewReader(strings.NewReader("Country Code;Pop.;Area\n# comment\nFR;67000000;551695.5\nAQ;NA;\nNA;2500000;825615\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var countries []country
+	for {
+		var c country
+		if err = r.ScanStruct(&c); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		countries = append(countries, c)
+	}
+	if want := []country{{"FR", 67000000, 551695.5}, {"AQ", 0, 0}, {"NA", 2500000, 825615}}; !reflect.DeepEqual(countries, want) {
+		t.Errorf("got %+v; want %+v", countries, want)
+	}
+
+	b := &bytes.Buffer{}
+	if err = NewCSVW("countries.csv", Dialect{Sep: ';', Comment: '#'}, schema).Write(b); err != nil {
+		t.Fatal(err)
+	}
+	m, err = ReadCSVW(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := m.Dialect(); d.Sep != ';' || d.Comment != '#' {
+		t.Errorf("got %+v", d)
+	}
+	if got := m.Schema(); !reflect.DeepEqual(got, schema) {
+		t.Errorf("got %+v; want %+v", got, schema)
+	}
+}
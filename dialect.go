@@ -0,0 +1,147 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"bytes"
+	"io"
+)
+
+// Dialect groups the settings needed to read or write a specific flavour of CSV.
+type Dialect struct {
+	Sep     byte // values separator
+	Quoted  bool // specify if values may be quoted (when they contain separator or newline)
+	UseCRLF bool // True to use \r\n as the line terminator (Writer only)
+	Escape  byte // see Reader.Escape and Writer.Escape (quoting is disabled when specified)
+	Header  bool // True when the first record is a header (informative only, see Reader.Dialect)
+
+	BOM              bool // True to write a UTF-8 byte order mark at the start of the output (Writer only)
+	SepHint          bool // True to write a "sep=" line at the start of the output (see Writer.WriteSepHint) or to detect it (see Reader.DetectSepHint)
+	SanitizeFormulas bool // see Writer.SanitizeFormulas (Writer only)
+
+	Trim    bool // see Reader.Trim (Reader only)
+	Comment byte // see Reader.Comment (Reader only)
+	Lazy    bool // see Reader.Lazy (Reader only)
+	BareCR  bool // see Reader.BareCR (Reader only)
+}
+
+// DialectDefault is the "standard" dialect (separator is comma and quoted mode active)
+var DialectDefault = Dialect{Sep: ',', Quoted: true}
+
+// DialectExcel is the dialect for files that must open correctly in Excel:
+// UTF-8 BOM (so that non-ASCII characters are not garbled), CRLF and formulas neutralized.
+// In locales where the comma is the decimal separator, use DialectExcelSemicolon.
+// Beware that Excel ignores the BOM when a "sep=" line is present (SepHint).
+var DialectExcel = Dialect{Sep: ',', Quoted: true, UseCRLF: true, BOM: true, SanitizeFormulas: true}
+
+// DialectExcelSemicolon is DialectExcel with a semicolon separator (for European locales).
+var DialectExcelSemicolon = Dialect{Sep: ';', Quoted: true, UseCRLF: true, BOM: true, SanitizeFormulas: true}
+
+// DialectHive is the default text format of Hive (and Pig): fields separated by ^A (\x01),
+// no quoting and backslash escapes.
+var DialectHive = Dialect{Sep: '\x01', Escape: '\\'}
+
+// NewReader returns a new CSV scanner configured with this dialect.
+func (d Dialect) NewReader(r io.Reader) *Reader {
+	s := NewReader(r, d.Sep, d.Quoted, false)
+	s.Trim = d.Trim
+	s.Comment = d.Comment
+	s.Lazy = d.Lazy
+	s.BareCR = d.BareCR
+	s.Escape = d.Escape
+	s.DetectSepHint = d.SepHint
+	return s
+}
+
+// NewWriter returns a new CSV writer configured with this dialect.
+func (d Dialect) NewWriter(w io.Writer) *Writer {
+	wr := NewWriter(w, d.Sep, d.Quoted)
+	wr.UseCRLF = d.UseCRLF
+	wr.Escape = d.Escape
+	wr.SanitizeFormulas = d.SanitizeFormulas
+	if d.BOM {
+		_, err := wr.b.WriteString("\uFEFF")
+		wr.setErr(err)
+	}
+	if d.SepHint {
+		wr.WriteSepHint()
+	}
+	return wr
+}
+
+// Dialect returns the dialect of the input as used or detected so far:
+// separator (see Sep), quoting, line terminator (see LineEnding), "sep=" line (see SepHinted)
+// and header presence (headers loaded by ScanHeaders or, in guess mode, first line that looks like a header).
+// The returned dialect can be used to create a Writer matching the input.
+func (s *Reader) Dialect() Dialect {
+	ending := s.LineEnding()
+	return Dialect{
+		Sep:     s.sep,
+		Quoted:  s.quoted,
+		UseCRLF: ending == CRLF,
+		Escape:  s.Escape,
+		Header:  s.header || s.Headers != nil,
+		SepHint: s.sepHint > 0,
+		Trim:    s.Trim,
+		Comment: s.Comment,
+		Lazy:    s.Lazy,
+		BareCR:  s.BareCR || ending == CR,
+	}
+}
+
+const (
+	numberColumn = -1 // all values are numbers
+	mixedColumn  = -2 // values of different types or lengths
+)
+
+// sniffHeader tells if the first line of the sample data looks like a header:
+// each column votes for a header when its first value does not match the type (number or not)
+// or the (constant) length of the following values.
+func sniffHeader(data []byte, atEOF bool, d Dialect) bool {
+	if !atEOF { // ignore the last (partial) line
+		if i := bytes.LastIndexByte(data, '\n'); i >= 0 {
+			data = data[:i+1]
+		} else {
+			return false
+		}
+	}
+	r := d.NewReader(bytes.NewReader(data))
+	var header []string
+	columns := map[int]int{} // length of the values (or numberColumn/mixedColumn) by column
+	for n := 0; n < 20; n++ {
+		fields, err := r.ReadRecord()
+		if err != nil {
+			break
+		} else if header == nil {
+			header = copyStrings(fields)
+			continue
+		} else if len(fields) != len(header) {
+			continue
+		}
+		for i, field := range fields {
+			kind := len(field)
+			if isNum, _ := IsNumber(field); isNum {
+				kind = numberColumn
+			}
+			if prev, ok := columns[i]; !ok {
+				columns[i] = kind
+			} else if prev != kind {
+				columns[i] = mixedColumn
+			}
+		}
+	}
+	votes := 0
+	for i, kind := range columns {
+		isNum, _ := IsNumber([]byte(header[i]))
+		switch {
+		case kind == mixedColumn:
+		case kind == numberColumn && isNum, kind >= 0 && len(header[i]) == kind:
+			votes--
+		default:
+			votes++
+		}
+	}
+	return votes > 0
+}
@@ -0,0 +1,108 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+)
+
+const (
+	dictDmer    = 8   // length of the substrings whose frequencies are counted
+	dictSegment = 64  // length of the segments selected
+	dictSamples = 100 // sampled bytes by byte of dictionary
+)
+
+// TrainDict samples the records of r to produce a compression dictionary of at most size bytes
+// suited to their content (frequent substrings like column values, formats or separators),
+// improving the compression of records stored individually (one row per key, message...).
+// The result is a raw content dictionary (without header nor entropy tables, most useful content last),
+// accepted by zstd (ZSTD_dct_rawContent, or zstd.WithEncoderDictRaw of github.com/klauspost/compress)
+// and by compress/flate (NewWriterDict, 32KB at most).
+// Records are sampled until about 100 times size bytes are read.
+// Segments are selected like the COVER algorithm of zstd does (simplified).
+func TrainDict(r *Reader, size int) ([]byte, error) {
+	if size <= 0 {
+		return nil, errors.New("invalid dictionary size")
+	}
+	var data []byte
+	for len(data) < dictSamples*size {
+		fields, err := r.ReadRecord()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		for i, field := range fields {
+			if i > 0 {
+				data = append(data, r.sep)
+			}
+			data = append(data, field...)
+		}
+		data = append(data, '\n')
+	}
+	if len(data) == 0 {
+		return nil, errors.New("no record to train the dictionary")
+	} else if len(data) <= size {
+		return data, nil
+	}
+
+	freqs := make(map[uint64]uint32)
+	for i := 0; i+dictDmer <= len(data); i++ {
+		freqs[dmer(data, i)]++
+	}
+	type segment struct {
+		start int
+		score uint64
+	}
+	epochs := (size + dictSegment - 1) / dictSegment
+	epochSize := len(data) / epochs
+	if epochSize < dictSegment {
+		epochSize = dictSegment
+	}
+	var segments []segment
+	for start := 0; start+dictSegment <= len(data) && len(segments) < epochs; start += epochSize {
+		end := start + epochSize
+		if end > len(data) {
+			end = len(data)
+		}
+		// sliding sum of the frequencies of the dmers of each segment
+		var best segment
+		var score uint64
+		n := dictSegment - dictDmer + 1 // dmers by segment
+		for i := start; i+dictDmer <= end; i++ {
+			score += uint64(freqs[dmer(data, i)])
+			if i-start >= n {
+				score -= uint64(freqs[dmer(data, i-n)])
+			}
+			if i-start >= n-1 && score > best.score {
+				best = segment{i - n + 1, score}
+			}
+		}
+		if best.score == 0 {
+			continue
+		}
+		for i := best.start; i < best.start+n; i++ { // already covered
+			freqs[dmer(data, i)] = 0
+		}
+		segments = append(segments, best)
+	}
+	sort.SliceStable(segments, func(i, j int) bool { return segments[i].score < segments[j].score })
+	dict := make([]byte, 0, len(segments)*dictSegment)
+	for _, s := range segments {
+		dict = append(dict, data[s.start:s.start+dictSegment]...)
+	}
+	if len(dict) > size {
+		dict = dict[len(dict)-size:]
+	}
+	return dict, nil
+}
+
+// dmer returns the substring of data at i (dictDmer bytes) as a map key.
+func dmer(data []byte, i int) uint64 {
+	return binary.LittleEndian.Uint64(data[i : i+dictDmer])
+}
@@ -0,0 +1,52 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+func deflatedSize(t *testing.T, record, dict []byte) int {
+	var b bytes.Buffer
+	w, err := flate.NewWriterDict(&b, flate.BestCompression, dict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(record)
+	w.Close()
+	return b.Len()
+}
+
+func TestTrainDict(t *testing.T) {
+	var b strings.Builder
+	statuses := []string{"shipped", "pending", "cancelled"}
+	for i := 0; i < 2000; i++ {
+		fmt.Fprintf(&b, "%d,customer-%04d@example.com,%s,2021-03-%02dT10:%02d:00Z,\"Paris, France\"\n", i, i*7%1000, statuses[i%3], i%28+1, i%60)
+	}
+	dict, err := TrainDict(DefaultReader(strings.NewReader(b.String())), 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dict) == 0 || len(dict) > 1024 {
+		t.Fatalf("got a dictionary of %d bytes; want at most 1024", len(dict))
+	}
+	record := []byte("4242,customer-0123@example.com,pending,2021-03-07T10:42:00Z,\"Paris, France\"\n")
+	if without, with := deflatedSize(t, record, nil), deflatedSize(t, record, dict); with >= without {
+		t.Errorf("got %d bytes with the dictionary; want less than %d", with, without)
+	}
+
+	if dict, err = TrainDict(DefaultReader(strings.NewReader("a,b\n")), 1024); err != nil || string(dict) != "a,b\n" {
+		t.Errorf("got (%q, %v); want the whole content", dict, err)
+	}
+	if _, err = TrainDict(DefaultReader(strings.NewReader("")), 1024); err == nil {
+		t.Error("error expected without record")
+	}
+}
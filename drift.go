@@ -0,0 +1,169 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DriftReport lists the differences between the schema of a previous ingest and the current one (see CompareSchema).
+type DriftReport struct {
+	Added     []string      // new columns
+	Removed   []string      // missing columns
+	Retyped   []ColumnDrift // columns whose type changed
+	Reordered bool          // true when the common columns are not in the same order
+}
+
+// ColumnDrift describes a type change.
+type ColumnDrift struct {
+	Name     string
+	Old, New FieldType
+}
+
+// Drifted tells if there is any difference.
+func (r DriftReport) Drifted() bool {
+	return len(r.Added) > 0 || len(r.Removed) > 0 || len(r.Retyped) > 0 || r.Reordered
+}
+
+// String returns a summary of the differences (like "added: a; retyped: b (integer -> string)").
+func (r DriftReport) String() string {
+	var parts []string
+	if len(r.Added) > 0 {
+		parts = append(parts, "added: "+strings.Join(r.Added, ", "))
+	}
+	if len(r.Removed) > 0 {
+		parts = append(parts, "removed: "+strings.Join(r.Removed, ", "))
+	}
+	if len(r.Retyped) > 0 {
+		retyped := make([]string, len(r.Retyped))
+		for i, c := range r.Retyped {
+			retyped[i] = fmt.Sprintf("%s (%s -> %s)", c.Name, c.Old, c.New)
+		}
+		parts = append(parts, "retyped: "+strings.Join(retyped, ", "))
+	}
+	if r.Reordered {
+		parts = append(parts, "reordered")
+	}
+	if len(parts) == 0 {
+		return "no drift"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// CompareSchema reports the columns added, removed or retyped (by name) between old and new schemas
+// (an empty type is StringType).
+func CompareSchema(old, new Schema) DriftReport {
+	var r DriftReport
+	oldIndex := make(map[string]int, len(old.Columns))
+	for i, c := range old.Columns {
+		oldIndex[c.Name] = i
+	}
+	newNames := make(map[string]bool, len(new.Columns))
+	last := -1 // index in old of the previous common column
+	for _, c := range new.Columns {
+		newNames[c.Name] = true
+		i, ok := oldIndex[c.Name]
+		if !ok {
+			r.Added = append(r.Added, c.Name)
+			continue
+		}
+		if i < last {
+			r.Reordered = true
+		}
+		last = i
+		if oldType, newType := columnType(old.Columns[i]), columnType(c); oldType != newType {
+			r.Retyped = append(r.Retyped, ColumnDrift{c.Name, oldType, newType})
+		}
+	}
+	for _, c := range old.Columns {
+		if !newNames[c.Name] {
+			r.Removed = append(r.Removed, c.Name)
+		}
+	}
+	return r
+}
+
+func columnType(c Column) FieldType {
+	if c.Type == "" {
+		return StringType
+	}
+	return c.Type
+}
+
+// CheckDrift compares the schema of the input, inferred from its header and (at most n) following records
+// (see InferSchema), with the schema persisted by a previous ingest (see ReadTableSchema),
+// so that upstream format changes are caught before processing starts.
+// Inferred types are only reported when the sampled values do not match the old types
+// (integer values match NumberType and all values match StringType).
+// The returned source yields all records (including the sampled ones).
+func CheckDrift(r *Reader, old Schema, n int) (DriftReport, RecordSource, error) {
+	var sample [][][]byte
+	src := &replaySource{r: r}
+	if r.Headers == nil {
+		if err := r.ScanHeaders(); err != nil {
+			return DriftReport{}, nil, err
+		}
+	}
+	for len(sample) < n {
+		fields, err := r.ReadRecord()
+		if err == io.EOF {
+			src.eof = true
+			break
+		} else if err != nil {
+			return DriftReport{}, nil, err
+		}
+		sample = append(sample, copyRecord(fields))
+	}
+	src.records = sample
+	inferred := Schema{Columns: headerColumns(r.Headers)}
+	types := make([]FieldType, len(inferred.Columns)) // "" until a non-empty value is seen
+	for _, fields := range sample {
+		for i, field := range fields {
+			if i < len(types) && len(field) > 0 {
+				types[i] = inferType(types[i], field)
+			}
+		}
+	}
+	oldTypes := make(map[string]FieldType, len(old.Columns))
+	for _, c := range old.Columns {
+		oldTypes[c.Name] = columnType(c)
+	}
+	for i := range inferred.Columns {
+		c := &inferred.Columns[i]
+		if oldType, ok := oldTypes[c.Name]; ok && matchesType(types[i], oldType) {
+			c.Type = oldType
+		} else if types[i] != "" {
+			c.Type = types[i]
+		} else {
+			c.Type = StringType
+		}
+	}
+	return CompareSchema(old, inferred), src, nil
+}
+
+// matchesType tells if values of the inferred type t ("" when there is no value) are valid values of type old.
+func matchesType(t, old FieldType) bool {
+	return t == "" || t == old || old == StringType || t == IntegerType && old == NumberType
+}
+
+// replaySource yields the sampled records and then the remaining records of r.
+type replaySource struct {
+	records [][][]byte
+	r       *Reader
+	eof     bool
+}
+
+func (s *replaySource) ReadRecord() ([][]byte, error) {
+	if len(s.records) > 0 {
+		fields := s.records[0]
+		s.records = s.records[1:]
+		return fields, nil
+	} else if s.eof {
+		return nil, io.EOF
+	}
+	return s.r.ReadRecord()
+}
@@ -0,0 +1,67 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+func TestCompareSchema(t *testing.T) {
+	old := Schema{Columns: []Column{{Name: "id", Type: IntegerType}, {Name: "name"}, {Name: "price", Type: NumberType}, {Name: "zip", Type: IntegerType}}}
+	new := Schema{Columns: []Column{{Name: "name", Type: StringType}, {Name: "id", Type: IntegerType}, {Name: "zip", Type: StringType}, {Name: "email"}}}
+	r := CompareSchema(old, new)
+	want := DriftReport{
+		Added:     []string{"email"},
+		Removed:   []string{"price"},
+		Retyped:   []ColumnDrift{{Name: "zip", Old: IntegerType, New: StringType}},
+		Reordered: true,
+	}
+	if !reflect.DeepEqual(r, want) {
+		t.Errorf("got %+v; want %+v", r, want)
+	}
+	if s := r.String(); s != "added: email; removed: price; retyped: zip (integer -> string); reordered" {
+		t.Errorf("unexpected summary: %s", s)
+	}
+	if r = CompareSchema(old, old); r.Drifted() || r.String() != "no drift" {
+		t.Errorf("no drift expected: %s", r)
+	}
+}
+
+func TestCheckDrift(t *testing.T) {
+	old := Schema{Columns: []Column{{Name: "id", Type: IntegerType}, {Name: "price", Type: NumberType}, {Name: "zip", Type: IntegerType}, {Name: "note"}}}
+	r := DefaultReader(strings.NewReader("id,price,zip,note,flag\n1,10,75001,,true\n2,,2A004,x,false\n3,1.5,13001,,true\n"))
+	report, src, err := CheckDrift(r, old, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := report.String(); s != "added: flag; retyped: zip (integer -> string)" {
+		t.Errorf("unexpected report: %s", s)
+	}
+	var ids []string
+	for {
+		fields, err := src.ReadRecord()
+		if err != nil {
+			break
+		}
+		ids = append(ids, string(fields[0]))
+	}
+	if !reflect.DeepEqual(ids, []string{"1", "2", "3"}) {
+		t.Errorf("got %v; want all records", ids)
+	}
+
+	r = DefaultReader(strings.NewReader("id,price,zip,note\n1,10,75001,\n"))
+	if report, src, err = CheckDrift(r, old, 10); err != nil {
+		t.Fatal(err)
+	} else if report.Drifted() {
+		t.Errorf("no drift expected: %s", report)
+	}
+	if fields, err := src.ReadRecord(); err != nil || string(fields[2]) != "75001" {
+		t.Errorf("got %q, %v", fields, err)
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+var (
+	// ErrUnescapedQuote is the error reported when a quoted value contains a quote not followed by a separator or a newline (see Reader.Lazy).
+	ErrUnescapedQuote = errors.New("unescaped \" character")
+	// ErrUnterminatedQuote is the error reported when the input ends inside a quoted value.
+	ErrUnterminatedQuote = errors.New("non-terminated quoted field")
+	// ErrFieldCount is the error reported when a record has more or fewer fields than expected
+	// (see Reader.MissingFields and Reader.ExtraFields).
+	ErrFieldCount = errors.New("wrong number of fields")
+	// ErrEncoding is the error reported when a value is not valid UTF-8 (see Reader.ValidUTF8 and RejectInvalidUTF8).
+	ErrEncoding = errors.New("invalid UTF-8 encoding")
+)
+
+// ParseError is the error type returned by Reader for malformed input.
+// The underlying error (one of the Err* values or a more specific error) can be tested with errors.Is:
+//
+//	if errors.Is(err, yacr.ErrUnescapedQuote) { ... }
+type ParseError struct {
+	StartLine int   // line where the value starts (0 when it is the same as Line)
+	Line      int   // line where the error occurred
+	Pos       int64 // offset (plus one) of the offending byte in the input (0 when unknown, see Explain)
+	Err       error // the actual error
+}
+
+func (e *ParseError) Error() string {
+	if e.StartLine != 0 && e.StartLine != e.Line {
+		return fmt.Sprintf("%s between lines %d and %d", e.Err, e.StartLine, e.Line)
+	}
+	return fmt.Sprintf("%s at line %d", e.Err, e.Line)
+}
+
+// Unwrap returns the underlying error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// fieldCountError details ErrFieldCount.
+type fieldCountError struct {
+	msg       string
+	got, want int
+}
+
+func (e *fieldCountError) Error() string {
+	return fmt.Sprintf("%s: got %d, want %d", e.msg, e.got, e.want)
+}
+
+func (e *fieldCountError) Is(target error) bool {
+	return target == ErrFieldCount
+}
+
+// encodingError details ErrEncoding.
+type encodingError struct {
+	b      byte // first invalid byte
+	offset int  // offset of the first invalid byte in the value
+}
+
+func invalidUTF8Err(value []byte) error {
+	for i := 0; i < len(value); {
+		r, size := utf8.DecodeRune(value[i:])
+		if r == utf8.RuneError && size == 1 {
+			return &encodingError{value[i], i}
+		}
+		i += size
+	}
+	return ErrEncoding
+}
+
+func (e *encodingError) Error() string {
+	return fmt.Sprintf("%s: byte 0x%02X at offset %d in value", ErrEncoding, e.b, e.offset)
+}
+
+func (e *encodingError) Is(target error) bool {
+	return target == ErrEncoding
+}
+
+// FieldError is the error returned when a field cannot be decoded into a struct field
+// (see Reader.ScanStruct and RecordUnmarshaler) or into a typed column (see Reader.ScanBatch).
+type FieldError struct {
+	Line   int    // line of the record (0 when unknown)
+	Column int    // index (first is 1)
+	Name   string // name of the column (or of the struct field), may be empty
+	Err    error  // the actual error
+}
+
+func (e *FieldError) Error() string {
+	msg := fmt.Sprintf("%s at column %d", e.Err, e.Column)
+	if e.Line > 0 {
+		msg = fmt.Sprintf("%s at line %d, column %d", e.Err, e.Line, e.Column)
+	}
+	if e.Name == "" {
+		return msg
+	}
+	return e.Name + ": " + msg
+}
+
+// Unwrap returns the underlying error.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,160 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxExplainedLine is the maximum number of bytes of the offending line rendered by Explain
+// (around the offending byte).
+const maxExplainedLine = 80
+
+// Explain renders err for humans (support tooling, CLI...): when it is a ParseError,
+// the offending line of input is rendered with a caret under the offending byte (when known, see ParseError.Pos)
+// followed by a suggestion:
+//
+//	unescaped " character at line 2
+//	2 | a,"b"c,d
+//	  |     ^
+//	looks like an unescaped quote: double it ("") or consider Reader.Lazy
+//
+// Other errors are returned as is (err.Error()).
+func Explain(err error, input io.ReaderAt) string {
+	if err == nil {
+		return ""
+	}
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		return err.Error()
+	}
+	var b strings.Builder
+	b.WriteString(err.Error())
+	lineno, col := perr.Line, -1
+	var text []byte
+	if perr.Pos > 0 {
+		if perr.StartLine != 0 { // the offending byte starts the value
+			lineno = perr.StartLine
+		}
+		text, col = lineAt(input, perr.Pos-1)
+	} else if lineno > 0 {
+		text = nthLine(input, lineno)
+	}
+	if text != nil {
+		text, col = clip(text, col)
+		prefix := fmt.Sprintf("%d | ", lineno)
+		fmt.Fprintf(&b, "\n%s%s", prefix, text)
+		if col >= 0 {
+			margin := strings.Repeat(" ", len(prefix)-2) + "| "
+			fmt.Fprintf(&b, "\n%s%s^", margin, strings.Repeat(" ", utf8.RuneCount(text[:col])))
+		}
+	}
+	if hint := suggestion(perr.Err); hint != "" {
+		b.WriteString("\n")
+		b.WriteString(hint)
+	}
+	return b.String()
+}
+
+// lineAt returns the line of input containing the byte at offset (without line terminator)
+// and the index of this byte in the line.
+func lineAt(input io.ReaderAt, offset int64) ([]byte, int) {
+	start := offset - maxExplainedLine
+	if start < 0 {
+		start = 0
+	}
+	buf := make([]byte, 2*maxExplainedLine)
+	n, _ := input.ReadAt(buf, start)
+	buf = buf[:n]
+	col := int(offset - start)
+	if col >= len(buf) {
+		return nil, -1
+	}
+	if i := bytes.LastIndexByte(buf[:col], '\n'); i >= 0 {
+		buf, col = buf[i+1:], col-i-1
+	}
+	if i := bytes.IndexByte(buf[col:], '\n'); i >= 0 {
+		buf = buf[:col+i]
+	}
+	return bytes.TrimSuffix(buf, []byte{'\r'}), col
+}
+
+// nthLine returns the line n (first is 1) of input (without line terminator) or nil.
+func nthLine(input io.ReaderAt, n int) []byte {
+	s := bufio.NewScanner(io.NewSectionReader(input, 0, 1<<62))
+	s.Buffer(nil, 1<<20)
+	for i := 1; s.Scan(); i++ {
+		if i == n {
+			return bytes.TrimSuffix(s.Bytes(), []byte{'\r'})
+		}
+	}
+	return nil
+}
+
+// clip returns at most maxExplainedLine bytes of text around col (elided parts being replaced by "..."),
+// with control characters replaced by spaces (so that the caret is aligned), and the new index of col.
+func clip(text []byte, col int) ([]byte, int) {
+	start, end := 0, len(text)
+	if col >= 0 && col > maxExplainedLine/2 {
+		start = col - maxExplainedLine/2
+	}
+	if end-start > maxExplainedLine {
+		end = start + maxExplainedLine
+	}
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start++
+	}
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end--
+	}
+	var clipped []byte
+	if start > 0 {
+		clipped = append(clipped, "..."...)
+	}
+	if col >= 0 {
+		col += len(clipped) - start
+	}
+	for _, c := range text[start:end] {
+		if c < ' ' {
+			c = ' '
+		}
+		clipped = append(clipped, c)
+	}
+	if end < len(text) {
+		clipped = append(clipped, "..."...)
+	}
+	return clipped, col
+}
+
+// suggestion returns a human-readable hint to fix the error.
+func suggestion(err error) string {
+	var serr *SeparatorError
+	var lerr *limitError
+	switch {
+	case errors.Is(err, ErrUnescapedQuote):
+		return `looks like an unescaped quote: double it ("") or consider Reader.Lazy`
+	case errors.Is(err, ErrUnterminatedQuote):
+		return "looks like a quoted value without closing quote: check the quotes or read the input unquoted"
+	case errors.As(err, &serr):
+		return "looks like a wrong separator: specify it or let it be guessed"
+	case errors.Is(err, ErrFieldCount):
+		return "records have different numbers of fields: check the separator and the quotes or consider Reader.MissingFields/Reader.ExtraFields"
+	case errors.Is(err, ErrEncoding):
+		return "looks like a legacy encoding (e.g. Windows-1252): transcode the input or consider Reader.InvalidUTF8"
+	case errors.As(err, &lerr) && lerr.open:
+		return "looks like a quoted value without closing quote swallowing the rest of the input: check the quotes from the starting line"
+	case errors.Is(err, ErrLimit):
+		return "the input exceeds the limits: consider raising Reader.Limits"
+	case strings.HasPrefix(err.Error(), "unicode line separator"):
+		return "consider Reader.UnicodeNewlines"
+	}
+	return ""
+}
@@ -0,0 +1,85 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+var explainTests = []struct {
+	Name       string
+	Input      string
+	Quoted     bool
+	MaxColumns int
+	Limits     *Limits
+	Expected   string
+}{
+	{
+		Name:   "UnescapedQuote",
+		Quoted: true,
+		Input:  "x,y,z\na,\"b\"c,d\n",
+		Expected: "unescaped \" character at line 2\n" +
+			"2 | a,\"b\"c,d\n" +
+			"  |     ^\n" +
+			"looks like an unescaped quote: double it (\"\") or consider Reader.Lazy",
+	},
+	{
+		Name:   "UnterminatedQuote",
+		Quoted: true,
+		Input:  "x,y\r\n\u00e9,\"b\r\nc\r\n",
+		Expected: "non-terminated quoted field between lines 2 and 4\n" +
+			"2 | \u00e9,\"b\n" +
+			"  |   ^\n" +
+			"looks like a quoted value without closing quote: check the quotes or read the input unquoted",
+	},
+	{
+		Name:       "Separator",
+		Input:      "a,b\nc;d,e;f,g\n",
+		MaxColumns: 2,
+		Expected: "suspicious record with 3 field(s) using separator ',': wrong separator? candidates: ';' at line 2\n" +
+			"2 | c;d,e;f,g\n" +
+			"looks like a wrong separator: specify it or let it be guessed",
+	},
+	{
+		Name:   "Limit",
+		Quoted: true,
+		Input:  "x,y\na,\"b\n" + strings.Repeat("c,d\n", 100),
+		Limits: &Limits{MaxRecordSize: 100},
+		Expected: "limit exceeded: more than 100 bytes per record (inside a quoted value, missing closing quote?) at line 2\n" +
+			"2 | a,\"b\n" +
+			"looks like a quoted value without closing quote swallowing the rest of the input: check the quotes from the starting line",
+	},
+	{
+		Name:   "LongLine",
+		Quoted: true,
+		Input:  strings.Repeat("a,", 50) + "\"b\"c," + strings.Repeat("d,", 50) + "\n",
+		Expected: "unescaped \" character at line 1\n" +
+			"1 | ..." + strings.Repeat("a,", 19) + "\"b\"c," + strings.Repeat("d,", 18) + "d...\n" +
+			"  | " + strings.Repeat(" ", 43) + "^\n" +
+			"looks like an unescaped quote: double it (\"\") or consider Reader.Lazy",
+	},
+}
+
+func TestExplain(t *testing.T) {
+	for _, test := range explainTests {
+		r := NewReader(strings.NewReader(test.Input), ',', test.Quoted, false)
+		r.MaxColumns = test.MaxColumns
+		r.Limits = test.Limits
+		var err error
+		for err == nil {
+			_, err = r.ReadRecord()
+		}
+		if got := Explain(err, strings.NewReader(test.Input)); got != test.Expected {
+			t.Errorf("%s: got\n%s\nwant\n%s", test.Name, got, test.Expected)
+		}
+	}
+	if got := Explain(errors.New("boom"), strings.NewReader("")); got != "boom" {
+		t.Errorf("got %q; want %q", got, "boom")
+	}
+}
@@ -0,0 +1,546 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Expr is a compiled expression over the columns of records, like:
+//
+//	price > 100 && country == "US"
+//	qty * price
+//
+// Operands are column names (quoted with backquotes when they are not identifiers, like `unit price`),
+// numbers, strings (double or single quoted), true, false and null.
+// A quote inside a quoted string or column name is escaped by doubling it (as in SQL).
+// Operators are, by increasing precedence: ||, &&, comparisons (==, !=, <, <=, >, >=),
+// + and -, *, / and %, unary ! and -.
+// Empty values are null: arithmetic with null gives null and null is only equal to null.
+// Arithmetic operators convert values to numbers while comparisons are numeric when both values are numbers
+// (and none is a quoted string literal).
+type Expr struct {
+	src  string
+	eval exprFunc
+}
+
+type exprFunc func(record Record) (interface{}, error) // nil, float64, string or bool
+
+// CompileExpr compiles the expression src binding column names to the columns described by headers (see Reader.Headers).
+func CompileExpr(src string, headers map[string]int) (*Expr, error) {
+	p := &exprParser{src: src, headers: headers}
+	p.next()
+	eval, err := p.parseOr()
+	if err == nil && p.err != nil {
+		err = p.err
+	} else if err == nil && p.tok.kind != eofTok {
+		err = p.errorf("unexpected %q", p.tok.text)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Expr{src: src, eval: eval}, nil
+}
+
+// String returns the source of the expression.
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Eval evaluates the expression on the record: the result is nil (null), a float64, a string or a bool.
+func (e *Expr) Eval(record Record) (interface{}, error) {
+	v, err := e.eval(record)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", e.src, err)
+	}
+	return v, nil
+}
+
+// Match evaluates the expression as a condition (null is false).
+func (e *Expr) Match(record Record) (bool, error) {
+	v, err := e.Eval(record)
+	if err != nil {
+		return false, err
+	}
+	switch v := v.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return v, nil
+	}
+	return false, fmt.Errorf("%s: boolean expected (got %s)", e.src, formatValue(v))
+}
+
+// ExprColumn returns the column computed by def, like "total = qty * price" (see Computer).
+// Numbers are formatted without exponent and null as an empty value.
+func ExprColumn(def string, headers map[string]int) (ComputedColumn, error) {
+	i := strings.IndexByte(def, '=')
+	if i < 0 || strings.HasPrefix(def[i:], "==") {
+		return ComputedColumn{}, fmt.Errorf("invalid computed column (name = expression expected): %s", def)
+	}
+	name := strings.TrimSpace(def[:i])
+	if name == "" {
+		return ComputedColumn{}, fmt.Errorf("missing computed column name: %s", def)
+	}
+	e, err := CompileExpr(strings.TrimSpace(def[i+1:]), headers)
+	if err != nil {
+		return ComputedColumn{}, err
+	}
+	return ComputedColumn{Name: name, Compute: func(record Record) (string, error) {
+		v, err := e.Eval(record)
+		if err != nil {
+			return "", err
+		}
+		return formatValue(v), nil
+	}}, nil
+}
+
+// Filter returns the records of src matching e (see Expr.Match).
+func Filter(src RecordSource, e *Expr) RecordSource {
+	return &filter{src, e}
+}
+
+type filter struct {
+	src RecordSource
+	e   *Expr
+}
+
+func (f *filter) ReadRecord() ([][]byte, error) {
+	for {
+		fields, err := f.src.ReadRecord()
+		if err != nil {
+			return nil, err
+		}
+		if ok, err := f.e.Match(fields); err != nil {
+			return nil, err
+		} else if ok {
+			return fields, nil
+		}
+	}
+}
+
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return v.(string)
+}
+
+// toNumber converts a string value to a number.
+func toNumber(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+type exprTokKind int
+
+const (
+	eofTok exprTokKind = iota
+	numberTok
+	stringTok
+	identTok
+	opTok
+)
+
+type exprTok struct {
+	kind exprTokKind
+	text string // operator, identifier or unquoted string
+	pos  int
+}
+
+type exprParser struct {
+	src     string
+	headers map[string]int
+	pos     int
+	tok     exprTok
+	last    exprTok // previous token
+	err     error   // lexical error
+}
+
+func (p *exprParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("syntax error at offset %d in %q: %s", p.tok.pos, p.src, fmt.Sprintf(format, args...))
+}
+
+var exprOps = []string{"||", "&&", "==", "!=", "<=", ">=", "<", ">", "+", "-", "*", "/", "%", "!", "(", ")"}
+
+// next scans the next token.
+func (p *exprParser) next() {
+	p.last = p.tok
+	for p.pos < len(p.src) && isSpace(p.src[p.pos]) {
+		p.pos++
+	}
+	start := p.pos
+	if p.pos >= len(p.src) {
+		p.tok = exprTok{eofTok, "", start}
+		return
+	}
+	c := p.src[p.pos]
+	switch {
+	case c >= '0' && c <= '9' || c == '.':
+		for p.pos < len(p.src) && (isDigit(p.src[p.pos]) || p.src[p.pos] == '.' ||
+			(p.src[p.pos] == 'e' || p.src[p.pos] == 'E') ||
+			(p.src[p.pos] == '+' || p.src[p.pos] == '-') && (p.src[p.pos-1] == 'e' || p.src[p.pos-1] == 'E')) {
+			p.pos++
+		}
+		p.tok = exprTok{numberTok, p.src[start:p.pos], start}
+	case c == '"' || c == '\'' || c == '`':
+		var text string
+		for p.pos = start + 1; ; p.pos++ {
+			end := strings.IndexByte(p.src[p.pos:], c)
+			if end < 0 {
+				p.tok = exprTok{eofTok, "", start}
+				p.err = p.errorf("unterminated %c", c)
+				p.pos = len(p.src)
+				return
+			}
+			text += p.src[p.pos : p.pos+end]
+			p.pos += end + 1
+			if p.pos == len(p.src) || p.src[p.pos] != c { // not a doubled quote
+				break
+			}
+			text += string(c)
+		}
+		kind := stringTok
+		if c == '`' {
+			kind = identTok
+		}
+		p.tok = exprTok{kind, text, start}
+	case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		for p.pos < len(p.src) && (p.src[p.pos] == '_' || isDigit(p.src[p.pos]) ||
+			p.src[p.pos] >= 'a' && p.src[p.pos] <= 'z' || p.src[p.pos] >= 'A' && p.src[p.pos] <= 'Z') {
+			p.pos++
+		}
+		p.tok = exprTok{identTok, p.src[start:p.pos], start}
+	default:
+		for _, op := range exprOps {
+			if strings.HasPrefix(p.src[start:], op) {
+				p.pos += len(op)
+				p.tok = exprTok{opTok, op, start}
+				return
+			}
+		}
+		p.tok = exprTok{eofTok, "", start}
+		p.err = p.errorf("unexpected character %q", c)
+		p.pos = len(p.src)
+	}
+}
+
+// accept consumes the current token if it is one of the operators ops.
+func (p *exprParser) accept(ops ...string) (string, bool) {
+	if p.tok.kind != opTok {
+		return "", false
+	}
+	for _, op := range ops {
+		if p.tok.text == op {
+			p.next()
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *exprParser) parseOr() (exprFunc, error) {
+	left, err := p.parseAnd()
+	for err == nil {
+		if _, ok := p.accept("||"); !ok {
+			break
+		}
+		var right exprFunc
+		if right, err = p.parseAnd(); err == nil {
+			left = logical(left, right, true)
+		}
+	}
+	return left, err
+}
+
+func (p *exprParser) parseAnd() (exprFunc, error) {
+	left, err := p.parseComparison()
+	for err == nil {
+		if _, ok := p.accept("&&"); !ok {
+			break
+		}
+		var right exprFunc
+		if right, err = p.parseComparison(); err == nil {
+			left = logical(left, right, false)
+		}
+	}
+	return left, err
+}
+
+// logical returns the evaluation of left || right (or) or left && right (short-circuit).
+func logical(left, right exprFunc, or bool) exprFunc {
+	return func(record Record) (interface{}, error) {
+		l, err := truth(left, record)
+		if err != nil || l == or {
+			return l, err
+		}
+		return truth(right, record)
+	}
+}
+
+func truth(f exprFunc, record Record) (bool, error) {
+	v, err := f(record)
+	if err != nil {
+		return false, err
+	}
+	switch v := v.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return v, nil
+	}
+	return false, fmt.Errorf("boolean expected (got %s)", formatValue(v))
+}
+
+func (p *exprParser) parseComparison() (exprFunc, error) {
+	left, quoted, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.accept("==", "!=", "<=", ">=", "<", ">")
+	if !ok {
+		return left, nil
+	}
+	right, rquoted, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	quoted = quoted || rquoted
+	return func(record Record) (interface{}, error) {
+		l, err := left(record)
+		if err != nil {
+			return nil, err
+		}
+		r, err := right(record)
+		if err != nil {
+			return nil, err
+		}
+		if l == nil || r == nil {
+			switch op {
+			case "==":
+				return l == r, nil
+			case "!=":
+				return l != r, nil
+			}
+			return false, nil
+		}
+		lb, lIsBool := l.(bool)
+		rb, rIsBool := r.(bool)
+		if lIsBool || rIsBool {
+			if !lIsBool || !rIsBool || op != "==" && op != "!=" {
+				return nil, fmt.Errorf("invalid comparison: %s %s %s", formatValue(l), op, formatValue(r))
+			}
+			return (lb == rb) == (op == "=="), nil
+		}
+		var c int
+		if quoted { // a quoted literal is a string, even when it looks like a number ("00123")
+			c = strings.Compare(formatValue(l), formatValue(r))
+		} else {
+			c = compareValues(l, r)
+		}
+		switch op {
+		case "==":
+			return c == 0, nil
+		case "!=":
+			return c != 0, nil
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		}
+		return c >= 0, nil
+	}, nil
+}
+
+// parseOperand parses an operand of a comparison and tells if it is a quoted string literal.
+func (p *exprParser) parseOperand() (exprFunc, bool, error) {
+	first := p.tok
+	x, err := p.parseAdditive()
+	return x, first.kind == stringTok && p.last == first, err
+}
+
+// compareValues compares numerically when both values are numbers (NaN excepted) and lexically otherwise.
+func compareValues(l, r interface{}) int {
+	if a, ok := toNumber(l); ok && !math.IsNaN(a) {
+		if b, ok := toNumber(r); ok && !math.IsNaN(b) {
+			if a < b {
+				return -1
+			} else if a > b {
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(formatValue(l), formatValue(r))
+}
+
+func (p *exprParser) parseAdditive() (exprFunc, error) {
+	left, err := p.parseMultiplicative()
+	for err == nil {
+		op, ok := p.accept("+", "-")
+		if !ok {
+			break
+		}
+		var right exprFunc
+		if right, err = p.parseMultiplicative(); err == nil {
+			left = arithmetic(op, left, right)
+		}
+	}
+	return left, err
+}
+
+func (p *exprParser) parseMultiplicative() (exprFunc, error) {
+	left, err := p.parseUnary()
+	for err == nil {
+		op, ok := p.accept("*", "/", "%")
+		if !ok {
+			break
+		}
+		var right exprFunc
+		if right, err = p.parseUnary(); err == nil {
+			left = arithmetic(op, left, right)
+		}
+	}
+	return left, err
+}
+
+func arithmetic(op string, left, right exprFunc) exprFunc {
+	return func(record Record) (interface{}, error) {
+		l, err := number(left, record)
+		if err != nil || l == nil {
+			return nil, err
+		}
+		r, err := number(right, record)
+		if err != nil || r == nil {
+			return nil, err
+		}
+		a, b := l.(float64), r.(float64)
+		switch op {
+		case "+":
+			return a + b, nil
+		case "-":
+			return a - b, nil
+		case "*":
+			return a * b, nil
+		}
+		if b == 0 {
+			return nil, fmt.Errorf("division by zero")
+		} else if op == "%" {
+			return math.Mod(a, b), nil
+		}
+		return a / b, nil
+	}
+}
+
+// number evaluates f as a number (or null).
+func number(f exprFunc, record Record) (interface{}, error) {
+	v, err := f(record)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	if n, ok := toNumber(v); ok {
+		return n, nil
+	}
+	return nil, fmt.Errorf("not a number: %q", formatValue(v))
+}
+
+func (p *exprParser) parseUnary() (exprFunc, error) {
+	op, ok := p.accept("!", "-")
+	if !ok {
+		return p.parsePrimary()
+	}
+	operand, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	if op == "-" {
+		return func(record Record) (interface{}, error) {
+			v, err := number(operand, record)
+			if err != nil || v == nil {
+				return nil, err
+			}
+			return -v.(float64), nil
+		}, nil
+	}
+	return func(record Record) (interface{}, error) {
+		v, err := truth(operand, record)
+		return !v, err
+	}, nil
+}
+
+func (p *exprParser) parsePrimary() (exprFunc, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	tok := p.tok
+	switch tok.kind {
+	case numberTok:
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number %q", tok.text)
+		}
+		p.next()
+		return constant(f), nil
+	case stringTok:
+		p.next()
+		return constant(tok.text), nil
+	case identTok:
+		switch tok.text {
+		case "true", "false":
+			p.next()
+			return constant(tok.text == "true"), nil
+		case "null":
+			p.next()
+			return constant(nil), nil
+		}
+		index, ok := p.headers[tok.text]
+		if !ok {
+			return nil, p.errorf("unknown field name: %s", tok.text)
+		}
+		p.next()
+		return func(record Record) (interface{}, error) {
+			if index > len(record) || len(record[index-1]) == 0 {
+				return nil, nil
+			}
+			return string(record[index-1]), nil
+		}, nil
+	case opTok:
+		if tok.text == "(" {
+			p.next()
+			f, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := p.accept(")"); !ok {
+				return nil, p.errorf("missing )")
+			}
+			return f, nil
+		}
+		return nil, p.errorf("unexpected %q", tok.text)
+	}
+	return nil, p.errorf("unexpected end of expression")
+}
+
+func constant(v interface{}) exprFunc {
+	return func(Record) (interface{}, error) {
+		return v, nil
+	}
+}
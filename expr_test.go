@@ -0,0 +1,107 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+var exprHeaders = map[string]int{"price": 1, "country": 2, "qty": 3, "unit price": 4}
+
+var exprTests = []struct {
+	Expr   string
+	Record []string
+	Result interface{}
+	Error  string
+}{
+	{Expr: `price > 100 && country == "US"`, Record: []string{"120", "US"}, Result: true},
+	{Expr: `price > 100 && country == "US"`, Record: []string{"99.5", "US"}, Result: false},
+	{Expr: `price > 100 || country == 'FR'`, Record: []string{"9", "FR"}, Result: true},
+	{Expr: `!(price >= 10)`, Record: []string{"9"}, Result: true},
+	{Expr: `qty * price`, Record: []string{"2.5", "", "4"}, Result: 10.0},
+	{Expr: `-qty + 10 % 3 * 2 - 1 / 4`, Record: []string{"", "", "4"}, Result: -2.25},
+	{Expr: "`unit price` * 2", Record: []string{"", "", "", "1e2"}, Result: 200.0},
+	{Expr: `qty * price`, Record: []string{"2", "US"}, Result: nil},
+	{Expr: `qty == null`, Record: []string{"2"}, Result: true},
+	{Expr: `qty != ""`, Record: []string{"2"}, Result: true},
+	{Expr: `price < qty`, Record: []string{"10", "", "9"}, Result: false},
+	{Expr: `country < "FR"`, Record: []string{"", "DE"}, Result: true},
+	{Expr: `price == 100`, Record: []string{"1e2"}, Result: true},
+	{Expr: `price == "00123"`, Record: []string{"123"}, Result: false},
+	{Expr: `'1e2' == price`, Record: []string{"100"}, Result: false},
+	{Expr: `price == 5`, Record: []string{"NaN"}, Result: false},
+	{Expr: `country == 'O''Brien'`, Record: []string{"", "O'Brien"}, Result: true},
+	{Expr: `country == '''" || true || "'`, Record: []string{"", "US"}, Result: false},
+	{Expr: `country == """"`, Record: []string{"", `"`}, Result: true},
+	{Expr: `country * 2`, Record: []string{"", "US"}, Error: `country * 2: not a number: "US"`},
+	{Expr: `price / qty`, Record: []string{"1", "", "0"}, Error: "division by zero"},
+	{Expr: `price && true`, Record: []string{"1"}, Error: "boolean expected (got 1)"},
+	{Expr: `price > `, Error: "syntax error at offset 8"},
+	{Expr: `(price > 1`, Error: "missing )"},
+	{Expr: `price > 1 $`, Error: "unexpected character '$'"},
+	{Expr: `price > 1 1`, Error: `unexpected "1"`},
+	{Expr: `cost > 1`, Error: "unknown field name: cost"},
+	{Expr: `country == "US`, Error: "unterminated \""},
+	{Expr: `country == 'US''`, Error: "unterminated '"},
+}
+
+func TestExpr(t *testing.T) {
+	for _, tt := range exprTests {
+		e, err := CompileExpr(tt.Expr, exprHeaders)
+		var result interface{}
+		if err == nil {
+			record := make(Record, len(tt.Record))
+			for i, value := range tt.Record {
+				record[i] = []byte(value)
+			}
+			result, err = e.Eval(record)
+		}
+		if tt.Error != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.Error) {
+				t.Errorf("%s: got %v; want %q", tt.Expr, err, tt.Error)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.Expr, err)
+			continue
+		}
+		if result != tt.Result {
+			t.Errorf("%s: got %#v; want %#v", tt.Expr, result, tt.Result)
+		}
+	}
+}
+
+func TestExprFilterAndColumn(t *testing.T) {
+	r := DefaultReader(strings.NewReader("price,country,qty\n120,US,2\n150,FR,1\n200,US,\n"))
+	if err := r.ScanHeaders(); err != nil {
+		t.Fatal(err)
+	}
+	filter, err := CompileExpr(`price > 100 && country == "US"`, r.Headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total, err := ExprColumn("total = qty * price", r.Headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Transformers = []RecordTransformer{NewComputer(total)}
+	var b bytes.Buffer
+	w := DefaultWriter(&b)
+	if _, err = Copy(w, Filter(r, filter)); err != nil {
+		t.Fatal(err)
+	}
+	if want := "120,US,2,240\n200,US,,\n"; b.String() != want {
+		t.Errorf("got %q; want %q", b.String(), want)
+	}
+	for _, def := range []string{"qty * price", "= qty", "total == qty"} {
+		if _, err = ExprColumn(def, r.Headers); err == nil {
+			t.Errorf("%s: error expected", def)
+		}
+	}
+}
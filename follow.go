@@ -0,0 +1,189 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// FollowReader reads records appended to a growing CSV file (like `tail -f`).
+// A partial trailing record is only returned once it is terminated by a newline
+// (it is dropped when the file is truncated or when the FollowReader is closed).
+// When the file is truncated, reading restarts from its beginning.
+// When the file is rotated (renamed and replaced by a new one), the remaining content of the old file is read
+// (its partial trailing record being terminated, or dropped when it ends inside a quoted value)
+// before switching to the new one.
+// Scan blocks until new data is available or until Close is called.
+type FollowReader struct {
+	*Reader
+	f *follower
+}
+
+type follower struct {
+	path   string
+	file   *os.File
+	fi     os.FileInfo
+	offset int64
+	poll   time.Duration
+
+	buf      []byte // data read from the file and not yet returned
+	complete int    // length of the complete records at the start of buf
+	scanned  int    // length of the data of buf already scanned for the end of records
+	sep      byte
+	quoted   bool // true when values may be quoted (see Dialect.Quoted and Dialect.Escape)
+	escape   byte
+	state    recordState
+
+	once sync.Once
+	done chan struct{}
+}
+
+// NewFollowReader opens the named file and returns a Reader following it.
+func NewFollowReader(path string, d Dialect) (*FollowReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	f := &follower{path: path, file: file, fi: fi, poll: time.Second, done: make(chan struct{}),
+		sep: d.Sep, quoted: d.Quoted && d.Escape == 0, escape: d.Escape, state: followFieldStart}
+	return &FollowReader{d.NewReader(f), f}, nil
+}
+
+// SetPollInterval changes the delay between two checks for new data (default is one second).
+// It must be called before Scan.
+func (r *FollowReader) SetPollInterval(d time.Duration) {
+	r.f.poll = d
+}
+
+// Close stops following the file: a pending or subsequent Scan stops like at the end of a regular file.
+// It may be called concurrently with Scan.
+func (r *FollowReader) Close() error {
+	r.f.once.Do(func() {
+		close(r.f.done)
+	})
+	return nil
+}
+
+// recordState is the state of the search for the end of records in the data read (see follower.scan).
+type recordState int
+
+const (
+	followFieldStart  recordState = iota // at the start of a field
+	followField                          // inside an unquoted field
+	followQuoted                         // inside a quoted value
+	followAfterQuote                     // after a quote closing a quoted value (or starting an escaped quote)
+	followAfterEscape                    // after the escape character
+)
+
+// Read returns complete records only (see FollowReader).
+func (f *follower) Read(b []byte) (int, error) {
+	for {
+		if f.complete > 0 {
+			n := copy(b, f.buf[:f.complete])
+			f.buf = f.buf[:copy(f.buf, f.buf[n:])]
+			f.complete -= n
+			f.scanned -= n
+			return n, nil
+		}
+		select {
+		case <-f.done:
+			f.buf = f.buf[:0] // partial trailing record
+			if f.file != nil {
+				err := f.file.Close()
+				f.file = nil
+				if err != nil {
+					return 0, err
+				}
+			}
+			return 0, io.EOF
+		default:
+		}
+		if cap(f.buf)-len(f.buf) < len(b) {
+			f.buf = append(make([]byte, 0, 2*cap(f.buf)+len(b)), f.buf...)
+		}
+		n, err := f.file.Read(f.buf[len(f.buf) : len(f.buf)+len(b)])
+		f.offset += int64(n)
+		if n > 0 {
+			f.buf = f.buf[:len(f.buf)+n]
+			f.scan()
+			continue
+		} else if err != nil && err != io.EOF {
+			return 0, err
+		}
+		if err = f.check(); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// scan looks for the end of records in the data of buf not scanned yet.
+func (f *follower) scan() {
+	for ; f.scanned < len(f.buf); f.scanned++ {
+		c := f.buf[f.scanned]
+		switch {
+		case f.state == followAfterEscape:
+			f.state = followField
+		case f.escape != 0 && c == f.escape:
+			f.state = followAfterEscape
+		case f.state == followQuoted:
+			if c == '"' {
+				f.state = followAfterQuote
+			}
+		case c == '"' && f.quoted && (f.state == followFieldStart || f.state == followAfterQuote):
+			f.state = followQuoted
+		case c == '\n':
+			f.state = followFieldStart
+			f.complete = f.scanned + 1
+		case c == f.sep:
+			f.state = followFieldStart
+		default:
+			f.state = followField
+		}
+	}
+}
+
+// reset drops the partial trailing record.
+func (f *follower) reset() {
+	f.buf = f.buf[:f.complete]
+	f.scanned = f.complete
+	f.state = followFieldStart
+}
+
+// check detects truncation or rotation and otherwise waits for new data.
+func (f *follower) check() error {
+	if fi, err := f.file.Stat(); err == nil && fi.Size() < f.offset { // truncated
+		f.reset()
+		f.offset, err = f.file.Seek(0, io.SeekStart)
+		return err
+	}
+	if fi, err := os.Stat(f.path); err == nil && !os.SameFile(f.fi, fi) { // rotated
+		file, err := os.Open(f.path)
+		if err != nil {
+			return err
+		}
+		_ = f.file.Close()
+		f.file, f.fi, f.offset = file, fi, 0
+		if f.state == followQuoted || f.state == followAfterEscape { // a newline would be part of the value
+			f.reset()
+		} else if len(f.buf) > f.complete { // the old file is complete
+			f.buf = append(f.buf, '\n')
+			f.complete, f.scanned, f.state = len(f.buf), len(f.buf), followFieldStart
+		}
+		return nil
+	}
+	select {
+	case <-f.done:
+	case <-time.After(f.poll):
+	}
+	return nil
+}
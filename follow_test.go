@@ -0,0 +1,137 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	. "github.com/gwenn/yacr"
+)
+
+func TestFollowReader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.csv")
+	if err := ioutil.WriteFile(path, []byte("a,b\n1,\"x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewFollowReader(path, DialectDefault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.SetPollInterval(5 * time.Millisecond)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		f.WriteString("\ny\"\n2,z\n") // completes the partial record
+		f.Close()
+		time.Sleep(20 * time.Millisecond)
+		if err = os.Rename(path, path+".1"); err != nil {
+			t.Error(err)
+		}
+		if err = ioutil.WriteFile(path, []byte("3,w\n"), 0644); err != nil {
+			t.Error(err)
+		}
+	}()
+	want := [][]string{{"a", "b"}, {"1", "x\ny"}, {"2", "z"}, {"3", "w"}}
+	var records [][]string
+	for len(records) < len(want) {
+		fields, err := r.ReadRecord()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var record []string
+		for _, field := range fields {
+			record = append(record, string(field))
+		}
+		records = append(records, record)
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("got %q; want %q", records, want)
+	}
+	r.Close()
+	if r.Scan() {
+		t.Errorf("unexpected field after Close: %q", r.Text())
+	}
+}
+
+func TestFollowReaderTruncate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.csv")
+	if err := ioutil.WriteFile(path, []byte("a,b\n1,xyz"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewFollowReader(path, DialectDefault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.SetPollInterval(5 * time.Millisecond)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		if err := ioutil.WriteFile(path, []byte("2,y\n3,\"p"), 0644); err != nil { // truncated
+			t.Error(err)
+		}
+	}()
+	want := [][]string{{"a", "b"}, {"2", "y"}}
+	var records [][]string
+	for len(records) < len(want) {
+		fields, err := r.ReadRecord()
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, []string{string(fields[0]), string(fields[1])})
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("got %q; want %q", records, want)
+	}
+	r.Close()
+	if fields, err := r.ReadRecord(); err == nil {
+		t.Errorf("unexpected partial record after Close: %q", fields)
+	}
+}
+
+func TestFollowReaderRotateInsideQuote(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.csv")
+	if err := ioutil.WriteFile(path, []byte("a,b\n1,\"x,"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewFollowReader(path, DialectDefault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.SetPollInterval(5 * time.Millisecond)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		if err := os.Rename(path, path+".1"); err != nil {
+			t.Error(err)
+		}
+		if err := ioutil.WriteFile(path, []byte("2,y\n"), 0644); err != nil {
+			t.Error(err)
+		}
+	}()
+	want := [][]string{{"a", "b"}, {"2", "y"}} // the record cut inside a quoted value is dropped
+	var records [][]string
+	for len(records) < len(want) {
+		fields, err := r.ReadRecord()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var record []string
+		for _, field := range fields {
+			record = append(record, string(field))
+		}
+		records = append(records, record)
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("got %q; want %q", records, want)
+	}
+	r.Close()
+}
@@ -0,0 +1,80 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"bytes"
+	"strconv"
+	"time"
+)
+
+// FloatFormat specifies how float values are written (see Writer.FloatFormats),
+// so that exported numbers are stable and compact (e.g. 0.33 instead of 0.3333333333333333 with 2 decimals,
+// or 1.5e+06 instead of 1500000).
+type FloatFormat struct {
+	Fmt       byte // 'f' (default), 'e', 'E', 'g' or 'G' (see strconv.FormatFloat)
+	Prec      int  // number of digits after the decimal point ('f', 'e') or of significant digits ('g'), -1 for the smallest number of digits representing the value exactly
+	TrimZeros bool // True to remove the trailing zeros after the decimal point (and the point itself when there is no more digit)
+}
+
+// Format returns the representation of v.
+func (f FloatFormat) Format(v float64) string {
+	return string(f.append(nil, v, 64))
+}
+
+// append appends the representation of v (a float of bitSize bits) to dst.
+func (f FloatFormat) append(dst []byte, v float64, bitSize int) []byte {
+	fmt := f.Fmt
+	if fmt == 0 {
+		fmt = 'f'
+	}
+	start := len(dst)
+	dst = strconv.AppendFloat(dst, v, fmt, f.Prec, bitSize)
+	if f.TrimZeros {
+		dst = dst[:start+len(trimZeros(dst[start:]))]
+	}
+	return dst
+}
+
+// trimZeros removes the trailing zeros after the decimal point of the mantissa of b (in place).
+func trimZeros(b []byte) []byte {
+	exp := bytes.IndexAny(b, "eE")
+	if exp < 0 {
+		exp = len(b)
+	}
+	mant := b[:exp]
+	if bytes.IndexByte(mant, '.') < 0 {
+		return b
+	}
+	end := len(bytes.TrimRight(mant, "0"))
+	if mant[end-1] == '.' {
+		end--
+	}
+	return append(b[:end], b[exp:]...)
+}
+
+// TimeFormat specifies how time values are written (see Writer.TimeFormats),
+// so that date exports are consistent across producing services (e.g. all in UTC).
+type TimeFormat struct {
+	Layout   string         // see time.Time.Format (default is time.RFC3339Nano, like MarshalText)
+	Location *time.Location // zone the times are converted to (e.g. time.UTC), nil to keep the zone of each time
+}
+
+// Format returns the representation of t.
+func (f TimeFormat) Format(t time.Time) string {
+	return string(f.append(nil, t))
+}
+
+// append appends the representation of t to dst.
+func (f TimeFormat) append(dst []byte, t time.Time) []byte {
+	if f.Location != nil {
+		t = t.In(f.Location)
+	}
+	layout := f.Layout
+	if layout == "" {
+		layout = time.RFC3339Nano
+	}
+	return t.AppendFormat(dst, layout)
+}
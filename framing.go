@@ -0,0 +1,87 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Framing describes feeds where records are framed by a typed header record and a typed trailer record
+// (like in positional/EDI batch files):
+//
+//	H,20160908,42
+//	D,...
+//	D,...
+//	T,2
+//
+// The record type is the first field.
+type Framing struct {
+	HeaderType  string // type of the (mandatory) header record, as the first record (empty if none)
+	TrailerType string // type of the (mandatory) trailer record, as the last record (empty if none)
+	CountField  int    // index (first is 1) of the trailer field holding the number of data records (0 means no verification)
+
+	OnHeaderRecord  func(fields [][]byte) error // called with the header record (optional)
+	OnTrailerRecord func(fields [][]byte) error // called with the trailer record (optional)
+}
+
+type framing struct {
+	records int64 // data records read
+	header  bool  // header record seen
+	trailer bool  // trailer record seen
+}
+
+func (s *Reader) readFramedRecord() ([][]byte, error) {
+	f := s.Framing
+	for {
+		fields, err := s.readRecord()
+		if err == io.EOF {
+			if f.HeaderType != "" && !s.framing.header {
+				return nil, fmt.Errorf("missing %q header record", f.HeaderType)
+			} else if f.TrailerType != "" && !s.framing.trailer {
+				return nil, fmt.Errorf("missing %q trailer record at line %d", f.TrailerType, s.lineno)
+			}
+			return nil, err
+		} else if err != nil {
+			return nil, err
+		}
+		if s.framing.trailer {
+			return nil, fmt.Errorf("unexpected record after %q trailer record at line %d", f.TrailerType, s.lineno)
+		}
+		if f.HeaderType != "" && !s.framing.header {
+			if string(fields[0]) != f.HeaderType {
+				return nil, fmt.Errorf("missing %q header record at line %d", f.HeaderType, s.lineno)
+			}
+			s.framing.header = true
+			if f.OnHeaderRecord != nil {
+				if err = f.OnHeaderRecord(fields); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		if f.TrailerType != "" && string(fields[0]) == f.TrailerType {
+			s.framing.trailer = true
+			if f.CountField > 0 {
+				if f.CountField > len(fields) {
+					return nil, fmt.Errorf("missing count field %d in %q trailer record at line %d", f.CountField, f.TrailerType, s.lineno)
+				}
+				count, err := strconv.ParseInt(string(fields[f.CountField-1]), 10, 64)
+				if err != nil || count != s.framing.records {
+					return nil, fmt.Errorf("%q trailer record mismatch at line %d: %d record(s) read; trailer says %s", f.TrailerType, s.lineno, s.framing.records, fields[f.CountField-1])
+				}
+			}
+			if f.OnTrailerRecord != nil {
+				if err = f.OnTrailerRecord(fields); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		s.framing.records++
+		return fields, nil
+	}
+}
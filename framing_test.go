@@ -0,0 +1,53 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+var framingTests = []struct {
+	Name  string
+	Input string
+	N     int
+	Error string
+}{
+	{Name: "Valid", Input: "H,20160908,42\nD,a\nD,b\nT,2\n", N: 2},
+	{Name: "Empty", Input: "H,20160908,42\nT,0\n"},
+	{Name: "MissingHeader", Input: "D,a\nT,1\n", Error: "missing \"H\" header record"},
+	{Name: "MissingTrailer", Input: "H,20160908,42\nD,a\n", N: 1, Error: "missing \"T\" trailer record"},
+	{Name: "CountMismatch", Input: "H,20160908,42\nD,a\nT,2\n", N: 1, Error: "trailer record mismatch"},
+	{Name: "AfterTrailer", Input: "H,20160908,42\nT,0\nD,a\n", Error: "unexpected record after"},
+}
+
+func TestFraming(t *testing.T) {
+	for _, tt := range framingTests {
+		var header, trailer string
+		r := DefaultReader(strings.NewReader(tt.Input))
+		r.Framing = &Framing{
+			HeaderType:      "H",
+			TrailerType:     "T",
+			CountField:      2,
+			OnHeaderRecord:  func(fields [][]byte) error { header = string(fields[1]); return nil },
+			OnTrailerRecord: func(fields [][]byte) error { trailer = string(fields[1]); return nil },
+		}
+		n, err := readAll(r)
+		if n != tt.N {
+			t.Errorf("%s: got %d record(s); want %d", tt.Name, n, tt.N)
+		}
+		if tt.Error == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.Name, err)
+			} else if header != "20160908" || trailer == "" {
+				t.Errorf("%s: hooks not called: %q, %q", tt.Name, header, trailer)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), tt.Error) {
+			t.Errorf("%s: error %v, want error %q", tt.Name, err, tt.Error)
+		}
+	}
+}
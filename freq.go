@@ -0,0 +1,174 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"container/heap"
+	"fmt"
+	"hash/maphash"
+	"io"
+	"sort"
+)
+
+// ValueCount is a distinct value of a column with its number of occurrences.
+type ValueCount struct {
+	Value string
+	Count int
+}
+
+// exactValues is the number of distinct values counted exactly by ValueCounts.
+const exactValues = 1 << 16
+
+// ValueCounts returns the topN most common values of the column col (first is 1)
+// of the remaining records of r, by decreasing count (and increasing value for equal counts).
+// Headers should be loaded before (see ScanHeaders). Records without the column are ignored.
+// Counts are exact while the column has at most 65536 distinct values;
+// beyond, memory is bounded by a count-min sketch and a heap of the topN candidates
+// and counts are estimated (they may be overestimated, never underestimated).
+func ValueCounts(r *Reader, col int, topN int) ([]ValueCount, error) {
+	if col <= 0 {
+		return nil, fmt.Errorf("invalid column index: %d", col)
+	} else if topN <= 0 {
+		return nil, nil
+	}
+	counter := newValueCounter(topN)
+	for {
+		fields, err := r.ReadRecord()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if col <= len(fields) {
+			counter.add(fields[col-1])
+		}
+	}
+	return counter.counts(), nil
+}
+
+// valueCounter counts the occurrences of the values of a column to find the topN most common ones.
+// Counts are exact while there are at most exactValues distinct values,
+// estimated by a count-min sketch beyond.
+type valueCounter struct {
+	topN   int
+	exact  map[string]int
+	sketch *countMinSketch
+	top    *topValues
+}
+
+func newValueCounter(topN int) *valueCounter {
+	return &valueCounter{topN: topN, exact: make(map[string]int)}
+}
+
+// add counts one occurrence of value.
+func (c *valueCounter) add(value []byte) {
+	if c.exact != nil {
+		c.exact[string(value)]++
+		if len(c.exact) <= exactValues {
+			return
+		}
+		// too many distinct values: only the topN candidates are kept
+		c.sketch = newCountMinSketch(4, 1<<16)
+		c.top = &topValues{index: make(map[string]int, c.topN)}
+		for v, n := range c.exact {
+			c.top.offer(v, c.sketch.addN([]byte(v), n), c.topN)
+		}
+		c.exact = nil
+		return
+	}
+	c.top.offer(string(value), c.sketch.addN(value, 1), c.topN)
+}
+
+// counts returns the topN most common values by decreasing count (and increasing value for equal counts).
+func (c *valueCounter) counts() []ValueCount {
+	var counts []ValueCount
+	if c.exact != nil {
+		counts = make([]ValueCount, 0, len(c.exact))
+		for v, n := range c.exact {
+			counts = append(counts, ValueCount{v, n})
+		}
+	} else {
+		counts = append(counts, c.top.values...)
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Value < counts[j].Value
+	})
+	if len(counts) > c.topN {
+		counts = counts[:c.topN]
+	}
+	return counts
+}
+
+// countMinSketch estimates the number of occurrences of values in bounded memory.
+type countMinSketch struct {
+	seeds []maphash.Seed
+	rows  [][]uint32
+}
+
+func newCountMinSketch(depth, width int) *countMinSketch {
+	s := &countMinSketch{seeds: make([]maphash.Seed, depth), rows: make([][]uint32, depth)}
+	for i := range s.rows {
+		s.seeds[i] = maphash.MakeSeed()
+		s.rows[i] = make([]uint32, width)
+	}
+	return s
+}
+
+// addN counts n occurrences of value and returns its estimated count.
+func (s *countMinSketch) addN(value []byte, n int) int {
+	var min uint32
+	for i, row := range s.rows {
+		c := &row[maphash.Bytes(s.seeds[i], value)%uint64(len(row))]
+		*c += uint32(n)
+		if i == 0 || *c < min {
+			min = *c
+		}
+	}
+	return int(min)
+}
+
+// topValues is a min-heap (by count) of the candidate values.
+type topValues struct {
+	values []ValueCount
+	index  map[string]int // position of each value in the heap
+}
+
+// offer updates the count of value when it is a candidate or makes it a candidate
+// when there are less than n candidates or when its count is greater than the smallest one.
+func (t *topValues) offer(value string, count, n int) {
+	if i, ok := t.index[value]; ok {
+		t.values[i].Count = count
+		heap.Fix(t, i)
+	} else if len(t.values) < n {
+		heap.Push(t, ValueCount{value, count})
+	} else if count > t.values[0].Count {
+		delete(t.index, t.values[0].Value)
+		t.values[0] = ValueCount{value, count}
+		t.index[value] = 0
+		heap.Fix(t, 0)
+	}
+}
+
+func (t *topValues) Len() int           { return len(t.values) }
+func (t *topValues) Less(i, j int) bool { return t.values[i].Count < t.values[j].Count }
+func (t *topValues) Swap(i, j int) {
+	t.values[i], t.values[j] = t.values[j], t.values[i]
+	t.index[t.values[i].Value] = i
+	t.index[t.values[j].Value] = j
+}
+func (t *topValues) Push(x interface{}) {
+	v := x.(ValueCount)
+	t.index[v.Value] = len(t.values)
+	t.values = append(t.values, v)
+}
+func (t *topValues) Pop() interface{} {
+	v := t.values[len(t.values)-1]
+	t.values = t.values[:len(t.values)-1]
+	delete(t.index, v.Value)
+	return v
+}
@@ -0,0 +1,56 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+func TestValueCounts(t *testing.T) {
+	r := DefaultReader(strings.NewReader("id,color\n1,red\n2,blue\n3,red\n4\n5,\"gr\neen\"\n6,blue\n7,red\n"))
+	if err := r.ScanHeaders(); err != nil {
+		t.Fatal(err)
+	}
+	counts, err := ValueCounts(r, 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ValueCount{{"red", 3}, {"blue", 2}}
+	if !reflect.DeepEqual(expected, counts) {
+		t.Errorf("got %v; want %v", counts, expected)
+	}
+
+	if _, err := ValueCounts(DefaultReader(strings.NewReader("a\n")), 0, 1); err == nil {
+		t.Error("error expected")
+	}
+}
+
+func TestValueCountsHighCardinality(t *testing.T) {
+	var b strings.Builder
+	for i := 0; i < 100000; i++ {
+		fmt.Fprintf(&b, "%d\n", i)
+		if i%1000 == 0 {
+			b.WriteString("frequent\n")
+		}
+		if i%2000 == 0 {
+			b.WriteString("common\n")
+		}
+	}
+	counts, err := ValueCounts(DefaultReader(strings.NewReader(b.String())), 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) != 2 || counts[0].Value != "frequent" || counts[1].Value != "common" {
+		t.Fatalf("unexpected top values: %v", counts)
+	}
+	if counts[0].Count < 100 || counts[1].Count < 50 {
+		t.Errorf("counts must not be underestimated: %v", counts)
+	}
+}
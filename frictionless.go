@@ -0,0 +1,200 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+)
+
+// tableSchema is a Frictionless Data "table schema".
+type tableSchema struct {
+	Fields        []tableField `json:"fields"`
+	MissingValues []string     `json:"missingValues,omitempty"`
+}
+
+type tableField struct {
+	Name        string            `json:"name"`
+	Type        string            `json:"type,omitempty"`
+	Format      string            `json:"format,omitempty"`
+	Constraints *tableConstraints `json:"constraints,omitempty"`
+}
+
+type tableConstraints struct {
+	Required bool              `json:"required,omitempty"`
+	Minimum  *float64          `json:"minimum,omitempty"`
+	Maximum  *float64          `json:"maximum,omitempty"`
+	Enum     []json.RawMessage `json:"enum,omitempty"`
+	Pattern  string            `json:"pattern,omitempty"`
+}
+
+// ReadTableSchema decodes a Frictionless Data table schema (JSON).
+// Types are mapped to FieldType ("object" and "array" to JSONType, unknown types are kept as is and handled as strings),
+// missingValues to Column.Nulls and constraints (required, minimum, maximum, enum and pattern) to the matching Column fields.
+func ReadTableSchema(r io.Reader) (Schema, error) {
+	var ts tableSchema
+	if err := json.NewDecoder(r).Decode(&ts); err != nil {
+		return Schema{}, err
+	}
+	return ts.schema(), nil
+}
+
+func (ts *tableSchema) schema() Schema {
+	var schema Schema
+	for _, f := range ts.Fields {
+		c := Column{Name: f.Name, Type: FieldType(f.Type), Nulls: ts.MissingValues}
+		switch f.Type {
+		case "", "any":
+			c.Type = StringType
+		case "object", "array":
+			c.Type = JSONType
+		}
+		if f.Format != "default" {
+			c.Format = f.Format
+		}
+		if cs := f.Constraints; cs != nil {
+			c.Required = cs.Required
+			c.Pattern = cs.Pattern
+			if cs.Minimum != nil {
+				c.Min, c.HasMin = *cs.Minimum, true
+			}
+			if cs.Maximum != nil {
+				c.Max, c.HasMax = *cs.Maximum, true
+			}
+			for _, raw := range cs.Enum {
+				var s string
+				if err := json.Unmarshal(raw, &s); err != nil { // not a string
+					s = string(raw)
+				}
+				c.Enum = append(c.Enum, s)
+			}
+		}
+		schema.Columns = append(schema.Columns, c)
+	}
+	return schema
+}
+
+// WriteTableSchema encodes schema as a Frictionless Data table schema (indented JSON).
+// Column.Nulls are saved as missingValues when they are the same for all columns.
+func WriteTableSchema(w io.Writer, schema Schema) error {
+	var ts tableSchema
+	for i, c := range schema.Columns {
+		if i == 0 {
+			ts.MissingValues = c.Nulls
+		} else if !reflect.DeepEqual(c.Nulls, ts.MissingValues) {
+			return fmt.Errorf("column %s: different null values are not supported by table schemas", c.Name)
+		}
+		f := tableField{Name: c.Name, Type: string(c.Type), Format: c.Format}
+		switch c.Type {
+		case "":
+			f.Type = string(StringType)
+		case JSONType:
+			f.Type = "object"
+		}
+		cs := &tableConstraints{Required: c.Required, Pattern: c.Pattern}
+		if min, max, ok := c.bounds(); ok {
+			if !math.IsInf(min, -1) {
+				cs.Minimum = &min
+			}
+			if !math.IsInf(max, 1) {
+				cs.Maximum = &max
+			}
+		}
+		for _, e := range c.Enum {
+			raw, _ := json.Marshal(e)
+			cs.Enum = append(cs.Enum, raw)
+		}
+		if cs.Required || cs.Pattern != "" || cs.Minimum != nil || cs.Maximum != nil || len(cs.Enum) > 0 {
+			f.Constraints = cs
+		}
+		ts.Fields = append(ts.Fields, f)
+	}
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(ts)
+}
+
+// DataResource is a tabular resource of a Frictionless data package (datapackage.json).
+type DataResource struct {
+	Name    string
+	Path    string // first path of the resource (relative to the data package)
+	Dialect Dialect
+	Header  bool // true when the file starts with a header line
+	Schema  Schema
+}
+
+// ReadDataPackage decodes the tabular resources (with an inline schema) of a Frictionless data package.
+func ReadDataPackage(r io.Reader) ([]DataResource, error) {
+	var dp struct {
+		Resources []struct {
+			Name    string          `json:"name"`
+			Path    json.RawMessage `json:"path"`
+			Schema  json.RawMessage `json:"schema"`
+			Dialect *struct {
+				Delimiter   string `json:"delimiter"`
+				Header      *bool  `json:"header"`
+				CommentChar string `json:"commentChar"`
+			} `json:"dialect"`
+		} `json:"resources"`
+	}
+	if err := json.NewDecoder(r).Decode(&dp); err != nil {
+		return nil, err
+	}
+	var resources []DataResource
+	for _, res := range dp.Resources {
+		dr := DataResource{Name: res.Name, Dialect: DialectDefault, Header: true}
+		var paths []string
+		if err := json.Unmarshal(res.Path, &dr.Path); err != nil {
+			if err = json.Unmarshal(res.Path, &paths); err != nil || len(paths) == 0 {
+				return nil, fmt.Errorf("resource %s: invalid path: %s", res.Name, res.Path)
+			}
+			dr.Path = paths[0]
+		}
+		if d := res.Dialect; d != nil {
+			if len(d.Delimiter) > 1 || len(d.CommentChar) > 1 {
+				return nil, fmt.Errorf("resource %s: unsupported dialect: delimiter %q, comment char %q", res.Name, d.Delimiter, d.CommentChar)
+			}
+			if d.Delimiter != "" {
+				dr.Dialect.Sep = d.Delimiter[0]
+			}
+			if d.CommentChar != "" {
+				dr.Dialect.Comment = d.CommentChar[0]
+			}
+			if d.Header != nil {
+				dr.Header = *d.Header
+			}
+		}
+		if len(res.Schema) > 0 && res.Schema[0] == '{' {
+			var ts tableSchema
+			if err := json.Unmarshal(res.Schema, &ts); err != nil {
+				return nil, fmt.Errorf("resource %s: %s", res.Name, err)
+			}
+			dr.Schema = ts.schema()
+		}
+		resources = append(resources, dr)
+	}
+	return resources, nil
+}
+
+// NewReader returns a Reader of the resource content configured with its dialect and columns
+// (the header line is consumed when present).
+func (dr *DataResource) NewReader(r io.Reader) (*Reader, error) {
+	s := dr.Dialect.NewReader(r)
+	if dr.Header {
+		if err := s.ScanHeaders(); err != nil {
+			return nil, err
+		}
+	}
+	if len(dr.Schema.Columns) > 0 {
+		s.Headers = make(map[string]int, len(dr.Schema.Columns))
+		for i, c := range dr.Schema.Columns {
+			s.Headers[c.Name] = i + 1
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,135 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+const dataPackage = `{
+  "name": "inventory",
+  "resources": [{
+    "name": "items",
+    "path": "items.csv",
+    "dialect": {"delimiter": ";"},
+    "schema": {
+      "fields": [
+        {"name": "id", "type": "integer", "constraints": {"required": true, "minimum": 1}},
+        {"name": "status", "type": "string", "constraints": {"enum": ["new", "used"]}},
+        {"name": "sku", "type": "string", "constraints": {"pattern": "[A-Z]{3}-[0-9]+"}},
+        {"name": "price", "type": "number"},
+        {"name": "attrs", "type": "object"}
+      ],
+      "missingValues": ["", "NA"]
+    }
+  }]
+}`
+
+func TestFrictionless(t *testing.T) {
+	resources, err := ReadDataPackage(strings.NewReader(dataPackage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resources) != 1 || resources[0].Path != "items.csv" || resources[0].Dialect.Sep != ';' {
+		t.Fatalf("got %+v", resources)
+	}
+	res := resources[0]
+	r, err := res.NewReader(strings.NewReader("id;status;sku;price;attrs\n1;new;ABC-1;9.5;{\"a\":1}\n2;NA;ABC-2;NA;\n0;old;abc;x;{\n;new;ABC-3;1;\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var errs []string
+	var values [][]interface{}
+	for {
+		fields, err := r.ReadRecord()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if err = res.Schema.Validate(fields); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		v, err := res.Schema.Decode(fields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, v)
+	}
+	want := [][]interface{}{
+		{int64(1), "new", "ABC-1", 9.5, map[string]interface{}{"a": json.Number("1")}},
+		{int64(2), nil, "ABC-2", nil, nil},
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("got %#v; want %#v", values, want)
+	}
+	if wantErrs := []string{"column 1 (id): value out of range [1, +Inf]: 0", "column 1 (id): missing required value"}; !reflect.DeepEqual(errs, wantErrs) {
+		t.Errorf("got %q; want %q", errs, wantErrs)
+	}
+
+	b := &bytes.Buffer{}
+	if err = WriteTableSchema(b, res.Schema); err != nil {
+		t.Fatal(err)
+	}
+	schema, err := ReadTableSchema(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(schema, res.Schema) {
+		t.Errorf("got %+v; want %+v", schema, res.Schema)
+	}
+	if c := schema.Columns[0]; c.Min != 1 || !c.HasMin || c.HasMax {
+		t.Errorf("got [%g, %g] (%t, %t)", c.Min, c.Max, c.HasMin, c.HasMax)
+	}
+}
+
+func TestFrictionlessOneSidedConstraints(t *testing.T) {
+	schema, err := ReadTableSchema(strings.NewReader(`{"fields": [
+		{"name": "qty", "type": "integer", "constraints": {"minimum": 5}},
+		{"name": "delta", "type": "number", "constraints": {"maximum": -1}},
+		{"name": "zero", "type": "integer", "constraints": {"minimum": 0, "maximum": 0}}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = schema.Validate([][]byte{[]byte("1000000000"), []byte("-1e9"), []byte("0")}); err != nil {
+		t.Error(err)
+	}
+	if err = schema.Validate([][]byte{[]byte("5"), []byte("-1"), []byte("1")}); err == nil {
+		t.Error("error expected for [0, 0] range")
+	}
+	var b bytes.Buffer
+	if err = Generate(DefaultWriter(&b), schema, 100, 1); err != nil {
+		t.Fatal(err)
+	}
+	r := DefaultReader(&b)
+	r.ScanHeaders()
+	for {
+		fields, err := r.ReadRecord()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if err = schema.Validate(fields); err != nil {
+			t.Errorf("generated record %q: %s", fields, err)
+		}
+	}
+	b.Reset()
+	if err = WriteTableSchema(&b, schema); err != nil {
+		t.Fatal(err)
+	}
+	if s := b.String(); !strings.Contains(s, `"minimum": 0`) || strings.Count(s, "maximum") != 2 {
+		t.Errorf("constraints not saved: %s", s)
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+var (
+	fakeFirstNames = []string{"James", "Mary", "John", "Patricia", "Robert", "Jennifer", "Michael", "Linda", "Ana", "Chloé", "Hiroshi", "Fatima", "Olga", "Luis"}
+	fakeLastNames  = []string{"Smith", "Johnson", "Williams", "Brown", "Garcia", "Miller", "Martin", "Dubois", "Müller", "Rossi", "Tanaka", "O'Brien", "Nguyen", "Kowalski"}
+	fakeDomains    = []string{"example.com", "example.org", "example.net", "mail.test"}
+	fakeWords      = strings.Fields("lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod tempor incididunt ut labore et dolore magna aliqua")
+)
+
+// Generate writes a header line followed by rows records of fake data matching schema (for load testing or benchmarks).
+// Values are realistic according to the column type and format (see Column),
+// numbers are within [Min, Max] (default is [0, 1000000]) and nulls (empty values) appear at NullRate.
+// The output is deterministic for a given seed.
+func Generate(w *Writer, schema Schema, rows int, seed int64) error {
+	rnd := rand.New(rand.NewSource(seed))
+	for _, c := range schema.Columns {
+		w.WriteString(c.Name)
+	}
+	w.EndOfRecord()
+	var buf []byte
+	for i := 0; i < rows && w.Err() == nil; i++ {
+		for _, c := range schema.Columns {
+			var err error
+			if buf, err = fakeValue(buf[:0], rnd, c); err != nil {
+				return err
+			}
+			w.Write(buf)
+		}
+		w.EndOfRecord()
+	}
+	w.Flush()
+	return w.Err()
+}
+
+// fakeRange returns the (finite) range of the numbers generated for the column c:
+// [0, 1000000] by default and a million wide when only one bound is finite.
+func fakeRange(c Column) (float64, float64, error) {
+	min, max, ok := c.bounds()
+	if !ok {
+		max = 1000000
+	}
+	if math.IsNaN(min) || math.IsNaN(max) || min > max || math.IsInf(min, 1) || math.IsInf(max, -1) {
+		return 0, 0, fmt.Errorf("invalid range [%g, %g] of column %s", min, max, c.Name)
+	}
+	if math.IsInf(min, -1) && math.IsInf(max, 1) {
+		min, max = 0, 1000000
+	} else if math.IsInf(min, -1) {
+		min = max - 1000000
+	} else if math.IsInf(max, 1) {
+		max = min + 1000000
+	}
+	return min, max, nil
+}
+
+func fakeValue(dst []byte, rnd *rand.Rand, c Column) ([]byte, error) {
+	if c.NullRate > 0 && rnd.Float64() < c.NullRate {
+		return dst, nil
+	}
+	switch c.Type {
+	case IntegerType:
+		min, max, err := fakeRange(c)
+		if err != nil {
+			return nil, err
+		}
+		lo, hi := int64(math.Max(math.Ceil(min), math.MinInt64)), int64(math.Min(math.Floor(max), math.MaxInt64/2))
+		if lo > hi {
+			return nil, fmt.Errorf("no integer in range [%g, %g] of column %s", min, max, c.Name)
+		}
+		span := uint64(hi-lo) + 1 // cannot overflow: hi is clamped
+		return strconv.AppendInt(dst, lo+int64(rnd.Uint64()%span), 10), nil
+	case NumberType:
+		min, max, err := fakeRange(c)
+		if err != nil {
+			return nil, err
+		}
+		return strconv.AppendFloat(dst, min+rnd.Float64()*(max-min), 'f', 2, 64), nil
+	case BooleanType:
+		return strconv.AppendBool(dst, rnd.Intn(2) == 0), nil
+	case JSONType:
+		dst = append(dst, `{"id":`...)
+		dst = strconv.AppendInt(dst, rnd.Int63n(1000000), 10)
+		dst = append(dst, `,"tag":"`...)
+		dst = append(dst, fakeWords[rnd.Intn(len(fakeWords))]...)
+		return append(dst, `"}`...), nil
+	case StringType, "":
+	default:
+		return nil, fmt.Errorf("unsupported type %q for column %s", c.Type, c.Name)
+	}
+	switch c.Format {
+	case "name":
+		dst = append(dst, fakeFirstNames[rnd.Intn(len(fakeFirstNames))]...)
+		dst = append(dst, ' ')
+		return append(dst, fakeLastNames[rnd.Intn(len(fakeLastNames))]...), nil
+	case "email":
+		dst = append(dst, strings.ToLower(fakeFirstNames[rnd.Intn(len(fakeFirstNames))])...)
+		dst = append(dst, '.')
+		dst = strconv.AppendInt(dst, int64(rnd.Intn(1000)), 10)
+		dst = append(dst, '@')
+		return append(dst, fakeDomains[rnd.Intn(len(fakeDomains))]...), nil
+	case "uuid":
+		var b [16]byte
+		rnd.Read(b[:])
+		b[6] = b[6]&0x0f | 0x40 // version 4
+		b[8] = b[8]&0x3f | 0x80 // variant
+		return append(dst, fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])...), nil
+	case "":
+		for n := 1 + rnd.Intn(5); n > 0; n-- {
+			dst = append(dst, fakeWords[rnd.Intn(len(fakeWords))]...)
+			if n > 1 {
+				dst = append(dst, ' ')
+			}
+		}
+		return dst, nil
+	}
+	return nil, fmt.Errorf("unsupported format %q for column %s", c.Format, c.Name)
+}
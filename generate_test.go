@@ -0,0 +1,95 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+var generateSchema = Schema{Columns: []Column{
+	{Name: "id", Type: IntegerType, Min: 1, Max: 10},
+	{Name: "name", Format: "name"},
+	{Name: "email", Format: "email", NullRate: 0.5},
+	{Name: "score", Type: NumberType, Min: -1, Max: 1},
+	{Name: "active", Type: BooleanType},
+	{Name: "uuid", Format: "uuid"},
+	{Name: "payload", Type: JSONType},
+	{Name: "comment"},
+}}
+
+func TestGenerate(t *testing.T) {
+	var b1, b2 bytes.Buffer
+	if err := Generate(DefaultWriter(&b1), generateSchema, 1000, 42); err != nil {
+		t.Fatal(err)
+	}
+	if err := Generate(DefaultWriter(&b2), generateSchema, 1000, 42); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b1.Bytes(), b2.Bytes()) {
+		t.Error("output not deterministic")
+	}
+	r := DefaultReader(&b1)
+	if err := r.ScanHeaders(); err != nil {
+		t.Fatal(err)
+	}
+	rows, nulls := 0, 0
+	for {
+		var id int
+		var name, email string
+		var score float64
+		var active bool
+		var uuid, payload, comment string
+		if n, err := r.ScanRecord(&id, &name, &email, &score, &active, &uuid, &payload, &comment); err != nil {
+			t.Fatal(err)
+		} else if n == 0 {
+			break
+		}
+		rows++
+		if id < 1 || id > 10 || score < -1 || score > 1 || name == "" || len(uuid) != 36 || !strings.HasPrefix(payload, "{") {
+			t.Errorf("unexpected record: %d, %q, %q, %s, %q, %q", id, name, email, strconv.FormatFloat(score, 'f', -1, 64), uuid, payload)
+		}
+		if email == "" {
+			nulls++
+		} else if !strings.Contains(email, "@") {
+			t.Errorf("invalid email: %q", email)
+		}
+	}
+	if rows != 1000 {
+		t.Errorf("got %d rows; want 1000", rows)
+	}
+	if nulls < 400 || nulls > 600 {
+		t.Errorf("got %d nulls; want about 500", nulls)
+	}
+	if err := Generate(DefaultWriter(&b1), Schema{Columns: []Column{{Name: "x", Format: "phone"}}}, 1, 0); err == nil {
+		t.Error("error expected for unknown format")
+	}
+	if err := Generate(DefaultWriter(&b1), Schema{Columns: []Column{{Name: "x", Type: IntegerType, Min: 0.2, Max: 0.8}}}, 1, 0); err == nil {
+		t.Error("error expected for empty range")
+	}
+	b1.Reset()
+	huge := Schema{Columns: []Column{{Name: "x", Type: IntegerType, Min: math.Inf(-1), Max: -5}, {Name: "y", Type: IntegerType, Min: -1e30, Max: 1e30}}}
+	if err := Generate(DefaultWriter(&b1), huge, 100, 0); err != nil {
+		t.Fatal(err)
+	}
+	r = DefaultReader(&b1)
+	r.ScanHeaders()
+	for {
+		var x, y int64
+		if n, err := r.ScanRecord(&x, &y); err != nil {
+			t.Fatal(err)
+		} else if n == 0 {
+			break
+		}
+		if x > -5 || x < -1000005 {
+			t.Errorf("value out of range: %d", x)
+		}
+	}
+}
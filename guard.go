@@ -0,0 +1,65 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SeparatorError is the error reported (wrapped in a ParseError) by ReadRecord when the separator is probably wrong
+// (see Reader.MaxColumns): a record has more than MaxColumns fields,
+// or the first record is one single field containing candidate separators.
+type SeparatorError struct {
+	Sep        byte   // separator used
+	Fields     int    // number of fields of the suspicious record
+	Candidates []byte // other separators found in the record values (most frequent first)
+}
+
+func (e *SeparatorError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "suspicious record with %d field(s) using separator %q: wrong separator?", e.Fields, e.Sep)
+	if len(e.Candidates) > 0 {
+		b.WriteString(" candidates:")
+		for _, c := range e.Candidates {
+			fmt.Fprintf(&b, " %q", c)
+		}
+	}
+	return b.String()
+}
+
+// checkColumns guards against a wrong separator (see MaxColumns).
+func (s *Reader) checkColumns() error {
+	n := len(s.recEnd)
+	if n <= s.MaxColumns && (n > 1 || s.prov.Record > 1) {
+		return nil
+	}
+	count := make(map[byte]uint)
+	start := 0
+	for i, end := range s.recEnd {
+		if !s.recQuoted[i] { // separators are legitimate in quoted values
+			for c, k := range countSeps(s.recBuf[start:end], false) {
+				count[c] += k
+			}
+		}
+		start = end
+	}
+	delete(count, s.sep)
+	if n <= s.MaxColumns && len(count) == 0 { // single column file
+		return nil
+	}
+	candidates := make([]byte, 0, len(count))
+	for c := range count {
+		candidates = append(candidates, c)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if count[candidates[i]] != count[candidates[j]] {
+			return count[candidates[i]] > count[candidates[j]]
+		}
+		return candidates[i] < candidates[j]
+	})
+	return &ParseError{Line: s.recordLine(), Err: &SeparatorError{Sep: s.sep, Fields: n, Candidates: candidates}}
+}
@@ -0,0 +1,72 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"io"
+)
+
+// HashRecord resets h, feeds it with the record fields and returns the resulting sum.
+// Fields are length-prefixed so that ("a,b") and ("a", "b") give different hashes.
+// Hashing parsed fields makes the result independent of quoting and record terminators.
+// When canonical is true, line breaks embedded in fields (\r\n or \r) are normalized to \n.
+func HashRecord(h hash.Hash, fields [][]byte, canonical bool) []byte {
+	h.Reset()
+	writeRecordHash(h, fields, canonical, nil)
+	return h.Sum(nil)
+}
+
+// writeRecordHash feeds h with the record fields (buf is a reusable scratch buffer).
+func writeRecordHash(h hash.Hash, fields [][]byte, canonical bool, buf []byte) []byte {
+	var n [binary.MaxVarintLen64]byte
+	h.Write(n[:binary.PutUvarint(n[:], uint64(len(fields)))])
+	for _, field := range fields {
+		if canonical && bytes.IndexByte(field, '\r') >= 0 {
+			buf = canonicalNewlines(buf[:0], field)
+			field = buf
+		}
+		h.Write(n[:binary.PutUvarint(n[:], uint64(len(field)))])
+		h.Write(field)
+	}
+	return buf
+}
+
+// canonicalNewlines appends field to dst with \r\n and \r replaced by \n.
+func canonicalNewlines(dst, field []byte) []byte {
+	for i := 0; i < len(field); i++ {
+		c := field[i]
+		if c == '\r' {
+			c = '\n'
+			if i+1 < len(field) && field[i+1] == '\n' {
+				i++
+			}
+		}
+		dst = append(dst, c)
+	}
+	return dst
+}
+
+// Fingerprint returns a SHA-256 (hex-encoded) of the content of all remaining records in r,
+// stable across quoting, record terminators and embedded line breaks differences (see HashRecord).
+// Records order matters. Empty lines are ignored.
+func Fingerprint(r *Reader) (string, error) {
+	h := sha256.New()
+	var buf []byte
+	for {
+		fields, err := r.ReadRecord()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return "", err
+		}
+		buf = writeRecordHash(h, fields, true, buf)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+func TestHashRecord(t *testing.T) {
+	h := sha1.New()
+	ab := HashRecord(h, [][]byte{[]byte("a,b")}, false)
+	if bytes.Equal(ab, HashRecord(h, [][]byte{[]byte("a"), []byte("b")}, false)) {
+		t.Error("fields boundaries must be hashed")
+	}
+	if !bytes.Equal(ab, HashRecord(h, [][]byte{[]byte("a,b")}, true)) {
+		t.Error("hash must be reproducible")
+	}
+	if bytes.Equal(HashRecord(h, [][]byte{[]byte("a\r\nb")}, false), HashRecord(h, [][]byte{[]byte("a\nb")}, false)) {
+		t.Error("line breaks are significant in non-canonical mode")
+	}
+	if !bytes.Equal(HashRecord(h, [][]byte{[]byte("a\r\nb")}, true), HashRecord(h, [][]byte{[]byte("a\nb")}, true)) {
+		t.Error("line breaks must be normalized in canonical mode")
+	}
+}
+
+var fingerprintTests = []struct {
+	Input string
+	Same  bool
+}{
+	{Input: "\"a\",b\r\n\"c\r\nd\",e\r\n\r\n", Same: true},
+	{Input: "a,b\n\"c\rd\",e", Same: true},
+	{Input: "a,b\nc\nd,e\n"},
+	{Input: "c\nd,e\na,b\n"},
+}
+
+func TestFingerprint(t *testing.T) {
+	want, err := Fingerprint(DefaultReader(strings.NewReader("a,b\n\"c\nd\",e\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range fingerprintTests {
+		got, err := Fingerprint(DefaultReader(strings.NewReader(tt.Input)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (got == want) != tt.Same {
+			t.Errorf("%q: got %s; want same: %t", tt.Input, got, tt.Same)
+		}
+	}
+}
+
+func TestIdempotentWriter(t *testing.T) {
+	records := [][][]byte{
+		{[]byte("1"), []byte("a")},
+		{[]byte("2"), []byte("b\r\nc")},
+		{[]byte("3"), []byte("d")},
+	}
+	// first run fails after two records
+	var out bytes.Buffer
+	w := DefaultWriter(&out)
+	iw := NewIdempotentWriter(w)
+	for _, fields := range records[:2] {
+		iw.WriteFields(fields)
+	}
+	w.Flush()
+	// retry with the set seeded from the existing output
+	w = DefaultWriter(&out)
+	iw = NewIdempotentWriter(w)
+	if err := iw.Seed(DefaultReader(bytes.NewReader(out.Bytes()))); err != nil {
+		t.Fatal(err)
+	}
+	for _, fields := range records {
+		if !iw.WriteFields(fields) {
+			t.Fatal(w.Err())
+		}
+	}
+	w.Flush()
+	if want := "1,a\n2,\"b\r\nc\"\n3,d\n"; out.String() != want {
+		t.Errorf("got %q; want %q", out.String(), want)
+	}
+	if iw.Skipped() != 2 || iw.Len() != 3 {
+		t.Errorf("got %d skipped and %d hashes; want 2 and 3", iw.Skipped(), iw.Len())
+	}
+
+	var saved bytes.Buffer
+	if err := iw.Save(&saved); err != nil {
+		t.Fatal(err)
+	}
+	iw = NewIdempotentWriter(DefaultWriter(&out))
+	if err := iw.Load(bytes.NewReader(saved.Bytes())); err != nil {
+		t.Fatal(err)
+	} else if iw.Len() != 3 {
+		t.Errorf("got %d loaded hashes; want 3", iw.Len())
+	}
+	if err := iw.Load(strings.NewReader("truncated")); err == nil {
+		t.Error("error expected for truncated hashes")
+	}
+}
+
+func TestIdempotentWriterError(t *testing.T) {
+	var out bytes.Buffer
+	iw := NewIdempotentWriter(NewWriter(&out, ',', false))
+	if iw.WriteFields([][]byte{[]byte("a,b")}) {
+		t.Fatal("error expected for separator in unquoted value")
+	}
+	if iw.Len() != 0 {
+		t.Errorf("got %d hashes; want 0 (record not written)", iw.Len())
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"io"
+	"math/rand"
+	"sort"
+)
+
+// Head returns the header (the first record) and the n following records.
+// Unlike the head command, multiline (quoted) records are never split.
+func Head(r *Reader, n int) (header []string, records [][]string, err error) {
+	if header, err = readStrings(r); err != nil || header == nil {
+		return
+	}
+	for len(records) < n {
+		var record []string
+		if record, err = readStrings(r); err != nil || record == nil {
+			return
+		}
+		records = append(records, record)
+	}
+	return
+}
+
+// Tail returns the header (the first record) and the n last records.
+// Only n records are kept in memory while the input is consumed.
+// Unlike the tail command, multiline (quoted) records are never split.
+func Tail(r *Reader, n int) (header []string, records [][]string, err error) {
+	if header, err = readStrings(r); err != nil || header == nil || n <= 0 {
+		return
+	}
+	var ring [][]string
+	next := 0 // oldest record once the ring is full
+	for {
+		var record []string
+		if record, err = readStrings(r); err != nil {
+			return
+		} else if record == nil {
+			break
+		}
+		if len(ring) < n {
+			ring = append(ring, record)
+		} else {
+			ring[next] = record
+			next = (next + 1) % n
+		}
+	}
+	records = append(ring[next:len(ring):len(ring)], ring[:next]...)
+	return
+}
+
+// Sample returns the header (the first record) and n records chosen uniformly at random (in input order),
+// using reservoir sampling: only n records are kept in memory while the input is consumed.
+// The sample is reproducible for a given source of rnd.
+// Unlike the shuf command, multiline (quoted) records are never split.
+func Sample(r *Reader, n int, rnd *rand.Rand) (header []string, records [][]string, err error) {
+	if header, err = readStrings(r); err != nil || header == nil || n <= 0 {
+		return
+	}
+	var reservoir [][]string
+	var positions []int // of the sampled records in the input
+	for i := 0; ; i++ {
+		var record []string
+		if record, err = readStrings(r); err != nil {
+			return
+		} else if record == nil {
+			break
+		}
+		if len(reservoir) < n {
+			reservoir = append(reservoir, record)
+			positions = append(positions, i)
+		} else if j := rnd.Intn(i + 1); j < n {
+			reservoir[j] = record
+			positions[j] = i
+		}
+	}
+	order := make([]int, len(reservoir))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return positions[order[a]] < positions[order[b]] })
+	records = make([][]string, len(reservoir))
+	for i, j := range order {
+		records[i] = reservoir[j]
+	}
+	return
+}
+
+// readStrings returns a copy of the next record (nil on EOF).
+func readStrings(r *Reader) ([]string, error) {
+	fields, err := r.ReadRecord()
+	if err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	record := make([]string, len(fields))
+	for i, field := range fields {
+		record[i] = string(field)
+	}
+	return record, nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+const headTailInput = "h1,h2\n1,\"a\nb\"\n2,c\n3,\"d\ne\"\n"
+
+var headTailTests = []struct {
+	N    int
+	Head [][]string
+	Tail [][]string
+}{
+	{N: 0},
+	{N: 2, Head: [][]string{{"1", "a\nb"}, {"2", "c"}}, Tail: [][]string{{"2", "c"}, {"3", "d\ne"}}},
+	{N: 5, Head: [][]string{{"1", "a\nb"}, {"2", "c"}, {"3", "d\ne"}}, Tail: [][]string{{"1", "a\nb"}, {"2", "c"}, {"3", "d\ne"}}},
+	{N: math.MaxInt32, Head: [][]string{{"1", "a\nb"}, {"2", "c"}, {"3", "d\ne"}}, Tail: [][]string{{"1", "a\nb"}, {"2", "c"}, {"3", "d\ne"}}}, // no preallocation
+}
+
+func TestHeadTail(t *testing.T) {
+	for _, tt := range headTailTests {
+		header, records, err := Head(DefaultReader(strings.NewReader(headTailInput)), tt.N)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(header, []string{"h1", "h2"}) {
+			t.Errorf("%d: unexpected header: %q", tt.N, header)
+		}
+		if !reflect.DeepEqual(records, tt.Head) {
+			t.Errorf("%d: got head %q; want %q", tt.N, records, tt.Head)
+		}
+		header, records, err = Tail(DefaultReader(strings.NewReader(headTailInput)), tt.N)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(header, []string{"h1", "h2"}) {
+			t.Errorf("%d: unexpected header: %q", tt.N, header)
+		}
+		if len(records) != 0 || len(tt.Tail) != 0 {
+			if !reflect.DeepEqual(records, tt.Tail) {
+				t.Errorf("%d: got tail %q; want %q", tt.N, records, tt.Tail)
+			}
+		}
+	}
+}
+
+func TestSample(t *testing.T) {
+	input := "h1,h2\n" + strings.Repeat("1,\"a\nb\"\n2,c\n3,\"d\ne\"\n", 100)
+	header, records, err := Sample(DefaultReader(strings.NewReader(input)), 10, rand.New(rand.NewSource(42)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(header, []string{"h1", "h2"}) {
+		t.Errorf("unexpected header: %q", header)
+	}
+	if len(records) != 10 {
+		t.Fatalf("got %d records; want 10", len(records))
+	}
+	for _, record := range records {
+		if len(record) != 2 || record[0] == "1" && record[1] != "a\nb" {
+			t.Errorf("unexpected record: %q", record)
+		}
+	}
+	_, again, _ := Sample(DefaultReader(strings.NewReader(input)), 10, rand.New(rand.NewSource(42)))
+	if !reflect.DeepEqual(records, again) {
+		t.Errorf("sample must be reproducible: %q; %q", records, again)
+	}
+	for _, n := range []int{5, math.MaxInt32} {
+		_, records, _ = Sample(DefaultReader(strings.NewReader(headTailInput)), n, rand.New(rand.NewSource(42)))
+		if want := [][]string{{"1", "a\nb"}, {"2", "c"}, {"3", "d\ne"}}; !reflect.DeepEqual(records, want) {
+			t.Errorf("%d: got %q; want all records in order", n, records)
+		}
+	}
+}
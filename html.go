@@ -0,0 +1,94 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"bufio"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// HTMLOptions controls the rendering of ToHTMLTable.
+type HTMLOptions struct {
+	Header     bool   // True to render the first record as the header row (th)
+	TableClass string // class attribute of the table element (optional)
+	RowClass   string // class attribute of the body rows (optional)
+	MaxRows    int    // maximum number of rendered records, header excluded (0 means no limit)
+}
+
+// ToHTMLTable streams records from r into an HTML table written to w.
+// Values are escaped and embedded newlines are rendered as line breaks.
+// When MaxRows is reached, a truncation notice is rendered in the table footer
+// and the remaining records are not read.
+func ToHTMLTable(r *Reader, w io.Writer, opts HTMLOptions) error {
+	b := bufio.NewWriter(w)
+	b.WriteString("<table")
+	writeClass(b, opts.TableClass)
+	b.WriteString(">\n")
+	ncols := 0
+	if opts.Header {
+		fields, err := r.ReadRecord()
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if len(fields) > 0 {
+			ncols = len(fields)
+			b.WriteString("<thead>")
+			writeHTMLRow(b, fields, "th", "")
+			b.WriteString("</thead>\n")
+		}
+	}
+	b.WriteString("<tbody>\n")
+	truncated := false
+	for n := 0; ; n++ {
+		fields, err := r.ReadRecord()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if opts.MaxRows > 0 && n == opts.MaxRows {
+			truncated = true
+			break
+		}
+		if len(fields) > ncols {
+			ncols = len(fields)
+		}
+		writeHTMLRow(b, fields, "td", opts.RowClass)
+		b.WriteByte('\n')
+	}
+	b.WriteString("</tbody>\n")
+	if truncated {
+		b.WriteString("<tfoot><tr><td colspan=\"")
+		b.WriteString(strconv.Itoa(ncols))
+		b.WriteString("\">Truncated after ")
+		b.WriteString(strconv.Itoa(opts.MaxRows))
+		b.WriteString(" rows</td></tr></tfoot>\n")
+	}
+	b.WriteString("</table>\n")
+	return b.Flush()
+}
+
+func writeClass(b *bufio.Writer, class string) {
+	if class != "" {
+		b.WriteString(` class="`)
+		b.WriteString(html.EscapeString(class))
+		b.WriteString(`"`)
+	}
+}
+
+func writeHTMLRow(b *bufio.Writer, fields [][]byte, cell, class string) {
+	b.WriteString("<tr")
+	writeClass(b, class)
+	b.WriteString(">")
+	for _, field := range fields {
+		b.WriteString("<" + cell + ">")
+		b.WriteString(strings.Replace(html.EscapeString(string(field)), "\n", "<br>", -1))
+		b.WriteString("</" + cell + ">")
+	}
+	b.WriteString("</tr>")
+}
@@ -0,0 +1,57 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+var htmlTests = []struct {
+	Name   string
+	Input  string
+	Opts   HTMLOptions
+	Output string
+}{
+	{
+		Name:  "Header",
+		Input: "a,b\n<x>,\"y\nz\"\n",
+		Opts:  HTMLOptions{Header: true, TableClass: "t", RowClass: "r"},
+		Output: `<table class="t">
+<thead><tr><th>a</th><th>b</th></tr></thead>
+<tbody>
+<tr class="r"><td>&lt;x&gt;</td><td>y<br>z</td></tr>
+</tbody>
+</table>
+`,
+	},
+	{
+		Name:  "Truncated",
+		Input: "1,2\n3,4\n5,6\n",
+		Opts:  HTMLOptions{MaxRows: 2},
+		Output: `<table>
+<tbody>
+<tr><td>1</td><td>2</td></tr>
+<tr><td>3</td><td>4</td></tr>
+</tbody>
+<tfoot><tr><td colspan="2">Truncated after 2 rows</td></tr></tfoot>
+</table>
+`,
+	},
+}
+
+func TestToHTMLTable(t *testing.T) {
+	for _, tt := range htmlTests {
+		b := &bytes.Buffer{}
+		if err := ToHTMLTable(DefaultReader(strings.NewReader(tt.Input)), b, tt.Opts); err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.Name, err)
+		} else if b.String() != tt.Output {
+			t.Errorf("%s: got %q; want %q", tt.Name, b.String(), tt.Output)
+		}
+	}
+}
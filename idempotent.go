@@ -0,0 +1,111 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// IdempotentWriter writes records only once: records whose hash (see HashRecord) is in the set
+// of already written records are skipped, so that a retried batch job appending to the same output is safe.
+// The set can be seeded with the content of the existing output (see Seed)
+// or persisted between runs (see Save and Load).
+type IdempotentWriter struct {
+	w       *Writer
+	seen    map[[sha256.Size]byte]struct{}
+	h       hash.Hash
+	buf     []byte
+	sum     []byte
+	skipped int
+}
+
+// NewIdempotentWriter returns a writer of the new records to w.
+func NewIdempotentWriter(w *Writer) *IdempotentWriter {
+	return &IdempotentWriter{w: w, seen: make(map[[sha256.Size]byte]struct{}), h: sha256.New()}
+}
+
+// key returns the hash of the record.
+func (iw *IdempotentWriter) key(fields [][]byte) [sha256.Size]byte {
+	iw.h.Reset()
+	iw.buf = writeRecordHash(iw.h, fields, true, iw.buf)
+	iw.sum = iw.h.Sum(iw.sum[:0])
+	var key [sha256.Size]byte
+	copy(key[:], iw.sum)
+	return key
+}
+
+// add adds the record to the set.
+func (iw *IdempotentWriter) add(fields [][]byte) {
+	iw.seen[iw.key(fields)] = struct{}{}
+}
+
+// WriteFields writes the record unless it has already been written (or seeded).
+// The record is added to the set only when it has been written successfully.
+// Returns false when an error occurred (see Writer.Err).
+func (iw *IdempotentWriter) WriteFields(fields [][]byte) bool {
+	key := iw.key(fields)
+	if _, ok := iw.seen[key]; ok {
+		iw.skipped++
+		return true
+	} else if !iw.w.WriteFields(fields) {
+		return false
+	}
+	iw.seen[key] = struct{}{}
+	return true
+}
+
+// Seed adds all records from src (typically the existing output) to the set.
+func (iw *IdempotentWriter) Seed(src RecordSource) error {
+	for {
+		fields, err := src.ReadRecord()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		iw.add(fields)
+	}
+}
+
+// Len returns the number of records in the set.
+func (iw *IdempotentWriter) Len() int {
+	return len(iw.seen)
+}
+
+// Skipped returns the number of records skipped by WriteFields.
+func (iw *IdempotentWriter) Skipped() int {
+	return iw.skipped
+}
+
+// Save writes the set (the raw record hashes) to w.
+func (iw *IdempotentWriter) Save(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	for key := range iw.seen {
+		if _, err := b.Write(key[:]); err != nil {
+			return err
+		}
+	}
+	return b.Flush()
+}
+
+// Load adds the hashes saved by Save to the set.
+func (iw *IdempotentWriter) Load(r io.Reader) error {
+	b := bufio.NewReader(r)
+	for {
+		var key [sha256.Size]byte
+		if _, err := io.ReadFull(b, key[:]); err == io.EOF {
+			return nil
+		} else if err == io.ErrUnexpectedEOF {
+			return fmt.Errorf("truncated record hashes")
+		} else if err != nil {
+			return err
+		}
+		iw.seen[key] = struct{}{}
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Index locates the records of an (uncompressed) file by their byte offsets,
+// so that single records can be read or edited without parsing the whole file.
+type Index struct {
+	path   string
+	d      Dialect
+	sep    byte    // separator actually used (see Reader.Sep)
+	starts []int64 // byte offset of each record
+	ends   []int64 // byte offset following each record (line terminator included)
+}
+
+// NewIndex reads the named file once to index its records (see Provenance).
+func NewIndex(path string, d Dialect) (*Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	x := &Index{path: path, d: d}
+	r := d.NewReader(f)
+	for {
+		if _, err = r.ReadRecord(); err == io.EOF {
+			x.sep = r.Sep()
+			return x, nil
+		} else if err != nil {
+			return nil, err
+		}
+		x.starts = append(x.starts, r.prov.Offset)
+		x.ends = append(x.ends, r.offset)
+	}
+}
+
+// Len returns the number of records (header included).
+func (x *Index) Len() int {
+	return len(x.starts)
+}
+
+// Offset returns the byte offset of the record recordNo (first is 1), -1 when there is no such record.
+func (x *Index) Offset(recordNo int) int64 {
+	if recordNo < 1 || recordNo > len(x.starts) {
+		return -1
+	}
+	return x.starts[recordNo-1]
+}
+
+// ReadRecord reads the record recordNo (first is 1).
+func (x *Index) ReadRecord(recordNo int) ([]string, error) {
+	f, err := os.Open(x.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fields, _, _, err := x.readRecord(f, recordNo)
+	if err != nil {
+		return nil, err
+	}
+	return copyStrings(fields), nil
+}
+
+// readRecord reads the raw content and the fields of the record recordNo.
+// A leading BOM is part of the raw content but not of the first field.
+func (x *Index) readRecord(f *os.File, recordNo int) (fields [][]byte, quoted []bool, raw []byte, err error) {
+	if recordNo < 1 || recordNo > len(x.starts) {
+		return nil, nil, nil, fmt.Errorf("no record %d (%d records)", recordNo, len(x.starts))
+	}
+	start, end := x.starts[recordNo-1], x.ends[recordNo-1]
+	raw = make([]byte, end-start)
+	if _, err = f.ReadAt(raw, start); err != nil {
+		return nil, nil, nil, err
+	}
+	d := x.d
+	d.Sep = x.sep
+	r := d.NewReader(bytes.NewReader(bytes.TrimPrefix(raw, []byte("\uFEFF"))))
+	r.DetectSepHint = false
+	if fields, err = r.ReadRecord(); err != nil {
+		return nil, nil, nil, fmt.Errorf("record %d: %s (file modified since indexed?)", recordNo, err)
+	}
+	return fields, r.QuotedFields(), raw, nil
+}
+
+// Edit replaces the value of the column col (first is 1) of the record recordNo (first is 1).
+// The original quoting and line terminator of the record are preserved.
+// When the new record has the same size, it is overwritten in place;
+// otherwise only the tail of the file (following the record) is rewritten and the index is updated.
+func (x *Index) Edit(recordNo, col int, newValue string) error {
+	f, err := os.OpenFile(x.path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	if err = x.edit(f, recordNo, col, newValue); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (x *Index) edit(f *os.File, recordNo, col int, newValue string) error {
+	fields, quoted, raw, err := x.readRecord(f, recordNo)
+	if err != nil {
+		return err
+	} else if col < 1 || col > len(fields) {
+		return fmt.Errorf("no column %d in record %d (%d fields)", col, recordNo, len(fields))
+	}
+	fields[col-1] = []byte(newValue)
+	var b bytes.Buffer
+	if bytes.HasPrefix(raw, []byte("\uFEFF")) {
+		b.WriteString("\uFEFF")
+	}
+	w := NewWriter(&b, x.sep, x.d.Quoted)
+	w.Escape = x.d.Escape
+	w.UseCRLF = bytes.HasSuffix(raw, []byte("\r\n"))
+	w.WriteFieldsQuoted(fields, quoted)
+	w.Flush()
+	if err = w.Err(); err != nil {
+		return err
+	}
+	record := b.Bytes()
+	if !bytes.HasSuffix(raw, []byte{'\n'}) { // last record without line terminator
+		record = bytes.TrimSuffix(record, []byte{'\n'})
+	}
+	start, end := x.starts[recordNo-1], x.ends[recordNo-1]
+	delta := int64(len(record)) - (end - start)
+	if delta == 0 {
+		_, err = f.WriteAt(record, start)
+		return err
+	}
+	// Save the tail, then write the record followed by the tail.
+	tmp, err := os.CreateTemp("", "yacr-edit-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	tail, err := io.Copy(tmp, io.NewSectionReader(f, end, 1<<62))
+	if err != nil {
+		return err
+	}
+	if _, err = f.WriteAt(record, start); err != nil {
+		return err
+	}
+	if _, err = tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err = f.Seek(start+int64(len(record)), io.SeekStart); err != nil {
+		return err
+	}
+	if _, err = io.Copy(f, tmp); err != nil {
+		return err
+	}
+	if err = f.Truncate(start + int64(len(record)) + tail); err != nil {
+		return err
+	}
+	x.ends[recordNo-1] += delta
+	for i := recordNo; i < len(x.starts); i++ {
+		x.starts[i] += delta
+		x.ends[i] += delta
+	}
+	return nil
+}
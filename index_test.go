@@ -0,0 +1,96 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+func TestIndexEdit(t *testing.T) {
+	path := writeFiles(t, "id,name\r\n1,\"a\nb\"\r\n\r\n#c\r\n2,bb\r\n3,c")[0]
+	d := DialectDefault
+	d.Comment = '#'
+	x, err := NewIndex(path, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x.Len() != 4 || x.Offset(3) != 24 {
+		t.Fatalf("got %d records, offset %d", x.Len(), x.Offset(3))
+	}
+	var tests = []struct {
+		Record, Col int
+		Value       string
+		Content     string
+	}{
+		{3, 2, "xy", "id,name\r\n1,\"a\nb\"\r\n\r\n#c\r\n2,xy\r\n3,c"},
+		{2, 2, "a,b,c", "id,name\r\n1,\"a,b,c\"\r\n\r\n#c\r\n2,xy\r\n3,c"},
+		{3, 1, "", "id,name\r\n1,\"a,b,c\"\r\n\r\n#c\r\n,xy\r\n3,c"},
+		{4, 2, "z\"", "id,name\r\n1,\"a,b,c\"\r\n\r\n#c\r\n,xy\r\n3,\"z\"\"\""},
+	}
+	for _, tt := range tests {
+		if err = x.Edit(tt.Record, tt.Col, tt.Value); err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != tt.Content {
+			t.Errorf("got %q; want %q", content, tt.Content)
+		}
+	}
+	record, err := x.ReadRecord(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"3", "z\""}; !reflect.DeepEqual(record, want) {
+		t.Errorf("got %q; want %q", record, want)
+	}
+	if err = x.Edit(5, 1, ""); err == nil {
+		t.Error("error expected for missing record")
+	}
+	if err = x.Edit(1, 3, ""); err == nil {
+		t.Error("error expected for missing column")
+	}
+}
+
+func TestIndexSepHintAndBOM(t *testing.T) {
+	paths := writeFiles(t, "sep=;\nid;name\n1;a\n", "\uFEFFid,name\n1,a\n")
+	d := DialectDefault
+	d.SepHint = true
+	x, err := NewIndex(paths[0], d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record, err := x.ReadRecord(2); err != nil {
+		t.Fatal(err)
+	} else if want := []string{"1", "a"}; !reflect.DeepEqual(record, want) {
+		t.Errorf("got %q; want %q", record, want)
+	}
+	if x.Offset(0) != -1 || x.Offset(3) != -1 {
+		t.Errorf("got offsets %d, %d; want -1", x.Offset(0), x.Offset(3))
+	}
+	if err = x.Edit(2, 2, "b;c"); err != nil {
+		t.Fatal(err)
+	}
+	x, err = NewIndex(paths[1], DialectDefault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = x.Edit(1, 1, "key"); err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []string{"sep=;\nid;name\n1;\"b;c\"\n", "\uFEFFkey,name\n1,a\n"} {
+		if content, err := ioutil.ReadFile(paths[i]); err != nil {
+			t.Fatal(err)
+		} else if string(content) != want {
+			t.Errorf("got %q; want %q", content, want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// jsonPath is one sub-path extracted from a JSON column.
+type jsonPath struct {
+	col  int      // index (first is 0) of the JSON column
+	name string   // virtual column name ("<column>.<path>")
+	keys []string // object keys or array indexes
+}
+
+// JSONExtractor flattens JSON columns: the values at the sub-paths declared in the Schema (see Column.Paths)
+// are appended to records as virtual columns, so semi-structured exports can be loaded as plain tables.
+// Strings are extracted unquoted, null or missing values as empty fields and objects or arrays as JSON.
+type JSONExtractor struct {
+	paths  []jsonPath  // grouped by JSON column
+	doc    interface{} // decoded document of the current JSON column
+	docCol int         // index of the current JSON column
+	buf    []byte      // extracted values
+	ends   []int       // end of each extracted value in buf
+	out    [][]byte
+}
+
+// NewJSONExtractor returns an extractor for the JSON columns of schema.
+func NewJSONExtractor(schema *Schema) (*JSONExtractor, error) {
+	e := &JSONExtractor{docCol: -1}
+	for i, c := range schema.Columns {
+		if len(c.Paths) == 0 {
+			continue
+		}
+		if c.Type != JSONType {
+			return nil, fmt.Errorf("sub-paths declared on non JSON column: %s", c.Name)
+		}
+		for _, p := range c.Paths {
+			if p == "" {
+				return nil, fmt.Errorf("empty sub-path declared on column: %s", c.Name)
+			}
+			e.paths = append(e.paths, jsonPath{col: i, name: c.Name + "." + p, keys: strings.Split(p, ".")})
+		}
+	}
+	return e, nil
+}
+
+// Headers returns the names of the virtual columns (like "payload.user.id").
+func (e *JSONExtractor) Headers() []string {
+	names := make([]string, len(e.paths))
+	for i, p := range e.paths {
+		names[i] = p.name
+	}
+	return names
+}
+
+// Transform returns the record followed by the extracted values.
+// The returned fields may be overwritten by a subsequent call.
+func (e *JSONExtractor) Transform(fields [][]byte) ([][]byte, error) {
+	e.buf = e.buf[:0]
+	e.ends = e.ends[:0]
+	for _, p := range e.paths {
+		if p.col != e.docCol {
+			e.doc = nil
+			e.docCol = p.col
+			if p.col < len(fields) && len(bytes.TrimSpace(fields[p.col])) > 0 {
+				var err error
+				if e.doc, err = decodeJSON(fields[p.col]); err != nil {
+					e.docCol = -1
+					return nil, fmt.Errorf("invalid JSON in column %d: %s", p.col+1, err)
+				}
+			}
+		}
+		var err error
+		if e.buf, err = appendJSONValue(e.buf, lookupJSON(e.doc, p.keys)); err != nil {
+			return nil, err
+		}
+		e.ends = append(e.ends, len(e.buf))
+	}
+	e.docCol = -1
+	e.out = append(e.out[:0], fields...)
+	start := 0
+	for _, end := range e.ends {
+		e.out = append(e.out, e.buf[start:end:end])
+		start = end
+	}
+	return e.out, nil
+}
+
+// decodeJSON decodes the JSON document b (numbers as json.Number), rejecting any trailing data.
+func decodeJSON(b []byte) (interface{}, error) {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var doc interface{}
+	if err := d.Decode(&doc); err != nil {
+		return nil, err
+	} else if _, err = d.Token(); err != io.EOF {
+		return nil, errors.New("trailing data after the JSON document")
+	}
+	return doc, nil
+}
+
+// lookupJSON returns the value at keys in doc (nil when missing).
+func lookupJSON(doc interface{}, keys []string) interface{} {
+	for _, key := range keys {
+		switch v := doc.(type) {
+		case map[string]interface{}:
+			doc = v[key]
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil
+			}
+			doc = v[i]
+		default:
+			return nil
+		}
+	}
+	return doc
+}
+
+func appendJSONValue(dst []byte, v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return dst, nil
+	case string:
+		return append(dst, v...), nil
+	case json.Number:
+		return append(dst, v...), nil
+	case bool:
+		return strconv.AppendBool(dst, v), nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append(dst, b...), nil
+}
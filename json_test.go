@@ -0,0 +1,62 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+func TestJSONExtractor(t *testing.T) {
+	schema := &Schema{Columns: []Column{
+		{Name: "id", Type: IntegerType},
+		{Name: "payload", Type: JSONType, Paths: []string{"user.id", "user.name", "tags.1", "user", "missing"}},
+	}}
+	e, err := NewJSONExtractor(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"payload.user.id", "payload.user.name", "payload.tags.1", "payload.user", "payload.missing"}; !reflect.DeepEqual(e.Headers(), want) {
+		t.Errorf("got %q; want %q", e.Headers(), want)
+	}
+	r := DefaultReader(strings.NewReader(`1,"{""user"":{""id"":12345678901234567890,""name"":""Joe""},""tags"":[""a"",true]}"` + "\n2,\n3,{\n4,{}x\n5,[1] 2\n"))
+	var got [][]string
+	for {
+		fields, err := r.ReadRecord()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if fields, err = e.Transform(fields); err != nil {
+			if !strings.Contains(err.Error(), "column 2") {
+				t.Errorf("unexpected error: %v", err)
+			}
+			continue
+		}
+		var record []string
+		for _, field := range fields {
+			record = append(record, string(field))
+		}
+		got = append(got, record)
+	}
+	want := [][]string{
+		{"1", `{"user":{"id":12345678901234567890,"name":"Joe"},"tags":["a",true]}`, "12345678901234567890", "Joe", "true", `{"id":12345678901234567890,"name":"Joe"}`, ""},
+		{"2", "", "", "", "", "", ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q; want %q", got, want)
+	}
+	if _, err = schema.Decode([][]byte{[]byte("4"), []byte("{} x")}); err == nil {
+		t.Error("error expected for trailing data after the JSON document")
+	}
+	if _, err = NewJSONExtractor(&Schema{Columns: []Column{{Name: "id", Paths: []string{"x"}}}}); err == nil {
+		t.Error("error expected for non JSON column")
+	}
+}
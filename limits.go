@@ -0,0 +1,87 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Limits bounds the resources used to read untrusted input (user uploads in servers, ...).
+// A zero value means no limit.
+// MaxRecordSize also protects pipelines against a quoted value whose closing quote is missing
+// and which swallows the rest of the input into one record: the error reports the line where the record starts.
+// Beware that the size of a single field is also bounded by the buffer of the underlying bufio.Scanner
+// (bufio.MaxScanTokenSize by default, see Reader.Buffer): the bufio.ErrTooLong error is returned when it is exceeded.
+type Limits struct {
+	MaxRecordSize int // maximum size (in bytes) of a record, including separators, quotes and line terminator
+	MaxFields     int // maximum number of fields per record
+	MaxRecords    int // maximum number of records (empty lines and comments excepted)
+}
+
+// ErrLimit is the error reported when one of the Limits is exceeded.
+var ErrLimit = errors.New("limit exceeded")
+
+// limitError details ErrLimit.
+type limitError struct {
+	limit int
+	what  string
+	open  bool // true when the limit is exceeded inside a quoted value
+}
+
+func (e *limitError) Error() string {
+	if e.open {
+		return fmt.Sprintf("%s: more than %d %s (inside a quoted value, missing closing quote?)", ErrLimit, e.limit, e.what)
+	}
+	return fmt.Sprintf("%s: more than %d %s", ErrLimit, e.limit, e.what)
+}
+
+func (e *limitError) Is(target error) bool {
+	return target == ErrLimit
+}
+
+// limitsState tracks the resources used so far.
+type limitsState struct {
+	size    int // size of the current record
+	fields  int // number of fields of the current record
+	records int // number of records read
+}
+
+// checkLimits is called by ScanField after each step started at line: a is the number of bytes consumed
+// and more is true when more data is requested while n bytes are buffered.
+// Errors about a record spanning several lines (like a quoted value whose closing quote is missing
+// and which swallows the rest of the input) report the line where the record starts.
+func (s *Reader) checkLimits(line, a int, token []byte, more bool, n int) error {
+	l, st := s.Limits, &s.limits
+	if st.fields > 0 { // the record started with a previous field
+		line = s.prov.Line
+	}
+	st.size += a
+	if l.MaxRecordSize > 0 && (st.size > l.MaxRecordSize || more && st.size+n > l.MaxRecordSize) {
+		end := s.recordLine()
+		if more { // nothing consumed
+			end = s.lineno
+		}
+		return &ParseError{StartLine: line, Line: end, Err: &limitError{l.MaxRecordSize, "bytes per record", more && s.quotedTok}}
+	}
+	if token != nil {
+		st.fields++
+		if l.MaxFields > 0 && st.fields > l.MaxFields {
+			return &ParseError{StartLine: line, Line: s.recordLine(), Err: &limitError{l.MaxFields, "fields per record", false}}
+		}
+	}
+	if !s.eor || a == 0 {
+		return nil
+	}
+	blank := token == nil || st.fields == 1 && (s.comment || s.BlankLines != BlankLineAsRecord && len(token) == 0)
+	if !blank {
+		st.records++
+		if l.MaxRecords > 0 && st.records > l.MaxRecords {
+			return &ParseError{Line: s.recordLine(), Err: &limitError{l.MaxRecords, "records", false}}
+		}
+	}
+	st.size, st.fields = 0, 0
+	return nil
+}
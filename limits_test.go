@@ -0,0 +1,131 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+var limitsTests = []struct {
+	Name    string
+	Input   string
+	Limits  Limits
+	Records int
+	Error   string
+}{
+	{Name: "NoLimit", Input: "a,b,c\nd,e,f\n", Records: 2},
+	{Name: "RecordSize", Input: "a,b,c\nd,e,ff\n", Limits: Limits{MaxRecordSize: 6}, Records: 1, Error: "more than 6 bytes per record at line 2"},
+	{Name: "RecordSizeQuoted", Input: "a\n\"" + strings.Repeat("x", 10000) + "\"\n", Limits: Limits{MaxRecordSize: 100}, Records: 1, Error: "more than 100 bytes per record"},
+	{Name: "RecordSizeUnterminated", Input: "a,b\nc,\"d\n" + strings.Repeat("e,f\n", 5000), Limits: Limits{MaxRecordSize: 100}, Records: 1,
+		Error: "more than 100 bytes per record (inside a quoted value, missing closing quote?) at line 2"},
+	{Name: "RecordSizeMultiLine", Input: "a,b\nc,\"d\ne\nf\",g\n", Limits: Limits{MaxRecordSize: 10}, Records: 1, Error: "more than 10 bytes per record between lines 2 and 4"},
+	{Name: "RecordSizeExact", Input: "a,b,c\r\nd,e,f", Limits: Limits{MaxRecordSize: 7}, Records: 2},
+	{Name: "Fields", Input: "a,b,c\nd,e,f,g\n", Limits: Limits{MaxFields: 3}, Records: 1, Error: "more than 3 fields per record at line 2"},
+	{Name: "Records", Input: "a\n\nb\n#c\nd\n", Limits: Limits{MaxRecords: 2}, Records: 2, Error: "more than 2 records at line 5"},
+	{Name: "RecordsExact", Input: "a\n\nb\n#c\n", Limits: Limits{MaxRecords: 2}, Records: 2},
+}
+
+func TestLimits(t *testing.T) {
+	for _, tt := range limitsTests {
+		r := DefaultReader(strings.NewReader(tt.Input))
+		r.Comment = '#'
+		limits := tt.Limits
+		r.Limits = &limits
+		n := 0
+		var err error
+		for {
+			if _, err = r.ReadRecord(); err != nil {
+				break
+			}
+			n++
+		}
+		if n != tt.Records {
+			t.Errorf("%s: got %d record(s); want %d", tt.Name, n, tt.Records)
+		}
+		if tt.Error == "" {
+			if err != io.EOF {
+				t.Errorf("%s: unexpected error: %v", tt.Name, err)
+			}
+		} else if !errors.Is(err, ErrLimit) || !strings.Contains(err.Error(), tt.Error) {
+			t.Errorf("%s: got %v; want %q", tt.Name, err, tt.Error)
+		}
+	}
+}
+
+// FuzzLimits checks that adversarial input never makes the Reader panic nor exceed the limits.
+func FuzzLimits(f *testing.F) {
+	for _, tt := range limitsTests {
+		f.Add([]byte(tt.Input))
+	}
+	for _, tt := range readTests {
+		f.Add([]byte(tt.Input))
+	}
+	limits := Limits{MaxRecordSize: 64, MaxFields: 8, MaxRecords: 16}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r := DefaultReader(strings.NewReader(string(data)))
+		r.Limits = &limits
+		for {
+			fields, err := r.ReadRecord()
+			if err != nil {
+				return
+			}
+			if len(fields) > limits.MaxFields {
+				t.Fatalf("got %d fields; want at most %d", len(fields), limits.MaxFields)
+			}
+			size := 0
+			for _, field := range fields {
+				size += len(field)
+			}
+			if size > limits.MaxRecordSize {
+				t.Fatalf("got %d bytes; want at most %d", size, limits.MaxRecordSize)
+			}
+		}
+	})
+}
+
+var maxColumnsTests = []struct {
+	Name    string
+	Input   string
+	Records int
+	Error   string
+}{
+	{Name: "Valid", Input: "a,b,c\nd,e,f\n", Records: 2},
+	{Name: "SingleColumn", Input: "a\nb\n", Records: 2},
+	{Name: "Semicolon", Input: "a;b;c\nd;e;f\n", Error: "suspicious record with 1 field(s) using separator ',': wrong separator? candidates: ';' at line 1"},
+	{Name: "Candidates", Input: "a;b;c|d\n", Error: "candidates: ';' '|' at line 1"},
+	{Name: "TooMany", Input: "a,b\nc,d,e,f,g\n", Records: 1, Error: "suspicious record with 5 field(s) using separator ',': wrong separator? at line 2"},
+	{Name: "Quoted", Input: "\"a;b\"\n\"c\"\n", Records: 2},
+}
+
+func TestMaxColumns(t *testing.T) {
+	for _, tt := range maxColumnsTests {
+		r := DefaultReader(strings.NewReader(tt.Input))
+		r.MaxColumns = 4
+		n := 0
+		var err error
+		for {
+			if _, err = r.ReadRecord(); err != nil {
+				break
+			}
+			n++
+		}
+		if n != tt.Records {
+			t.Errorf("%s: got %d record(s); want %d", tt.Name, n, tt.Records)
+		}
+		var serr *SeparatorError
+		if tt.Error == "" {
+			if err != io.EOF {
+				t.Errorf("%s: unexpected error: %v", tt.Name, err)
+			}
+		} else if !errors.As(err, &serr) || !strings.Contains(err.Error(), tt.Error) {
+			t.Errorf("%s: got %v; want %q", tt.Name, err, tt.Error)
+		}
+	}
+}
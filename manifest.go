@@ -0,0 +1,124 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Manifest lists the files of a multi-file export (see ShardedWriter.Commit).
+// Consumers should only read the files listed by a manifest (and may check them with Verify):
+// as the manifest is published last and atomically, they never see a partial export.
+type Manifest struct {
+	Files []ManifestFile `json:"files"`
+}
+
+// ManifestFile describes one file of a Manifest.
+type ManifestFile struct {
+	Name    string `json:"name"` // path relative to the directory of the manifest
+	Size    int64  `json:"size"`
+	Records int    `json:"records"` // number of records (header excluded)
+	SHA256  string `json:"sha256"`  // hex-encoded checksum of the content
+}
+
+// Commit closes the shards, syncs them and then atomically publishes the manifest (as indented JSON)
+// listing them with their sizes, numbers of records and checksums.
+// Nothing is published when an error occurred while writing records.
+func (sw *ShardedWriter) Commit(manifestPath string) (*Manifest, error) {
+	files, err := sw.Close()
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(manifestPath)
+	m := &Manifest{Files: []ManifestFile{}}
+	for i, file := range files {
+		mf, err := checksumFile(file, true)
+		if err != nil {
+			return nil, err
+		}
+		if mf.Name, err = filepath.Rel(dir, file); err != nil {
+			return nil, err
+		}
+		mf.Records = sw.counts[i]
+		m.Files = append(m.Files, mf)
+	}
+	err = writeFileAtomic(manifestPath, func(f *os.File) error {
+		e := json.NewEncoder(f)
+		e.SetIndent("", "  ")
+		return e.Encode(m)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// checksumFile computes the size and checksum of the named file (synced first when sync is true).
+func checksumFile(path string, sync bool) (ManifestFile, error) {
+	flag := os.O_RDONLY
+	if sync {
+		flag = os.O_RDWR // required by Sync on some platforms
+	}
+	f, err := os.OpenFile(path, flag, 0)
+	if err != nil {
+		return ManifestFile{}, err
+	}
+	defer f.Close()
+	if sync {
+		if err = f.Sync(); err != nil {
+			return ManifestFile{}, err
+		}
+	}
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return ManifestFile{}, err
+	}
+	return ManifestFile{Name: path, Size: n, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// ReadManifest decodes the named manifest (see ShardedWriter.Commit).
+func ReadManifest(path string) (*Manifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	m := &Manifest{}
+	if err = json.NewDecoder(f).Decode(m); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return m, nil
+}
+
+// Paths returns the paths of the files listed by the manifest located in dir.
+func (m *Manifest) Paths(dir string) []string {
+	paths := make([]string, len(m.Files))
+	for i, mf := range m.Files {
+		paths[i] = filepath.Join(dir, mf.Name)
+	}
+	return paths
+}
+
+// Verify checks the sizes and checksums of the files listed by the manifest located in dir.
+func (m *Manifest) Verify(dir string) error {
+	for _, mf := range m.Files {
+		actual, err := checksumFile(filepath.Join(dir, mf.Name), false)
+		if err != nil {
+			return err
+		} else if actual.Size != mf.Size {
+			return fmt.Errorf("%s: size mismatch: %d bytes (expected %d)", mf.Name, actual.Size, mf.Size)
+		} else if actual.SHA256 != mf.SHA256 {
+			return fmt.Errorf("%s: checksum mismatch", mf.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// FieldMarshaler is the interface implemented by types that encode themselves into a CSV field
+// (see Writer.WriteValue and Writer.WriteStruct).
+// Unlike encoding.TextMarshaler, which it takes precedence over, it is specific to CSV:
+// the returned value is written raw and quoted by the Writer when needed
+// (so it may contain separators, quotes or newlines).
+type FieldMarshaler interface {
+	MarshalCSVField() ([]byte, error)
+}
+
+// FieldUnmarshaler is the interface implemented by types that decode themselves from a CSV field
+// (see Reader.ScanRecord and Reader.ScanStruct).
+// The value is unquoted and may be overwritten by a subsequent read: it must be copied to be retained.
+// It takes precedence over encoding.TextUnmarshaler.
+type FieldUnmarshaler interface {
+	UnmarshalCSVField(value []byte) error
+}
+
+// Marshal returns the CSV encoding of v, a slice of structs (or of pointers to structs):
+// the header (see Writer.WriteStructHeader) followed by one record per element (see Writer.WriteStruct).
+// It is meant for small in-memory data sets; use a Writer to stream records.
+func Marshal(v interface{}) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("unsupported type %T (slice of structs expected)", v)
+	}
+	t, err := structSliceType(rv.Type())
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	w := DefaultWriter(&b)
+	w.WriteStructHeader(reflect.Zero(t).Interface())
+	for i := 0; i < rv.Len() && w.Err() == nil; i++ {
+		elem := rv.Index(i)
+		if elem.Kind() != reflect.Ptr {
+			elem = elem.Addr() // addressable for pointer methods
+		}
+		w.WriteStruct(elem.Interface())
+	}
+	w.Flush()
+	if err = w.Err(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Unmarshal decodes data, a header followed by records, into the slice of structs (or of pointers to structs)
+// pointed to by v: fields are bound to columns by name (see Reader.ScanStruct).
+// Like encoding/json, the slice length is reset to zero before the records are appended.
+// It is meant for small in-memory data sets; use a Reader to stream records.
+func Unmarshal(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("unsupported type %T (pointer to slice expected)", v)
+	}
+	t, err := structSliceType(rv.Type().Elem())
+	if err != nil {
+		return err
+	}
+	slice := rv.Elem()
+	slice.SetLen(0)
+	r := DefaultReader(bytes.NewReader(data))
+	if err = r.ScanHeaders(); err != nil {
+		return err
+	}
+	for {
+		elem := reflect.New(t)
+		if err = r.ScanStruct(elem.Interface()); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if slice.Type().Elem().Kind() == reflect.Ptr {
+			slice.Set(reflect.Append(slice, elem))
+		} else {
+			slice.Set(reflect.Append(slice, elem.Elem()))
+		}
+	}
+}
+
+// structSliceType returns the struct type of the elements of the slice type t.
+func structSliceType(t reflect.Type) (reflect.Type, error) {
+	if t.Kind() == reflect.Slice {
+		et := t.Elem()
+		if et.Kind() == reflect.Ptr {
+			et = et.Elem()
+		}
+		if et.Kind() == reflect.Struct {
+			return et, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported type %s (slice of structs expected)", t)
+}
@@ -0,0 +1,178 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/gwenn/yacr"
+)
+
+func TestMarshal(t *testing.T) {
+	products := []product{
+		{ID: 1, Name: "shirt, blue", Price: 9.99, Tags: []string{"a", "b"}, Sizes: []string{"S", "M"}, Stock: 3},
+		{ID: 2, Name: "hat", Tags: []string{}, Sizes: []string{}},
+	}
+	data, err := Marshal(products)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "id,name,price,tags,sizes,Stock\n1,\"shirt, blue\",9.99,a|b,S/M,3\n2,hat,0,,,0\n"; string(data) != expected {
+		t.Errorf("got %q; want %q", data, expected)
+	}
+	var decoded []*product
+	if err = Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2 || !reflect.DeepEqual(*decoded[0], products[0]) || !reflect.DeepEqual(*decoded[1], products[1]) {
+		t.Errorf("got %+v; want %+v", decoded, products)
+	}
+	if pdata, err := Marshal(decoded); err != nil {
+		t.Fatal(err)
+	} else if string(pdata) != string(data) {
+		t.Errorf("got %q; want %q", pdata, data)
+	}
+
+	records := []cdr{{Caller: "a", Attrs: map[string]string{"z": "1", "k": "2"}}}
+	if data, err = Marshal(records); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "caller,attrs,extra\na,k=2;z=1,\n"; string(data) != expected {
+		t.Errorf("got %q; want %q", data, expected)
+	}
+
+	if _, err = Marshal(product{}); err == nil {
+		t.Error("error expected for non-slice")
+	}
+	if err = Unmarshal(data, []cdr{}); err == nil {
+		t.Error("error expected for non-pointer")
+	}
+}
+
+// cents is an amount in cents formatted with two decimals.
+type cents int64
+
+func (c *cents) MarshalCSVField() ([]byte, error) {
+	return []byte(fmt.Sprintf("%d.%02d", *c/100, *c%100)), nil
+}
+
+func (c *cents) UnmarshalCSVField(value []byte) error {
+	f, err := strconv.ParseFloat(string(value), 64)
+	*c = cents(math.Round(f * 100))
+	return err
+}
+
+// note is a multiline text (separator and newlines must be quoted).
+type note []string
+
+func (n note) MarshalCSVField() ([]byte, error) {
+	return []byte(strings.Join(n, ",\n")), nil
+}
+
+func (n *note) UnmarshalCSVField(value []byte) error {
+	*n = strings.Split(string(value), ",\n")
+	return nil
+}
+
+type invoice struct {
+	Amount cents     `yacr:"amount"`
+	Notes  note      `yacr:"notes"`
+	Due    time.Time `yacr:"due"`
+}
+
+func TestFieldMarshaler(t *testing.T) {
+	invoices := []invoice{{Amount: 1205, Notes: note{"first", "second"}, Due: time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC)}}
+	data, err := Marshal(invoices)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "amount,notes,due\n12.05,\"first,\nsecond\",2021-03-04T00:00:00Z\n"; string(data) != expected {
+		t.Errorf("got %q; want %q", data, expected)
+	}
+	var decoded []invoice
+	if err = Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, invoices) {
+		t.Errorf("got %+v; want %+v", decoded, invoices)
+	}
+
+	r := DefaultReader(strings.NewReader("9.99\n"))
+	var c cents
+	if n, err := r.ScanRecord(&c); err != nil || n != 1 || c != 999 {
+		t.Errorf("got %d, %v (%d field(s)); want 999", c, err, n)
+	}
+}
+
+// level is encoded by its name (pointer receivers).
+type level int
+
+var levels = []string{"debug", "info", "error"}
+
+func (l *level) MarshalText() ([]byte, error) {
+	return []byte(levels[*l]), nil
+}
+
+func (l *level) UnmarshalText(text []byte) error {
+	for i, name := range levels {
+		if name == string(text) {
+			*l = level(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown level: %q", text)
+}
+
+type access struct {
+	IP    net.IP `yacr:"ip"`
+	Level level  `yacr:"level"`
+}
+
+func TestTextMarshaler(t *testing.T) {
+	entries := []access{{net.ParseIP("192.168.0.1").To4(), 2}, {net.ParseIP("::1"), 0}}
+	data, err := Marshal(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "ip,level\n192.168.0.1,error\n::1,debug\n"; string(data) != expected {
+		t.Errorf("got %q; want %q", data, expected)
+	}
+	var decoded []access
+	if err = Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2 || !decoded[0].IP.Equal(entries[0].IP) || !decoded[1].IP.Equal(entries[1].IP) || decoded[0].Level != 2 {
+		t.Errorf("got %+v; want %+v", decoded, entries)
+	}
+	if err = Unmarshal([]byte("ip,level\n::1,fatal\n"), &decoded); err == nil || !strings.Contains(err.Error(), "unknown level") {
+		t.Errorf("error expected: %v", err)
+	}
+}
+
+type event struct {
+	Name  string     `yacr:"name"`
+	At    *time.Time `yacr:"at"`
+	Level *level     `yacr:"level"`
+	Count *int       `yacr:"count"`
+}
+
+func TestWriteStructNilPointers(t *testing.T) {
+	at := time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC)
+	lvl, count := level(1), 3
+	data, err := Marshal([]event{{Name: "nil"}, {"set", &at, &lvl, &count}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "name,at,level,count\nnil,,,\nset,2021-03-04T00:00:00Z,info,3\n"; string(data) != expected {
+		t.Errorf("got %q; want %q", data, expected)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Mask scrubs one field value: the result is appended to dst (a reusable buffer) and returned.
+type Mask func(dst, value []byte) []byte
+
+// HashMask replaces values with their (hex-encoded) HMAC-SHA256 keyed by salt.
+// Equal values give equal hashes, so masked columns can still be joined or counted.
+// Empty values are kept empty.
+func HashMask(salt []byte) Mask {
+	return func(dst, value []byte) []byte {
+		if len(value) == 0 {
+			return dst
+		}
+		mac := hmac.New(sha256.New, salt)
+		mac.Write(value)
+		var sum [sha256.Size]byte
+		return append(dst, hex.EncodeToString(mac.Sum(sum[:0]))...)
+	}
+}
+
+// RedactMask replaces non-empty values with replacement.
+func RedactMask(replacement string) Mask {
+	return func(dst, value []byte) []byte {
+		if len(value) == 0 {
+			return dst
+		}
+		return append(dst, replacement...)
+	}
+}
+
+// TruncateMask keeps only the first n characters (runes) of values.
+func TruncateMask(n int) Mask {
+	return func(dst, value []byte) []byte {
+		count := 0
+		for i := range string(value) {
+			if count == n {
+				return append(dst, value[:i]...)
+			}
+			count++
+		}
+		return append(dst, value...)
+	}
+}
+
+// FakeMask replaces values with fake ones preserving their format:
+// digits are replaced by digits, letters by (ASCII) letters of the same case and other characters are kept
+// (so "John.Doe@mail.com" gives something like "Qkfr.Abc@zkeo.xyq").
+// The output is deterministic for a given salt and value.
+func FakeMask(salt []byte) Mask {
+	return func(dst, value []byte) []byte {
+		if len(value) == 0 {
+			return dst
+		}
+		mac := hmac.New(sha256.New, salt)
+		mac.Write(value)
+		var sum [sha256.Size]byte
+		rnd := rand.New(rand.NewSource(int64(binary.BigEndian.Uint64(mac.Sum(sum[:0])))))
+		for len(value) > 0 {
+			r, size := utf8.DecodeRune(value)
+			switch {
+			case r >= '0' && r <= '9':
+				dst = append(dst, byte('0'+rnd.Intn(10)))
+			case unicode.IsUpper(r):
+				dst = append(dst, byte('A'+rnd.Intn(26)))
+			case unicode.IsLetter(r):
+				dst = append(dst, byte('a'+rnd.Intn(26)))
+			default:
+				dst = append(dst, value[:size]...)
+			}
+			value = value[size:]
+		}
+		return dst
+	}
+}
+
+// Masker applies masks to the columns of records, selected by name.
+type Masker struct {
+	masks []Mask // by column index
+	rw    recordRewriter
+}
+
+// NewMasker binds masks (by column name) to the columns described by headers (see Reader.Headers).
+func NewMasker(headers map[string]int, masks map[string]Mask) (*Masker, error) {
+	m := &Masker{}
+	for name, mask := range masks {
+		index, ok := headers[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown field name: %s", name)
+		}
+		for len(m.masks) < index {
+			m.masks = append(m.masks, nil)
+		}
+		m.masks[index-1] = mask
+	}
+	return m, nil
+}
+
+// Transform returns the record with the selected columns masked.
+// The returned fields may be overwritten by a subsequent call.
+func (m *Masker) Transform(fields [][]byte) ([][]byte, error) {
+	return m.rw.rewrite(fields, func(i int, dst, field []byte) ([]byte, bool, error) {
+		if i >= len(m.masks) || m.masks[i] == nil {
+			return dst, false, nil
+		}
+		return m.masks[i](dst, field), true, nil
+	})
+}
@@ -0,0 +1,52 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+func TestMasker(t *testing.T) {
+	r := DefaultReader(strings.NewReader("name,email,phone,city,ssn\nJohn Doe,John.Doe@mail.com,+33 612,Paris,123\nJane,,,Lyon,456\n"))
+	if err := r.ScanHeaders(); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewMasker(r.Headers, map[string]Mask{
+		"name":  TruncateMask(3),
+		"email": FakeMask([]byte("salt")),
+		"phone": HashMask([]byte("salt")),
+		"ssn":   RedactMask("***"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, _ := r.ReadRecord()
+	fields, _ = m.Transform(fields)
+	if string(fields[0]) != "Joh" || string(fields[3]) != "Paris" || string(fields[4]) != "***" {
+		t.Errorf("unexpected masked record: %q", fields)
+	}
+	if ok, _ := regexp.Match(`^[A-Z][a-z]{3}\.[A-Z][a-z]{2}@[a-z]{4}\.[a-z]{3}$`, fields[1]); !ok {
+		t.Errorf("format not preserved: %q", fields[1])
+	}
+	if ok, _ := regexp.Match(`^[0-9a-f]{64}$`, fields[2]); !ok {
+		t.Errorf("hash expected: %q", fields[2])
+	}
+	email := string(fields[1])
+	if fields, _ = m.Transform([][]byte{[]byte("John Doe"), []byte("John.Doe@mail.com")}); string(fields[1]) != email {
+		t.Errorf("fake values must be deterministic: %q; want %q", fields[1], email)
+	}
+	fields, _ = r.ReadRecord()
+	fields, _ = m.Transform(fields)
+	if string(fields[0]) != "Jan" || len(fields[1]) != 0 || len(fields[2]) != 0 || string(fields[4]) != "***" {
+		t.Errorf("unexpected masked record: %q", fields)
+	}
+	if _, err = NewMasker(r.Headers, map[string]Mask{"unknown": RedactMask("")}); err == nil {
+		t.Error("error expected for unknown column")
+	}
+}
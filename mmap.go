@@ -0,0 +1,78 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"io"
+	"os"
+)
+
+// MmapReader reads a local file through a memory mapping:
+// the whole content is exposed to the scanner at once, so there is no read syscall nor buffer copy
+// and fields (see Bytes or ReadRecord) are slices of the mapping, valid until Close.
+// The mapping is private (copy-on-write) so that quoted values can still be unescaped in place.
+// On platforms without mmap (or when the mapping fails), the file is read as usual.
+type MmapReader struct {
+	*Reader
+	data []byte // mapping (nil when not mapped)
+}
+
+// NewMmapReader opens the named file and returns a Reader over its mapping.
+func NewMmapReader(path string, d Dialect) (*MmapReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	r := &MmapReader{}
+	if size := fi.Size(); size > 0 && int64(int(size)) == size {
+		r.data, _ = mmap(f, int(size)) // fall back to regular reads on error
+	}
+	if r.data == nil {
+		r.Reader = d.NewReader(f)
+		r.OnClose(f)
+		return r, nil
+	}
+	r.Reader = d.NewReader(&mappedReader{})
+	r.Buffer(r.data, len(r.data))
+	r.OnClose(f)
+	r.OnClose(closerFunc(r.unmap))
+	return r, nil
+}
+
+// Mapped tells if the file is actually memory-mapped.
+func (r *MmapReader) Mapped() bool {
+	return r.data != nil
+}
+
+// Close releases the mapping and the file: fields previously returned must not be used anymore.
+func (r *MmapReader) Close() error {
+	return r.Reader.Close()
+}
+
+func (r *MmapReader) unmap() error {
+	err := munmap(r.data)
+	r.data = nil
+	return err
+}
+
+// mappedReader fakes a reader whose content is already in the scanner buffer (the mapping):
+// the first read reports the whole buffer as filled and the end of file,
+// so that the scanner never reads, grows nor shifts its buffer.
+type mappedReader struct {
+	done bool
+}
+
+func (m *mappedReader) Read(p []byte) (int, error) {
+	if m.done {
+		return 0, io.EOF
+	}
+	m.done = true
+	return len(p), io.EOF
+}
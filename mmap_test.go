@@ -0,0 +1,68 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+func TestMmapReader(t *testing.T) {
+	dir := t.TempDir()
+	for _, content := range []string{"", "a,\"b\"\"c\"\n\"d\ne\",f", strings.Repeat("x,y\n", 100000)} {
+		path := filepath.Join(dir, "test.csv")
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		var want [][]string
+		ref := DefaultReader(strings.NewReader(content))
+		for {
+			fields, err := ref.ReadRecord()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			want = append(want, toStrings(fields))
+		}
+		r, err := NewMmapReader(path, DialectDefault)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got [][]string
+		for {
+			fields, err := r.ReadRecord()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, toStrings(fields))
+		}
+		if err = r.Close(); err != nil {
+			t.Error(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %d record(s); want %d", len(got), len(want))
+		}
+		if b, err := ioutil.ReadFile(path); err != nil || string(b) != content {
+			t.Errorf("file modified by in place unescaping: %v", err)
+		}
+	}
+}
+
+func toStrings(fields [][]byte) []string {
+	record := make([]string, len(fields))
+	for i, field := range fields {
+		record[i] = string(field)
+	}
+	return record
+}
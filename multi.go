@@ -0,0 +1,182 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"fmt"
+	"io"
+)
+
+// MultiReader reads the records of several files in sequence (like their concatenation),
+// each one starting with its own header record (gzip/bzip files are supported, see Zopen).
+// By default, all files must have the same header.
+// In HeaderUnion mode, differing headers are reconciled by name: the output header is the union
+// of all headers (in order of appearance) and absent columns are returned as empty (NULL) fields,
+// so that periodic exports with added columns can be loaded together.
+type MultiReader struct {
+	paths []string
+	d     Dialect
+
+	header  []string       // output header
+	index   map[string]int // index (first is 0) in header by name
+	cur     int            // index of the current file in paths
+	file    io.Closer      // current file
+	r       *Reader        // reader of the current file
+	mapping []int          // index in header of each column of the current file
+	out     [][]byte
+	prov    Provenance // provenance of the last record
+
+	HeaderUnion  bool                                // True to reconcile differing headers by name
+	OnNewColumns func(path string, columns []string) // called in HeaderUnion mode for the columns absent from the first file (warning)
+}
+
+// NewMultiReader returns a reader of the named files.
+func NewMultiReader(d Dialect, paths ...string) *MultiReader {
+	return &MultiReader{paths: paths, d: d, cur: -1}
+}
+
+// Header returns the output header.
+// In HeaderUnion mode, the headers of all files are read (once) to compute their union.
+func (m *MultiReader) Header() ([]string, error) {
+	if m.index != nil {
+		return m.header, nil
+	}
+	m.index = make(map[string]int)
+	if len(m.paths) == 0 {
+		return m.header, nil
+	}
+	last := 1
+	if m.HeaderUnion {
+		last = len(m.paths)
+	}
+	for i, path := range m.paths[:last] {
+		header, err := m.readHeader(path)
+		if err != nil {
+			return nil, err
+		}
+		var added []string
+		for _, name := range header {
+			if _, ok := m.index[name]; !ok {
+				m.index[name] = len(m.header)
+				m.header = append(m.header, name)
+				added = append(added, name)
+			}
+		}
+		if i > 0 && len(added) > 0 && m.OnNewColumns != nil {
+			m.OnNewColumns(path, added)
+		}
+	}
+	return m.header, nil
+}
+
+func (m *MultiReader) readHeader(path string) ([]string, error) {
+	f, err := Zopen(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	header, err := readStrings(m.d.NewReader(f))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return header, nil
+}
+
+// ReadRecord returns the next record (with one field per column of the output header) or io.EOF.
+// Extra fields (without header) are ignored.
+// It implements RecordSource.
+func (m *MultiReader) ReadRecord() ([][]byte, error) {
+	if _, err := m.Header(); err != nil {
+		return nil, err
+	}
+	for {
+		if m.r == nil {
+			if m.cur+1 >= len(m.paths) {
+				return nil, io.EOF
+			}
+			if err := m.next(); err != nil {
+				return nil, err
+			}
+		}
+		fields, err := m.r.ReadRecord()
+		if err == io.EOF {
+			m.closeFile()
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: %s", m.paths[m.cur], err)
+		}
+		m.prov = m.r.Provenance()
+		m.out = m.out[:0]
+		for range m.header {
+			m.out = append(m.out, nil)
+		}
+		for i, field := range fields {
+			if i < len(m.mapping) && m.mapping[i] >= 0 {
+				m.out[m.mapping[i]] = field
+			}
+		}
+		return m.out, nil
+	}
+}
+
+// next opens the next file and binds its header.
+func (m *MultiReader) next() error {
+	m.cur++
+	path := m.paths[m.cur]
+	f, err := Zopen(path)
+	if err != nil {
+		return err
+	}
+	m.file, m.r = f, m.d.NewReader(f)
+	m.r.Source = path
+	header, err := readStrings(m.r)
+	if err != nil {
+		m.closeFile()
+		return fmt.Errorf("%s: %s", path, err)
+	}
+	m.mapping = m.mapping[:0]
+	seen := make(map[string]bool, len(header))
+	for i, name := range header {
+		index, ok := m.index[name]
+		if seen[name] {
+			m.closeFile()
+			return fmt.Errorf("%s: duplicate header name: %s", path, name)
+		} else if !m.HeaderUnion && (!ok || index != i) {
+			m.closeFile()
+			return fmt.Errorf("%s: header mismatch: %q (expected %q)", path, header, m.header)
+		} else if !ok {
+			index = -1
+		}
+		seen[name] = true
+		m.mapping = append(m.mapping, index)
+	}
+	if !m.HeaderUnion && len(header) != len(m.header) {
+		m.closeFile()
+		return fmt.Errorf("%s: header mismatch: %q (expected %q)", path, header, m.header)
+	}
+	return nil
+}
+
+// Path returns the name of the file being read.
+func (m *MultiReader) Path() string {
+	if m.cur < 0 || m.cur >= len(m.paths) {
+		return ""
+	}
+	return m.paths[m.cur]
+}
+
+func (m *MultiReader) closeFile() error {
+	if m.file == nil {
+		return nil
+	}
+	err := m.file.Close()
+	m.file, m.r = nil, nil
+	return err
+}
+
+// Close closes the file being read.
+func (m *MultiReader) Close() error {
+	return m.closeFile()
+}
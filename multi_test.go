@@ -0,0 +1,94 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+func writeFiles(t *testing.T, contents ...string) []string {
+	dir := t.TempDir()
+	var paths []string
+	for i, content := range contents {
+		path := filepath.Join(dir, string(rune('a'+i))+".csv")
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func readMulti(m *MultiReader) ([][]string, error) {
+	var records [][]string
+	for {
+		fields, err := m.ReadRecord()
+		if err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return records, err
+		}
+		record := make([]string, len(fields))
+		for i, field := range fields {
+			record[i] = string(field)
+		}
+		records = append(records, record)
+	}
+}
+
+func TestMultiReader(t *testing.T) {
+	paths := writeFiles(t, "id,name\n1,a\n", "id,name\n2,b\n3,c\n", "id,name\n")
+	m := NewMultiReader(DialectDefault, paths...)
+	defer m.Close()
+	records, err := readMulti(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]string{{"1", "a"}, {"2", "b"}, {"3", "c"}}; !reflect.DeepEqual(records, want) {
+		t.Errorf("got %q; want %q", records, want)
+	}
+
+	paths = writeFiles(t, "id,name\n1,a\n", "id,email\n2,b@c\n")
+	m = NewMultiReader(DialectDefault, paths...)
+	defer m.Close()
+	if _, err = readMulti(m); err == nil || !strings.Contains(err.Error(), "header mismatch") {
+		t.Errorf("error expected: %v", err)
+	}
+}
+
+func TestMultiReaderHeaderUnion(t *testing.T) {
+	paths := writeFiles(t, "id,name\n1,a\n", "name,id,email\nb,2,b@c\n", "id\n3\n")
+	m := NewMultiReader(DialectDefault, paths...)
+	defer m.Close()
+	m.HeaderUnion = true
+	var warnings []string
+	m.OnNewColumns = func(path string, columns []string) {
+		warnings = append(warnings, filepath.Base(path)+":"+strings.Join(columns, ","))
+	}
+	header, err := m.Header()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"id", "name", "email"}; !reflect.DeepEqual(header, want) {
+		t.Errorf("got %q; want %q", header, want)
+	}
+	if want := []string{"b.csv:email"}; !reflect.DeepEqual(warnings, want) {
+		t.Errorf("got %q; want %q", warnings, want)
+	}
+	records, err := readMulti(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]string{{"1", "a", ""}, {"2", "b", "b@c"}, {"3", "", ""}}; !reflect.DeepEqual(records, want) {
+		t.Errorf("got %q; want %q", records, want)
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// PgArray parses the current field as a (one-dimensional) Postgres array literal like `{a,"b,c",NULL}`.
+// NULL elements are returned as empty strings.
+func (s *Reader) PgArray() ([]string, error) {
+	return parsePgArray(s.Bytes())
+}
+
+// parsePgArray parses a Postgres array literal: elements may be double-quoted (with backslash escapes),
+// unquoted elements are trimmed and the unquoted NULL denotes a null element.
+func parsePgArray(b []byte) ([]string, error) {
+	if len(b) < 2 || b[0] != '{' || b[len(b)-1] != '}' {
+		return nil, fmt.Errorf("invalid array literal: %q", b)
+	}
+	content := b[1 : len(b)-1]
+	values := []string{}
+	if len(bytes.TrimSpace(content)) == 0 {
+		return values, nil
+	}
+	var buf []byte
+	for i := 0; ; i++ {
+		for i < len(content) && isSpace(content[i]) {
+			i++
+		}
+		buf = buf[:0]
+		if i < len(content) && content[i] == '"' { // quoted element
+			for i++; ; i++ {
+				if i >= len(content) {
+					return nil, fmt.Errorf("non-terminated quoted element in array literal: %q", b)
+				}
+				c := content[i]
+				if c == '\\' && i+1 < len(content) {
+					i++
+					c = content[i]
+				} else if c == '"' {
+					break
+				}
+				buf = append(buf, c)
+			}
+			for i++; i < len(content) && isSpace(content[i]); i++ {
+			}
+			values = append(values, string(buf))
+		} else { // unquoted element
+			escaped := false
+			end := 0 // end of buf without trailing spaces
+			for ; i < len(content) && content[i] != ','; i++ {
+				c := content[i]
+				switch {
+				case c == '{' || c == '"':
+					return nil, fmt.Errorf("unsupported (nested) array literal: %q", b)
+				case c == '\\' && i+1 < len(content):
+					i++
+					buf = append(buf, content[i])
+					escaped = true
+					end = len(buf)
+					continue
+				}
+				buf = append(buf, c)
+				if !isSpace(c) {
+					end = len(buf)
+				}
+			}
+			buf = buf[:end]
+			if len(buf) == 0 {
+				return nil, fmt.Errorf("empty element in array literal: %q", b)
+			} else if !escaped && len(buf) == 4 && bytes.EqualFold(buf, []byte("NULL")) {
+				values = append(values, "")
+			} else {
+				values = append(values, string(buf))
+			}
+		}
+		if i >= len(content) {
+			return values, nil
+		} else if content[i] != ',' {
+			return nil, fmt.Errorf("unexpected character %q in array literal: %q", content[i], b)
+		}
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
@@ -0,0 +1,53 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+func TestPgArray(t *testing.T) {
+	var tests = []struct {
+		Input  string
+		Output []string
+		Error  string
+	}{
+		{`{}`, []string{}, ""},
+		{`{a,b}`, []string{"a", "b"}, ""},
+		{`{a,"b,c",NULL}`, []string{"a", "b,c", ""}, ""},
+		{`{ a b , c }`, []string{"a b", "c"}, ""},
+		{`{"NULL",null,\NULL}`, []string{"NULL", "", "NULL"}, ""},
+		{`{"a\"b","c\\d",""}`, []string{`a"b`, `c\d`, ""}, ""},
+		{`{a\,b}`, []string{"a,b"}, ""},
+		{`a,b`, nil, "invalid array literal"},
+		{`{a,,b}`, nil, "empty element"},
+		{`{"a}`, nil, "non-terminated"},
+		{`{"a"b}`, nil, "unexpected character"},
+		{`{{1,2},{3,4}}`, nil, "unsupported"},
+	}
+	for _, test := range tests {
+		r := NewReader(strings.NewReader(test.Input), '\t', false, false)
+		if !r.Scan() {
+			t.Fatalf("%s: %v", test.Input, r.Err())
+		}
+		values, err := r.PgArray()
+		if test.Error != "" {
+			if err == nil || !strings.Contains(err.Error(), test.Error) {
+				t.Errorf("%s: got %v; want error %q", test.Input, err, test.Error)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.Input, err)
+			continue
+		}
+		if !reflect.DeepEqual(values, test.Output) {
+			t.Errorf("%s: got %q; want %q", test.Input, values, test.Output)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"io"
+	"sync"
+)
+
+// Prefetch returns a reader that reads ahead from r in a background goroutine (double buffering):
+// one buffer of size bytes is filled while the other is consumed, so that I/O overlaps with parsing
+// (useful with slow readers like network streams or decompressors):
+//
+//	p := yacr.Prefetch(gzipReader, 1 << 20)
+//	defer p.Close()
+//	r := yacr.DefaultReader(p)
+//
+// Close must be called to stop the goroutine when the reader is not consumed until the end.
+// It does not close r.
+func Prefetch(r io.Reader, size int) io.ReadCloser {
+	if size <= 0 {
+		size = 64 * 1024
+	}
+	p := &prefetcher{full: make(chan chunk, 1), free: make(chan []byte, 2), done: make(chan struct{})}
+	p.free <- make([]byte, size)
+	p.free <- make([]byte, size)
+	go p.fill(r)
+	return p
+}
+
+type chunk struct {
+	b   []byte
+	err error
+}
+
+type prefetcher struct {
+	full chan chunk  // filled buffers
+	free chan []byte // consumed buffers
+	done chan struct{}
+	once sync.Once
+
+	buf []byte // buffer being consumed
+	cur []byte // unread part of buf
+	err error  // error following buf content
+}
+
+func (p *prefetcher) fill(r io.Reader) {
+	for {
+		var buf []byte
+		select {
+		case buf = <-p.free:
+		case <-p.done:
+			return
+		}
+		n, err := r.Read(buf)
+		for n == 0 && err == nil {
+			n, err = r.Read(buf)
+		}
+		select {
+		case p.full <- chunk{buf[:n], err}:
+		case <-p.done:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (p *prefetcher) Read(b []byte) (int, error) {
+	if len(p.cur) == 0 {
+		if p.buf != nil {
+			p.free <- p.buf[:cap(p.buf)]
+			p.buf = nil
+		}
+		if p.err != nil {
+			return 0, p.err
+		}
+		select {
+		case c := <-p.full:
+			p.buf, p.cur, p.err = c.b, c.b, c.err
+		case <-p.done:
+			return 0, io.ErrClosedPipe
+		}
+	}
+	n := copy(b, p.cur)
+	p.cur = p.cur[n:]
+	return n, nil
+}
+
+// Close stops reading ahead.
+func (p *prefetcher) Close() error {
+	p.once.Do(func() {
+		close(p.done)
+	})
+	return nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	. "github.com/gwenn/yacr"
+)
+
+func TestPrefetch(t *testing.T) {
+	content := strings.Repeat("a,\"b\nc\",d\n", 10000)
+	for _, size := range []int{0, 1, 7, 1 << 20} {
+		p := Prefetch(iotest.HalfReader(strings.NewReader(content)), size)
+		b, err := ioutil.ReadAll(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != content {
+			t.Errorf("%d: got %d bytes; want %d", size, len(b), len(content))
+		}
+		p.Close()
+	}
+}
+
+func TestPrefetchError(t *testing.T) {
+	boom := errors.New("boom")
+	p := Prefetch(io.MultiReader(strings.NewReader("a,b\n"), iotest.ErrReader(boom)), 2)
+	defer p.Close()
+	r := DefaultReader(p)
+	n := 0
+	for r.Scan() {
+		n++
+	}
+	if n != 2 || r.Err() != boom {
+		t.Errorf("got %d field(s), %v; want 2, %v", n, r.Err(), boom)
+	}
+}
+
+func TestPrefetchClose(t *testing.T) {
+	p := Prefetch(bytes.NewReader(make([]byte, 1<<20)), 16)
+	b := make([]byte, 8)
+	if _, err := p.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	p.Close()
+	for i := 0; i < 4; i++ { // buffered content may still be returned
+		if _, err := p.Read(b); err == io.ErrClosedPipe {
+			return
+		}
+	}
+	t.Error("ErrClosedPipe expected")
+}
@@ -0,0 +1,44 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import "strconv"
+
+// Provenance locates a record in its source (for audited ingestion pipelines).
+type Provenance struct {
+	Source string // name of the source (see Reader.Source and MultiReader.Path)
+	Record int    // record number in the source (first is 1, header and records skipped by Grep included; empty lines and comments excluded)
+	Line   int    // line number of the start of the record
+	Offset int64  // byte offset of the start of the record in the source
+}
+
+// Provenance returns the provenance of the current record (the last one read by ReadRecord
+// or the one of the current field when scanning field by field).
+func (s *Reader) Provenance() Provenance {
+	p := s.prov
+	p.Source = s.Source
+	return p
+}
+
+// Provenance returns the provenance of the last record returned by ReadRecord.
+func (m *MultiReader) Provenance() Provenance {
+	return m.prov
+}
+
+// ProvenanceColumns returns computed columns (see Computer) emitting the provenance
+// of the records read from src: "_source", "_record" and "_offset".
+func ProvenanceColumns(src interface{ Provenance() Provenance }) []ComputedColumn {
+	return []ComputedColumn{
+		{Name: "_source", Compute: func(Record) (string, error) {
+			return src.Provenance().Source, nil
+		}},
+		{Name: "_record", Compute: func(Record) (string, error) {
+			return strconv.Itoa(src.Provenance().Record), nil
+		}},
+		{Name: "_offset", Compute: func(Record) (string, error) {
+			return strconv.FormatInt(src.Provenance().Offset, 10), nil
+		}},
+	}
+}
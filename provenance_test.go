@@ -0,0 +1,82 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"io"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+func TestProvenance(t *testing.T) {
+	for _, tt := range []struct {
+		Grep *regexp.Regexp
+		Want []Provenance
+	}{
+		{nil, []Provenance{
+			{Source: "test.csv", Record: 1, Line: 1, Offset: 0},
+			{Source: "test.csv", Record: 2, Line: 4, Offset: 12},
+			{Source: "test.csv", Record: 3, Line: 6, Offset: 21},
+		}},
+		{regexp.MustCompile("^(id|2),"), []Provenance{ // filtered records are still numbered
+			{Source: "test.csv", Record: 1, Line: 1, Offset: 0},
+			{Source: "test.csv", Record: 3, Line: 6, Offset: 21},
+		}},
+	} {
+		r := DefaultReader(strings.NewReader("id,name\n\n#x\n1,\"a\nb\"\r\n2,c\n"))
+		r.Comment = '#'
+		r.Source = "test.csv"
+		r.Grep(tt.Grep)
+		var provs []Provenance
+		for {
+			if _, err := r.ReadRecord(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			provs = append(provs, r.Provenance())
+		}
+		if !reflect.DeepEqual(provs, tt.Want) {
+			t.Errorf("%v: got %+v; want %+v", tt.Grep, provs, tt.Want)
+		}
+	}
+}
+
+func TestMultiReaderProvenance(t *testing.T) {
+	paths := writeFiles(t, "id\n1\n", "id\n2\n3\n")
+	m := NewMultiReader(DialectDefault, paths...)
+	defer m.Close()
+	if _, err := m.Header(); err != nil {
+		t.Fatal(err)
+	}
+	c := NewComputer(ProvenanceColumns(m)...)
+	var records [][]string
+	for {
+		fields, err := m.ReadRecord()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if fields, err = c.Transform(fields); err != nil {
+			t.Fatal(err)
+		}
+		record := toStrings(fields)
+		record[1] = filepath.Base(record[1])
+		records = append(records, record)
+	}
+	want := [][]string{{"1", "a.csv", "2", "3"}, {"2", "b.csv", "2", "3"}, {"3", "b.csv", "3", "5"}}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("got %q; want %q", records, want)
+	}
+	if want := []string{"_source", "_record", "_offset"}; !reflect.DeepEqual(c.Headers(), want) {
+		t.Errorf("got %q; want %q", c.Headers(), want)
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// FoldPunctuation replaces the typographic punctuation of value (common from Excel or word processors copy-paste)
+// by its ASCII equivalent and appends the result to dst:
+// smart quotes by ' or ", dashes and minus sign by -, ellipsis by ... and no-break space by a space.
+// Windows-1252 punctuation not transcoded (invalid UTF-8 bytes) or decoded as Latin-1 (C1 control characters,
+// see Latin1InvalidUTF8) is folded too.
+// It can be used as Reader.Normalize (all columns), as a Mask or by NewPunctuationFolder (selected columns).
+func FoldPunctuation(dst, value []byte) []byte {
+	for i := 0; i < len(value); {
+		c := value[i]
+		if c < utf8.RuneSelf {
+			dst = append(dst, c)
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRune(value[i:])
+		if r == utf8.RuneError && size == 1 { // Windows-1252 byte
+			r = rune(c)
+		}
+		if r >= 0x80 && r <= 0x9f {
+			r = cp1252Punctuation(byte(r))
+		}
+		if s := asciiPunctuation(r); s != "" {
+			dst = append(dst, s...)
+		} else {
+			dst = append(dst, value[i:i+size]...)
+		}
+		i += size
+	}
+	return dst
+}
+
+// cp1252Punctuation returns the punctuation character encoded as b (0x80 to 0x9F) in Windows-1252
+// (b itself otherwise).
+func cp1252Punctuation(b byte) rune {
+	switch b {
+	case 0x82:
+		return '\u201a'
+	case 0x84:
+		return '\u201e'
+	case 0x85:
+		return '\u2026'
+	case 0x91:
+		return '\u2018'
+	case 0x92:
+		return '\u2019'
+	case 0x93:
+		return '\u201c'
+	case 0x94:
+		return '\u201d'
+	case 0x96:
+		return '\u2013'
+	case 0x97:
+		return '\u2014'
+	}
+	return rune(b)
+}
+
+// asciiPunctuation returns the ASCII equivalent of the punctuation character r ("" when there is none).
+func asciiPunctuation(r rune) string {
+	switch r {
+	case '\u2018', '\u2019', '\u201a', '\u201b', '\u2032': // single quotes and prime
+		return "'"
+	case '\u201c', '\u201d', '\u201e', '\u201f', '\u2033': // double quotes and double prime
+		return `"`
+	case '\u2010', '\u2011', '\u2012', '\u2013', '\u2014', '\u2015', '\u2212': // hyphens, dashes and minus sign
+		return "-"
+	case '\u2026':
+		return "..."
+	case '\u00a0', '\u202f': // no-break spaces
+		return " "
+	}
+	return ""
+}
+
+// punctuationFolder folds the punctuation of selected columns (see NewPunctuationFolder).
+type punctuationFolder struct {
+	columns []bool // by column index (nil for all columns)
+	rw      recordRewriter
+}
+
+// NewPunctuationFolder returns a transformer folding the typographic punctuation (see FoldPunctuation)
+// of the columns selected by name among headers (see Reader.Headers), or of all columns when no name is specified,
+// to be appended to Reader.Transformers so that downstream matching logic stays simple.
+func NewPunctuationFolder(headers map[string]int, names ...string) (RecordTransformer, error) {
+	f := &punctuationFolder{}
+	for _, name := range names {
+		index, ok := headers[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown field name: %s", name)
+		}
+		for len(f.columns) < index {
+			f.columns = append(f.columns, false)
+		}
+		f.columns[index-1] = true
+	}
+	return f, nil
+}
+
+// Transform returns the record with the punctuation of the selected columns folded.
+// The returned fields may be overwritten by a subsequent call.
+func (f *punctuationFolder) Transform(fields [][]byte) ([][]byte, error) {
+	return f.rw.rewrite(fields, func(i int, dst, field []byte) ([]byte, bool, error) {
+		if f.columns != nil && (i >= len(f.columns) || !f.columns[i]) || isASCII(field) {
+			return dst, false, nil
+		}
+		return FoldPunctuation(dst, field), true, nil
+	})
+}
@@ -0,0 +1,55 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+func TestFoldPunctuation(t *testing.T) {
+	var tests = []struct {
+		Value    string
+		Expected string
+	}{
+		{"plain", "plain"},
+		{"\u201cJohn\u2019s\u201d \u2013 ok\u2026", "\"John's\" - ok..."},
+		{"caf\u00e9\u00a010\u2212\u00bd", "caf\u00e9 10-\u00bd"},
+		{"\x93cp1252\x94 \x96 \x85\xff", "\"cp1252\" - ...\xff"},
+		{"\u0091latin1\u0092", "'latin1'"},
+	}
+	for _, test := range tests {
+		if got := string(FoldPunctuation(nil, []byte(test.Value))); got != test.Expected {
+			t.Errorf("%q: got %q; want %q", test.Value, got, test.Expected)
+		}
+	}
+}
+
+func TestPunctuationFolder(t *testing.T) {
+	r := DefaultReader(strings.NewReader("name,quote\n\u201cA\u201d,\u201cB\u201d\n"))
+	if err := r.ScanHeaders(); err != nil {
+		t.Fatal(err)
+	}
+	folder, err := NewPunctuationFolder(r.Headers, "quote")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Transformers = append(r.Transformers, folder)
+	fields, err := r.ReadRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]byte{[]byte("\u201cA\u201d"), []byte(`"B"`)}
+	if !reflect.DeepEqual(expected, fields) {
+		t.Errorf("got %q; want %q", fields, expected)
+	}
+
+	if _, err = NewPunctuationFolder(r.Headers, "unknown"); err == nil {
+		t.Error("error expected")
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+// Quarantine is a sink for rejected records (see Reader.Quarantine):
+// each record is written to a side CSV (with its fields as read, before transformation, re-encoded by the Writer)
+// followed by an extra field describing why it was rejected,
+// so that failed records can be reprocessed once the data or the code is fixed.
+// A malformed record (that cannot be split into fields) is written as one field holding its raw content
+// (line terminator excluded).
+type Quarantine struct {
+	Header []string // when specified, written (followed by "errors") before the first rejected record
+
+	w *Writer
+	n int // number of rejected records
+}
+
+// NewQuarantine returns a quarantine writing rejected records to w.
+func NewQuarantine(w *Writer) *Quarantine {
+	return &Quarantine{w: w}
+}
+
+// Reject writes the rejected record with the reason of its rejection.
+// It can be used directly for records rejected by the application (like Schema.Validate failures).
+func (q *Quarantine) Reject(fields [][]byte, reason error) error {
+	if q.n == 0 && len(q.Header) > 0 {
+		for _, h := range q.Header {
+			q.w.WriteString(h)
+		}
+		q.w.WriteString("errors")
+		q.w.EndOfRecord()
+	}
+	q.n++
+	for _, field := range fields {
+		q.w.Write(field)
+	}
+	q.w.WriteString(reason.Error())
+	q.w.EndOfRecord()
+	return q.w.Err()
+}
+
+// Len returns the number of records rejected so far.
+func (q *Quarantine) Len() int {
+	return q.n
+}
+
+// Flush writes any buffered data to the underlying io.Writer.
+func (q *Quarantine) Flush() error {
+	q.w.Flush()
+	return q.w.Err()
+}
+
+// quarantine writes the rejected record to the Quarantine, if any, and returns nil
+// (the record is skipped) or returns err otherwise.
+func (s *Reader) quarantine(fields [][]byte, err error) error {
+	if s.Quarantine == nil {
+		return err
+	}
+	return s.Quarantine.Reject(fields, err)
+}
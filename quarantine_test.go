@@ -0,0 +1,100 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+func TestQuarantine(t *testing.T) {
+	r := DefaultReader(strings.NewReader("code,label\nab,first\nabcd,\"too\nlong\"\n1,2,3,4,5,6\ncd,last\n"))
+	if err := r.ScanHeaders(); err != nil {
+		t.Fatal(err)
+	}
+	we, err := NewWidthEnforcer(r.Headers, map[string]int{"code": 3}, RejectWidth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Transformers = []RecordTransformer{we}
+	r.MaxColumns = 4
+	var b bytes.Buffer
+	q := NewQuarantine(DefaultWriter(&b))
+	q.Header = []string{"code", "label"}
+	r.Quarantine = q
+
+	var codes []string
+	for {
+		fields, err := r.ReadRecord()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		codes = append(codes, string(fields[0]))
+	}
+	if expected := []string{"ab", "cd"}; strings.Join(codes, ",") != strings.Join(expected, ",") {
+		t.Errorf("got %q; want %q", codes, expected)
+	}
+	if err := q.Reject([][]byte{[]byte("zz")}, errors.New("invalid code")); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if q.Len() != 3 {
+		t.Errorf("got %d rejected record(s); want 3", q.Len())
+	}
+	out := DefaultReader(&b)
+	expected := []string{"code,label,errors", "abcd,too\nlong,value too long", "1,2,3,4,5,6,suspicious record", "zz,invalid code"}
+	for i, prefix := range expected {
+		fields, err := out.ReadRecord()
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		if s := string(bytes.Join(fields, []byte(","))); !strings.HasPrefix(s, prefix) {
+			t.Errorf("%d: got %q; want %q...", i, s, prefix)
+		}
+	}
+}
+
+func TestQuarantineMalformed(t *testing.T) {
+	r := DefaultReader(strings.NewReader("a,b\n1,\"x\"y\",z\n2,\"ok\"\n3,\xff\n4,\"open\nend\n"))
+	r.InvalidUTF8 = RejectInvalidUTF8
+	var b bytes.Buffer
+	q := NewQuarantine(DefaultWriter(&b))
+	r.Quarantine = q
+	var ids []string
+	for {
+		fields, err := r.ReadRecord()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, string(fields[0]))
+	}
+	if want := "a,2,end"; strings.Join(ids, ",") != want {
+		t.Errorf("got %q; want %q", ids, want)
+	}
+	if err := q.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	out := DefaultReader(&b)
+	for i, want := range []string{"1,\"x\"y\",z", "3,\xff", "4,\"open"} {
+		fields, err := out.ReadRecord()
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		if len(fields) != 2 || string(fields[0]) != want {
+			t.Errorf("%d: got %q; want %q and the reason", i, fields, want)
+		}
+	}
+}
@@ -0,0 +1,738 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Query runs a (subset of) SQL query over CSV inputs and returns a Reader of the result,
+// whose first record is the header:
+//
+//	SELECT * | expr [AS name], ...
+//	FROM table [[AS] alias]
+//	[[INNER] JOIN table [[AS] alias] ON column = column]
+//	[WHERE condition]
+//	[ORDER BY expr [ASC | DESC], ...]
+//	[LIMIT n]
+//
+// Tables are the sources by name, whose headers are loaded by ScanHeaders when missing.
+// Columns are referenced by name (qualified by the table name or alias when ambiguous) and
+// expressions support the same values and operators as Expr plus the SQL keywords AND, OR, NOT,
+// IS [NOT] NULL and the = and <> operators (strings are single quoted and identifiers double quoted).
+// Records are streamed, except the joined table that is loaded in memory and
+// the whole result when it is ordered.
+// The Reader should be closed (see Reader.Close) when the result is not read until the end.
+func Query(sql string, sources map[string]*Reader) (*Reader, error) {
+	q, err := parseQuery(sql)
+	if err != nil {
+		return nil, err
+	}
+	if err = q.bind(sources); err != nil {
+		return nil, err
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(q.run(DefaultWriter(pw)))
+	}()
+	r := DefaultReader(pr)
+	r.OnClose(pr)
+	return r, nil
+}
+
+// QueryTables returns the names of the tables referenced by the query sql (see Query),
+// so that only the needed sources are opened.
+func QueryTables(sql string) ([]string, error) {
+	q, err := parseQuery(sql)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, t := range q.tables() {
+		names = append(names, t.name)
+	}
+	return names, nil
+}
+
+// QueryColumn is the origin of a column of the result of a query (see QueryColumns).
+type QueryColumn struct {
+	Table  string // name of the table ("" for a computed value)
+	Column string // name of the column in the table ("" for a computed value)
+}
+
+// QueryColumns returns the origin of each column of the result of the query sql (see Query)
+// over the tables whose column names are given by table name,
+// so that the metadata of the copied columns (types, ...) can be propagated.
+// Computed values have a zero QueryColumn, whatever their alias.
+func QueryColumns(sql string, headers map[string][]string) ([]QueryColumn, error) {
+	q, err := parseQuery(sql)
+	if err != nil {
+		return nil, err
+	}
+	refs := make(map[string]QueryColumn)
+	ambiguous := make(map[string]bool)
+	for _, t := range q.tables() {
+		names, ok := headers[t.name]
+		if !ok {
+			return nil, fmt.Errorf("unknown table: %s", t.name)
+		}
+		for _, name := range names {
+			c := QueryColumn{t.name, name}
+			refs[t.alias+"."+name] = c
+			if _, ok := refs[name]; ok || ambiguous[name] {
+				delete(refs, name)
+				ambiguous[name] = true
+			} else {
+				refs[name] = c
+			}
+		}
+	}
+	var columns []QueryColumn
+	for _, item := range q.items {
+		if item.expr != nil {
+			columns = append(columns, refs[item.expr.column()])
+			continue
+		}
+		for _, t := range q.tables() {
+			for _, name := range headers[t.name] {
+				columns = append(columns, QueryColumn{t.name, name})
+			}
+		}
+	}
+	return columns, nil
+}
+
+type sqlTokKind int
+
+const (
+	sqlWord sqlTokKind = iota
+	sqlIdent           // double quoted identifier
+	sqlString
+	sqlNumber
+	sqlPunct
+)
+
+type sqlTok struct {
+	kind sqlTokKind
+	text string // unquoted
+}
+
+// is tells if the token is the (case-insensitive) keyword or the punctuation s.
+func (t sqlTok) is(s string) bool {
+	return (t.kind == sqlWord || t.kind == sqlPunct) && strings.EqualFold(t.text, s)
+}
+
+func tokenizeSQL(sql string) ([]sqlTok, error) {
+	var toks []sqlTok
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case isSpace(c):
+			i++
+		case c == '\'' || c == '"':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(sql); j++ {
+				if sql[j] == c {
+					if j+1 < len(sql) && sql[j+1] == c { // doubled quote
+						j++
+					} else {
+						break
+					}
+				}
+				b.WriteByte(sql[j])
+			}
+			if j >= len(sql) {
+				return nil, fmt.Errorf("unterminated %c in query at offset %d", c, i)
+			}
+			kind := sqlString
+			if c == '"' {
+				kind = sqlIdent
+			}
+			toks = append(toks, sqlTok{kind, b.String()})
+			i = j + 1
+		case isDigit(c):
+			j := i
+			for j < len(sql) && (isDigit(sql[j]) || sql[j] == '.') {
+				j++
+			}
+			toks = append(toks, sqlTok{sqlNumber, sql[i:j]})
+			i = j
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			j := i
+			for j < len(sql) && (sql[j] == '_' || isDigit(sql[j]) || sql[j] >= 'a' && sql[j] <= 'z' || sql[j] >= 'A' && sql[j] <= 'Z') {
+				j++
+			}
+			toks = append(toks, sqlTok{sqlWord, sql[i:j]})
+			i = j
+		default:
+			n := 1
+			if i+1 < len(sql) {
+				switch sql[i : i+2] {
+				case "<>", "!=", "<=", ">=", "==":
+					n = 2
+				}
+			}
+			if n == 1 && strings.IndexByte("*,().=<>+-/%", c) < 0 {
+				return nil, fmt.Errorf("unexpected character %q in query at offset %d", c, i)
+			}
+			toks = append(toks, sqlTok{sqlPunct, sql[i : i+n]})
+			i += n
+		}
+	}
+	return toks, nil
+}
+
+// sqlExpr is an expression of a query (still in SQL tokens until bound).
+type sqlExpr struct {
+	toks []sqlTok
+	e    *Expr
+}
+
+// column returns the name of the referenced column ("" when the expression is not a column reference).
+func (x *sqlExpr) column() string {
+	switch {
+	case len(x.toks) == 1 && (x.toks[0].kind == sqlWord || x.toks[0].kind == sqlIdent):
+		return x.toks[0].text
+	case len(x.toks) == 3 && x.toks[1].is("."):
+		return x.toks[0].text + "." + x.toks[2].text
+	}
+	return ""
+}
+
+// source translates the SQL tokens to an Expr.
+// As NOT has a lower precedence than comparisons in SQL, its operand is parenthesized.
+func (x *sqlExpr) source() string {
+	var b strings.Builder
+	var nots []int // depth of the pending NOT operands
+	depth := 0
+	closeNots := func() {
+		for len(nots) > 0 && nots[len(nots)-1] == depth {
+			b.WriteString(" )")
+			nots = nots[:len(nots)-1]
+		}
+	}
+	for i := 0; i < len(x.toks); i++ {
+		t := x.toks[i]
+		if t.is("AND") || t.is("OR") || t.is(")") {
+			closeNots()
+		}
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		switch {
+		case t.is("("):
+			depth++
+			b.WriteString("(")
+		case t.is(")"):
+			depth--
+			b.WriteString(")")
+		case t.kind == sqlString:
+			b.WriteString("'" + strings.Replace(t.text, "'", "''", -1) + "'")
+		case t.kind == sqlNumber:
+			b.WriteString(t.text)
+		case t.is("AND"):
+			b.WriteString("&&")
+		case t.is("OR"):
+			b.WriteString("||")
+		case t.is("NOT"):
+			b.WriteString("!(")
+			nots = append(nots, depth)
+		case t.is("NULL"), t.is("TRUE"), t.is("FALSE"):
+			b.WriteString(strings.ToLower(t.text))
+		case t.is("IS"):
+			if i+1 < len(x.toks) && x.toks[i+1].is("NOT") {
+				i++
+				b.WriteString("!=")
+			} else {
+				b.WriteString("==")
+			}
+		case t.is("="):
+			b.WriteString("==")
+		case t.is("<>"):
+			b.WriteString("!=")
+		case t.kind == sqlPunct:
+			b.WriteString(t.text)
+		default: // column reference
+			name := t.text
+			if i+2 < len(x.toks) && x.toks[i+1].is(".") {
+				name += "." + x.toks[i+2].text
+				i += 2
+			}
+			b.WriteString("`" + strings.Replace(name, "`", "``", -1) + "`")
+		}
+	}
+	closeNots()
+	return b.String()
+}
+
+// text returns the expression as written (almost).
+func (x *sqlExpr) text() string {
+	texts := make([]string, len(x.toks))
+	for i, t := range x.toks {
+		switch t.kind {
+		case sqlString:
+			texts[i] = "'" + strings.Replace(t.text, "'", "''", -1) + "'"
+		case sqlIdent:
+			texts[i] = `"` + strings.Replace(t.text, `"`, `""`, -1) + `"`
+		default:
+			texts[i] = t.text
+		}
+	}
+	return strings.Join(texts, " ")
+}
+
+type sqlTable struct {
+	name, alias string
+	r           *Reader
+	names       []string // header
+}
+
+type sqlSelectItem struct {
+	expr *sqlExpr // nil for *
+	name string
+}
+
+type sqlOrderItem struct {
+	expr *sqlExpr
+	desc bool
+}
+
+type query struct {
+	items   []sqlSelectItem
+	from    sqlTable
+	join    *sqlTable
+	on      [2]*sqlExpr
+	where   *sqlExpr
+	orderBy []sqlOrderItem
+	limit   int // -1 when unlimited
+
+	onIndexes [2]int         // indexes (first is 0) of the joined columns in the from and join tables
+	headers   map[string]int // index (first is 1) of the columns of the joined record by (qualified) name
+}
+
+type sqlParser struct {
+	toks []sqlTok
+	i    int
+}
+
+func (p *sqlParser) peek() sqlTok {
+	if p.i < len(p.toks) {
+		return p.toks[p.i]
+	}
+	return sqlTok{sqlPunct, ""}
+}
+
+// accept consumes the current token if it is one of the keywords.
+func (p *sqlParser) accept(keywords ...string) bool {
+	for _, k := range keywords {
+		if p.peek().is(k) {
+			p.i++
+			return true
+		}
+	}
+	return false
+}
+
+func (p *sqlParser) expect(keyword string) error {
+	if !p.accept(keyword) {
+		return p.unexpected(keyword)
+	}
+	return nil
+}
+
+func (p *sqlParser) unexpected(expected string) error {
+	if p.i >= len(p.toks) {
+		return fmt.Errorf("syntax error in query: %s expected at end", expected)
+	}
+	return fmt.Errorf("syntax error in query: %s expected near %q", expected, p.toks[p.i].text)
+}
+
+// expr consumes the tokens of an expression up to one of the clause keywords or a top-level comma.
+func (p *sqlParser) expr(stops ...string) (*sqlExpr, error) {
+	start, depth := p.i, 0
+	for ; p.i < len(p.toks); p.i++ {
+		t := p.toks[p.i]
+		if t.is("(") {
+			depth++
+		} else if t.is(")") {
+			depth--
+		} else if depth == 0 && t.is(",") {
+			break
+		} else if depth == 0 && t.kind != sqlIdent && t.kind != sqlString && isSQLKeyword(t.text, stops) {
+			break
+		}
+	}
+	if p.i == start {
+		return nil, p.unexpected("expression")
+	}
+	return &sqlExpr{toks: p.toks[start:p.i]}, nil
+}
+
+func isSQLKeyword(word string, keywords []string) bool {
+	for _, k := range keywords {
+		if strings.EqualFold(word, k) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *sqlParser) table() (sqlTable, error) {
+	t := p.peek()
+	if t.kind != sqlWord && t.kind != sqlIdent {
+		return sqlTable{}, p.unexpected("table name")
+	}
+	p.i++
+	table := sqlTable{name: t.text, alias: t.text}
+	p.accept("AS")
+	if a := p.peek(); a.kind == sqlIdent || a.kind == sqlWord && !isSQLKeyword(a.text, []string{"INNER", "JOIN", "ON", "WHERE", "ORDER", "LIMIT"}) {
+		table.alias = a.text
+		p.i++
+	}
+	return table, nil
+}
+
+func parseQuery(sql string) (*query, error) {
+	toks, err := tokenizeSQL(sql)
+	if err != nil {
+		return nil, err
+	}
+	p := &sqlParser{toks: toks}
+	q := &query{limit: -1}
+	if err = p.expect("SELECT"); err != nil {
+		return nil, err
+	}
+	for {
+		if p.accept("*") {
+			q.items = append(q.items, sqlSelectItem{})
+		} else {
+			x, err := p.expr("AS", "FROM")
+			if err != nil {
+				return nil, err
+			}
+			item := sqlSelectItem{expr: x, name: x.column()}
+			if i := strings.LastIndexByte(item.name, '.'); i >= 0 {
+				item.name = item.name[i+1:]
+			}
+			if p.accept("AS") {
+				if t := p.peek(); t.kind == sqlWord || t.kind == sqlIdent {
+					item.name = t.text
+					p.i++
+				} else {
+					return nil, p.unexpected("column alias")
+				}
+			} else if item.name == "" {
+				item.name = x.text()
+			}
+			q.items = append(q.items, item)
+		}
+		if !p.accept(",") {
+			break
+		}
+	}
+	if err = p.expect("FROM"); err != nil {
+		return nil, err
+	}
+	if q.from, err = p.table(); err != nil {
+		return nil, err
+	}
+	if p.accept("INNER") {
+		if err = p.expect("JOIN"); err != nil {
+			return nil, err
+		}
+		p.i--
+	}
+	if p.accept("JOIN") {
+		join, err := p.table()
+		if err != nil {
+			return nil, err
+		}
+		q.join = &join
+		if err = p.expect("ON"); err != nil {
+			return nil, err
+		}
+		if q.on[0], err = p.expr("=", "WHERE", "ORDER", "LIMIT"); err != nil {
+			return nil, err
+		}
+		if !p.accept("=", "==") {
+			return nil, p.unexpected("=")
+		}
+		if q.on[1], err = p.expr("WHERE", "ORDER", "LIMIT"); err != nil {
+			return nil, err
+		}
+	}
+	if p.accept("WHERE") {
+		if q.where, err = p.expr("ORDER", "LIMIT"); err != nil {
+			return nil, err
+		} else if p.peek().is(",") {
+			return nil, p.unexpected("ORDER BY or LIMIT")
+		}
+	}
+	if p.accept("ORDER") {
+		if err = p.expect("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			x, err := p.expr("ASC", "DESC", "LIMIT")
+			if err != nil {
+				return nil, err
+			}
+			item := sqlOrderItem{expr: x}
+			if p.accept("DESC") {
+				item.desc = true
+			} else {
+				p.accept("ASC")
+			}
+			q.orderBy = append(q.orderBy, item)
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+	if p.accept("LIMIT") {
+		t := p.peek()
+		n, err := strconv.Atoi(t.text)
+		if t.kind != sqlNumber || err != nil {
+			return nil, p.unexpected("number")
+		}
+		p.i++
+		q.limit = n
+	}
+	if p.i < len(p.toks) {
+		return nil, p.unexpected("end of query")
+	}
+	return q, nil
+}
+
+// bind loads the headers of the tables and compiles the expressions.
+func (q *query) bind(sources map[string]*Reader) error {
+	q.headers = make(map[string]int)
+	ambiguous := make(map[string]bool)
+	tables := []*sqlTable{&q.from}
+	if q.join != nil {
+		tables = append(tables, q.join)
+	}
+	offset := 0
+	for _, t := range tables {
+		if t.r = sources[t.name]; t.r == nil {
+			return fmt.Errorf("unknown table: %s", t.name)
+		}
+		if t.r.Headers == nil {
+			if err := t.r.ScanHeaders(); err != nil {
+				return fmt.Errorf("%s: %s", t.name, err)
+			}
+		}
+		t.names = make([]string, len(t.r.Headers))
+		for name, index := range t.r.Headers {
+			t.names[index-1] = name
+		}
+		for i, name := range t.names {
+			q.headers[t.alias+"."+name] = offset + i + 1
+			if _, ok := q.headers[name]; ok || ambiguous[name] {
+				delete(q.headers, name)
+				ambiguous[name] = true
+			} else {
+				q.headers[name] = offset + i + 1
+			}
+		}
+		offset += len(t.names)
+	}
+	exprs := []*sqlExpr{q.where}
+	for _, item := range q.items {
+		exprs = append(exprs, item.expr)
+	}
+	for _, item := range q.orderBy {
+		exprs = append(exprs, item.expr)
+	}
+	for _, x := range exprs {
+		if x == nil {
+			continue
+		}
+		e, err := CompileExpr(x.source(), q.headers)
+		if err != nil {
+			return err
+		}
+		x.e = e
+	}
+	if q.join != nil {
+		for i, x := range q.on {
+			index, ok := q.headers[x.column()]
+			if !ok {
+				return fmt.Errorf("unknown join column: %s", x.source())
+			}
+			q.onIndexes[i] = index - 1
+		}
+		n := len(q.from.names)
+		if q.onIndexes[0] >= n { // ON join.col = from.col
+			q.onIndexes[0], q.onIndexes[1] = q.onIndexes[1], q.onIndexes[0]
+		}
+		if q.onIndexes[0] >= n || q.onIndexes[1] < n {
+			return fmt.Errorf("join condition must compare one column of each table")
+		}
+		q.onIndexes[1] -= n
+	}
+	return nil
+}
+
+// run writes the header and the records of the result.
+func (q *query) run(w *Writer) error {
+	for _, item := range q.items {
+		if item.expr != nil {
+			w.WriteString(item.name)
+			continue
+		}
+		for _, t := range q.tables() {
+			for _, name := range t.names {
+				w.WriteString(name)
+			}
+		}
+	}
+	w.EndOfRecord()
+	var joined map[string][][][]byte // records of the joined table by key
+	if q.join != nil {
+		joined = make(map[string][][][]byte)
+		for {
+			fields, err := q.join.r.ReadRecord()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return err
+			}
+			if i := q.onIndexes[1]; i < len(fields) {
+				key := string(fields[i])
+				joined[key] = append(joined[key], copyRecord(fields))
+			}
+		}
+	}
+	var sorted []Record
+	n := 0
+	var record Record
+	for q.limit < 0 || n < q.limit || q.orderBy != nil {
+		fields, err := q.from.r.ReadRecord()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		matches := [][][]byte{nil}
+		if joined != nil {
+			if i := q.onIndexes[0]; i < len(fields) {
+				matches = joined[string(fields[i])]
+			} else {
+				matches = nil
+			}
+		}
+		for _, match := range matches {
+			if len(fields) > len(q.from.names) { // extra fields (without header) are ignored
+				fields = fields[:len(q.from.names)]
+			}
+			record = append(record[:0], fields...)
+			for len(record) < len(q.from.names) {
+				record = append(record, nil)
+			}
+			record = append(record, match...)
+			if q.where != nil {
+				if ok, err := q.where.e.Match(record); err != nil {
+					return err
+				} else if !ok {
+					continue
+				}
+			}
+			if q.orderBy != nil {
+				sorted = append(sorted, copyRecord(record))
+				continue
+			}
+			if err := q.write(w, record); err != nil {
+				return err
+			}
+			if n++; n == q.limit {
+				break
+			}
+		}
+	}
+	if q.orderBy != nil {
+		if err := q.sort(sorted); err != nil {
+			return err
+		}
+		for i, record := range sorted {
+			if i == q.limit {
+				break
+			}
+			if err := q.write(w, record); err != nil {
+				return err
+			}
+		}
+	}
+	w.Flush()
+	return w.Err()
+}
+
+func (q *query) tables() []*sqlTable {
+	if q.join != nil {
+		return []*sqlTable{&q.from, q.join}
+	}
+	return []*sqlTable{&q.from}
+}
+
+// write writes the selected values of the record.
+func (q *query) write(w *Writer, record Record) error {
+	for _, item := range q.items {
+		if item.expr == nil {
+			for _, field := range record {
+				w.Write(field)
+			}
+			continue
+		}
+		v, err := item.expr.e.Eval(record)
+		if err != nil {
+			return err
+		}
+		w.WriteString(formatValue(v))
+	}
+	w.EndOfRecord()
+	return w.Err()
+}
+
+// sort sorts the records according to the ORDER BY clause (nulls first).
+func (q *query) sort(records []Record) error {
+	keys := make([][]interface{}, len(records))
+	for i, record := range records {
+		keys[i] = make([]interface{}, len(q.orderBy))
+		for j, item := range q.orderBy {
+			v, err := item.expr.e.Eval(record)
+			if err != nil {
+				return err
+			}
+			keys[i][j] = v
+		}
+	}
+	index := make([]int, len(records))
+	for i := range index {
+		index[i] = i
+	}
+	sort.SliceStable(index, func(a, b int) bool {
+		for j, item := range q.orderBy {
+			c := compareValues(keys[index[a]][j], keys[index[b]][j])
+			if c == 0 {
+				continue
+			} else if item.desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+	sorted := make([]Record, len(records))
+	for i, k := range index {
+		sorted[i] = records[k]
+	}
+	copy(records, sorted)
+	return nil
+}
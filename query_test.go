@@ -0,0 +1,80 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+const (
+	ordersCSV    = "id,customer,qty,price\n1,10,2,5.5\n2,20,1,100\n3,10,4,30\n4,30,1,\n"
+	customersCSV = "id,name,country\n10,Alice,US\n20,Bob,FR\n30,\"Carol, Jr\",US\n"
+)
+
+var queryTests = []struct {
+	Name   string
+	SQL    string
+	Output string
+	Error  string
+}{
+	{Name: "Star", SQL: "SELECT * FROM orders LIMIT 2", Output: "id,customer,qty,price\n1,10,2,5.5\n2,20,1,100\n"},
+	{Name: "Where", SQL: "select id, qty * price AS total from orders where price > 10 and not qty = 1", Output: "id,total\n3,120\n"},
+	{Name: "IsNull", SQL: "SELECT id FROM orders WHERE price IS NULL OR id = 1", Output: "id\n1\n4\n"},
+	{Name: "OrderBy", SQL: "SELECT id, qty * price FROM orders ORDER BY qty * price DESC, id LIMIT 3", Output: "id,qty * price\n3,120\n2,100\n1,11\n"},
+	{Name: "OrderByNulls", SQL: "SELECT id FROM orders ORDER BY price", Output: "id\n4\n1\n3\n2\n"},
+	{Name: "Join", SQL: "SELECT o.id, c.name FROM orders o JOIN customers AS c ON c.id = o.customer WHERE country = 'US' ORDER BY o.id", Output: "id,name\n1,Alice\n3,Alice\n4,\"Carol, Jr\"\n"},
+	{Name: "InnerJoin", SQL: "SELECT name, \"qty\" FROM orders INNER JOIN customers ON customer = customers.id WHERE name <> 'Alice'", Output: "name,qty\nBob,1\n\"Carol, Jr\",1\n"},
+	{Name: "LimitZero", SQL: "SELECT id FROM orders LIMIT 0", Output: "id\n"},
+	{Name: "UnknownTable", SQL: "SELECT * FROM products", Error: "unknown table: products"},
+	{Name: "UnknownColumn", SQL: "SELECT cost FROM orders", Error: "unknown field name: cost"},
+	{Name: "Ambiguous", SQL: "SELECT id FROM orders JOIN customers ON customer = customers.id", Error: "unknown field name: id"},
+	{Name: "Syntax", SQL: "SELECT id orders", Error: "FROM expected at end"},
+	{Name: "Trailing", SQL: "SELECT id FROM orders LIMIT 1 2", Error: `end of query expected near "2"`},
+	{Name: "JoinCondition", SQL: "SELECT * FROM orders JOIN customers ON qty = price", Error: "join condition must compare one column of each table"},
+}
+
+func TestQuery(t *testing.T) {
+	for _, tt := range queryTests {
+		sources := map[string]*Reader{
+			"orders":    DefaultReader(strings.NewReader(ordersCSV)),
+			"customers": DefaultReader(strings.NewReader(customersCSV)),
+		}
+		r, err := Query(tt.SQL, sources)
+		if tt.Error != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.Error) {
+				t.Errorf("%s: got %v; want %q", tt.Name, err, tt.Error)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.Name, err)
+			continue
+		}
+		var b bytes.Buffer
+		if _, err = Copy(DefaultWriter(&b), r); err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.Name, err)
+		}
+		if b.String() != tt.Output {
+			t.Errorf("%s: got %q; want %q", tt.Name, b.String(), tt.Output)
+		}
+		r.Close()
+	}
+}
+
+func TestQueryClose(t *testing.T) {
+	r, err := Query("SELECT * FROM t", map[string]*Reader{"t": DefaultReader(strings.NewReader("a\n" + strings.Repeat("1\n", 100000)))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = r.ReadRecord(); err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
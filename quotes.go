@@ -0,0 +1,109 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"fmt"
+	"io"
+)
+
+// maxQuotedLines is the number of lines spanned by a quoted value beyond which CheckQuotes
+// reports it as suspicious (most likely, its closing quote is missing).
+const maxQuotedLines = 100
+
+// Imbalance is a region of the input where quotes look unbalanced (see CheckQuotes).
+type Imbalance struct {
+	Line   int    // line of the suspicious quote (first is 1)
+	Offset int64  // byte offset of the suspicious quote in the input
+	Reason string // what looks wrong
+}
+
+func (i Imbalance) String() string {
+	return fmt.Sprintf("%s at line %d (offset %d)", i.Reason, i.Line, i.Offset)
+}
+
+// quotes scanning states
+const (
+	fieldStart = iota
+	unquotedField
+	quotedField
+	quoteInQuotedField // quote seen in a quoted value: closing or escaped quote
+	commentLine
+)
+
+// CheckQuotes scans r in one fast pass (without parsing records) for unbalanced quotes
+// according to the dialect d (separator, Comment and Lazy only), so that the bad record of a huge file
+// can be located before (or instead of) a full parse:
+//   - a quoted value not terminated at the end of the input,
+//   - a quoted value spanning more than 100 lines (the scan resumes at the following line as unquoted),
+//   - a quote in a quoted value not followed by a separator, a newline or another quote (unless d.Lazy),
+//     the scan resuming as if the value was unquoted.
+//
+// Nothing is reported when d is not quoted (or escaped).
+func CheckQuotes(r io.Reader, d Dialect) ([]Imbalance, error) {
+	if !d.Quoted || d.Escape != 0 {
+		return nil, nil
+	}
+	var imbalances []Imbalance
+	state, startOfRecord := fieldStart, true
+	line, quoteLine := 1, 0
+	var offset, quoteOffset int64 // offsets of the current byte and of the last opening quote
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := r.Read(buf)
+		for _, c := range buf[:n] {
+			switch state {
+			case fieldStart:
+				if c == '"' {
+					state, quoteLine, quoteOffset = quotedField, line, offset
+				} else if startOfRecord && d.Comment != 0 && c == d.Comment {
+					state = commentLine
+				} else if c != d.Sep && c != '\n' {
+					state = unquotedField
+				}
+			case quotedField:
+				if c == '"' {
+					state = quoteInQuotedField
+				} else if c == '\n' && line+1-quoteLine >= maxQuotedLines {
+					imbalances = append(imbalances, Imbalance{quoteLine, quoteOffset,
+						fmt.Sprintf("quoted value spanning more than %d lines", maxQuotedLines)})
+					state = unquotedField // resync
+				}
+			case quoteInQuotedField:
+				if c == '"' { // escaped quote
+					state = quotedField
+				} else if c == d.Sep || c == '\n' {
+					state = unquotedField
+				} else if c != '\r' && d.Lazy { // literal quote
+					state = quotedField
+				} else if c != '\r' {
+					imbalances = append(imbalances, Imbalance{line, offset - 1,
+						"quote not followed by a separator or a newline"})
+					state = unquotedField // resync
+				}
+			}
+			if c == '\n' {
+				line++
+				if state != quotedField {
+					state, startOfRecord = fieldStart, true
+				}
+			} else if c == d.Sep && state == unquotedField {
+				state, startOfRecord = fieldStart, false
+			} else if state == fieldStart && c == d.Sep {
+				startOfRecord = false
+			}
+			offset++
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return imbalances, err
+		}
+	}
+	if state == quotedField {
+		imbalances = append(imbalances, Imbalance{quoteLine, quoteOffset, "quoted value not terminated at the end of the input"})
+	}
+	return imbalances, nil
+}
@@ -0,0 +1,42 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+var checkQuotesTests = []struct {
+	Name     string
+	Input    string
+	Dialect  Dialect
+	Expected []Imbalance
+}{
+	{"Balanced", "a,\"b\nc\",\"d\"\"e\"\r\nf\"g,h\n", DialectDefault, nil},
+	{"Unterminated", "a,b\nc,\"d\ne,f\n", DialectDefault, []Imbalance{{2, 6, "quoted value not terminated at the end of the input"}}},
+	{"UnescapedQuote", "a,b\n\"c\"d,\"e\"\n", DialectDefault, []Imbalance{{2, 6, "quote not followed by a separator or a newline"}}},
+	{"Lazy", "a,b\n\"c\"d\",e\n", Dialect{Sep: ',', Quoted: true, Lazy: true}, nil},
+	{"Comment", "#\"\na,b\n", Dialect{Sep: ',', Quoted: true, Comment: '#'}, nil},
+	{"Unquoted", "\"a\n", Dialect{Sep: ','}, nil},
+	{"TooManyLines", "a\n\"b\n" + strings.Repeat("c\n", 200) + "\"d\"\n", DialectDefault, []Imbalance{
+		{2, 2, "quoted value spanning more than 100 lines"},
+	}},
+}
+
+func TestCheckQuotes(t *testing.T) {
+	for _, test := range checkQuotesTests {
+		imbalances, err := CheckQuotes(strings.NewReader(test.Input), test.Dialect)
+		if err != nil {
+			t.Fatalf("%s: %s", test.Name, err)
+		}
+		if !reflect.DeepEqual(test.Expected, imbalances) {
+			t.Errorf("%s: got %v; want %v", test.Name, imbalances, test.Expected)
+		}
+	}
+}
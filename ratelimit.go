@@ -0,0 +1,103 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket limiting the throughput of pipelines that must not overwhelm
+// downstream APIs or shared storage: records per second (see Reader.RecordRate and Writer.RecordRate)
+// or bytes per second (see RateLimitedReader and RateLimitedWriter).
+// It can be shared by several readers and writers (it is safe for concurrent use).
+type RateLimiter struct {
+	rate  float64 // tokens per second
+	burst float64 // capacity of the bucket
+
+	mu     sync.Mutex
+	tokens float64 // available tokens (negative when borrowed)
+	last   time.Time
+}
+
+// NewRateLimiter returns a limiter allowing rate tokens per second on average
+// and up to burst tokens at once (at least 1).
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{rate: rate, burst: float64(burst), tokens: float64(burst)}
+}
+
+// Wait blocks until n tokens are available and consumes them.
+// More than burst tokens can be requested: the wait is then proportional to n.
+func (l *RateLimiter) Wait(n int) {
+	if l.rate <= 0 || n <= 0 {
+		return
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	delay := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+type rateLimitedReader struct {
+	r io.Reader
+	l *RateLimiter
+}
+
+// RateLimitedReader returns a reader of r whose throughput (bytes per second) is bounded by l.
+func RateLimitedReader(r io.Reader, l *RateLimiter) io.Reader {
+	return &rateLimitedReader{r, l}
+}
+
+func (r *rateLimitedReader) Read(p []byte) (int, error) {
+	if len(p) > int(r.l.burst) {
+		p = p[:int(r.l.burst)]
+	}
+	n, err := r.r.Read(p)
+	r.l.Wait(n)
+	return n, err
+}
+
+type rateLimitedWriter struct {
+	w io.Writer
+	l *RateLimiter
+}
+
+// RateLimitedWriter returns a writer to w whose throughput (bytes per second) is bounded by l.
+func RateLimitedWriter(w io.Writer, l *RateLimiter) io.Writer {
+	return &rateLimitedWriter{w, l}
+}
+
+func (w *rateLimitedWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > int(w.l.burst) {
+			chunk = chunk[:int(w.l.burst)]
+		}
+		w.l.Wait(len(chunk))
+		n, err := w.w.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/gwenn/yacr"
+)
+
+func TestRecordRate(t *testing.T) {
+	r := DefaultReader(strings.NewReader(strings.Repeat("a,b\n", 11)))
+	r.RecordRate = NewRateLimiter(200, 1)
+	var b bytes.Buffer
+	w := DefaultWriter(&b)
+	w.RecordRate = r.RecordRate // shared
+	start := time.Now()
+	n, err := Copy(w, r)
+	if err != nil {
+		t.Fatal(err)
+	} else if n != 11 {
+		t.Errorf("got %d records; want 11", n)
+	}
+	// 11 records read + 12 waits (EOF included) + 11 records written, the first one being free
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("too fast: %s", elapsed)
+	}
+}
+
+func TestRateLimitedReaderWriter(t *testing.T) {
+	content := strings.Repeat("x", 1000)
+	l := NewRateLimiter(10000, 100)
+	start := time.Now()
+	var b bytes.Buffer
+	if _, err := io.Copy(RateLimitedWriter(&b, l), RateLimitedReader(strings.NewReader(content), l)); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != content {
+		t.Errorf("got %d bytes; want %d", b.Len(), len(content))
+	}
+	// 2000 bytes at 10000 bytes/s with a burst of 100 bytes
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("too fast: %s", elapsed)
+	}
+}
@@ -12,7 +12,11 @@ import (
 	"fmt"
 	"io"
 	"reflect"
+	"regexp"
 	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 // Reader provides an interface for reading CSV data
@@ -27,12 +31,75 @@ type Reader struct {
 	eor    bool // true when the most recent field has been terminated by a newline (not a separator).
 	lineno int  // current line number (not record number)
 
-	Trim    bool // trim spaces (only on unquoted values). Break rfc4180 rule: "Spaces are considered part of a field and should not be ignored."
-	Comment byte // character marking the start of a line comment. When specified (not 0), line comment appears as empty line.
-	Lazy    bool // specify if quoted values may contains unescaped quote not followed by a separator or a newline
+	Trim           bool // trim spaces (only on unquoted values). Break rfc4180 rule: "Spaces are considered part of a field and should not be ignored."
+	Comment        byte // character marking the start of a line comment. When specified (not 0), line comment is skipped (see KeepComments).
+	Lazy           bool // specify if quoted values may contains unescaped quote not followed by a separator or a newline
+	AllowTruncated bool // when true, a quoted value not terminated at the end of the input (truncated upload) is returned as is instead of ErrUnterminatedQuote (see Truncated)
 
-	UseDefaults bool           // When parsing numbers, if value is empty string use type-dependent Go defaults  (0 for ints, 0.0 for floats, false for bool)
-	Headers     map[string]int // Index (first is 1) by header
+	DetectSepHint    bool  // when true, a leading "sep=X" line (Excel hint, optionally preceded by a BOM) is skipped and X is used as the separator (see SepHinted)
+	Escape           byte  // when specified (not 0, typically '\\'), quoting is disabled and the separator, newline and escape characters are escaped by this character (DSV style)
+	BareCR           bool  // when true, a bare \r (not followed by \n) is a line terminator (classic Mac OS files). By default, it is kept in the value.
+	ValidUTF8        bool  // when true, values that are not valid UTF-8 are reported as ErrEncoding (like InvalidUTF8 set to RejectInvalidUTF8)
+	UnprotectText    bool  // when true, spreadsheet text protections (="00123" string formula or tab prefix, see Writer.TextProtection) are removed from the values of UnprotectColumns
+	UnprotectColumns []int // indexes (first is 1) of the columns unprotected by UnprotectText (see UnprotectNames)
+
+	Normalize func(dst, value []byte) []byte // when specified, applied to values containing non-ASCII characters to normalize their Unicode form (e.g. norm.NFC.Append from golang.org/x/text/unicode/norm): the result is appended to dst
+
+	KeepComments           bool                 // when true (and Comment specified), line comment is returned as a single field (without the comment character) for which IsComment returns true
+	BlankLines             BlankLinePolicy      // how empty lines are handled
+	SkipBlankFieldsRecords bool                 // when true, records whose fields are all empty or whitespace-only (like " ; ; ;" report footers) are skipped by ReadRecord like empty lines
+	UnicodeNewlines        UnicodeNewlinePolicy // how NEL (U+0085), LS (U+2028) and PS (U+2029) in unquoted values are handled
+	InvalidUTF8            InvalidUTF8Policy    // how values that are not valid UTF-8 are handled
+
+	UseDefaults      bool                  // When parsing numbers, if value is empty string use type-dependent Go defaults  (0 for ints, 0.0 for floats, false for bool)
+	Headers          map[string]int        // Index (first is 1) by header
+	DuplicateHeaders DuplicateHeaderPolicy // how duplicate header names are handled by ScanHeaders
+	HeaderNormalizer func(string) string   // applied to header names by ScanHeaders and to the names looked up by name (see NormalizeHeader)
+
+	MissingFields    MissingFieldPolicy // how records with fewer fields than expected are decoded by ScanRecord and ScanStruct
+	Defaults         map[string]string  // values of missing fields by column name (see FillMissingFields)
+	ExtraFields      ExtraFieldPolicy   // how records with more fields than expected are decoded by ScanRecord and ScanStruct
+	NullValues       []string           // field values decoded as null (zero values) by ScanStruct, like "NA" or "\\N"
+	ColumnNullValues map[int][]string   // null values of specific columns by index (first is 1), replacing NullValues for those columns
+
+	record     [][]byte       // fields returned by ReadRecord
+	recBuf     []byte         // copy of the fields content returned by ReadRecord
+	recEnd     []int          // end of each field in recBuf
+	afterBlank bool           // true when the last record returned by ReadRecord was preceded by an empty line
+	blank      bool           // true when the current token is an empty line
+	comment    bool           // true when the current token is a line comment
+	quotedTok  bool           // true when the current token was quoted (see WasQuoted)
+	normBuf    []byte         // buffer of the normalized token (see Normalize)
+	utf8Buf    []byte         // buffer of the fixed token (see InvalidUTF8)
+	recQuoted  []bool         // quoting of each field returned by ReadRecord (see QuotedFields)
+	stopAt     []byte         // footer marker (see StopAt)
+	grep       *regexp.Regexp // records pre-filter (see Grep)
+	binding    *structBinding // last struct type bound to columns (see ScanStruct)
+	endings    uint8          // line endings seen so far (bit set by LineEnding)
+	sepHint    int8           // 0: not checked yet, 1: "sep=" line found, -1: no "sep=" line
+	header     bool           // true when the first line looks like a header (guess mode only, see Dialect)
+	truncated  bool           // true when the input ended inside a quoted value (see Truncated)
+	rawRecord  []byte         // raw content of the current record (see Quarantine)
+	malformed  error          // error of the current record, skipped by ScanField (see Quarantine)
+
+	trailer *trailer    // expected trailer record (see VerifyTrailer)
+	framing framing     // state of Framing verification
+	limits  limitsState // resources used so far (see Limits)
+	closers []io.Closer // resources closed by Close (see OnClose)
+	prov    Provenance  // provenance of the current record (see Provenance)
+	offset  int64       // number of bytes consumed
+	col     int         // index (first is 0) of the current field in its record
+
+	Framing      *Framing            // typed header and trailer records conventions (see ReadRecord)
+	ColTypes     []ColType           // types of the columns decoded by ScanBatch
+	Limits       *Limits             // hard limits for untrusted input (see Limits)
+	MaxColumns   int                 // when > 0, guard against a wrong separator (see SeparatorError)
+	Source       string              // name of the input (file path, URL...) reported by Provenance
+	Transformers []RecordTransformer // applied in order to the records returned by ReadRecord
+	Coercions    *CoercionReport     // when not nil, updated with the values converted by ScanRecord and ScanStruct
+	Quarantine   *Quarantine         // when not nil, malformed records and records rejected by Transformers or MaxColumns are written to it and skipped
+	RecordRate   *RateLimiter        // when not nil, bounds the number of records read per second
+	Trace        Logger              // when not nil, scanner state transitions are logged (see Logger)
 }
 
 // DefaultReader creates a "standard" CSV reader (separator is comma and quoted mode active)
@@ -43,21 +110,123 @@ func DefaultReader(rd io.Reader) *Reader {
 // NewReader returns a new CSV scanner to read from r.
 // When quoted is false, values must not contain a separator or newline.
 func NewReader(r io.Reader, sep byte, quoted, guess bool) *Reader {
-	s := &Reader{bufio.NewScanner(r), sep, quoted, guess, true, 1, false, 0, false, false, nil}
+	s := &Reader{Scanner: bufio.NewScanner(r), sep: sep, quoted: quoted, guess: guess, eor: true, lineno: 1}
 	s.Split(s.ScanField)
 	return s
 }
 
 // ScanHeaders loads current line as the header line.
+// Duplicate names are handled according to DuplicateHeaders.
 func (s *Reader) ScanHeaders() error {
-	s.Headers = make(map[string]int)
-	for i := 1; s.Scan(); i++ {
-		s.Headers[s.Text()] = i
+	var names []string
+	for s.Scan() {
+		name := s.Text()
+		if s.HeaderNormalizer != nil {
+			name = s.HeaderNormalizer(name)
+		}
+		names = append(names, name)
 		if s.EndOfRecord() {
 			break
 		}
 	}
-	return s.Err()
+	if err := s.Err(); err != nil {
+		return err
+	}
+	headers, err := s.DuplicateHeaders.index(names)
+	if err != nil {
+		return err
+	}
+	s.Headers = headers
+	return nil
+}
+
+// HeaderIndex returns the index (first is 1) of the named column (normalized by HeaderNormalizer).
+func (s *Reader) HeaderIndex(name string) (int, bool) {
+	if s.HeaderNormalizer != nil {
+		name = s.HeaderNormalizer(name)
+	}
+	index, ok := s.Headers[name]
+	return index, ok
+}
+
+// headerName returns the name of the column index (first is 1) in Headers ("" when unknown).
+func (s *Reader) headerName(index int) string {
+	for name, i := range s.Headers {
+		if i == index {
+			return name
+		}
+	}
+	return ""
+}
+
+// NormalizeHeader returns the canonical form of a header name:
+// BOM and surrounding spaces are removed, letters are lowercased
+// and runs of spaces, underscores and hyphens are replaced by a single underscore
+// (so "Email Address", "email_address" and " EMAIL-ADDRESS " are the same column).
+// It can be used as HeaderNormalizer.
+func NormalizeHeader(name string) string {
+	name = strings.TrimSpace(strings.TrimPrefix(name, "\uFEFF"))
+	var b strings.Builder
+	sep := false
+	for _, r := range name {
+		switch {
+		case r == '_' || r == '-' || unicode.IsSpace(r):
+			sep = true
+			continue
+		case sep && b.Len() > 0:
+			b.WriteByte('_')
+		}
+		sep = false
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// DuplicateHeaderPolicy specifies how duplicate header names are handled.
+type DuplicateHeaderPolicy int
+
+// Duplicate header policies
+const (
+	KeepLastHeader         DuplicateHeaderPolicy = iota // by-name access refers to the last column with the name
+	KeepFirstHeader                                     // by-name access refers to the first column with the name
+	SuffixDuplicateHeaders                              // the second occurrence of name is renamed name_1, the third name_2... (skipping names already used)
+	RejectDuplicateHeaders                              // duplicate names are reported as errors
+)
+
+// index returns the index (first is 1) by header name.
+func (p DuplicateHeaderPolicy) index(names []string) (map[string]int, error) {
+	headers := make(map[string]int, len(names))
+	seen := make(map[string]int) // occurrences by original name
+	for i, name := range names {
+		if _, dup := headers[name]; dup {
+			switch p {
+			case KeepFirstHeader:
+				continue
+			case SuffixDuplicateHeaders:
+				for {
+					seen[name]++
+					suffixed := name + "_" + strconv.Itoa(seen[name])
+					if _, used := headers[suffixed]; !used && !contains(names, suffixed) {
+						name = suffixed
+						break
+					}
+				}
+			case RejectDuplicateHeaders:
+				return nil, fmt.Errorf("duplicate header name: %s (columns %d and %d)", name, headers[name], i+1)
+			}
+		}
+		headers[name] = i + 1
+	}
+	return headers, nil
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
 }
 
 // ScanRecordByName decodes one line fields by name (name1, value1, ...).
@@ -72,7 +241,7 @@ func (s *Reader) ScanRecordByName(args ...interface{}) (int, error) {
 		if !ok {
 			return 0, fmt.Errorf("non-string field name at %d: %T", i, args[i])
 		}
-		index, ok := s.Headers[name]
+		index, ok := s.HeaderIndex(name)
 		if !ok {
 			return 0, fmt.Errorf("unknown field name: %s", name)
 		}
@@ -103,12 +272,15 @@ func (s *Reader) ScanRecordByName(args ...interface{}) (int, error) {
 //     // error handling
 //   }
 func (s *Reader) ScanRecord(values ...interface{}) (int, error) {
+	if s.RecordRate != nil {
+		s.RecordRate.Wait(1)
+	}
 	for i, value := range values {
 		if !s.Scan() {
 			return i, s.Err()
 		}
 		if i == 0 { // skip empty line (or line comment)
-			for s.EndOfRecord() && len(s.Bytes()) == 0 {
+			for s.skippable() {
 				if !s.Scan() {
 					return i, s.Err()
 				}
@@ -116,8 +288,12 @@ func (s *Reader) ScanRecord(values ...interface{}) (int, error) {
 		}
 		if err := s.value(value, true); err != nil {
 			return i + 1, err
-		} else if s.EndOfRecord() && i != len(values)-1 {
-			return i + 1, nil
+		}
+		if s.Coercions != nil {
+			s.checkCoercion(s.Bytes(), reflect.Indirect(reflect.ValueOf(value)), i+1, "")
+		}
+		if s.EndOfRecord() && i != len(values)-1 {
+			return s.missingValues(i+1, values)
 		}
 	}
 	if !s.EndOfRecord() {
@@ -127,11 +303,180 @@ func (s *Reader) ScanRecord(values ...interface{}) (int, error) {
 				return i, s.Err()
 			}
 		}
+		if s.ExtraFields == RejectExtraFields {
+			return i, s.extraFieldsErr(i, len(values))
+		}
 		return i, nil
 	}
 	return len(values), nil
 }
 
+// MissingFieldPolicy specifies how missing trailing fields (ragged records) are decoded.
+type MissingFieldPolicy int
+
+// Missing field policies
+const (
+	LeaveMissingFields  MissingFieldPolicy = iota // missing values are left untouched (ScanRecord returns the number of fields read)
+	FillMissingFields                             // missing values are decoded from Defaults (zero values when there is no default)
+	RejectMissingFields                           // missing fields are reported as errors
+)
+
+// missingValues handles the values of ScanRecord after the n-th one according to MissingFields.
+func (s *Reader) missingValues(n int, values []interface{}) (int, error) {
+	switch s.MissingFields {
+	case RejectMissingFields:
+		return n, s.missingFieldsErr(n, len(values))
+	case FillMissingFields:
+		for i := n; i < len(values); i++ {
+			if err := s.defaultValue(i, values[i]); err != nil {
+				return i, err
+			}
+		}
+		return len(values), nil
+	}
+	return n, nil
+}
+
+// ExtraFieldPolicy specifies how extra fields (records longer than expected) are decoded.
+type ExtraFieldPolicy int
+
+// Extra field policies
+const (
+	IgnoreExtraFields ExtraFieldPolicy = iota // extra fields are skipped (ScanRecord returns the number of fields read)
+	RejectExtraFields                         // extra fields are reported as errors
+)
+
+func (s *Reader) extraFieldsErr(n, expected int) error {
+	return &ParseError{Line: s.recordLine(), Err: &fieldCountError{"extra field(s)", n, expected}}
+}
+
+func (s *Reader) missingFieldsErr(n, expected int) error {
+	return &ParseError{Line: s.recordLine(), Err: &fieldCountError{"missing field(s)", n, expected}}
+}
+
+// defaultValue decodes the default value of the i-th (first is 0) column to value.
+func (s *Reader) defaultValue(i int, value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	var def string
+	var ok bool
+	for name, index := range s.Headers {
+		if index == i+1 {
+			def, ok = s.Defaults[name]
+			break
+		}
+	}
+	if u, isText := value.(encoding.TextUnmarshaler); isText && ok {
+		return u.UnmarshalText([]byte(def))
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("unsupported type %T", value)
+	}
+	dv := rv.Elem()
+	if !ok {
+		dv.Set(reflect.Zero(dv.Type()))
+		return nil
+	}
+	return s.decodeField(dv, []byte(def), &structField{sep: '|', kvSep: '=', self: isUnmarshaler(dv.Type())})
+}
+
+// ReadRecord reads one record (a slice of fields).
+// Empty lines are ignored/skipped.
+// When Framing is specified, header and trailer records are handled by its hooks (not returned) and verified.
+// The Transformers are applied in order to the record (their errors are wrapped in a ParseError).
+// Malformed records (see ErrUnescapedQuote, ErrUnterminatedQuote and RejectInvalidUTF8)
+// and records rejected by Transformers or MaxColumns are skipped when Quarantine is specified.
+// Returns (nil, io.EOF) when there is no more record.
+// The returned fields are copied from the scanner's buffer
+// but may be overwritten by a subsequent call to ReadRecord.
+func (s *Reader) ReadRecord() ([][]byte, error) {
+	for {
+		var fields [][]byte
+		var err error
+		if s.Framing != nil {
+			fields, err = s.readFramedRecord()
+		} else {
+			fields, err = s.readRecord()
+		}
+		if err != nil || len(s.Transformers) == 0 {
+			return fields, err
+		}
+		var raw [][]byte
+		if s.Quarantine != nil {
+			raw = copyRecord(fields)
+		}
+		if transformed, err := chain(s.Transformers).Transform(fields); err == nil {
+			return transformed, nil
+		} else if err = s.quarantine(raw, &ParseError{Line: s.recordLine(), Err: err}); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (s *Reader) readRecord() ([][]byte, error) {
+	for {
+		fields, err := s.scanRecord()
+		if err == nil && s.malformed != nil {
+			err, s.malformed = s.malformed, nil
+			if err = s.quarantine([][]byte{bytes.TrimRight(s.rawRecord, "\r\n")}, err); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil || s.MaxColumns <= 0 {
+			return fields, err
+		} else if err = s.checkColumns(); err == nil {
+			return fields, nil
+		} else if err = s.quarantine(fields, err); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// scanRecord reads the fields of one record.
+func (s *Reader) scanRecord() ([][]byte, error) {
+	if s.RecordRate != nil {
+		s.RecordRate.Wait(1)
+	}
+	s.recBuf = s.recBuf[:0]
+	s.recEnd = s.recEnd[:0]
+	s.recQuoted = s.recQuoted[:0]
+	s.afterBlank = false
+	for s.Scan() {
+		if len(s.recEnd) == 0 && s.skippable() { // skip empty line (or line comment)
+			s.afterBlank = s.afterBlank || s.blank // raw empty line (not a comment nor a quoted empty value)
+			continue
+		}
+		s.recBuf = append(s.recBuf, s.Bytes()...)
+		s.recEnd = append(s.recEnd, len(s.recBuf))
+		s.recQuoted = append(s.recQuoted, s.quotedTok)
+		if !s.EndOfRecord() {
+			continue
+		} else if s.SkipBlankFieldsRecords && len(bytes.TrimSpace(s.recBuf)) == 0 {
+			s.recBuf = s.recBuf[:0]
+			s.recEnd = s.recEnd[:0]
+			s.recQuoted = s.recQuoted[:0]
+			s.afterBlank = true
+			continue
+		}
+		break
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	} else if len(s.recEnd) == 0 {
+		return nil, io.EOF
+	}
+	s.record = s.record[:0]
+	start := 0
+	for _, end := range s.recEnd {
+		s.record = append(s.record, s.recBuf[start:end:end])
+		start = end
+	}
+	return s.record, nil
+}
+
 // ScanValue advances to the next token and decodes field's content to value.
 // The value may point to data that will be overwritten by a subsequent call to Scan.
 func (s *Reader) ScanValue(value interface{}) error {
@@ -195,6 +540,8 @@ func (s *Reader) value(value interface{}, copied bool) error {
 		} else {
 			*value = s.Bytes()
 		}
+	case FieldUnmarshaler:
+		err = value.UnmarshalCSVField(s.Bytes())
 	case encoding.TextUnmarshaler:
 		err = value.UnmarshalText(s.Bytes())
 	default:
@@ -242,6 +589,193 @@ func (s *Reader) scanReflect(v interface{}) (err error) {
 	return
 }
 
+// StopAt makes parsing terminate cleanly (like at the end of the input) when a record starts with prefix
+// (e.g. a human-readable footer like "Totals:" or "*** END OF REPORT ***").
+// An empty prefix disables the detection.
+func (s *Reader) StopAt(prefix []byte) {
+	if len(prefix) == 0 {
+		s.stopAt = nil
+		return
+	}
+	s.stopAt = append([]byte(nil), prefix...)
+}
+
+// Grep makes the Reader skip records whose raw content (including quotes and separators)
+// does not match re, before fields are split and unescaped.
+// This is much faster than filtering parsed fields when only a few records match.
+// A nil re disables filtering.
+func (s *Reader) Grep(re *regexp.Regexp) {
+	s.grep = re
+}
+
+// recordEnd returns the end (after the newline) of the raw record at the start of data,
+// or -1 when more data is needed.
+func (s *Reader) recordEnd(data []byte, atEOF bool) int {
+	fieldStart, inQuotes := true, false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if inQuotes {
+			if c == '"' {
+				if i+1 == len(data) && !atEOF {
+					return -1
+				} else if i+1 < len(data) && data[i+1] == '"' { // escaped quote
+					i++
+				} else {
+					inQuotes = false
+				}
+			}
+			continue
+		} else if s.Escape != 0 && c == s.Escape { // escaped character (like scanEscapedField)
+			if i+1 == len(data) && !atEOF {
+				return -1
+			}
+			i++
+			fieldStart = false
+			continue
+		}
+		switch c {
+		case '"':
+			inQuotes = s.quoted && s.Escape == 0 && fieldStart
+		case s.sep:
+			fieldStart = true
+			continue
+		case '\n':
+			return i + 1
+		case '\r':
+			if s.isBareCR(data, i, atEOF) {
+				return i + 1
+			}
+		case 0xC2, 0xE2:
+			if s.UnicodeNewlines == UnicodeNewlinesAsTerminators {
+				if _, n := unicodeNewline(data[i:]); n > 0 {
+					return i + n
+				}
+			}
+		}
+		fieldStart = false
+	}
+	if atEOF {
+		return len(data)
+	}
+	return -1
+}
+
+// BlankLinePolicy specifies how empty lines are handled.
+type BlankLinePolicy int
+
+// Blank line policies
+const (
+	SkipBlankLines    BlankLinePolicy = iota // empty lines are ignored by record-oriented methods (ScanRecord, ReadRecord...)
+	BlankLineAsRecord                        // an empty line is a record with a single empty field
+	StopAtBlankLine                          // parsing terminates at the first empty line (footer convention)
+)
+
+// UnicodeNewlinePolicy specifies how Unicode line separators are handled
+// (NEL from mainframe data converted to UTF-8, LS/PS from JavaScript generated data).
+// Only unquoted values are concerned: quoted values keep them as is.
+type UnicodeNewlinePolicy int
+
+// Unicode newline policies
+const (
+	KeepUnicodeNewlines          UnicodeNewlinePolicy = iota // Unicode line separators are kept in values
+	UnicodeNewlinesAsTerminators                             // Unicode line separators terminate records (like \n)
+	RejectUnicodeNewlines                                    // Unicode line separators are reported as errors
+)
+
+// unicodeNewline returns the Unicode line separator (and its length) at the start of b
+// (n is 0 when there is none or when b is incomplete).
+func unicodeNewline(b []byte) (r rune, n int) {
+	if len(b) >= 2 && b[0] == 0xC2 && b[1] == 0x85 {
+		return '\u0085', 2
+	} else if len(b) >= 3 && b[0] == 0xE2 && b[1] == 0x80 && (b[2] == 0xA8 || b[2] == 0xA9) {
+		return rune(0x2000) | rune(b[2]-0x80), 3
+	}
+	return 0, 0
+}
+
+// LineEnding is a line terminator convention.
+type LineEnding int
+
+// Line endings
+const (
+	UnknownLineEnding LineEnding = iota // no line terminator seen yet
+	LF                                  // \n
+	CRLF                                // \r\n
+	CR                                  // bare \r
+	MixedLineEndings                    // more than one convention
+)
+
+func (le LineEnding) String() string {
+	switch le {
+	case LF:
+		return "LF"
+	case CRLF:
+		return "CRLF"
+	case CR:
+		return "CR"
+	case MixedLineEndings:
+		return "mixed"
+	}
+	return "unknown"
+}
+
+// LineEnding reports the line terminators seen so far (in unquoted values for bare CR).
+// Mixed line endings usually denote a file concatenated from different sources
+// and bare CRs are kept in values (unless they are treated as line terminators).
+func (s *Reader) LineEnding() LineEnding {
+	switch s.endings {
+	case 0:
+		return UnknownLineEnding
+	case 1 << LF:
+		return LF
+	case 1 << CRLF:
+		return CRLF
+	case 1 << CR:
+		return CR
+	}
+	return MixedLineEndings
+}
+
+// IsBlank tells if the current token is an empty line
+// (not to be confused with a record with a single empty quoted field).
+func (s *Reader) IsBlank() bool {
+	return s.blank
+}
+
+// IsComment tells if the current token is a line comment (see KeepComments).
+func (s *Reader) IsComment() bool {
+	return s.comment
+}
+
+// WasQuoted tells if the current token was quoted in the input (see Writer.WriteQuoted).
+func (s *Reader) WasQuoted() bool {
+	return s.quotedTok
+}
+
+// Truncated tells if the input ended inside a quoted value (see AllowTruncated):
+// the last value read is partial (from the opening quote to the end of the input).
+func (s *Reader) Truncated() bool {
+	return s.truncated
+}
+
+// QuotedFields tells, for each field of the last record returned by ReadRecord, if it was quoted in the input
+// (see Writer.WriteFieldsQuoted).
+// The returned slice may be overwritten by a subsequent call to ReadRecord.
+func (s *Reader) QuotedFields() []bool {
+	return s.recQuoted
+}
+
+// skippable tells if the current token, at the start of a record,
+// is an empty line (or a line comment) to be ignored by record-oriented methods.
+func (s *Reader) skippable() bool {
+	if s.comment {
+		return true
+	} else if s.BlankLines == BlankLineAsRecord {
+		return false
+	}
+	return s.eor && len(s.Bytes()) == 0
+}
+
 // LineNumber returns current line number (not record number)
 func (s *Reader) LineNumber() int {
 	return s.lineno
@@ -257,6 +791,14 @@ func (s *Reader) Sep() byte {
 	return s.sep
 }
 
+// Quote returns the quote character ('"') or 0 when values cannot be quoted.
+func (s *Reader) Quote() byte {
+	if s.quoted && s.Escape == 0 {
+		return '"'
+	}
+	return 0
+}
+
 // SkipRecords skips n records/headers
 func (s *Reader) SkipRecords(n int) error {
 	i := 0
@@ -278,8 +820,67 @@ func (s *Reader) SkipRecords(n int) error {
 func (s *Reader) ScanField(data []byte, atEOF bool) (advance int, token []byte, err error) {
 	var a int
 	for {
+		startOfRecord, lineno := s.eor, s.lineno
+		if startOfRecord {
+			s.col = 0
+			if s.Quarantine != nil {
+				s.rawRecord = s.rawRecord[:0]
+			}
+		}
 		a, token, err = s.scanField(data, atEOF)
+		if err != nil && s.Quarantine != nil {
+			a, token, err = s.skipMalformed(data, atEOF, lineno, err)
+		}
+		if s.Trace != nil {
+			s.trace(startOfRecord, lineno, a, token, err, len(data), atEOF)
+		}
+		if startOfRecord && token != nil && !s.comment && (s.BlankLines == BlankLineAsRecord || !s.eor || len(token) > 0) {
+			s.prov.Record++
+			s.prov.Line = lineno
+			s.prov.Offset = s.offset
+		}
+		s.offset += int64(a)
+		if s.Quarantine != nil {
+			s.rawRecord = append(s.rawRecord, data[:a]...)
+		}
+		if s.trailer != nil && a > 0 {
+			s.trailer.consume(data[:a], token != nil && s.eor)
+		}
 		advance += a
+		if token != nil {
+			if s.UnprotectText && s.inColumns(s.UnprotectColumns) {
+				token = unprotectText(token)
+			}
+			s.col++
+		}
+		if policy := s.invalidUTF8(); policy != KeepInvalidUTF8 && token != nil && err == nil && !utf8.Valid(token) {
+			if policy == RejectInvalidUTF8 {
+				err = invalidUTF8Err(token)
+				pos := int64(0)
+				if e, ok := err.(*encodingError); ok && !s.quotedTok {
+					pos = s.offset - int64(a) + int64(e.offset) + 1
+				}
+				perr := &ParseError{Line: s.recordLine(), Pos: pos, Err: err}
+				if s.Quarantine == nil {
+					return 0, nil, perr
+				} else if s.malformed == nil { // the whole record is quarantined (see readRecord)
+					s.malformed = perr
+				}
+				err = nil
+			} else {
+				s.utf8Buf = policy.fix(s.utf8Buf[:0], token)
+				token = s.utf8Buf
+			}
+		}
+		if s.Normalize != nil && token != nil && !isASCII(token) {
+			s.normBuf = s.Normalize(s.normBuf[:0], token)
+			token = s.normBuf
+		}
+		if s.Limits != nil && err == nil {
+			if lerr := s.checkLimits(lineno, a, token, a == 0 && token == nil && !atEOF, len(data)); lerr != nil {
+				return 0, nil, lerr
+			}
+		}
 		if err != nil || a == 0 || token != nil {
 			return
 		}
@@ -287,19 +888,94 @@ func (s *Reader) ScanField(data []byte, atEOF bool) (advance int, token []byte,
 	}
 }
 
+// skipMalformed skips the rest of the line where the quoting error err occurred (as an empty field ending the record),
+// so that the malformed record can be quarantined (see readRecord) instead of stopping the scan.
+// Other errors are returned as is.
+func (s *Reader) skipMalformed(data []byte, atEOF bool, lineno int, err error) (int, []byte, error) {
+	perr, ok := err.(*ParseError)
+	if !ok || perr.Err != ErrUnescapedQuote && perr.Err != ErrUnterminatedQuote {
+		return 0, nil, err
+	}
+	end := len(data)
+	if i := bytes.IndexByte(data[perr.Pos-s.offset:], '\n'); i >= 0 {
+		end = int(perr.Pos-s.offset) + i + 1
+	} else if !atEOF {
+		s.lineno = lineno
+		return 0, nil, nil // request more data
+	}
+	s.lineno = lineno + bytes.Count(data[:end], []byte{'\n'})
+	s.eor, s.quotedTok = true, false
+	if s.malformed == nil {
+		s.malformed = err
+	}
+	return end, []byte{}, nil
+}
+
 func (s *Reader) scanField(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if s.trailer != nil && s.eor {
+		if more, err := s.scanTrailer(data, atEOF); more || err != nil {
+			return 0, nil, err
+		}
+	}
 	if atEOF && len(data) == 0 && s.eor {
 		return 0, nil, nil
 	}
+	if s.DetectSepHint && s.sepHint == 0 {
+		n, sep := sepHintLine(data, atEOF)
+		if n < 0 && !atEOF {
+			return 0, nil, nil // request more data
+		} else if n > 0 {
+			s.sepHint = 1
+			s.sep = sep
+			s.guess = false
+			s.lineno++
+			return n, nil, nil
+		}
+		s.sepHint = -1
+	}
 	if s.guess {
 		s.guess = false
-		if b := guess(data); b > 0 {
+		if b := guess(data, s.quoted && s.Escape == 0); b > 0 {
 			s.sep = b
 		}
+		s.header = sniffHeader(data, atEOF, s.Dialect())
+	}
+	startOfRecord := s.eor
+	s.blank, s.comment, s.quotedTok = false, false, false
+	if startOfRecord && s.BlankLines == StopAtBlankLine && len(data) > 0 {
+		if data[0] == '\n' || len(data) > 1 && data[0] == '\r' && data[1] == '\n' || s.isBareCR(data, 0, atEOF) {
+			return 0, nil, bufio.ErrFinalToken
+		} else if len(data) == 1 && data[0] == '\r' && !atEOF {
+			return 0, nil, nil // request more data
+		}
 	}
-	if s.quoted && len(data) > 0 && data[0] == '"' { // quoted field (may contains separator, newline and escaped quote)
+	if startOfRecord && s.stopAt != nil {
+		if bytes.HasPrefix(data, s.stopAt) {
+			return 0, nil, bufio.ErrFinalToken
+		} else if len(data) < len(s.stopAt) && bytes.HasPrefix(s.stopAt, data) && !atEOF {
+			return 0, nil, nil // request more data
+		}
+	}
+	if startOfRecord && s.grep != nil && len(data) > 0 && (s.Comment == 0 || data[0] != s.Comment) {
+		end := s.recordEnd(data, atEOF)
+		if end < 0 {
+			return 0, nil, nil // request more data
+		}
+		if !s.grep.Match(data[:end]) { // skip the whole record
+			s.lineno += bytes.Count(data[:end], []byte{'\n'})
+			if s.trailer != nil { // still counted (see VerifyTrailer)
+				s.trailer.records++
+			}
+			if s.BlankLines == BlankLineAsRecord || len(bytes.TrimRight(data[:end], "\r\n")) > 0 { // still numbered (see Provenance)
+				s.prov.Record++
+			}
+			return end, nil, nil
+		}
+	}
+	if s.quoted && s.Escape == 0 && len(data) > 0 && data[0] == '"' { // quoted field (may contains separator, newline and escaped quote)
 		startLineno := s.lineno
 		escapedQuotes := 0
+		s.quotedTok = true
 		strict := true
 		var c, pc, ppc byte
 		// Scan until the separator or newline following the closing quote (and ignore escaped quote)
@@ -319,16 +995,23 @@ func (s *Reader) scanField(data []byte, atEOF bool) (advance int, token []byte,
 				return i + 1, unescapeQuotes(data[1:i-1], escapedQuotes, strict), nil
 			} else if pc == '"' && c == '\n' {
 				s.eor = true
+				s.endings |= 1 << LF
 				return i + 1, unescapeQuotes(data[1:i-1], escapedQuotes, strict), nil
 			} else if c == '\n' && pc == '\r' && ppc == '"' {
 				s.eor = true
+				s.endings |= 1 << CRLF
 				return i + 1, unescapeQuotes(data[1:i-2], escapedQuotes, strict), nil
+			} else if s.BareCR && pc == '\r' && ppc == '"' {
+				s.lineno++
+				s.eor = true
+				s.endings |= 1 << CR
+				return i, unescapeQuotes(data[1:i-2], escapedQuotes, strict), nil
 			}
 			if pc == '"' && c != '\r' {
 				if s.Lazy {
 					strict = false
 				} else {
-					return 0, nil, fmt.Errorf("unescaped %c character at line %d", pc, s.lineno)
+					return 0, nil, &ParseError{Line: s.lineno, Pos: s.offset + int64(i), Err: ErrUnescapedQuote}
 				}
 			}
 			ppc = pc
@@ -338,20 +1021,51 @@ func (s *Reader) scanField(data []byte, atEOF bool) (advance int, token []byte,
 			if c == '"' {
 				s.eor = true
 				return len(data), unescapeQuotes(data[1:len(data)-1], escapedQuotes, strict), nil
+			} else if s.BareCR && c == '\r' && ppc == '"' && len(data) > 2 {
+				s.lineno++
+				s.eor = true
+				s.endings |= 1 << CR
+				return len(data), unescapeQuotes(data[1:len(data)-2], escapedQuotes, strict), nil
 			}
 			// If we're at EOF, we have a non-terminated field.
-			return 0, nil, fmt.Errorf("non-terminated quoted field between lines %d and %d", startLineno, s.lineno)
+			if s.AllowTruncated {
+				s.truncated = true
+				s.eor = true
+				return len(data), unescapeQuotes(data[1:], escapedQuotes, strict), nil
+			}
+			return 0, nil, &ParseError{StartLine: startLineno, Line: s.lineno, Pos: s.offset + 1, Err: ErrUnterminatedQuote}
 		}
+		s.lineno = startLineno // newlines are counted again when more data is available
 	} else if s.eor && s.Comment != 0 && len(data) > 0 && data[0] == s.Comment { // line comment
 		for i, c := range data {
 			if c == '\n' {
 				s.lineno++
+				if s.KeepComments {
+					s.comment = true
+					if i > 1 && data[i-1] == '\r' {
+						return i + 1, data[1 : i-1], nil
+					}
+					return i + 1, data[1:i], nil
+				}
+				return i + 1, nil, nil
+			} else if s.isBareCR(data, i, atEOF) {
+				s.lineno++
+				if s.KeepComments {
+					s.comment = true
+					return i + 1, data[1:i], nil
+				}
 				return i + 1, nil, nil
 			}
 		}
 		if atEOF {
+			if s.KeepComments {
+				s.comment = true
+				return len(data), data[1:], nil
+			}
 			return len(data), nil, nil
 		}
+	} else if s.Escape != 0 { // escaped field
+		return s.scanEscapedField(data, atEOF, startOfRecord)
 	} else { // unquoted field
 		// Scan until separator or newline, marking end of field.
 		for i, c := range data {
@@ -363,18 +1077,46 @@ func (s *Reader) scanField(data []byte, atEOF bool) (advance int, token []byte,
 				return i + 1, data[0:i], nil
 			} else if c == '\n' {
 				s.lineno++
+				s.blank = startOfRecord && (i == 0 || i == 1 && data[0] == '\r')
 				if i > 0 && data[i-1] == '\r' {
 					s.eor = true
+					s.endings |= 1 << CRLF
 					if s.Trim {
 						return i + 1, trim(data[0 : i-1]), nil
 					}
 					return i + 1, data[0 : i-1], nil
 				}
 				s.eor = true
+				s.endings |= 1 << LF
 				if s.Trim {
 					return i + 1, trim(data[0:i]), nil
 				}
 				return i + 1, data[0:i], nil
+			} else if c == '\r' && (i+1 < len(data) && data[i+1] != '\n' || i+1 == len(data) && atEOF) {
+				s.endings |= 1 << CR
+				if s.BareCR {
+					s.lineno++
+					s.blank = startOfRecord && i == 0
+					s.eor = true
+					if s.Trim {
+						return i + 1, trim(data[0:i]), nil
+					}
+					return i + 1, data[0:i], nil
+				}
+			} else if (c == 0xC2 || c == 0xE2) && s.UnicodeNewlines != KeepUnicodeNewlines {
+				r, n := unicodeNewline(data[i:])
+				if n == 0 {
+					continue
+				} else if s.UnicodeNewlines == RejectUnicodeNewlines {
+					return 0, nil, &ParseError{Line: s.lineno, Pos: s.offset + int64(i) + 1, Err: fmt.Errorf("unicode line separator U+%04X", r)}
+				}
+				s.lineno++
+				s.blank = startOfRecord && i == 0
+				s.eor = true
+				if s.Trim {
+					return i + n, trim(data[0:i]), nil
+				}
+				return i + n, data[0:i], nil
 			}
 		}
 		// If we're at EOF, we have a final field. Return it.
@@ -390,6 +1132,117 @@ func (s *Reader) scanField(data []byte, atEOF bool) (advance int, token []byte,
 	return 0, nil, nil
 }
 
+// scanEscapedField scans one field where the separator, newline and escape characters are preceded by the escape character.
+// An escape character followed by any other character is also removed.
+func (s *Reader) scanEscapedField(data []byte, atEOF, startOfRecord bool) (advance int, token []byte, err error) {
+	escapes, escaped := 0, -1 // number of escapes and index of the last escaped character
+	startLineno := s.lineno
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if c == s.Escape {
+			if i+1 == len(data) {
+				if !atEOF {
+					s.lineno = startLineno
+					return 0, nil, nil // request more data
+				}
+				break // trailing escape character kept as is
+			}
+			escapes++
+			i++
+			escaped = i
+			if data[i] == '\n' {
+				s.lineno++
+			}
+		} else if c == s.sep {
+			s.eor = false
+			return i + 1, s.unescape(data[0:i], escapes), nil
+		} else if c == '\n' {
+			s.lineno++
+			s.eor = true
+			s.blank = startOfRecord && (i == 0 || i == 1 && data[0] == '\r')
+			end := i
+			if i > 0 && data[i-1] == '\r' && escaped != i-1 {
+				s.endings |= 1 << CRLF
+				end--
+			} else {
+				s.endings |= 1 << LF
+			}
+			return i + 1, s.unescape(data[0:end], escapes), nil
+		}
+	}
+	if atEOF {
+		s.eor = true
+		return len(data), s.unescape(data, escapes), nil
+	}
+	// Request more data (escaped newlines are counted again).
+	s.lineno = startLineno
+	return 0, nil, nil
+}
+
+// unescape removes the escape characters (in place).
+func (s *Reader) unescape(b []byte, count int) []byte {
+	if count > 0 {
+		j := 0
+		for i := 0; i < len(b); i, j = i+1, j+1 {
+			if b[i] == s.Escape && i+1 < len(b) {
+				i++
+			}
+			b[j] = b[i]
+		}
+		b = b[:j]
+	}
+	if s.Trim {
+		return trim(b)
+	}
+	return b
+}
+
+// isBareCR tells if data[i] is a carriage return used as a line terminator (see BareCR).
+func (s *Reader) isBareCR(data []byte, i int, atEOF bool) bool {
+	return s.BareCR && data[i] == '\r' && (i+1 < len(data) && data[i+1] != '\n' || i+1 == len(data) && atEOF)
+}
+
+// sepHintLine returns the length of the "sep=X" line at the start of data (0 when there is none, -1 when more data is needed)
+// and the separator.
+func sepHintLine(data []byte, atEOF bool) (int, byte) {
+	const bom, prefix = "\uFEFF", "sep="
+	start := 0
+	if bytes.HasPrefix(data, []byte(bom)) {
+		start = len(bom)
+	} else if len(data) < len(bom) && bytes.HasPrefix([]byte(bom), data) {
+		return -1, 0
+	}
+	line := data[start:]
+	if len(line) < len(prefix)+1 {
+		if bytes.HasPrefix([]byte(prefix), line) || bytes.HasPrefix(line, []byte(prefix)) {
+			return -1, 0
+		}
+		return 0, 0
+	} else if !bytes.HasPrefix(line, []byte(prefix)) {
+		return 0, 0
+	}
+	sep := line[len(prefix)]
+	n := start + len(prefix) + 1
+	switch {
+	case len(data) == n && atEOF:
+		return n, sep
+	case len(data) == n:
+		return -1, 0 // the line terminator may follow
+	case data[n] == '\n':
+		return n + 1, sep
+	case data[n] == '\r' && len(data) == n+1:
+		return -1, 0
+	case data[n] == '\r' && data[n+1] == '\n':
+		return n + 2, sep
+	}
+	return 0, 0
+}
+
+// SepHinted tells if a "sep=" line has been found (see DetectSepHint and Sep).
+func (s *Reader) SepHinted() bool {
+	return s.sepHint > 0
+}
+
 func unescapeQuotes(b []byte, count int, strict bool) []byte {
 	if count == 0 {
 		return b
@@ -403,16 +1256,97 @@ func unescapeQuotes(b []byte, count int, strict bool) []byte {
 	return b[:len(b)-count]
 }
 
-func guess(data []byte) byte {
-	seps := []byte{',', ';', '\t', '|', ':'}
-	count := make(map[byte]uint)
-	for _, b := range data {
-		if bytes.IndexByte(seps, b) >= 0 {
-			count[b]++
-			/*} else if b == '\n' {
-			break*/
+// invalidUTF8 returns the effective InvalidUTF8 policy (see ValidUTF8).
+func (s *Reader) invalidUTF8() InvalidUTF8Policy {
+	if s.ValidUTF8 && s.InvalidUTF8 == KeepInvalidUTF8 {
+		return RejectInvalidUTF8
+	}
+	return s.InvalidUTF8
+}
+
+// InvalidUTF8Policy specifies how values that are not valid UTF-8 are handled.
+type InvalidUTF8Policy int
+
+// Invalid UTF-8 policies
+const (
+	KeepInvalidUTF8    InvalidUTF8Policy = iota // values are returned as is
+	ReplaceInvalidUTF8                          // each invalid byte is replaced by U+FFFD
+	RejectInvalidUTF8                           // invalid values are reported as ErrEncoding (with the offset of the first invalid byte)
+	Latin1InvalidUTF8                           // invalid values are decoded as Latin-1 (ISO-8859-1), valid ones are kept as is
+)
+
+// fix appends the valid UTF-8 version of b to dst.
+func (p InvalidUTF8Policy) fix(dst, b []byte) []byte {
+	if p == Latin1InvalidUTF8 {
+		for _, c := range b {
+			dst = utf8.AppendRune(dst, rune(c))
+		}
+		return dst
+	}
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		if r == utf8.RuneError && size == 1 {
+			dst = append(dst, "\uFFFD"...)
+		} else {
+			dst = append(dst, b[:size]...)
+		}
+		b = b[size:]
+	}
+	return dst
+}
+
+func isASCII(b []byte) bool {
+	for _, c := range b {
+		if c >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// inColumns tells if the current field is in one of the columns of indexes.
+func (s *Reader) inColumns(indexes []int) bool {
+	for _, index := range indexes {
+		if index == s.col+1 {
+			return true
+		}
+	}
+	return false
+}
+
+// UnprotectNames adds the named columns of header to UnprotectColumns.
+func (s *Reader) UnprotectNames(header []string, names ...string) error {
+	indexes, err := columnIndexes(header, names)
+	s.UnprotectColumns = append(s.UnprotectColumns, indexes...)
+	return err
+}
+
+// unprotectText removes the ="..." string formula or the tab prefix protecting a value.
+// Other formulas are kept as is.
+func unprotectText(b []byte) []byte {
+	if len(b) > 0 && b[0] == '\t' {
+		return b[1:]
+	} else if len(b) < 3 || b[0] != '=' || b[1] != '"' || b[len(b)-1] != '"' {
+		return b
+	}
+	v := b[2 : len(b)-1]
+	count := 0
+	for i := 0; i < len(v); i++ {
+		if v[i] != '"' {
+			continue
+		} else if i+1 == len(v) || v[i+1] != '"' { // not a single string (="a"&"b")
+			return b
 		}
+		count++
+		i++
 	}
+	return unescapeQuotes(v, count, true)
+}
+
+// guess returns the most frequent candidate separator in data.
+// When quoted is true, candidates appearing inside quoted values are not counted.
+func guess(data []byte, quoted bool) byte {
+	count := countSeps(data, quoted)
 	var max uint
 	var sep byte
 	for b, c := range count {
@@ -424,6 +1358,25 @@ func guess(data []byte) byte {
 	return sep
 }
 
+// candidateSeps lists the characters commonly used as separators.
+var candidateSeps = []byte{',', ';', '\t', '|', ':'}
+
+// countSeps counts the occurrences of each candidate separator in data.
+func countSeps(data []byte, quoted bool) map[byte]uint {
+	count := make(map[byte]uint)
+	inQuotes := false
+	for _, b := range data {
+		if quoted && b == '"' { // an escaped quote toggles twice
+			inQuotes = !inQuotes
+		} else if !inQuotes && bytes.IndexByte(candidateSeps, b) >= 0 {
+			count[b]++
+			/*} else if b == '\n' {
+			break*/
+		}
+	}
+	return count
+}
+
 // bytes.TrimSpace may return nil...
 func trim(s []byte) []byte {
 	t := bytes.TrimSpace(s)
@@ -5,7 +5,11 @@
 package yacr_test
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 	"testing"
@@ -292,6 +296,13 @@ x,,,
 		Input:  "a,b;c\td:e|f;g",
 		Output: [][]string{{"a,b", "c\td:e|f", "g"}},
 	},
+	{
+		Name:   "GuessQuoted",
+		Quoted: true,
+		Guess:  ';',
+		Input:  "\"a,b,c\";\"d,\"\"e,f\"\n\"g,h\";i",
+		Output: [][]string{{"a,b,c", "d,\"e,f"}, {"g,h", "i"}},
+	},
 	{
 		Name:   "6287",
 		Input:  `Field1,Field2,"LazyQuotes" Field3,Field4,Field5`,
@@ -413,6 +424,155 @@ func TestScanRecord(t *testing.T) {
 	}
 }
 
+func TestReadRecord(t *testing.T) {
+	for _, tt := range readTests {
+		if tt.Error != "" {
+			continue
+		}
+		var sep byte = ','
+		if tt.Sep != 0 {
+			sep = tt.Sep
+		}
+		r := NewReader(strings.NewReader(tt.Input), sep, tt.Quoted, tt.Guess != 0)
+		r.Comment = tt.Comment
+		r.Trim = tt.Trim
+		r.Lazy = tt.Lazy
+
+		var records [][]string
+		for {
+			fields, err := r.ReadRecord()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s: unexpected error: %v", tt.Name, err)
+			}
+			record := make([]string, len(fields))
+			for i, field := range fields {
+				record[i] = string(field)
+			}
+			records = append(records, record)
+		}
+		if !reflect.DeepEqual(records, tt.Output) {
+			t.Errorf("%s: got %q; want %q", tt.Name, records, tt.Output)
+		}
+	}
+}
+
+var blankLineTests = []struct {
+	Name        string
+	Input       string
+	Policy      BlankLinePolicy
+	Comments    bool
+	BlankFields bool
+	Output      [][]string
+}{
+	{Name: "Skip", Input: "a,b\n\n\"\"\nc\n", Output: [][]string{{"a", "b"}, {"c"}}},
+	{Name: "AsRecord", Input: "a,b\r\n\r\n\"\"\nc\n", Policy: BlankLineAsRecord, Output: [][]string{{"a", "b"}, {""}, {""}, {"c"}}},
+	{Name: "Stop", Input: "a,b\n\"\"\n\r\nTotal,2\n", Policy: StopAtBlankLine, Output: [][]string{{"a", "b"}}},
+	{Name: "Comments", Input: "#x,y\na,b\n#z\r\n\nc\n", Comments: true, Output: [][]string{{"a", "b"}, {"c"}}},
+	{Name: "BlankFields", Input: "a,b\n , ,\n\"\",\" \"\nc,\n", BlankFields: true, Output: [][]string{{"a", "b"}, {"c", ""}}},
+	{Name: "BlankFieldsStop", Input: "a,b\n , ,\n\nTotal,2\n", Policy: StopAtBlankLine, BlankFields: true, Output: [][]string{{"a", "b"}}},
+}
+
+func TestBlankLines(t *testing.T) {
+	for _, tt := range blankLineTests {
+		r := DefaultReader(strings.NewReader(tt.Input))
+		r.BlankLines = tt.Policy
+		r.Comment = '#'
+		r.KeepComments = tt.Comments
+		r.SkipBlankFieldsRecords = tt.BlankFields
+		var records [][]string
+		for {
+			fields, err := r.ReadRecord()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s: unexpected error: %v", tt.Name, err)
+			}
+			var record []string
+			for _, field := range fields {
+				record = append(record, string(field))
+			}
+			records = append(records, record)
+		}
+		if !reflect.DeepEqual(records, tt.Output) {
+			t.Errorf("%s: got %q; want %q", tt.Name, records, tt.Output)
+		}
+	}
+}
+
+func TestStopAt(t *testing.T) {
+	r := DefaultReader(strings.NewReader("a,b\n\"Totals:\nx\",1\nTotals:,2\n*** END ***\n"))
+	r.StopAt([]byte("Totals:"))
+	var got []string
+	for r.Scan() {
+		got = append(got, r.Text())
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "b", "Totals:\nx", "1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
+
+func TestGrep(t *testing.T) {
+	r := DefaultReader(strings.NewReader("id,city\n1,Paris\n2,\"Lyon\nParis\"\n3,\"Nice,\"\"Paris\"\"\"\n4,Lille\n"))
+	r.Grep(regexp.MustCompile(`Paris`))
+	var got [][]string
+	for {
+		fields, err := r.ReadRecord()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		var record []string
+		for _, field := range fields {
+			record = append(record, string(field))
+		}
+		got = append(got, record)
+	}
+	if want := [][]string{{"1", "Paris"}, {"2", "Lyon\nParis"}, {"3", `Nice,"Paris"`}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q; want %q", got, want)
+	}
+	if r.LineNumber() != 7 {
+		t.Errorf("got line %d; want %d", r.LineNumber(), 7)
+	}
+}
+
+func TestGrepEscape(t *testing.T) {
+	r := DefaultReader(strings.NewReader("1,Lyon\\\nParis\n2,Lille\n3,\"Paris\n4,\"Nice\n"))
+	r.Escape = '\\'
+	r.Grep(regexp.MustCompile(`Paris`))
+	var got []string
+	for {
+		fields, err := r.ReadRecord()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, string(fields[0])+"|"+string(fields[1]))
+	}
+	if want := []string{"1|Lyon\nParis", "3|\"Paris"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
+
+func TestIsBlankIsComment(t *testing.T) {
+	r := DefaultReader(strings.NewReader("#c\r\n\n\"\"\n"))
+	r.Comment = '#'
+	r.KeepComments = true
+	var got []string
+	for r.Scan() {
+		got = append(got, fmt.Sprintf("%q:%t:%t", r.Text(), r.IsComment(), r.IsBlank()))
+	}
+	if want := []string{`"c":true:false`, `"":false:true`, `"":false:false`}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v; want %v", got, want)
+	}
+}
+
 func TestScanTypedRecord(t *testing.T) {
 	r := DefaultReader(strings.NewReader(",nil,123,3.14,1970-01-01T00:00:00Z\n"))
 	var str string
@@ -658,3 +818,430 @@ func TestIsNumber(t *testing.T) {
 		}
 	}
 }
+
+var lineEndingTests = []struct {
+	Input  string
+	Ending LineEnding
+}{
+	{"a,b", UnknownLineEnding},
+	{"a,b\nc,d\n", LF},
+	{"a,b\r\n\"c\r\nd\",e\r\n", CRLF},
+	{"a,b\rc,d\r", CR},
+	{"a,b\r\nc,d\n", MixedLineEndings},
+	{"a,b\rc,d\r\n", MixedLineEndings},
+}
+
+func TestLineEnding(t *testing.T) {
+	for _, tt := range lineEndingTests {
+		r := DefaultReader(strings.NewReader(tt.Input))
+		for r.Scan() {
+		}
+		if err := r.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if ending := r.LineEnding(); ending != tt.Ending {
+			t.Errorf("%q: got %s; want %s", tt.Input, ending, tt.Ending)
+		}
+	}
+}
+
+var bareCRTests = []struct {
+	Name   string
+	Input  string
+	Output [][]string
+}{
+	{"Unquoted", "a,b\rc,d\r", [][]string{{"a", "b"}, {"c", "d"}}},
+	{"Quoted", "\"a\rb\",\"c\"\r\"d\"\r", [][]string{{"a\rb", "c"}, {"d"}}},
+	{"CRLF", "a,b\r\nc\rd", [][]string{{"a", "b"}, {"c"}, {"d"}}},
+	{"Blank", "a\r\rb\r", [][]string{{"a"}, {"b"}}},
+	{"Comment", "#x\ra\r", [][]string{{"a"}}},
+}
+
+func TestBareCR(t *testing.T) {
+	for _, tt := range bareCRTests {
+		r := DefaultReader(strings.NewReader(tt.Input))
+		r.BareCR = true
+		r.Comment = '#'
+		var records [][]string
+		for {
+			fields, err := r.ReadRecord()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s: unexpected error: %v", tt.Name, err)
+			}
+			record := make([]string, len(fields))
+			for i, field := range fields {
+				record[i] = string(field)
+			}
+			records = append(records, record)
+		}
+		if !reflect.DeepEqual(records, tt.Output) {
+			t.Errorf("%s: got %q; want %q", tt.Name, records, tt.Output)
+		}
+	}
+}
+
+var unicodeNewlineTests = []struct {
+	Name   string
+	Policy UnicodeNewlinePolicy
+	Input  string
+	Output [][]string
+	Error  string
+}{
+	{Name: "Keep", Policy: KeepUnicodeNewlines, Input: "a\u0085b,c\u2028", Output: [][]string{{"a\u0085b", "c\u2028"}}},
+	{Name: "Terminators", Policy: UnicodeNewlinesAsTerminators, Input: "a\u0085b,c\u2028d\u2029\"e\u2028\"\n", Output: [][]string{{"a"}, {"b", "c"}, {"d"}, {"e\u2028"}}},
+	{Name: "Reject", Policy: RejectUnicodeNewlines, Input: "a\nb,c\u2029", Error: "U+2029 at line 2"},
+	{Name: "NotNewline", Policy: RejectUnicodeNewlines, Input: "é‰\n", Output: [][]string{{"é‰"}}},
+}
+
+func TestUnicodeNewlines(t *testing.T) {
+	for _, tt := range unicodeNewlineTests {
+		r := DefaultReader(strings.NewReader(tt.Input))
+		r.UnicodeNewlines = tt.Policy
+		var records [][]string
+		var err error
+		for {
+			var fields [][]byte
+			if fields, err = r.ReadRecord(); err != nil {
+				break
+			}
+			record := make([]string, len(fields))
+			for i, field := range fields {
+				record[i] = string(field)
+			}
+			records = append(records, record)
+		}
+		if tt.Error != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.Error) {
+				t.Errorf("%s: got %v; want %s", tt.Name, err, tt.Error)
+			}
+		} else if err != io.EOF {
+			t.Errorf("%s: unexpected error: %v", tt.Name, err)
+		} else if !reflect.DeepEqual(records, tt.Output) {
+			t.Errorf("%s: got %q; want %q", tt.Name, records, tt.Output)
+		}
+	}
+}
+
+var duplicateHeaderTests = []struct {
+	Policy  DuplicateHeaderPolicy
+	Headers map[string]int
+	Error   string
+}{
+	{KeepLastHeader, map[string]int{"a": 4, "b": 2, "a_1": 5}, ""},
+	{KeepFirstHeader, map[string]int{"a": 1, "b": 2, "a_1": 5}, ""},
+	{SuffixDuplicateHeaders, map[string]int{"a": 1, "b": 2, "a_2": 3, "a_3": 4, "a_1": 5}, ""},
+	{RejectDuplicateHeaders, nil, "duplicate header name: a (columns 1 and 3)"},
+}
+
+func TestDuplicateHeaders(t *testing.T) {
+	for _, tt := range duplicateHeaderTests {
+		r := DefaultReader(strings.NewReader("a,b,a,a,a_1\n1,2,3,4,5\n"))
+		r.DuplicateHeaders = tt.Policy
+		err := r.ScanHeaders()
+		if tt.Error != "" {
+			if err == nil || err.Error() != tt.Error {
+				t.Errorf("%d: got %v; want %s", tt.Policy, err, tt.Error)
+			}
+			continue
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(r.Headers, tt.Headers) {
+			t.Errorf("%d: got %v; want %v", tt.Policy, r.Headers, tt.Headers)
+		}
+	}
+}
+
+func TestNormalizeHeader(t *testing.T) {
+	for _, name := range []string{"Email Address", "email_address", " EMAIL-ADDRESS ", "\uFEFFEmail__Address", "_email address_"} {
+		if got := NormalizeHeader(name); got != "email_address" {
+			t.Errorf("%q: got %q; want %q", name, got, "email_address")
+		}
+	}
+	r := DefaultReader(strings.NewReader("\uFEFFUser ID, Email Address \n1,a@b.c\n"))
+	r.HeaderNormalizer = NormalizeHeader
+	if err := r.ScanHeaders(); err != nil {
+		t.Fatal(err)
+	}
+	var id int
+	var email string
+	if _, err := r.ScanRecordByName("user-id", &id, "EMAIL_ADDRESS", &email); err != nil {
+		t.Fatal(err)
+	}
+	if id != 1 || email != "a@b.c" {
+		t.Errorf("got %d, %q", id, email)
+	}
+	if index, ok := r.HeaderIndex("Email address"); !ok || index != 2 {
+		t.Errorf("got %d, %t; want 2, true", index, ok)
+	}
+}
+
+func TestMissingFields(t *testing.T) {
+	for _, policy := range []MissingFieldPolicy{LeaveMissingFields, FillMissingFields, RejectMissingFields} {
+		r := DefaultReader(strings.NewReader("id,name,qty\n1\n"))
+		r.MissingFields = policy
+		r.Defaults = map[string]string{"qty": "10"}
+		if err := r.ScanHeaders(); err != nil {
+			t.Fatal(err)
+		}
+		id, name, qty := 0, "x", 1
+		n, err := r.ScanRecord(&id, &name, &qty)
+		switch policy {
+		case LeaveMissingFields:
+			if err != nil || n != 1 || id != 1 || name != "x" || qty != 1 {
+				t.Errorf("%d: got %d, %v, %d, %q, %d", policy, n, err, id, name, qty)
+			}
+		case FillMissingFields:
+			if err != nil || n != 3 || id != 1 || name != "" || qty != 10 {
+				t.Errorf("%d: got %d, %v, %d, %q, %d", policy, n, err, id, name, qty)
+			}
+		case RejectMissingFields:
+			if err == nil || err.Error() != "missing field(s): got 1, want 3 at line 2" {
+				t.Errorf("%d: got %v", policy, err)
+			}
+		}
+	}
+}
+
+func TestExtraFields(t *testing.T) {
+	r := DefaultReader(strings.NewReader("1,a,x\n2,b,y,z\n"))
+	var id int
+	var name string
+	if n, err := r.ScanRecord(&id, &name); err != nil || n != 3 {
+		t.Errorf("got %d, %v; want 3, <nil>", n, err)
+	}
+	r.ExtraFields = RejectExtraFields
+	if _, err := r.ScanRecord(&id, &name); err == nil || err.Error() != "extra field(s): got 4, want 2 at line 2" {
+		t.Errorf("got %v", err)
+	}
+}
+
+var sepHintTests = []struct {
+	Input  string
+	Hinted bool
+	Output [][]string
+}{
+	{"sep=;\na;b\n", true, [][]string{{"a", "b"}}},
+	{"\uFEFFsep=\t\r\na\tb\r\n", true, [][]string{{"a", "b"}}},
+	{"sep=|", true, nil},
+	{"sep,x\n", false, [][]string{{"sep", "x"}}},
+	{"sep=;x\n", false, [][]string{{"sep=;x"}}},
+	{"s", false, [][]string{{"s"}}},
+}
+
+func TestSepHint(t *testing.T) {
+	for _, tt := range sepHintTests {
+		r := DefaultReader(strings.NewReader(tt.Input))
+		r.DetectSepHint = true
+		var records [][]string
+		for {
+			fields, err := r.ReadRecord()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%q: unexpected error: %v", tt.Input, err)
+			}
+			record := make([]string, len(fields))
+			for i, field := range fields {
+				record[i] = string(field)
+			}
+			records = append(records, record)
+		}
+		if r.SepHinted() != tt.Hinted {
+			t.Errorf("%q: got %t; want %t", tt.Input, r.SepHinted(), tt.Hinted)
+		}
+		if !reflect.DeepEqual(records, tt.Output) {
+			t.Errorf("%q: got %q; want %q", tt.Input, records, tt.Output)
+		}
+	}
+}
+
+var escapeTests = []struct {
+	Input  string
+	Output [][]string
+}{
+	{"a\\,b,c\n", [][]string{{"a,b", "c"}}},
+	{"a\\\nb,\"c\"\r\n", [][]string{{"a\nb", "\"c\""}}},
+	{"a\\\\,b\\\r\n", [][]string{{"a\\", "b\r"}}},
+	{"a\\x\\", [][]string{{"ax\\"}}},
+	{"\\\\\r\n\n", [][]string{{"\\"}}},
+}
+
+func TestEscape(t *testing.T) {
+	for _, tt := range escapeTests {
+		r := DefaultReader(strings.NewReader(tt.Input))
+		r.Escape = '\\'
+		var records [][]string
+		for {
+			fields, err := r.ReadRecord()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%q: unexpected error: %v", tt.Input, err)
+			}
+			record := make([]string, len(fields))
+			for i, field := range fields {
+				record[i] = string(field)
+			}
+			records = append(records, record)
+		}
+		if !reflect.DeepEqual(records, tt.Output) {
+			t.Errorf("%q: got %q; want %q", tt.Input, records, tt.Output)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	var tests = []struct {
+		Name  string
+		Input string
+		Valid bool
+		Err   error
+		Line  int
+	}{
+		{Name: "UnescapedQuote", Input: "a,\"b\"c\"\n", Err: ErrUnescapedQuote, Line: 1},
+		{Name: "UnterminatedQuote", Input: "a\n\"b\nc", Err: ErrUnterminatedQuote, Line: 3},
+		{Name: "Encoding", Input: "a\nb,\xff\xfe\n", Valid: true, Err: ErrEncoding, Line: 2},
+		{Name: "NoEncodingCheck", Input: "a\nb,\xff\xfe\n"},
+	}
+	for _, tt := range tests {
+		r := DefaultReader(strings.NewReader(tt.Input))
+		r.ValidUTF8 = tt.Valid
+		var err error
+		for err == nil {
+			_, err = r.ReadRecord()
+		}
+		if tt.Err == nil {
+			if err != io.EOF {
+				t.Errorf("%s: unexpected error: %v", tt.Name, err)
+			}
+			continue
+		}
+		var perr *ParseError
+		if !errors.Is(err, tt.Err) {
+			t.Errorf("%s: got %v; want %v", tt.Name, err, tt.Err)
+		} else if !errors.As(err, &perr) || perr.Line != tt.Line {
+			t.Errorf("%s: got %#v; want error at line %d", tt.Name, err, tt.Line)
+		}
+	}
+
+	r := DefaultReader(strings.NewReader("1,a\n2\n"))
+	r.MissingFields = RejectMissingFields
+	var id int
+	var name string
+	if _, err := r.ScanRecord(&id, &name); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.ScanRecord(&id, &name); !errors.Is(err, ErrFieldCount) {
+		t.Errorf("got %v; want %v", err, ErrFieldCount)
+	}
+}
+
+func TestGuessDialect(t *testing.T) {
+	var tests = []struct {
+		Name   string
+		Input  string
+		Sep    byte
+		CRLF   bool
+		Header bool
+	}{
+		{Name: "NumericColumns", Input: "id;price\r\n1;2.5\r\n2;3\r\n", Sep: ';', CRLF: true, Header: true},
+		{Name: "NoHeader", Input: "1\t2.5\n2\t3\n", Sep: '\t'},
+		{Name: "FixedLength", Input: "code|name\nFR|France\nDE|Germany\n", Sep: '|', Header: true},
+		{Name: "PartialLine", Input: "a,b\nc,d\n1,", Sep: ','},
+	}
+	for _, tt := range tests {
+		r := NewReader(strings.NewReader(tt.Input), ',', true, true)
+		for {
+			if _, err := r.ReadRecord(); err != nil {
+				break
+			}
+		}
+		d := r.Dialect()
+		if d.Sep != tt.Sep || d.UseCRLF != tt.CRLF || d.Header != tt.Header || !d.Quoted || r.Quote() != '"' {
+			t.Errorf("%s: got %+v", tt.Name, d)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	calls := 0
+	// composes "e" followed by U+0301 (combining acute accent) like NFC
+	nfc := func(dst, value []byte) []byte {
+		calls++
+		return append(dst, strings.ReplaceAll(string(value), "e\u0301", "\u00e9")...)
+	}
+	r := DefaultReader(strings.NewReader("Cafe\u0301,\"caf\u00e9\",cafe\n"))
+	r.Normalize = nfc
+	fields, err := r.ReadRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Caf\u00e9", "caf\u00e9", "cafe"}; !reflect.DeepEqual(toStrings(fields), want) {
+		t.Errorf("got %q; want %q", fields, want)
+	}
+	if calls != 2 {
+		t.Errorf("got %d calls; want 2 (ASCII values are not normalized)", calls)
+	}
+}
+
+func TestInvalidUTF8(t *testing.T) {
+	input := "caf\xe9,ok\u00e9,a\xff\xfeb\n"
+	for _, tt := range []struct {
+		Policy InvalidUTF8Policy
+		Output []string
+	}{
+		{KeepInvalidUTF8, []string{"caf\xe9", "ok\u00e9", "a\xff\xfeb"}},
+		{ReplaceInvalidUTF8, []string{"caf\uFFFD", "ok\u00e9", "a\uFFFD\uFFFDb"}},
+		{Latin1InvalidUTF8, []string{"caf\u00e9", "ok\u00e9", "a\u00ff\u00feb"}},
+	} {
+		r := DefaultReader(strings.NewReader(input))
+		r.InvalidUTF8 = tt.Policy
+		fields, err := r.ReadRecord()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(toStrings(fields), tt.Output) {
+			t.Errorf("%d: got %q; want %q", tt.Policy, fields, tt.Output)
+		}
+	}
+	for _, valid := range []bool{false, true} {
+		r := DefaultReader(strings.NewReader(input))
+		if valid {
+			r.ValidUTF8 = true
+		} else {
+			r.InvalidUTF8 = RejectInvalidUTF8
+		}
+		if _, err := r.ReadRecord(); err == nil || err.Error() != "invalid UTF-8 encoding: byte 0xE9 at offset 3 in value at line 1" {
+			t.Errorf("ValidUTF8 %t: got %v", valid, err)
+		}
+	}
+}
+
+func TestAllowTruncated(t *testing.T) {
+	r := DefaultReader(strings.NewReader("a,\"b\"\nc,\"d \"\"e\"\"\nf"))
+	r.AllowTruncated = true
+	var records [][]string
+	for {
+		fields, err := r.ReadRecord()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if r.Truncated() != (len(records) == 1) {
+			t.Errorf("record %d: got truncated %t", len(records)+1, r.Truncated())
+		}
+		var record []string
+		for _, field := range fields {
+			record = append(record, string(field))
+		}
+		records = append(records, record)
+	}
+	expected := [][]string{{"a", "b"}, {"c", "d \"e\"\nf"}}
+	if !reflect.DeepEqual(expected, records) {
+		t.Errorf("got %q; want %q", records, expected)
+	}
+}
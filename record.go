@@ -0,0 +1,104 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import "io"
+
+// RecordSource is the interface implemented by types yielding records one at a time (like Reader).
+type RecordSource interface {
+	// ReadRecord returns the fields of the next record or io.EOF when there is no more record.
+	// The returned fields may be overwritten by a subsequent call.
+	ReadRecord() ([][]byte, error)
+}
+
+// RecordTransformer is the interface implemented by record-level processing stages
+// (like Masker, Encrypter, Decrypter, JSONExtractor, Computer or WidthEnforcer)
+// that can be chained by Reader and Writer (see Reader.Transformers and Writer.Transformers).
+type RecordTransformer interface {
+	// Transform returns the transformed record or an error.
+	// The returned fields may be overwritten by a subsequent call.
+	Transform(fields [][]byte) ([][]byte, error)
+}
+
+// Chain returns a transformer applying ts in order.
+func Chain(ts ...RecordTransformer) RecordTransformer {
+	return chain(ts)
+}
+
+type chain []RecordTransformer
+
+func (c chain) Transform(fields [][]byte) ([][]byte, error) {
+	var err error
+	for _, t := range c {
+		if fields, err = t.Transform(fields); err != nil {
+			return nil, err
+		}
+	}
+	return fields, nil
+}
+
+// Copy writes all records from src to w and flushes w.
+// Returns the number of records copied.
+func Copy(w *Writer, src RecordSource) (int, error) {
+	n := 0
+	for {
+		fields, err := src.ReadRecord()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return n, err
+		}
+		if !w.WriteFields(fields) {
+			return n, w.Err()
+		}
+		n++
+	}
+	w.Flush()
+	return n, w.Err()
+}
+
+// recordRewriter rebuilds records where some fields are replaced.
+type recordRewriter struct {
+	buf  []byte   // content of the replaced fields
+	ends []int    // end of each replaced field in buf (-1 when the field is kept)
+	out  [][]byte // rewritten record
+}
+
+// rewrite calls f for each field: f appends the new value to dst and tells if the field is replaced.
+// The returned fields may be overwritten by a subsequent call.
+func (rw *recordRewriter) rewrite(fields [][]byte, f func(i int, dst, field []byte) ([]byte, bool, error)) ([][]byte, error) {
+	rw.buf = rw.buf[:0]
+	rw.ends = rw.ends[:0]
+	for i, field := range fields {
+		var replaced bool
+		var err error
+		if rw.buf, replaced, err = f(i, rw.buf, field); err != nil {
+			return nil, err
+		} else if replaced {
+			rw.ends = append(rw.ends, len(rw.buf))
+		} else {
+			rw.ends = append(rw.ends, -1)
+		}
+	}
+	rw.out = rw.out[:0]
+	start := 0
+	for i, field := range fields {
+		if end := rw.ends[i]; end >= 0 {
+			field = rw.buf[start:end:end]
+			start = end
+		}
+		rw.out = append(rw.out, field)
+	}
+	return rw.out, nil
+}
+
+// copyRecord returns a deep copy of the record fields.
+func copyRecord(fields [][]byte) [][]byte {
+	record := make([][]byte, len(fields))
+	for i, field := range fields {
+		record[i] = append([]byte(nil), field...)
+	}
+	return record
+}
@@ -0,0 +1,65 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+// upper is a third-party like transformer.
+type upper struct{}
+
+func (upper) Transform(fields [][]byte) ([][]byte, error) {
+	for i, field := range fields {
+		fields[i] = bytes.ToUpper(field)
+	}
+	return fields, nil
+}
+
+func TestTransformers(t *testing.T) {
+	headers := map[string]int{"code": 1, "city": 2}
+	masker, err := NewMasker(headers, map[string]Mask{"city": TruncateMask(3)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	enforcer, err := NewWidthEnforcer(headers, map[string]int{"code": 2}, RejectWidth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := DefaultReader(strings.NewReader("ab,paris\nabc,lyon\n"))
+	r.Transformers = []RecordTransformer{masker, upper{}, enforcer}
+	fields, err := r.ReadRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values := toStrings(fields); !reflect.DeepEqual(values, []string{"AB", "PAR"}) {
+		t.Errorf("got %q; want %q", values, []string{"AB", "PAR"})
+	}
+	if _, err = r.ReadRecord(); !errors.Is(err, ErrTooLong) || !strings.HasSuffix(err.Error(), "at line 2") {
+		t.Errorf("got %v; want ErrTooLong at line 2", err)
+	}
+
+	var b bytes.Buffer
+	w := DefaultWriter(&b)
+	w.Transformers = []RecordTransformer{Chain(upper{}, masker)}
+	w.WriteFields([][]byte{[]byte("ab"), []byte("paris")})
+	w.Flush()
+	if err = w.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "AB,PAR\n" {
+		t.Errorf("got %q; want %q", b.String(), "AB,PAR\n")
+	}
+	w.Transformers = append(w.Transformers, enforcer)
+	if w.WriteFields([][]byte{[]byte("abc"), []byte("lyon")}) || !errors.Is(w.Err(), ErrTooLong) {
+		t.Errorf("got %v; want ErrTooLong", w.Err())
+	}
+}
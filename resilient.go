@@ -0,0 +1,129 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ResilientReader reads a flaky source (network stream, remote file...) that can be reopened at an offset:
+// after an io error, the source is transparently reopened where the reading stopped, so that a Reader
+// on top of it resumes parsing as if nothing happened.
+// Errors marked as Permanent (by the open function), context cancellation and io.EOF are not retried.
+type ResilientReader struct {
+	MaxRetries int                           // maximum number of consecutive failures retried (a successful read resets the count)
+	RetryDelay time.Duration                 // delay before the first retry (doubled after each consecutive failure)
+	OnRetry    func(offset int64, err error) // called before each retry (logging)
+
+	ctx     context.Context
+	open    func(offset int64) (io.ReadCloser, error)
+	rc      io.ReadCloser
+	offset  int64 // number of bytes read
+	retries int   // consecutive failures
+}
+
+// NewResilientReader returns a reader of the source opened (and reopened) by open.
+// The source is opened by the first Read.
+func NewResilientReader(ctx context.Context, open func(offset int64) (io.ReadCloser, error)) *ResilientReader {
+	return &ResilientReader{ctx: ctx, open: open}
+}
+
+// permanentError marks errors that must not be retried.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks err as not retriable by ResilientReader.
+func Permanent(err error) error {
+	return permanentError{err}
+}
+
+// Offset returns the number of bytes read so far.
+func (r *ResilientReader) Offset() int64 {
+	return r.offset
+}
+
+// reopen (re)opens the source at the current offset, retrying after failures.
+func (r *ResilientReader) reopen() error {
+	for {
+		rc, err := r.open(r.offset)
+		if err == nil {
+			r.rc = rc
+			return nil
+		} else if err = r.retry(err); err != nil {
+			return err
+		}
+	}
+}
+
+// retry waits before the next attempt or returns err when it is permanent or there is no retry left.
+func (r *ResilientReader) retry(err error) error {
+	var perr permanentError
+	if errors.As(err, &perr) {
+		return perr.err
+	} else if cerr := r.ctx.Err(); cerr != nil {
+		return cerr
+	} else if r.retries >= r.MaxRetries {
+		return err
+	}
+	if r.OnRetry != nil {
+		r.OnRetry(r.offset, err)
+	}
+	delay := r.RetryDelay << uint(r.retries)
+	r.retries++
+	select {
+	case <-time.After(delay):
+		return nil
+	case <-r.ctx.Done():
+		return r.ctx.Err()
+	}
+}
+
+// Read reads from the source, reopening it after failures.
+func (r *ResilientReader) Read(p []byte) (int, error) {
+	for {
+		if r.rc == nil {
+			if err := r.reopen(); err != nil {
+				return 0, err
+			}
+		}
+		n, err := r.rc.Read(p)
+		r.offset += int64(n)
+		if n > 0 {
+			r.retries = 0
+		}
+		if err == nil || err == io.EOF {
+			return n, err
+		}
+		_ = r.rc.Close()
+		r.rc = nil
+		if rerr := r.retry(err); rerr != nil {
+			return n, rerr
+		} else if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+// Close closes the current source.
+func (r *ResilientReader) Close() error {
+	if r.rc == nil {
+		return nil
+	}
+	err := r.rc.Close()
+	r.rc = nil
+	return err
+}
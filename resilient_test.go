@@ -0,0 +1,87 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+// flakyReader fails after limit bytes.
+type flakyReader struct {
+	r     io.Reader
+	limit int
+}
+
+func (f *flakyReader) Read(p []byte) (int, error) {
+	if f.limit == 0 {
+		return 0, errors.New("connection reset")
+	} else if len(p) > f.limit {
+		p = p[:f.limit]
+	}
+	n, err := f.r.Read(p)
+	f.limit -= n
+	return n, err
+}
+
+func TestResilientReader(t *testing.T) {
+	content := strings.Repeat("a,\"b\nc\"\n", 100)
+	opened := 0
+	rr := NewResilientReader(context.Background(), func(offset int64) (io.ReadCloser, error) {
+		opened++
+		return ioutil.NopCloser(&flakyReader{strings.NewReader(content[offset:]), 50}), nil
+	})
+	rr.MaxRetries = 1
+	retries := 0
+	rr.OnRetry = func(offset int64, err error) {
+		retries++
+	}
+	r := DefaultReader(rr)
+	r.OnClose(rr)
+	n := 0
+	for {
+		fields, err := r.ReadRecord()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		} else if len(fields) != 2 || string(fields[1]) != "b\nc" {
+			t.Fatalf("unexpected record: %q", fields)
+		}
+		n++
+	}
+	if n != 100 || rr.Offset() != int64(len(content)) || retries != opened-1 || retries < 10 {
+		t.Errorf("got %d records, offset %d, %d retries and %d opens", n, rr.Offset(), retries, opened)
+	}
+	if err := r.Close(); err != nil {
+		t.Error(err)
+	}
+
+	errGone := errors.New("gone")
+	rr = NewResilientReader(context.Background(), func(offset int64) (io.ReadCloser, error) {
+		if offset > 0 {
+			return nil, Permanent(errGone)
+		}
+		return ioutil.NopCloser(&flakyReader{strings.NewReader(content), 10}), nil
+	})
+	rr.MaxRetries = 5
+	if _, err := ioutil.ReadAll(rr); err != errGone {
+		t.Errorf("got %v; want %v", err, errGone)
+	}
+
+	rr = NewResilientReader(context.Background(), func(offset int64) (io.ReadCloser, error) {
+		return ioutil.NopCloser(&flakyReader{strings.NewReader(content), 0}), nil
+	})
+	rr.MaxRetries = 2
+	if _, err := ioutil.ReadAll(rr); err == nil || err.Error() != "connection reset" {
+		t.Errorf("got %v; want connection reset", err)
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+// FieldType is the (logical) type of a Schema column.
+type FieldType string
+
+// Field types
+const (
+	StringType  FieldType = "string"
+	IntegerType FieldType = "integer"
+	NumberType  FieldType = "number"
+	BooleanType FieldType = "boolean"
+	JSONType    FieldType = "json" // JSON document (see Column.Paths)
+)
+
+// Schema describes the columns of a file (in order).
+type Schema struct {
+	Columns []Column
+}
+
+// Column describes one column of a Schema.
+type Column struct {
+	Name   string
+	Type   FieldType // StringType when empty
+	Format string    // format of string values: "name", "email", "uuid" or "" for free text (see Generate)
+	Paths  []string  // dotted sub-paths (like "user.id") extracted from JSON columns (see JSONExtractor)
+	Nulls  []string  // values denoting null (nil means the empty string)
+
+	Required bool     // true when null values are invalid (see Validate)
+	Min, Max float64  // range of numeric values (a bound equal to 0 is ignored, unless HasMin or HasMax)
+	HasMin   bool     // true when Min applies (implied when Min is not 0)
+	HasMax   bool     // true when Max applies (implied when Max is not 0)
+	Enum     []string // allowed values (any when empty)
+	Pattern  string   // regular expression that (whole) string values must match
+	NullRate float64  // proportion (between 0 and 1) of null (empty) values produced by Generate
+}
+
+// InferSchema infers the schema of the input from its header (loaded by ScanHeaders when missing)
+// and (at most n, all when n <= 0) following records.
+// The type of each column is the narrowest one (IntegerType, NumberType, BooleanType or StringType)
+// matching all its non-empty values (StringType when there is none).
+func InferSchema(r *Reader, n int) (Schema, error) {
+	if r.Headers == nil {
+		if err := r.ScanHeaders(); err != nil {
+			return Schema{}, err
+		}
+	}
+	columns := headerColumns(r.Headers)
+	types := make([]FieldType, len(columns)) // "" until a non-empty value is seen
+	for i := 0; n <= 0 || i < n; i++ {
+		fields, err := r.ReadRecord()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return Schema{}, err
+		}
+		for j, field := range fields {
+			if j < len(types) && len(field) > 0 {
+				types[j] = inferType(types[j], field)
+			}
+		}
+	}
+	for i, t := range types {
+		if t == "" {
+			t = StringType
+		}
+		columns[i].Type = t
+	}
+	return Schema{Columns: columns}, nil
+}
+
+// headerColumns returns the (untyped) columns of the header.
+func headerColumns(headers map[string]int) []Column {
+	columns := make([]Column, len(headers))
+	for name, index := range headers {
+		columns[index-1].Name = name
+	}
+	return columns
+}
+
+// inferType returns the narrowest type matching value and the values of type t.
+func inferType(t FieldType, value []byte) FieldType {
+	s := string(value)
+	if t == "" || t == BooleanType {
+		if s == "true" || s == "false" {
+			return BooleanType
+		} else if t == BooleanType {
+			return StringType
+		}
+	}
+	if t == "" || t == IntegerType {
+		if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return IntegerType
+		}
+	}
+	if t != StringType {
+		if isNum, _ := IsNumber(value); isNum {
+			return NumberType
+		}
+	}
+	return StringType
+}
+
+// Names returns the names of the columns.
+func (s *Schema) Names() []string {
+	names := make([]string, len(s.Columns))
+	for i, c := range s.Columns {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// Decode converts the fields of a record to typed values according to the column types:
+// nil (null value), string, int64, float64, bool or the decoded JSON document.
+// Fields without column are returned as strings.
+func (s *Schema) Decode(fields [][]byte) ([]interface{}, error) {
+	values := make([]interface{}, len(fields))
+	for i, field := range fields {
+		if i >= len(s.Columns) {
+			values[i] = string(field)
+			continue
+		}
+		c := &s.Columns[i]
+		v, err := c.decode(field)
+		if err != nil {
+			return nil, fmt.Errorf("column %d (%s): %s", i+1, c.Name, err)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// Validate checks that a record conforms to the schema:
+// number of fields, required values, types and constraints (Min/Max, Enum and Pattern).
+func (s *Schema) Validate(fields [][]byte) error {
+	if len(fields) != len(s.Columns) {
+		return &fieldCountError{"wrong number of fields", len(fields), len(s.Columns)}
+	}
+	for i, field := range fields {
+		c := &s.Columns[i]
+		if err := c.validate(field); err != nil {
+			return fmt.Errorf("column %d (%s): %s", i+1, c.Name, err)
+		}
+	}
+	return nil
+}
+
+// IsNull tells if value denotes null.
+func (c *Column) IsNull(value []byte) bool {
+	if c.Nulls == nil {
+		return len(value) == 0
+	}
+	for _, null := range c.Nulls {
+		if string(value) == null {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Column) decode(b []byte) (interface{}, error) {
+	if c.IsNull(b) {
+		return nil, nil
+	}
+	switch c.Type {
+	case IntegerType:
+		return strconv.ParseInt(string(b), 10, 64)
+	case NumberType:
+		return strconv.ParseFloat(string(b), 64)
+	case BooleanType:
+		return strconv.ParseBool(string(b))
+	case JSONType:
+		return decodeJSON(b)
+	}
+	return string(b), nil
+}
+
+func (c *Column) validate(b []byte) error {
+	v, err := c.decode(b)
+	if err != nil {
+		return err
+	} else if v == nil {
+		if c.Required {
+			return fmt.Errorf("missing required value")
+		}
+		return nil
+	}
+	if f, ok := v.(float64); ok && math.IsNaN(f) {
+		return fmt.Errorf("invalid number: %s", b)
+	}
+	if min, max, ok := c.bounds(); ok {
+		var f float64
+		switch v := v.(type) {
+		case int64:
+			f = float64(v)
+		case float64:
+			f = v
+		}
+		if f < min || f > max {
+			return fmt.Errorf("value out of range [%g, %g]: %s", min, max, b)
+		}
+	}
+	if len(c.Enum) > 0 {
+		found := false
+		for _, e := range c.Enum {
+			if string(b) == e {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("value not in %q: %s", c.Enum, b)
+		}
+	}
+	if c.Pattern != "" {
+		re, err := compilePattern(c.Pattern)
+		if err != nil {
+			return err
+		}
+		if !re.Match(b) {
+			return fmt.Errorf("value does not match %q: %s", c.Pattern, b)
+		}
+	}
+	return nil
+}
+
+// patterns caches the compiled (anchored) Column.Pattern.
+var patterns sync.Map // map[string]*regexp.Regexp
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return nil, err
+	}
+	patterns.Store(pattern, re)
+	return re, nil
+}
+
+// bounds returns the range of numeric values (infinite when a bound does not apply)
+// and false when there is none.
+func (c Column) bounds() (float64, float64, bool) {
+	hasMin, hasMax := c.HasMin || c.Min != 0, c.HasMax || c.Max != 0
+	min, max := math.Inf(-1), math.Inf(1)
+	if hasMin {
+		min = c.Min
+	}
+	if hasMax {
+		max = c.Max
+	}
+	return min, max, hasMin || hasMax
+}
@@ -0,0 +1,58 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+func TestInferSchema(t *testing.T) {
+	r := DefaultReader(strings.NewReader("i,n,b,s,e,x\n1,1,true,a,,1\n-2,2.5,false,1,,true\n,,,,,x\n3,nan,true,2,,"))
+	schema, err := InferSchema(r, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []FieldType
+	for _, c := range schema.Columns {
+		types = append(types, c.Type)
+	}
+	if want := []FieldType{IntegerType, NumberType, BooleanType, StringType, StringType, StringType}; !reflect.DeepEqual(types, want) {
+		t.Errorf("got %v; want %v", types, want)
+	}
+	if names := schema.Names(); !reflect.DeepEqual(names, []string{"i", "n", "b", "s", "e", "x"}) {
+		t.Errorf("unexpected names: %v", names)
+	}
+}
+
+func TestColumnBounds(t *testing.T) {
+	schema := Schema{Columns: []Column{{Name: "qty", Type: IntegerType, Min: 5}, {Name: "delta", Type: NumberType, Max: -1}}}
+	if err := schema.Validate([][]byte{[]byte("1000"), []byte("-1e9")}); err != nil {
+		t.Error(err) // a zero bound does not apply
+	}
+	for _, fields := range [][][]byte{{[]byte("4"), []byte("-2")}, {[]byte("5"), []byte("0")}, {[]byte("5"), []byte("NaN")}} {
+		if err := schema.Validate(fields); err == nil {
+			t.Errorf("error expected for %q", fields)
+		}
+	}
+	var b bytes.Buffer
+	if err := WriteTableSchema(&b, schema); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadTableSchema(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := read.Columns[0]; c.Min != 5 || !c.HasMin || c.HasMax {
+		t.Errorf("got [%g, %g] (%t, %t)", c.Min, c.Max, c.HasMin, c.HasMax)
+	}
+	if c := read.Columns[1]; c.Max != -1 || c.HasMin || !c.HasMax {
+		t.Errorf("got [%g, %g] (%t, %t)", c.Min, c.Max, c.HasMin, c.HasMax)
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"io"
+	"iter"
+)
+
+// Section is one table of a multi-section file:
+// sections are separated by empty lines and each one starts with its own header record.
+type Section struct {
+	Header  []string       // first record of the section
+	Headers map[string]int // Index (first is 1) by header (normalized by Reader.HeaderNormalizer)
+
+	r    *sections
+	done bool
+}
+
+type sections struct {
+	r       *Reader
+	pending []string // header of the next section (already read)
+	err     error
+}
+
+// ReadRecord reads the next record of the section.
+// Returns (nil, io.EOF) at the end of the section.
+// The returned fields may be overwritten by a subsequent call.
+func (sec *Section) ReadRecord() ([][]byte, error) {
+	if sec.done {
+		return nil, io.EOF
+	}
+	ss := sec.r
+	fields, err := ss.r.ReadRecord()
+	if err != nil {
+		sec.done = true
+		if err != io.EOF {
+			ss.err = err
+		}
+		return nil, err
+	}
+	if ss.r.afterBlank { // next section header
+		sec.done = true
+		ss.pending = copyStrings(fields)
+		return nil, io.EOF
+	}
+	return fields, nil
+}
+
+func copyStrings(fields [][]byte) []string {
+	record := make([]string, len(fields))
+	for i, field := range fields {
+		record[i] = string(field)
+	}
+	return record
+}
+
+// Sections iterates over the sections of a multi-section file
+// (several tables separated by empty lines, each one starting with its own header record).
+// Records of a section not consumed by the caller are skipped when moving to the next section.
+func (s *Reader) Sections() iter.Seq2[*Section, error] {
+	return func(yield func(*Section, error) bool) {
+		ss := &sections{r: s}
+		fields, err := s.ReadRecord()
+		if err == io.EOF {
+			return
+		} else if err != nil {
+			yield(nil, err)
+			return
+		}
+		ss.pending = copyStrings(fields)
+		for ss.pending != nil {
+			names := ss.pending
+			if s.HeaderNormalizer != nil {
+				names = make([]string, len(ss.pending))
+				for i, name := range ss.pending {
+					names[i] = s.HeaderNormalizer(name)
+				}
+			}
+			headers, err := s.DuplicateHeaders.index(names)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			sec := &Section{Header: ss.pending, Headers: headers, r: ss}
+			ss.pending = nil
+			if !yield(sec, nil) {
+				return
+			}
+			if ss.err != nil { // already returned by Section.ReadRecord
+				return
+			}
+			for { // skip unread records
+				if _, err := sec.ReadRecord(); err == io.EOF {
+					break
+				} else if err != nil {
+					yield(nil, err)
+					return
+				}
+			}
+		}
+	}
+}
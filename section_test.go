@@ -0,0 +1,74 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+func TestSections(t *testing.T) {
+	r := DefaultReader(strings.NewReader("id,name\n1,a\n2,\"b\n\nc\"\n\n\ncode,price,qty\nx,1.5,3\n\nskipped\nnot read\n"))
+	var headers [][]string
+	var counts []int
+	for sec, err := range r.Sections() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		headers = append(headers, sec.Header)
+		n := 0
+		for n < 2 { // second record of the last section is never read
+			fields, err := sec.ReadRecord()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if len(fields) != len(sec.Header) {
+				t.Errorf("unexpected record %q in section %q", fields, sec.Header)
+			}
+			n++
+		}
+		counts = append(counts, n)
+	}
+	if want := [][]string{{"id", "name"}, {"code", "price", "qty"}, {"skipped"}}; !reflect.DeepEqual(headers, want) {
+		t.Errorf("got headers %q; want %q", headers, want)
+	}
+	if want := []int{2, 1, 1}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("got counts %v; want %v", counts, want)
+	}
+}
+
+func TestSectionsErrorsAndQuotedEmptyLine(t *testing.T) {
+	r := DefaultReader(strings.NewReader("id\n1\n\"\"\n2\n\ncode\n\"x\n"))
+	var ids []string
+	errors := 0
+	for sec, err := range r.Sections() {
+		if err != nil {
+			errors++
+			continue
+		}
+		for {
+			fields, err := sec.ReadRecord()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				errors++
+				break
+			}
+			ids = append(ids, string(fields[0]))
+		}
+	}
+	if want := []string{"1", "2"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("got %q; want %q", ids, want)
+	}
+	if errors != 1 {
+		t.Errorf("got %d errors; want 1", errors)
+	}
+}
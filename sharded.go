@@ -0,0 +1,268 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"container/list"
+	"encoding"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ShardedWriter writes records to a sequence of files (shards).
+// A new shard is started when MaxRows or MaxBytes is reached.
+// When Partition is specified, records are dispatched to one shard per distinct value
+// of the partition column (e.g. one file per date).
+// Shards are named after the path given to NewShardedWriter:
+// "out.csv" gives "out-001.csv", "out-002.csv"... or "out-<value>-001.csv" when partitioned.
+// Characters of the partition value that are not allowed in a file name are
+// percent-encoded (so that distinct values never share a shard).
+// At most MaxOpenFiles shards are kept open: the least recently used one is
+// closed and reopened in append mode when a record is dispatched to it again.
+type ShardedWriter struct {
+	path   string
+	sep    byte
+	quoted bool
+	shards map[string]*shard // current shards by partition value
+	lru    *list.List        // open shards, most recently used first
+	seqs   map[string]int    // last shard sequence by partition value
+	files  []string          // produced files
+	counts []int             // number of records of each produced file (set when closed)
+	err    error             // sticky error.
+
+	Headers   []string // written at the start of each shard (optional)
+	MaxRows   int      // maximum number of records (headers excluded) per shard (0 means no limit)
+	MaxBytes  int64    // approximate maximum size in bytes of a shard (0 means no limit)
+	Partition int      // index (first is 1) of the column used to partition records (0 means no partitioning)
+	UseCRLF   bool     // True to use \r\n as the line terminator
+
+	MaxOpenFiles int // maximum number of shards kept open at once (0 means DefaultMaxOpenFiles)
+}
+
+// DefaultMaxOpenFiles is the number of shards kept open when MaxOpenFiles is not set.
+const DefaultMaxOpenFiles = 64
+
+type shard struct {
+	name  string
+	f     *os.File // nil when closed by the LRU
+	c     *countingWriter
+	w     *Writer
+	rows  int
+	index int           // in files
+	elem  *list.Element // in lru
+}
+
+// size returns the number of bytes written to the shard (including buffered ones).
+func (s *shard) size() int64 {
+	return s.c.n + int64(s.w.b.Buffered())
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	return n, err
+}
+
+// NewShardedWriter returns a new ShardedWriter.
+// Shards are created in the directory of path.
+func NewShardedWriter(path string, sep byte, quoted bool) *ShardedWriter {
+	return &ShardedWriter{path: path, sep: sep, quoted: quoted, shards: make(map[string]*shard), lru: list.New(), seqs: make(map[string]int)}
+}
+
+// WriteRecord writes one record to the matching shard, rotating it when needed.
+// It's like Writer.WriteRecord.
+func (sw *ShardedWriter) WriteRecord(values ...interface{}) bool {
+	if sw.err != nil {
+		return false
+	}
+	var key string
+	if sw.Partition > 0 {
+		if sw.Partition > len(values) {
+			sw.setErr(fmt.Errorf("missing partition column %d in record with %d value(s)", sw.Partition, len(values)))
+			return false
+		}
+		key = partitionKey(values[sw.Partition-1])
+	}
+	s := sw.shards[key]
+	if s != nil && (sw.MaxRows > 0 && s.rows >= sw.MaxRows || sw.MaxBytes > 0 && s.size() >= sw.MaxBytes) {
+		delete(sw.shards, key)
+		if !sw.closeShard(s) {
+			return false
+		}
+		s = nil
+	}
+	if s == nil {
+		if s = sw.open(key); s == nil {
+			return false
+		}
+	} else if !sw.reopen(s) {
+		return false
+	}
+	if !s.w.WriteRecord(values...) {
+		sw.setErr(s.w.Err())
+		return false
+	}
+	s.rows++
+	return true
+}
+
+func (sw *ShardedWriter) open(key string) *shard {
+	sw.seqs[key]++
+	ext := filepath.Ext(sw.path)
+	name := strings.TrimSuffix(sw.path, ext)
+	if sw.Partition > 0 {
+		name += "-" + key
+	}
+	name = fmt.Sprintf("%s-%03d%s", name, sw.seqs[key], ext)
+	if !sw.evict() {
+		return nil
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		sw.setErr(err)
+		return nil
+	}
+	sw.files = append(sw.files, name)
+	sw.counts = append(sw.counts, 0)
+	c := &countingWriter{w: f}
+	s := &shard{name: name, f: f, c: c, w: NewWriter(c, sw.sep, sw.quoted), index: len(sw.files) - 1}
+	s.w.UseCRLF = sw.UseCRLF
+	s.elem = sw.lru.PushFront(s)
+	sw.shards[key] = s
+	if len(sw.Headers) > 0 {
+		for _, h := range sw.Headers {
+			s.w.WriteString(h)
+		}
+		s.w.EndOfRecord()
+		if err = s.w.Err(); err != nil {
+			sw.setErr(err)
+			return nil
+		}
+	}
+	return s
+}
+
+// reopen marks s as the most recently used shard, reopening its file if it has been closed by the LRU.
+func (sw *ShardedWriter) reopen(s *shard) bool {
+	if s.f != nil {
+		sw.lru.MoveToFront(s.elem)
+		return true
+	}
+	if !sw.evict() {
+		return false
+	}
+	f, err := os.OpenFile(s.name, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		sw.setErr(err)
+		return false
+	}
+	s.f = f
+	s.c.w = f
+	s.elem = sw.lru.PushFront(s)
+	return true
+}
+
+// evict closes the least recently used shards so that one more can be opened.
+func (sw *ShardedWriter) evict() bool {
+	max := sw.MaxOpenFiles
+	if max <= 0 {
+		max = DefaultMaxOpenFiles
+	}
+	for sw.lru.Len() >= max {
+		if !sw.release(sw.lru.Back().Value.(*shard)) {
+			return false
+		}
+	}
+	return true
+}
+
+// release flushes and closes the file of s, which can be reopened later.
+func (sw *ShardedWriter) release(s *shard) bool {
+	if s.f == nil {
+		return sw.err == nil
+	}
+	sw.lru.Remove(s.elem)
+	s.elem = nil
+	s.w.Flush()
+	sw.setErr(s.w.Err())
+	sw.setErr(s.f.Close())
+	s.f = nil
+	return sw.err == nil
+}
+
+func (sw *ShardedWriter) closeShard(s *shard) bool {
+	sw.counts[s.index] = s.rows
+	return sw.release(s)
+}
+
+// partitionKey converts a partition value to a string usable in a file name.
+// The conversion is injective: '%' and the characters not allowed in a file name are percent-encoded,
+// an empty value gives "_" and a "_" value gives "%5F".
+func partitionKey(value interface{}) string {
+	var key string
+	switch value := value.(type) {
+	case nil:
+	case string:
+		key = value
+	case []byte:
+		key = string(value)
+	case encoding.TextMarshaler:
+		if text, err := value.MarshalText(); err == nil {
+			key = string(text)
+		}
+	default:
+		key = fmt.Sprint(value)
+	}
+	switch key {
+	case "":
+		return "_"
+	case "_":
+		return "%5F"
+	}
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if c < ' ' || c == 0x7f || strings.IndexByte(`%/\:*?"<>|`, c) >= 0 {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// Files returns the names of the shards created so far.
+func (sw *ShardedWriter) Files() []string {
+	return sw.files
+}
+
+// Close flushes and closes all opened shards and returns the names of the produced files.
+func (sw *ShardedWriter) Close() ([]string, error) {
+	for key, s := range sw.shards {
+		delete(sw.shards, key)
+		sw.closeShard(s)
+	}
+	return sw.files, sw.err
+}
+
+// Err returns the first error that was encountered by the ShardedWriter.
+func (sw *ShardedWriter) Err() error {
+	return sw.err
+}
+
+// setErr records the first error encountered.
+func (sw *ShardedWriter) setErr(err error) {
+	if sw.err == nil {
+		sw.err = err
+	}
+}
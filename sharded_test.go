@@ -0,0 +1,153 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+var shardTests = []struct {
+	Name         string
+	MaxRows      int
+	Partition    int
+	MaxOpenFiles int
+	Input        [][]interface{}
+	Output       map[string]string
+}{
+	{
+		Name:    "MaxRows",
+		MaxRows: 2,
+		Input:   [][]interface{}{{1, "a"}, {2, "b"}, {3, "c"}},
+		Output: map[string]string{
+			"out-001.csv": "id,name\n1,a\n2,b\n",
+			"out-002.csv": "id,name\n3,c\n",
+		},
+	},
+	{
+		Name:      "Partition",
+		Partition: 2,
+		Input:     [][]interface{}{{1, "2016-09-08"}, {2, "2016/09/09"}, {3, "2016-09-08"}},
+		Output: map[string]string{
+			"out-2016-09-08-001.csv":     "id,name\n1,2016-09-08\n3,2016-09-08\n",
+			"out-2016%2F09%2F09-001.csv": "id,name\n2,2016/09/09\n",
+		},
+	},
+	{
+		Name:      "Collision",
+		Partition: 2,
+		Input:     [][]interface{}{{1, "2016/09/09"}, {2, "2016_09_09"}, {3, "_"}, {4, ""}, {5, "100%"}},
+		Output: map[string]string{
+			"out-2016%2F09%2F09-001.csv": "id,name\n1,2016/09/09\n",
+			"out-2016_09_09-001.csv":     "id,name\n2,2016_09_09\n",
+			"out-%5F-001.csv":            "id,name\n3,_\n",
+			"out-_-001.csv":              "id,name\n4,\n",
+			"out-100%25-001.csv":         "id,name\n5,100%\n",
+		},
+	},
+	{
+		Name:         "MaxOpenFiles",
+		Partition:    2,
+		MaxRows:      2,
+		MaxOpenFiles: 1,
+		Input:        [][]interface{}{{1, "a"}, {2, "b"}, {3, "a"}, {4, "b"}, {5, "a"}},
+		Output: map[string]string{
+			"out-a-001.csv": "id,name\n1,a\n3,a\n",
+			"out-a-002.csv": "id,name\n5,a\n",
+			"out-b-001.csv": "id,name\n2,b\n4,b\n",
+		},
+	},
+}
+
+func TestShardedWriter(t *testing.T) {
+	for _, tt := range shardTests {
+		dir := t.TempDir()
+		w := NewShardedWriter(filepath.Join(dir, "out.csv"), ',', true)
+		w.Headers = []string{"id", "name"}
+		w.MaxRows = tt.MaxRows
+		w.Partition = tt.Partition
+		w.MaxOpenFiles = tt.MaxOpenFiles
+		for _, values := range tt.Input {
+			if !w.WriteRecord(values...) {
+				break
+			}
+		}
+		files, err := w.Close()
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.Name, err)
+			continue
+		}
+		var names, want []string
+		for _, f := range files {
+			names = append(names, filepath.Base(f))
+			content, err := ioutil.ReadFile(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != tt.Output[filepath.Base(f)] {
+				t.Errorf("%s: %s: got %q; want %q", tt.Name, f, content, tt.Output[filepath.Base(f)])
+			}
+		}
+		for name := range tt.Output {
+			want = append(want, name)
+		}
+		sort.Strings(names)
+		sort.Strings(want)
+		if !reflect.DeepEqual(names, want) {
+			t.Errorf("%s: got files %v; want %v", tt.Name, names, want)
+		}
+	}
+}
+
+func TestShardedWriterCommit(t *testing.T) {
+	dir := t.TempDir()
+	w := NewShardedWriter(filepath.Join(dir, "out.csv"), ',', true)
+	w.Headers = []string{"id", "name"}
+	w.MaxRows = 2
+	for _, values := range [][]interface{}{{1, "a"}, {2, "b"}, {3, "c"}} {
+		w.WriteRecord(values...)
+	}
+	manifestPath := filepath.Join(dir, "manifest.json")
+	m, err := w.Commit(manifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Files) != 2 || m.Files[0].Name != "out-001.csv" || m.Files[0].Records != 2 || m.Files[1].Records != 1 || m.Files[1].Size != int64(len("id,name\n3,c\n")) {
+		t.Errorf("unexpected manifest: %+v", m)
+	}
+	read, err := ReadManifest(manifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, m) {
+		t.Errorf("got %+v; want %+v", read, m)
+	}
+	if err = read.Verify(dir); err != nil {
+		t.Error(err)
+	}
+	if paths := read.Paths(dir); len(paths) != 2 || paths[1] != filepath.Join(dir, "out-002.csv") {
+		t.Errorf("unexpected paths: %v", paths)
+	}
+	if err = ioutil.WriteFile(read.Paths(dir)[1], []byte("id,name\n3,d\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = read.Verify(dir); err == nil {
+		t.Error("checksum mismatch expected")
+	}
+
+	w = NewShardedWriter(filepath.Join(dir, "bad.csv"), ',', true)
+	w.Partition = 3
+	w.WriteRecord(1, "a")
+	if _, err = w.Commit(filepath.Join(dir, "bad.json")); err == nil {
+		t.Error("error expected")
+	} else if _, err = ReadManifest(filepath.Join(dir, "bad.json")); err == nil {
+		t.Error("manifest must not be published after an error")
+	}
+}
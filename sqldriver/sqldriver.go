@@ -0,0 +1,385 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package sqldriver is a read-only database/sql driver (named "yacr") exposing the CSV files of a directory as tables,
+// so that standard Go SQL tooling can query them (with the subset of SQL supported by yacr.Query):
+//
+//	db, err := sql.Open("yacr", "yacr:///path/to/dir")
+//	rows, err := db.Query("SELECT name FROM customers WHERE country = ?", "US")
+//
+// Each file named table.csv (or table.tsv, optionally compressed, see yacr.Zopen) is the table named table.
+// Its first record is the header and the column types (INTEGER, NUMBER, BOOLEAN or STRING)
+// are inferred from the first records (see yacr.InferSchema):
+// values are returned as int64, float64, bool, string or nil (empty values),
+// values not matching the inferred type of their column (beyond the first records) as strings.
+// Computed values are strings.
+// Placeholders (?) are replaced by the (quoted) arguments.
+package sqldriver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gwenn/yacr"
+)
+
+// InferenceSample is the number of records read to infer the column types of a table (all when <= 0).
+var InferenceSample = 1000
+
+// ErrReadOnly is the error returned for statements other than SELECT and transactions.
+var ErrReadOnly = errors.New("yacr: read-only driver")
+
+func init() {
+	sql.Register("yacr", Driver{})
+}
+
+// Driver implements database/sql/driver.Driver.
+type Driver struct{}
+
+// Open opens the directory named by dsn ("yacr://dir" or "dir").
+func (Driver) Open(dsn string) (driver.Conn, error) {
+	dir := strings.TrimPrefix(dsn, "yacr://")
+	if fi, err := os.Stat(dir); err != nil {
+		return nil, err
+	} else if !fi.IsDir() {
+		return nil, fmt.Errorf("yacr: not a directory: %s", dir)
+	}
+	return &conn{dir: dir, schemas: make(map[string]cachedSchema)}, nil
+}
+
+type conn struct {
+	dir string
+
+	mu      sync.Mutex
+	schemas map[string]cachedSchema // by path
+}
+
+type cachedSchema struct {
+	modTime time.Time
+	schema  yacr.Schema
+}
+
+// table is a CSV file of the directory.
+type table struct {
+	name, path string
+	d          yacr.Dialect
+}
+
+// tables lists the CSV files of the directory.
+func (c *conn) tables() ([]table, error) {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return nil, err
+	}
+	var tables []table
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		name := e.Name()
+		for _, ext := range []string{".gz", ".bz2"} {
+			name = strings.TrimSuffix(name, ext)
+		}
+		d := yacr.DialectDefault
+		switch filepath.Ext(name) {
+		case ".csv":
+		case ".tsv":
+			d.Sep = '\t'
+		default:
+			continue
+		}
+		tables = append(tables, table{strings.TrimSuffix(name, filepath.Ext(name)), filepath.Join(c.dir, e.Name()), d})
+	}
+	return tables, nil
+}
+
+func (t table) open() (*yacr.Reader, error) {
+	r, err := yacr.Open(t.path, t.d)
+	if err != nil {
+		return nil, err
+	}
+	if err = r.ScanHeaders(); err != nil {
+		r.Close()
+		return nil, fmt.Errorf("%s: %s", t.path, err)
+	}
+	return r, nil
+}
+
+// schema returns the (cached) inferred schema of the table.
+func (c *conn) schema(t table) (yacr.Schema, error) {
+	fi, err := os.Stat(t.path)
+	if err != nil {
+		return yacr.Schema{}, err
+	}
+	c.mu.Lock()
+	cached, ok := c.schemas[t.path]
+	c.mu.Unlock()
+	if ok && cached.modTime.Equal(fi.ModTime()) {
+		return cached.schema, nil
+	}
+	r, err := t.open()
+	if err != nil {
+		return yacr.Schema{}, err
+	}
+	defer r.Close()
+	schema, err := yacr.InferSchema(r, InferenceSample)
+	if err != nil {
+		return yacr.Schema{}, fmt.Errorf("%s: %s", t.path, err)
+	}
+	c.mu.Lock()
+	c.schemas[t.path] = cachedSchema{fi.ModTime(), schema}
+	c.mu.Unlock()
+	return schema, nil
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{c: c, query: query}, nil
+}
+
+func (c *conn) Close() error {
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return nil, ErrReadOnly
+}
+
+// query runs the query over the tables it references.
+func (c *conn) query(ctx context.Context, query string, args []driver.Value) (driver.Rows, error) {
+	query, err := bind(query, args)
+	if err != nil {
+		return nil, err
+	}
+	if fields := strings.Fields(query); len(fields) == 0 || !strings.EqualFold(fields[0], "SELECT") {
+		return nil, ErrReadOnly
+	}
+	names, err := yacr.QueryTables(query)
+	if err != nil {
+		return nil, err
+	}
+	tables, err := c.tables()
+	if err != nil {
+		return nil, err
+	}
+	rows := &rows{}
+	sources := make(map[string]*yacr.Reader)
+	headers := make(map[string][]string)
+	types := make(map[yacr.QueryColumn]yacr.FieldType)
+	for _, t := range tables {
+		if !contains(names, t.name) || sources[t.name] != nil {
+			continue
+		}
+		schema, err := c.schema(t)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		headers[t.name] = schema.Names()
+		for _, col := range schema.Columns {
+			types[yacr.QueryColumn{Table: t.name, Column: col.Name}] = col.Type
+		}
+		r, err := t.open()
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		rows.sources = append(rows.sources, r)
+		sources[t.name] = r
+	}
+	if rows.r, err = yacr.Query(query, sources); err != nil {
+		rows.Close()
+		return nil, err
+	}
+	if ctx.Done() != nil {
+		rows.stop = context.AfterFunc(ctx, func() { rows.r.Close() })
+	}
+	header, err := rows.r.ReadRecord()
+	if err != nil {
+		rows.Close()
+		return nil, err
+	}
+	origins, err := yacr.QueryColumns(query, headers)
+	if err != nil {
+		rows.Close()
+		return nil, err
+	}
+	for i, name := range header {
+		col := yacr.Column{Name: string(name), Type: yacr.StringType}
+		if i < len(origins) && types[origins[i]] != "" { // computed values are strings
+			col.Type = types[origins[i]]
+		}
+		rows.schema.Columns = append(rows.schema.Columns, col)
+	}
+	return rows, nil
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// bind replaces the placeholders (?) outside quotes by the quoted arguments.
+func bind(query string, args []driver.Value) (string, error) {
+	var b strings.Builder
+	var quote byte
+	n := 0
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '?':
+			if n >= len(args) {
+				return "", fmt.Errorf("yacr: missing argument for placeholder %d", n+1)
+			}
+			b.WriteString(literal(args[n]))
+			n++
+			continue
+		}
+		b.WriteByte(c)
+	}
+	if n != len(args) {
+		return "", fmt.Errorf("yacr: %d argument(s) for %d placeholder(s)", len(args), n)
+	}
+	return b.String(), nil
+}
+
+func literal(v driver.Value) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return "'" + v.Format(time.RFC3339Nano) + "'"
+	case []byte:
+		return "'" + strings.Replace(string(v), "'", "''", -1) + "'"
+	}
+	return "'" + strings.Replace(fmt.Sprint(v), "'", "''", -1) + "'"
+}
+
+type stmt struct {
+	c     *conn
+	query string
+}
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+// NumInput returns -1 as placeholders are checked when the query is run.
+func (s *stmt) NumInput() int {
+	return -1
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, ErrReadOnly
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.c.query(context.Background(), s.query, args)
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, fmt.Errorf("yacr: named arguments are not supported: %s", arg.Name)
+		}
+		values[i] = arg.Value
+	}
+	return s.c.query(ctx, s.query, values)
+}
+
+type rows struct {
+	sources []*yacr.Reader
+	r       *yacr.Reader // result
+	schema  yacr.Schema  // of the result
+	stop    func() bool  // cancels the context watch
+}
+
+func (r *rows) Columns() []string {
+	return r.schema.Names()
+}
+
+// ColumnTypeDatabaseTypeName returns the inferred type of the column (STRING for computed values).
+func (r *rows) ColumnTypeDatabaseTypeName(index int) string {
+	return strings.ToUpper(string(r.schema.Columns[index].Type))
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	fields, err := r.r.ReadRecord()
+	if err != nil {
+		return err
+	}
+	values, err := r.schema.Decode(fields)
+	if err != nil { // a value not matching the type inferred from the first records
+		values = r.decodeText(fields)
+	}
+	for i := range dest {
+		if i < len(values) {
+			dest[i] = values[i]
+		} else {
+			dest[i] = nil
+		}
+	}
+	return nil
+}
+
+// decodeText decodes the fields one by one, those not matching their column type being returned as strings.
+func (r *rows) decodeText(fields [][]byte) []interface{} {
+	values := make([]interface{}, len(fields))
+	for i, field := range fields {
+		values[i] = string(field)
+		if i < len(r.schema.Columns) {
+			column := yacr.Schema{Columns: r.schema.Columns[i : i+1]}
+			if v, err := column.Decode(fields[i : i+1]); err == nil {
+				values[i] = v[0]
+			}
+		}
+	}
+	return values
+}
+
+func (r *rows) Close() error {
+	if r.stop != nil {
+		r.stop()
+	}
+	var err error
+	if r.r != nil {
+		err = r.r.Close()
+	}
+	for _, source := range r.sources {
+		if cerr := source.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+var (
+	_ driver.StmtQueryContext               = (*stmt)(nil)
+	_ driver.RowsColumnTypeDatabaseTypeName = (*rows)(nil)
+)
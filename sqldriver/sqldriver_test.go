@@ -0,0 +1,130 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqldriver_test
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	. "github.com/gwenn/yacr/sqldriver"
+)
+
+func openDB(t *testing.T) *sql.DB {
+	return openFiles(t, map[string]string{
+		"orders.csv":    "id,customer,qty,price,paid\n1,10,2,5.5,true\n2,20,1,100,false\n3,10,4,30,\n",
+		"customers.tsv": "id\tname\tcountry\n10\tAlice\tUS\n20\tO'Brien\tIE\n",
+		"notes.txt":     "ignored",
+	})
+}
+
+func openFiles(t *testing.T, files map[string]string) *sql.DB {
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db, err := sql.Open("yacr", "yacr://"+dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestDriver(t *testing.T) {
+	db := openDB(t)
+	rows, err := db.Query("SELECT id, qty, price, paid, qty * price AS total FROM orders WHERE price > ? ORDER BY id", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, ct := range types {
+		names = append(names, ct.DatabaseTypeName())
+	}
+	if want := []string{"INTEGER", "INTEGER", "NUMBER", "BOOLEAN", "STRING"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got %v; want %v", names, want)
+	}
+	var got [][]interface{}
+	for rows.Next() {
+		values := make([]interface{}, 5)
+		pointers := make([]interface{}, 5)
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err = rows.Scan(pointers...); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, values)
+	}
+	if err = rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	want := [][]interface{}{
+		{int64(1), int64(2), 5.5, true, "11"},
+		{int64(2), int64(1), 100.0, false, "100"},
+		{int64(3), int64(4), 30.0, nil, "120"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v; want %v", got, want)
+	}
+
+	var name string
+	if err = db.QueryRow("SELECT name FROM orders JOIN customers c ON customer = c.id WHERE name = ?", "O'Brien").Scan(&name); err != nil {
+		t.Fatal(err)
+	} else if name != "O'Brien" {
+		t.Errorf("got %q; want %q", name, "O'Brien")
+	}
+	if err = db.QueryRow("SELECT name FROM customers WHERE name = ?", `'" || true || "`).Scan(&name); err != sql.ErrNoRows {
+		t.Errorf("got %v (%q); a bound value must not be evaluated as an expression", err, name)
+	}
+	if _, err = db.Exec("DELETE FROM orders"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("got %v; want ErrReadOnly", err)
+	}
+	if _, err = db.Query("SELECT * FROM notes"); err == nil {
+		t.Error("error expected for unknown table")
+	}
+	if _, err = db.Query("SELECT * FROM orders WHERE id = ?"); err == nil {
+		t.Error("error expected for missing argument")
+	}
+}
+
+func TestDriverInference(t *testing.T) {
+	defer func(n int) { InferenceSample = n }(InferenceSample)
+	InferenceSample = 2
+	db := openFiles(t, map[string]string{
+		"items.csv":  "id,qty\n1,2\n2,3\nx3,4\n",
+		"broken.csv": "\"id\n",
+	})
+	rows, err := db.Query("SELECT id, qty * 1.5 AS qty FROM items WHERE id <> 'broken'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got [][]interface{}
+	for rows.Next() {
+		var id, qty interface{}
+		if err = rows.Scan(&id, &qty); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, []interface{}{id, qty})
+	}
+	if err = rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	want := [][]interface{}{{int64(1), "3"}, {int64(2), "4.5"}, {"x3", "6"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v; want %v", got, want)
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// ColumnStats is the profile of a column computed in one pass (see StatsCollector):
+// distribution of the numeric values (estimated quantiles) and most common values (heavy hitters).
+type ColumnStats struct {
+	Column   int     // index of the column (first is 1)
+	Count    int     // number of values (records without the column are ignored)
+	Empty    int     // number of empty (or blank) values
+	Numbers  int     // number of numeric values
+	Min, Max float64 // smallest and greatest numeric values
+	Sum      float64 // sum of the numeric values
+
+	digest tDigest
+	values *valueCounter
+}
+
+func (s *ColumnStats) add(value []byte) {
+	s.Count++
+	value = bytes.TrimSpace(value)
+	if len(value) == 0 {
+		s.Empty++
+	} else if f, err := strconv.ParseFloat(string(value), 64); err == nil && !math.IsNaN(f) {
+		if s.Numbers == 0 || f < s.Min {
+			s.Min = f
+		}
+		if s.Numbers == 0 || f > s.Max {
+			s.Max = f
+		}
+		s.Numbers++
+		s.Sum += f
+		s.digest.add(f)
+	}
+	if s.values != nil {
+		s.values.add(value)
+	}
+}
+
+// Mean returns the average of the numeric values (NaN when there is none).
+func (s *ColumnStats) Mean() float64 {
+	if s.Numbers == 0 {
+		return math.NaN()
+	}
+	return s.Sum / float64(s.Numbers)
+}
+
+// Quantile returns an estimation of the quantile q (between 0 and 1) of the numeric values
+// (NaN when there is none): Quantile(0.5) is the median, Quantile(0.95) the 95th percentile.
+// Estimations are more accurate near the extremes (t-digest).
+func (s *ColumnStats) Quantile(q float64) float64 {
+	if s.Numbers == 0 {
+		return math.NaN()
+	} else if q <= 0 {
+		return s.Min
+	} else if q >= 1 {
+		return s.Max
+	}
+	return s.digest.quantile(q, s.Min, s.Max)
+}
+
+// Median returns an estimation of the median of the numeric values (NaN when there is none).
+func (s *ColumnStats) Median() float64 {
+	return s.Quantile(0.5)
+}
+
+// TopValues returns the most common values (trimmed) by decreasing count (see ValueCounts).
+func (s *ColumnStats) TopValues() []ValueCount {
+	if s.values == nil {
+		return nil
+	}
+	return s.values.counts()
+}
+
+// StatsCollector profiles the selected columns of the records it sees, in bounded memory.
+// It can be plugged in Reader.Transformers (or Writer.Transformers) to profile records
+// while they are processed, without a second pass.
+type StatsCollector struct {
+	Columns []*ColumnStats
+}
+
+// NewStatsCollector returns a collector of the stats of the columns (first is 1)
+// with their topN most common values (none when topN <= 0).
+func NewStatsCollector(topN int, columns ...int) (*StatsCollector, error) {
+	c := &StatsCollector{Columns: make([]*ColumnStats, len(columns))}
+	for i, col := range columns {
+		if col < 1 {
+			return nil, fmt.Errorf("invalid column index: %d", col)
+		}
+		c.Columns[i] = &ColumnStats{Column: col}
+		if topN > 0 {
+			c.Columns[i].values = newValueCounter(topN)
+		}
+	}
+	return c, nil
+}
+
+// Add updates the stats with the record fields.
+func (c *StatsCollector) Add(fields [][]byte) {
+	for _, s := range c.Columns {
+		if s.Column <= len(fields) {
+			s.add(fields[s.Column-1])
+		}
+	}
+}
+
+// Transform updates the stats with the record fields and returns them unchanged.
+// Implements the RecordTransformer interface.
+func (c *StatsCollector) Transform(fields [][]byte) ([][]byte, error) {
+	c.Add(fields)
+	return fields, nil
+}
+
+// CollectStats reads all remaining records of r and returns the stats of the columns (first is 1)
+// with their topN most common values (see NewStatsCollector).
+// Headers should be loaded before (see ScanHeaders).
+func CollectStats(r *Reader, topN int, columns ...int) ([]*ColumnStats, error) {
+	c, err := NewStatsCollector(topN, columns...)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		fields, err := r.ReadRecord()
+		if err == io.EOF {
+			return c.Columns, nil
+		} else if err != nil {
+			return nil, err
+		}
+		c.Add(fields)
+	}
+}
+
+const (
+	digestCompression = 100 // the greater, the more accurate and the more centroids
+	digestBuffer      = 500 // number of values buffered before being merged
+)
+
+// tDigest estimates quantiles in bounded memory by clustering values in centroids,
+// smaller near the extremes (merging t-digest of Dunning).
+type tDigest struct {
+	centroids []centroid // sorted by mean
+	buf       []centroid // values not yet merged
+	n         float64    // number of values (buffered included)
+}
+
+type centroid struct {
+	mean, count float64
+}
+
+func (d *tDigest) add(x float64) {
+	d.buf = append(d.buf, centroid{x, 1})
+	d.n++
+	if len(d.buf) >= digestBuffer {
+		d.merge()
+	}
+}
+
+// merge merges the buffered values with the centroids.
+func (d *tDigest) merge() {
+	if len(d.buf) == 0 {
+		return
+	}
+	all := append(d.buf, d.centroids...)
+	sort.Slice(all, func(i, j int) bool { return all[i].mean < all[j].mean })
+	merged := make([]centroid, 0, len(d.centroids)+1)
+	cur := all[0]
+	var soFar float64 // number of values before cur
+	for _, c := range all[1:] {
+		q := (soFar + (cur.count+c.count)/2) / d.n
+		if cur.count+c.count <= 4*d.n*q*(1-q)/digestCompression {
+			cur.count += c.count
+			cur.mean += (c.mean - cur.mean) * c.count / cur.count
+			continue
+		}
+		merged = append(merged, cur)
+		soFar += cur.count
+		cur = c
+	}
+	d.centroids = append(merged, cur)
+	d.buf = all[:0]
+}
+
+// quantile interpolates the quantile q between the centroids (and the extreme values min and max).
+func (d *tDigest) quantile(q, min, max float64) float64 {
+	d.merge()
+	target := q * d.n
+	prevMean, prevPos := min, 0.0 // mean and position (number of values before its center) of the previous centroid
+	var soFar float64
+	for _, c := range d.centroids {
+		pos := soFar + c.count/2
+		if target < pos {
+			if pos == prevPos {
+				return c.mean
+			}
+			return prevMean + (c.mean-prevMean)*(target-prevPos)/(pos-prevPos)
+		}
+		prevMean, prevPos = c.mean, pos
+		soFar += c.count
+	}
+	if d.n == prevPos {
+		return max
+	}
+	return prevMean + (max-prevMean)*(target-prevPos)/(d.n-prevPos)
+}
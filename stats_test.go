@@ -0,0 +1,77 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+func TestCollectStats(t *testing.T) {
+	r := DefaultReader(strings.NewReader("id,amount,color\n1,10,red\n2,,blue\n3,30,red\n4\n5,n/a,green\n6,20,red\n"))
+	if err := r.ScanHeaders(); err != nil {
+		t.Fatal(err)
+	}
+	stats, err := CollectStats(r, 2, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	amount, color := stats[0], stats[1]
+	if amount.Count != 5 || amount.Empty != 1 || amount.Numbers != 3 || amount.Min != 10 || amount.Max != 30 {
+		t.Errorf("unexpected stats: %+v", *amount)
+	}
+	if amount.Mean() != 20 || amount.Median() != 20 {
+		t.Errorf("got mean %g and median %g; want 20", amount.Mean(), amount.Median())
+	}
+	if !math.IsNaN(color.Median()) {
+		t.Errorf("got median %g; want NaN", color.Median())
+	}
+	expected := []ValueCount{{"red", 3}, {"blue", 1}}
+	if counts := color.TopValues(); !reflect.DeepEqual(expected, counts) {
+		t.Errorf("got %v; want %v", counts, expected)
+	}
+
+	if _, err = NewStatsCollector(1, 0); err == nil {
+		t.Error("error expected")
+	}
+}
+
+func TestStatsQuantiles(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	var b strings.Builder
+	for _, i := range rnd.Perm(100000) {
+		fmt.Fprintf(&b, "%d\n", i)
+	}
+	c, err := NewStatsCollector(0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := DefaultReader(strings.NewReader(b.String()))
+	r.Transformers = append(r.Transformers, c)
+	for {
+		if _, err = r.ReadRecord(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := c.Columns[0]
+	for _, q := range []float64{0, 0.01, 0.5, 0.95, 0.999, 1} {
+		expected := q * 99999
+		if got := s.Quantile(q); math.Abs(got-expected) > 0.005*100000 {
+			t.Errorf("quantile %g: got %g; want %g", q, got, expected)
+		}
+	}
+	if s.TopValues() != nil {
+		t.Errorf("no top values expected")
+	}
+}
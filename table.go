@@ -0,0 +1,102 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// TableOptions controls the rendering of ToTable.
+type TableOptions struct {
+	Header   bool   // True to underline the first record
+	Gap      string // columns separator (default is two spaces)
+	MaxWidth int    // maximum width (in runes) of a column, longer values are truncated with an ellipsis (0 means no limit)
+	MaxRows  int    // maximum number of rendered records, header excluded (0 means no limit)
+}
+
+// ToTable renders records from r as a fixed-width aligned text table (like `column -t`).
+// Records are loaded in memory to compute columns width (use MaxRows to bound it).
+// Values with embedded newlines are rendered on multiple lines within their column.
+func ToTable(r *Reader, w io.Writer, opts TableOptions) error {
+	if opts.Gap == "" {
+		opts.Gap = "  "
+	}
+	var rows [][][]string // lines of each cell of each record
+	var widths []int
+	max := opts.MaxRows
+	if max > 0 && opts.Header {
+		max++
+	}
+	for max <= 0 || len(rows) < max {
+		fields, err := r.ReadRecord()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		row := make([][]string, len(fields))
+		for i, field := range fields {
+			lines := strings.Split(strings.Replace(string(field), "\r\n", "\n", -1), "\n")
+			for j, line := range lines {
+				lines[j] = ellipsis(line, opts.MaxWidth)
+				if i >= len(widths) {
+					widths = append(widths, 0)
+				}
+				if n := utf8.RuneCountInString(lines[j]); n > widths[i] {
+					widths[i] = n
+				}
+			}
+			row[i] = lines
+		}
+		rows = append(rows, row)
+	}
+	b := bufio.NewWriter(w)
+	for n, row := range rows {
+		height := 1
+		for _, lines := range row {
+			if len(lines) > height {
+				height = len(lines)
+			}
+		}
+		for l := 0; l < height; l++ {
+			var line strings.Builder
+			for i, lines := range row {
+				if i > 0 {
+					line.WriteString(opts.Gap)
+				}
+				var s string
+				if l < len(lines) {
+					s = lines[l]
+				}
+				line.WriteString(s)
+				line.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(s)))
+			}
+			b.WriteString(strings.TrimRight(line.String(), " "))
+			b.WriteByte('\n')
+		}
+		if n == 0 && opts.Header {
+			for i, width := range widths {
+				if i > 0 {
+					b.WriteString(opts.Gap)
+				}
+				b.WriteString(strings.Repeat("-", width))
+			}
+			b.WriteByte('\n')
+		}
+	}
+	return b.Flush()
+}
+
+// ellipsis truncates s to max runes (0 means no limit).
+func ellipsis(s string, max int) string {
+	if max <= 0 || utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:max-1]) + "…"
+}
@@ -0,0 +1,49 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+var tableTests = []struct {
+	Name   string
+	Input  string
+	Opts   TableOptions
+	Output string
+}{
+	{
+		Name:  "Header",
+		Input: "id,name,city\n1,\"two\nlines\",Paris\n22,é,\n",
+		Opts:  TableOptions{Header: true},
+		Output: `id  name   city
+--  -----  -----
+1   two    Paris
+    lines
+22  é
+`,
+	},
+	{
+		Name:   "Truncated",
+		Input:  "1,abcdefgh\n2,ab\n3,c\n",
+		Opts:   TableOptions{MaxWidth: 4, MaxRows: 2, Gap: " | "},
+		Output: "1 | abc…\n2 | ab\n",
+	},
+}
+
+func TestToTable(t *testing.T) {
+	for _, tt := range tableTests {
+		b := &bytes.Buffer{}
+		if err := ToTable(DefaultReader(strings.NewReader(tt.Input)), b, tt.Opts); err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.Name, err)
+		} else if b.String() != tt.Output {
+			t.Errorf("%s: got %q; want %q", tt.Name, b.String(), tt.Output)
+		}
+	}
+}
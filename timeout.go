@@ -0,0 +1,130 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// ErrTimeout is the error returned by TimeoutReader when a read does not complete in time.
+var ErrTimeout = errors.New("yacr: read timeout")
+
+// TimeoutReader bounds the duration of each read of a slow source (network stream...),
+// so that a stalled upstream connection surfaces as ErrTimeout (through Reader.Err)
+// instead of hanging the ingestion forever. Reads are also interrupted when the context is done.
+// Sources with a SetReadDeadline method (like net.Conn) are interrupted by their deadline;
+// otherwise each read runs in a goroutine that is abandoned after a timeout
+// (its result is returned by the next read, the source should be closed to release it).
+// A TimeoutReader can be the source of a ResilientReader, timeouts being retried like other io errors.
+type TimeoutReader struct {
+	Timeout time.Duration // maximum duration of each read (0 means no limit)
+
+	ctx     context.Context
+	r       io.Reader
+	pending chan readResult // result of the read in progress (goroutine mode)
+	buf     []byte          // buffer of the read in progress
+	rest    []byte          // data read but not yet returned
+	err     error           // error returned with the last data
+}
+
+type readResult struct {
+	n   int
+	err error
+}
+
+// deadliner is implemented by sources supporting read deadlines (like net.Conn).
+type deadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// NewTimeoutReader returns a reader of r whose reads fail with ErrTimeout after timeout.
+func NewTimeoutReader(ctx context.Context, r io.Reader, timeout time.Duration) *TimeoutReader {
+	return &TimeoutReader{Timeout: timeout, ctx: ctx, r: r}
+}
+
+// Read reads from the source, waiting at most Timeout.
+func (t *TimeoutReader) Read(p []byte) (int, error) {
+	if len(t.rest) > 0 {
+		n := copy(p, t.rest)
+		t.rest = t.rest[n:]
+		if len(t.rest) > 0 {
+			return n, nil
+		}
+		err := t.err
+		t.err = nil
+		return n, err
+	} else if err := t.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if d, ok := t.r.(deadliner); ok && t.pending == nil {
+		return t.readDeadline(d, p)
+	}
+	if t.pending == nil {
+		if cap(t.buf) < len(p) {
+			t.buf = make([]byte, len(p))
+		}
+		buf := t.buf[:len(p)]
+		t.pending = make(chan readResult, 1)
+		go func(pending chan<- readResult) {
+			n, err := t.r.Read(buf)
+			pending <- readResult{n, err}
+		}(t.pending)
+	}
+	var timeout <-chan time.Time
+	if t.Timeout > 0 {
+		timer := time.NewTimer(t.Timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case res := <-t.pending:
+		t.pending = nil
+		n := copy(p, t.buf[:res.n])
+		if n < res.n { // smaller buffer than the one of the abandoned read
+			t.rest, t.err = t.buf[n:res.n], res.err
+			return n, nil
+		}
+		return n, res.err
+	case <-timeout:
+		return 0, ErrTimeout
+	case <-t.ctx.Done():
+		return 0, t.ctx.Err()
+	}
+}
+
+// readDeadline reads from a source supporting read deadlines.
+func (t *TimeoutReader) readDeadline(d deadliner, p []byte) (int, error) {
+	var deadline time.Time
+	if t.Timeout > 0 {
+		deadline = time.Now().Add(t.Timeout)
+	}
+	if err := d.SetReadDeadline(deadline); err != nil {
+		return 0, err
+	}
+	stop := context.AfterFunc(t.ctx, func() {
+		_ = d.SetReadDeadline(time.Unix(1, 0)) // interrupts the pending read
+	})
+	defer stop()
+	n, err := t.r.Read(p)
+	if err != nil && errors.Is(err, os.ErrDeadlineExceeded) {
+		if cerr := t.ctx.Err(); cerr != nil {
+			return n, cerr
+		}
+		return n, ErrTimeout
+	}
+	return n, err
+}
+
+// Close closes the source (when it is an io.Closer).
+func (t *TimeoutReader) Close() error {
+	if c, ok := t.r.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/gwenn/yacr"
+)
+
+func TestTimeoutReader(t *testing.T) {
+	for _, tt := range []struct {
+		Name string
+		Pipe func() (io.ReadCloser, io.WriteCloser)
+	}{
+		{"Goroutine", func() (io.ReadCloser, io.WriteCloser) { return io.Pipe() }},
+		{"Deadline", func() (io.ReadCloser, io.WriteCloser) { return net.Pipe() }},
+	} {
+		pr, pw := tt.Pipe()
+		go func() {
+			pw.Write([]byte("a,b\n"))
+			time.Sleep(200 * time.Millisecond) // stalled upstream
+			pw.Write([]byte("c,d\n"))
+			pw.Close()
+		}()
+		tr := NewTimeoutReader(context.Background(), pr, 20*time.Millisecond)
+		r := DefaultReader(tr)
+		if fields, err := r.ReadRecord(); err != nil || string(fields[1]) != "b" {
+			t.Fatalf("%s: got %q, %v", tt.Name, fields, err)
+		}
+		if _, err := r.ReadRecord(); !errors.Is(err, ErrTimeout) {
+			t.Errorf("%s: got %v; want %v", tt.Name, err, ErrTimeout)
+		}
+		tr.Close()
+	}
+}
+
+func TestTimeoutReaderContext(t *testing.T) {
+	pr, pw := net.Pipe()
+	defer pw.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	tr := NewTimeoutReader(ctx, pr, 0)
+	defer tr.Close()
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if _, err := tr.Read(make([]byte, 10)); err != context.Canceled {
+		t.Errorf("got %v; want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"bufio"
+	"fmt"
+)
+
+// Logger is the interface used to trace the Reader scanner (see Reader.Trace), implemented by *log.Logger.
+// Each step of the scanner is logged with its line and byte offset: fields (with their quoting and terminator),
+// quote entries and exits, comments, blank lines, skipped bytes, buffer refills and errors,
+// so that "why did this parse this way" questions can be answered without patching the library:
+//
+//	r.Trace = log.New(os.Stderr, "yacr: ", 0)
+//
+// Tracing is slow: it is meant for debugging only.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// trace logs the step of the scanner which started at lineno (at the start of a record or not),
+// when n bytes were buffered: a bytes consumed, token returned or err reported.
+func (s *Reader) trace(startOfRecord bool, lineno, a int, token []byte, err error, n int, atEOF bool) {
+	prefix := fmt.Sprintf("line %d, offset %d: ", lineno, s.offset)
+	switch {
+	case err == bufio.ErrFinalToken:
+		s.Trace.Printf("%sstop (blank line or footer marker)", prefix)
+	case err != nil:
+		s.Trace.Printf("%serror: %v", prefix, err)
+	case a == 0 && token == nil && !atEOF:
+		if s.quotedTok {
+			s.Trace.Printf("%sinside quoted value, buffer refill requested (%d bytes buffered)", prefix, n)
+		} else {
+			s.Trace.Printf("%sbuffer refill requested (%d bytes buffered)", prefix, n)
+		}
+	case a == 0 && token == nil:
+		s.Trace.Printf("%send of input", prefix)
+	case token == nil:
+		s.Trace.Printf("%sskipped %d bytes (comment or filtered record)", prefix, a)
+	case s.comment:
+		s.Trace.Printf("%scomment %q", prefix, token)
+	case s.blank:
+		s.Trace.Printf("%sblank line", prefix)
+	default:
+		var start, quoting, end string
+		if startOfRecord {
+			start = "start of record, "
+		}
+		if s.quotedTok {
+			quoting = fmt.Sprintf("quoted field %q (quote entered at line %d, exited at line %d)", token, lineno, s.recordLine())
+		} else {
+			quoting = fmt.Sprintf("unquoted field %q", token)
+		}
+		if s.eor {
+			end = "end of record"
+		} else {
+			end = "separator"
+		}
+		s.Trace.Printf("%s%s%s followed by %s (%d bytes consumed)", prefix, start, quoting, end, a)
+	}
+}
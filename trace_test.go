@@ -0,0 +1,47 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+type traceLogger []string
+
+func (l *traceLogger) Printf(format string, v ...interface{}) {
+	*l = append(*l, fmt.Sprintf(format, v...))
+}
+
+func TestTrace(t *testing.T) {
+	r := DefaultReader(strings.NewReader("#x\na,\"b\nc\"\n"))
+	r.Comment = '#'
+	r.Buffer(make([]byte, 4), 64)
+	var logger traceLogger
+	r.Trace = &logger
+	for {
+		if _, err := r.ReadRecord(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+	trace := strings.Join(logger, "\n")
+	for _, expected := range []string{
+		"line 1, offset 0: skipped 3 bytes (comment or filtered record)",
+		"line 2, offset 3: start of record, unquoted field \"a\" followed by separator (2 bytes consumed)",
+		"line 2, offset 5: inside quoted value, buffer refill requested",
+		"line 2, offset 5: quoted field \"b\\nc\" (quote entered at line 2, exited at line 3) followed by end of record (6 bytes consumed)",
+		"line 4, offset 11: end of input",
+	} {
+		if !strings.Contains(trace, expected) {
+			t.Errorf("%q not found in trace:\n%s", expected, trace)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"strconv"
+)
+
+// TrailerMarker is the first field of a trailer record:
+//
+//	TRAILER,<record count>,<byte count>,<algorithm>:<hex checksum>
+//
+// Counts and checksum cover everything written before the trailer record
+// (the "sep=" line, see Writer.WriteSepHint, is not counted as a record but its bytes are).
+const TrailerMarker = "TRAILER"
+
+// trailerHashes are the supported checksum algorithms.
+var trailerHashes = map[string]func() hash.Hash{
+	"crc32":  func() hash.Hash { return crc32.NewIEEE() },
+	"sha256": sha256.New,
+}
+
+type trailer struct {
+	algo    string
+	h       hash.Hash
+	records int64
+	bytes   int64
+	prefix  []byte // TrailerMarker followed by the separator (Reader only)
+}
+
+func newTrailer(algo string) (*trailer, error) {
+	newHash, ok := trailerHashes[algo]
+	if !ok {
+		return nil, fmt.Errorf("unsupported checksum algorithm: %q", algo)
+	}
+	return &trailer{algo: algo, h: newHash()}, nil
+}
+
+func (t *trailer) checksum() string {
+	return t.algo + ":" + hex.EncodeToString(t.h.Sum(nil))
+}
+
+// consume accounts for content preceding the trailer record.
+func (t *trailer) consume(b []byte, eor bool) {
+	t.h.Write(b)
+	t.bytes += int64(len(b))
+	if eor {
+		t.records++
+	}
+}
+
+// trailerWriter accounts for all bytes flushed by the Writer.
+type trailerWriter struct {
+	w io.Writer
+	t *trailer
+}
+
+func (tw trailerWriter) Write(b []byte) (int, error) {
+	n, err := tw.w.Write(b)
+	tw.t.consume(b[:n], false)
+	return n, err
+}
+
+// EnableTrailer makes the Writer count records and bytes and compute a checksum ("crc32" or "sha256")
+// to be written in a trailer record by WriteTrailer.
+// It must be called before anything is written.
+func (w *Writer) EnableTrailer(algo string) error {
+	if w.b.Buffered() > 0 {
+		return errors.New("yacr.Writer: trailer must be enabled before writing")
+	}
+	t, err := newTrailer(algo)
+	if err != nil {
+		return err
+	}
+	w.trailer = t
+	w.b.Reset(trailerWriter{w.w, t})
+	return nil
+}
+
+// WriteTrailer terminates the output with the trailer record (see TrailerMarker) and flushes the writer.
+func (w *Writer) WriteTrailer() bool {
+	if w.trailer == nil {
+		w.setErr(errors.New("yacr.Writer: trailer not enabled"))
+		return false
+	}
+	if !w.sor {
+		w.EndOfRecord()
+	}
+	w.Flush()
+	t := w.trailer
+	w.trailer = nil
+	w.WriteString(TrailerMarker)
+	w.WriteString(strconv.FormatInt(t.records, 10))
+	w.WriteString(strconv.FormatInt(t.bytes, 10))
+	w.WriteString(t.checksum())
+	w.EndOfRecord()
+	w.Flush()
+	return w.err == nil
+}
+
+// VerifyTrailer makes the Reader expect a trailer record (see TrailerMarker) at the end of the input:
+// the trailer record is not returned but verified against the content read,
+// and an error is reported when it is missing or when counts or checksum ("crc32" or "sha256") do not match.
+// It must be called before Scan.
+func (s *Reader) VerifyTrailer(algo string) error {
+	t, err := newTrailer(algo)
+	if err != nil {
+		return err
+	}
+	t.prefix = append([]byte(TrailerMarker), s.sep)
+	s.trailer = t
+	return nil
+}
+
+// scanTrailer detects and verifies the trailer record at the start of a record.
+// Returns more when more data is needed to decide,
+// or an error when the scanning must stop (bufio.ErrFinalToken when the trailer is valid).
+func (s *Reader) scanTrailer(data []byte, atEOF bool) (more bool, err error) {
+	t := s.trailer
+	if atEOF && len(data) == 0 {
+		return false, fmt.Errorf("missing trailer record at line %d", s.lineno)
+	}
+	t.prefix[len(t.prefix)-1] = s.sep // may have been guessed
+	if len(data) < len(t.prefix) && bytes.HasPrefix(t.prefix, data) && !atEOF {
+		return true, nil
+	} else if !bytes.HasPrefix(data, t.prefix) {
+		return false, nil
+	}
+	i := bytes.IndexByte(data, '\n')
+	if i < 0 {
+		if !atEOF {
+			return true, nil
+		}
+		i = len(data)
+	}
+	fields := bytes.Split(bytes.TrimSuffix(data[len(t.prefix):i], []byte{'\r'}), []byte{s.sep})
+	if len(fields) != 3 {
+		return false, fmt.Errorf("invalid trailer record at line %d", s.lineno)
+	}
+	if records, err := strconv.ParseInt(string(fields[0]), 10, 64); err != nil || records != t.records {
+		return false, fmt.Errorf("trailer mismatch at line %d: %d record(s) read; trailer says %s", s.lineno, t.records, fields[0])
+	}
+	if n, err := strconv.ParseInt(string(fields[1]), 10, 64); err != nil || n != t.bytes {
+		return false, fmt.Errorf("trailer mismatch at line %d: %d byte(s) read; trailer says %s", s.lineno, t.bytes, fields[1])
+	}
+	if checksum := t.checksum(); string(fields[2]) != checksum {
+		return false, fmt.Errorf("trailer mismatch at line %d: checksum is %s; trailer says %s", s.lineno, checksum, fields[2])
+	}
+	return false, bufio.ErrFinalToken
+}
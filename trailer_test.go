@@ -0,0 +1,91 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+func readAll(r *Reader) (int, error) {
+	n := 0
+	for {
+		_, err := r.ReadRecord()
+		if err == io.EOF {
+			return n, nil
+		} else if err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+func TestTrailer(t *testing.T) {
+	for _, algo := range []string{"crc32", "sha256"} {
+		b := &bytes.Buffer{}
+		w := DefaultWriter(b)
+		if err := w.EnableTrailer(algo); err != nil {
+			t.Fatal(err)
+		}
+		writeRow(w, []string{"a", "b,c"})
+		writeRow(w, []string{"d\ne", "f"})
+		if !w.WriteTrailer() {
+			t.Fatal(w.Err())
+		}
+		content := b.String()
+		if !strings.Contains(content, "\nTRAILER,2,16,"+algo+":") {
+			t.Errorf("unexpected trailer: %q", content)
+		}
+
+		r := DefaultReader(strings.NewReader(content))
+		r.VerifyTrailer(algo)
+		if n, err := readAll(r); err != nil || n != 2 {
+			t.Errorf("%s: got %d record(s), error: %v", algo, n, err)
+		}
+
+		for _, input := range []string{
+			strings.Replace(content, "b,c", "b;c", 1),   // corrupted
+			content[strings.Index(content, "\n")+1:],    // missing record
+			content[:strings.Index(content, "TRAILER")], // missing trailer
+		} {
+			r = DefaultReader(strings.NewReader(input))
+			r.VerifyTrailer(algo)
+			if _, err := readAll(r); err == nil {
+				t.Errorf("%s: error expected for %q", algo, input)
+			}
+		}
+	}
+}
+
+func TestTrailerSepHintAndGrep(t *testing.T) {
+	b := &bytes.Buffer{}
+	w := NewWriter(b, ';', true)
+	if err := w.EnableTrailer("crc32"); err != nil {
+		t.Fatal(err)
+	}
+	w.WriteSepHint()
+	writeRow(w, []string{"a", "1"})
+	writeRow(w, []string{"b", "2"})
+	if !w.WriteTrailer() {
+		t.Fatal(w.Err())
+	}
+	if content := b.String(); !strings.Contains(content, "\nTRAILER;2;") {
+		t.Fatalf("unexpected trailer: %q", content)
+	}
+	r := NewReader(strings.NewReader(b.String()), ';', true, false)
+	r.DetectSepHint = true
+	r.Grep(regexp.MustCompile("^b"))
+	if err := r.VerifyTrailer("crc32"); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := readAll(r); err != nil || n != 1 {
+		t.Errorf("got %d record(s), error: %v; want 1", n, err)
+	}
+}
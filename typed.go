@@ -0,0 +1,173 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"io"
+	"reflect"
+)
+
+// RecordUnmarshaler is the interface implemented by (pointers to) structs decoding themselves from a whole record
+// without reflection, like the code generated by the yacr-gen command. It is used by TypedReader.
+// Records are passed as returned by ReadRecord: the decoding settings of the Reader applied by ScanStruct
+// (NullValues, ColumnNullValues, UseDefaults, Coercions, MissingFields and ExtraFields) are not,
+// so UnmarshalCSVRecord is responsible for them.
+type RecordUnmarshaler interface {
+	// CSVColumns returns the names of the columns bound to the struct fields (in field order).
+	CSVColumns() []string
+	// UnmarshalCSVRecord decodes the fields of a record, cols being the index (first is 0) of the field
+	// of each column returned by CSVColumns (-1 when the column is missing).
+	// Decoding errors should be reported as FieldError.
+	UnmarshalCSVRecord(fields [][]byte, cols []int) error
+}
+
+// RecordMarshaler is the interface implemented by (pointers to) structs encoding themselves as a whole record
+// without reflection, like the code generated by the yacr-gen command. It is used by TypedWriter.
+type RecordMarshaler interface {
+	// CSVColumns returns the names of the columns written by MarshalCSVRecord (the header).
+	CSVColumns() []string
+	// MarshalCSVRecord writes the fields of one record (EndOfRecord included).
+	MarshalCSVRecord(w *Writer) error
+}
+
+// ForEach decodes each remaining record of r into a T (a struct or a pointer to a struct, see ScanStruct)
+// and calls fn with it, until the end of the input or the first error (returned, io.EOF excepted).
+// When Headers are not loaded, the first record is loaded as the header (see ScanHeaders),
+// so that fields are bound to columns by name (once for all records).
+// A new T is decoded for each record: it can be retained by fn.
+func ForEach[T any](r *Reader, fn func(T) error) error {
+	if r.Headers == nil {
+		if err := r.ScanHeaders(); err != nil {
+			return err
+		}
+	}
+	for {
+		var v T
+		if err := r.ScanStruct(structTarget(&v)); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+}
+
+// TypedReader decodes records into values of type T (a struct or a pointer to a struct, see Reader.ScanStruct).
+// The first record is the header: fields are bound to columns by name.
+// The reflection plan of T (fields and binding) is computed once and cached.
+// The embedded Reader can be configured before the first Read.
+type TypedReader[T any] struct {
+	*Reader
+
+	bound bool  // true once the columns are bound
+	cols  []int // index of the field of each column when T implements RecordUnmarshaler (nil otherwise)
+}
+
+// NewTypedReader returns a reader of the records of r (configured with the dialect d) as values of type T.
+// When (a pointer to) T implements RecordUnmarshaler, records are decoded by its method instead of reflection:
+// in this case, the Reader settings specific to ScanStruct (NullValues, UseDefaults, Coercions,
+// MissingFields, ExtraFields...) are ignored (see RecordUnmarshaler).
+func NewTypedReader[T any](r io.Reader, d Dialect) *TypedReader[T] {
+	return &TypedReader[T]{Reader: d.NewReader(r)}
+}
+
+// Read decodes the next record (a new T is returned for each record).
+// Returns io.EOF when there is no more record.
+func (tr *TypedReader[T]) Read() (T, error) {
+	var v T
+	if !tr.bound {
+		if tr.Headers == nil {
+			if err := tr.ScanHeaders(); err != nil {
+				return v, err
+			}
+		}
+		if u, ok := structTarget(&v).(RecordUnmarshaler); ok {
+			tr.cols = bindColumns(tr.Reader, u.CSVColumns())
+		}
+		tr.bound = true
+	}
+	if tr.cols == nil {
+		err := tr.ScanStruct(structTarget(&v))
+		return v, err
+	}
+	fields, err := tr.ReadRecord()
+	if err != nil {
+		return v, err
+	}
+	if err = structTarget(&v).(RecordUnmarshaler).UnmarshalCSVRecord(fields, tr.cols); err != nil {
+		if ferr, ok := err.(*FieldError); ok && ferr.Line == 0 {
+			ferr.Line = tr.recordLine()
+		}
+	}
+	return v, err
+}
+
+// bindColumns returns the index (first is 0) of each named column (-1 when missing).
+func bindColumns(r *Reader, names []string) []int {
+	cols := make([]int, len(names))
+	for i, name := range names {
+		index, ok := r.HeaderIndex(name)
+		if !ok {
+			index = 0
+		}
+		cols[i] = index - 1
+	}
+	return cols
+}
+
+// TypedWriter encodes values of type T (a struct or a pointer to a struct, see Writer.WriteStruct)
+// as records, preceded by the header (see Writer.WriteStructHeader).
+// The embedded Writer must be flushed (or closed) once done.
+type TypedWriter[T any] struct {
+	*Writer
+	header bool // true once the header is written
+}
+
+// NewTypedWriter returns a writer of values of type T to w (configured with the dialect d).
+func NewTypedWriter[T any](w io.Writer, d Dialect) *TypedWriter[T] {
+	return &TypedWriter[T]{Writer: d.NewWriter(w)}
+}
+
+// Write writes v as one record (the header is written before the first one).
+// When (a pointer to) T implements RecordMarshaler, v is encoded by its method instead of reflection.
+func (tw *TypedWriter[T]) Write(v T) error {
+	var target interface{} = &v // addressable for pointer methods
+	if rv := reflect.ValueOf(&v).Elem(); rv.Kind() == reflect.Ptr {
+		target = v
+	}
+	m, fast := target.(RecordMarshaler)
+	if !tw.header {
+		if fast {
+			for _, name := range m.CSVColumns() {
+				tw.WriteString(name)
+			}
+			tw.EndOfRecord()
+		} else {
+			var zero T
+			tw.WriteStructHeader(structTarget(&zero))
+		}
+		tw.header = true
+	}
+	if fast {
+		if err := m.MarshalCSVRecord(tw.Writer); err != nil {
+			tw.setErr(err)
+		}
+	} else {
+		tw.WriteStruct(target)
+	}
+	return tw.Err()
+}
+
+// structTarget returns the pointer to the struct to be decoded into *v:
+// v itself or, when *v is a pointer, a newly allocated struct referenced by *v.
+func structTarget[T any](v *T) interface{} {
+	if rv := reflect.ValueOf(v).Elem(); rv.Kind() == reflect.Ptr {
+		rv.Set(reflect.New(rv.Type().Elem()))
+		return rv.Interface()
+	}
+	return v
+}
@@ -0,0 +1,61 @@
+// Code generated by yacr-gen; DO NOT EDIT.
+
+package yacr_test
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gwenn/yacr"
+)
+
+// CSVColumns implements yacr.RecordUnmarshaler and yacr.RecordMarshaler.
+func (*item) CSVColumns() []string {
+	return []string{"id", "name", "price", "tags"}
+}
+
+// UnmarshalCSVRecord implements yacr.RecordUnmarshaler.
+func (v *item) UnmarshalCSVRecord(fields [][]byte, cols []int) error {
+	if i := cols[0]; i >= 0 && i < len(fields) {
+		field := fields[i]
+		n, err := strconv.ParseInt(string(field), 10, 0)
+		if err != nil {
+			return &yacr.FieldError{Column: i + 1, Name: "id", Err: err}
+		}
+		v.ID = int(n)
+	}
+	if i := cols[1]; i >= 0 && i < len(fields) {
+		field := fields[i]
+		v.Name = string(field)
+	}
+	{
+		i, field := cols[2], []byte("0")
+		if i >= 0 && i < len(fields) && len(fields[i]) > 0 {
+			field = fields[i]
+		}
+		n, err := strconv.ParseFloat(string(field), 64)
+		if err != nil {
+			return &yacr.FieldError{Column: i + 1, Name: "price", Err: err}
+		}
+		v.Price = n
+	}
+	if i := cols[3]; i >= 0 && i < len(fields) {
+		field := fields[i]
+		if len(field) == 0 {
+			v.Tags = []string{}
+		} else {
+			v.Tags = strings.Split(string(field), "|")
+		}
+	}
+	return nil
+}
+
+// MarshalCSVRecord implements yacr.RecordMarshaler.
+func (v *item) MarshalCSVRecord(w *yacr.Writer) error {
+	w.WriteString(strconv.FormatInt(int64(v.ID), 10))
+	w.WriteString(v.Name)
+	w.WriteString(strconv.FormatFloat(v.Price, 'f', -1, 64))
+	w.WriteString(strings.Join(v.Tags, "|"))
+	w.EndOfRecord()
+	return w.Err()
+}
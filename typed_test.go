@@ -0,0 +1,118 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/gwenn/yacr"
+)
+
+func TestForEach(t *testing.T) {
+	input := "name,id,tags\nshirt,1,a|b\nhat,2,\n"
+	var products []product
+	err := ForEach(DefaultReader(strings.NewReader(input)), func(p product) error {
+		products = append(products, p)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []product{{ID: 1, Name: "shirt", Tags: []string{"a", "b"}}, {ID: 2, Name: "hat", Tags: []string{}}}
+	if !reflect.DeepEqual(products, want) {
+		t.Errorf("got %+v; want %+v", products, want)
+	}
+
+	var pointers []*product
+	stop := errors.New("stop")
+	err = ForEach(DefaultReader(strings.NewReader(input)), func(p *product) error {
+		pointers = append(pointers, p)
+		return stop
+	})
+	if err != stop {
+		t.Errorf("got %v; want %v", err, stop)
+	}
+	if len(pointers) != 1 || !reflect.DeepEqual(*pointers[0], want[0]) {
+		t.Errorf("got %+v; want %+v", pointers, want[:1])
+	}
+
+	err = ForEach(DefaultReader(strings.NewReader("id\nx\n")), func(p product) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("error expected: %v", err)
+	}
+}
+
+func TestTypedReaderWriter(t *testing.T) {
+	invoices := []invoice{
+		{Amount: 1205, Notes: note{"first", "second"}, Due: time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC)},
+		{Amount: 7, Notes: note{"x"}, Due: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	var b bytes.Buffer
+	tw := NewTypedWriter[invoice](&b, DialectExcelSemicolon)
+	for _, inv := range invoices {
+		if err := tw.Write(inv); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw.Flush()
+	if expected := "\uFEFFamount;notes;due\r\n12.05;\"first,\nsecond\";2021-03-04T00:00:00Z\r\n0.07;x;2022-01-01T00:00:00Z\r\n"; b.String() != expected {
+		t.Errorf("got %q; want %q", b.String(), expected)
+	}
+
+	tr := NewTypedReader[*invoice](strings.NewReader(strings.TrimPrefix(b.String(), "\uFEFF")), DialectExcelSemicolon)
+	var decoded []invoice
+	for {
+		inv, err := tr.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		decoded = append(decoded, *inv)
+	}
+	if !reflect.DeepEqual(decoded, invoices) {
+		t.Errorf("got %+v; want %+v", decoded, invoices)
+	}
+}
+
+// item decodes and encodes itself (see typed_gen_test.go, generated by yacr-gen).
+type item struct {
+	ID    int      `yacr:"id"`
+	Name  string   `yacr:"name"`
+	Price float64  `yacr:"price,default=0"`
+	Tags  []string `yacr:"tags"`
+}
+
+func TestTypedRecordCodec(t *testing.T) {
+	tr := NewTypedReader[item](strings.NewReader("name,id,extra,tags\nhat,2,x,a|b\nshirt,x,,\n"), DialectDefault)
+	p, err := tr.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (item{ID: 2, Name: "hat", Tags: []string{"a", "b"}}); !reflect.DeepEqual(p, expected) {
+		t.Errorf("got %+v; want %+v", p, expected)
+	}
+	_, err = tr.Read()
+	var ferr *FieldError
+	if !errors.As(err, &ferr) || ferr.Line != 3 || ferr.Column != 2 || ferr.Name != "id" {
+		t.Errorf("got error %v; want invalid id at line 3, column 2", err)
+	}
+
+	var b bytes.Buffer
+	tw := NewTypedWriter[*item](&b, DialectDefault)
+	if err = tw.Write(&item{ID: 1, Name: "shirt", Price: 9.5, Tags: []string{"c"}}); err != nil {
+		t.Fatal(err)
+	}
+	tw.Flush()
+	if expected := "id,name,price,tags\n1,shirt,9.5,c\n"; b.String() != expected {
+		t.Errorf("got %q; want %q", b.String(), expected)
+	}
+}
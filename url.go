@@ -0,0 +1,150 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// URLOptions configures OpenURL.
+type URLOptions struct {
+	Client      *http.Client  // http.DefaultClient when nil
+	MaxRetries  int           // maximum number of consecutive attempts to resume after a transient failure
+	RetryDelay  time.Duration // delay before the first retry (doubled after each consecutive failure)
+	ReadTimeout time.Duration // maximum duration of each read of the response body (0 means no limit, see TimeoutReader)
+}
+
+// ErrResourceChanged is the error returned when a remote resource has been modified
+// while being read, so that the download cannot be resumed.
+var ErrResourceChanged = errors.New("yacr: remote resource changed")
+
+// OpenURL streams the CSV resource at url.
+// On transient failures (network errors, read timeouts, truncated bodies, 5xx or 429 responses), the download is resumed
+// where it stopped with a Range request (validated by the ETag or Last-Modified date of the resource)
+// up to opts.MaxRetries times.
+// A gzip Content-Encoding is decoded.
+// The response body is closed by the Reader's Close method.
+func OpenURL(ctx context.Context, url string, d Dialect, opts *URLOptions) (*Reader, error) {
+	if opts == nil {
+		opts = &URLOptions{}
+	}
+	h := &httpSource{ctx: ctx, url: url, opts: opts}
+	rr := NewResilientReader(ctx, h.open)
+	rr.MaxRetries = opts.MaxRetries
+	rr.RetryDelay = opts.RetryDelay
+	if err := rr.reopen(); err != nil {
+		return nil, err
+	}
+	var rd io.Reader = rr
+	var s *Reader
+	if h.gzipped {
+		zr, err := gzip.NewReader(rr)
+		if err != nil {
+			_ = rr.Close()
+			return nil, err
+		}
+		rd = zr
+		s = d.NewReader(rd)
+		s.OnClose(rr)
+		s.OnClose(zr)
+	} else {
+		s = d.NewReader(rd)
+		s.OnClose(rr)
+	}
+	s.Source = url
+	return s, nil
+}
+
+// httpSource opens an HTTP resource at an offset.
+type httpSource struct {
+	ctx          context.Context
+	url          string
+	opts         *URLOptions
+	etag         string // validator of the resource (first response)
+	lastModified string
+	gzipped      bool
+}
+
+func (h *httpSource) open(offset int64) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(h.ctx, http.MethodGet, h.url, nil)
+	if err != nil {
+		return nil, Permanent(err)
+	}
+	req.Header.Set("Accept-Encoding", "gzip") // ranges apply to the encoded content
+	if offset > 0 {
+		validator := h.etag
+		if validator == "" {
+			validator = h.lastModified
+		}
+		if validator == "" {
+			return nil, Permanent(fmt.Errorf("%s: cannot resume download without ETag nor Last-Modified", h.url))
+		}
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+		req.Header.Set("If-Range", validator)
+	}
+	client := h.opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case offset == 0 && resp.StatusCode == http.StatusOK:
+		h.etag = resp.Header.Get("ETag")
+		h.lastModified = resp.Header.Get("Last-Modified")
+		h.gzipped = resp.Header.Get("Content-Encoding") == "gzip"
+		return h.body(resp), nil
+	case offset > 0 && resp.StatusCode == http.StatusPartialContent:
+		if etag := resp.Header.Get("ETag"); etag != "" && h.etag != "" && etag != h.etag {
+			_ = resp.Body.Close()
+			return nil, Permanent(ErrResourceChanged)
+		}
+		if start, ok := rangeStart(resp.Header.Get("Content-Range")); !ok || start != offset { // range ignored or misapplied
+			_ = resp.Body.Close()
+			return nil, Permanent(ErrResourceChanged)
+		}
+		return h.body(resp), nil
+	case offset > 0 && resp.StatusCode == http.StatusOK: // If-Range not satisfied
+		_ = resp.Body.Close()
+		return nil, Permanent(ErrResourceChanged)
+	}
+	_ = resp.Body.Close()
+	err = fmt.Errorf("%s: unexpected HTTP status: %s", h.url, resp.Status)
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+		return nil, err
+	}
+	return nil, Permanent(err)
+}
+
+// rangeStart returns the first byte position of a Content-Range header like "bytes 100-199/200".
+func rangeStart(contentRange string) (int64, bool) {
+	if !strings.HasPrefix(contentRange, "bytes ") {
+		return 0, false
+	}
+	i := strings.IndexByte(contentRange, '-')
+	if i < 0 {
+		return 0, false
+	}
+	start, err := strconv.ParseInt(contentRange[len("bytes "):i], 10, 64)
+	return start, err == nil && start >= 0
+}
+
+// body returns the response body, with a read timeout when specified.
+func (h *httpSource) body(resp *http.Response) io.ReadCloser {
+	if h.opts.ReadTimeout <= 0 {
+		return resp.Body
+	}
+	return NewTimeoutReader(h.ctx, resp.Body, h.opts.ReadTimeout)
+}
@@ -0,0 +1,179 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/gwenn/yacr"
+)
+
+// truncatingWriter aborts the response after limit bytes.
+type truncatingWriter struct {
+	http.ResponseWriter
+	limit int
+}
+
+func (w *truncatingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.limit {
+		n, _ := w.ResponseWriter.Write(p[:w.limit])
+		w.ResponseWriter.(http.Flusher).Flush()
+		w.limit -= n
+		panic(http.ErrAbortHandler)
+	}
+	w.limit -= len(p)
+	return w.ResponseWriter.Write(p)
+}
+
+// flakyServer serves content, truncating the first failures responses after limit bytes.
+func flakyServer(content []byte, encoding string, failures int32, limit int) (*httptest.Server, *int32) {
+	var requests int32
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		w.Header().Set("ETag", `"v1"`)
+		if encoding != "" {
+			w.Header().Set("Content-Encoding", encoding)
+		}
+		if n <= failures {
+			w = &truncatingWriter{w, limit}
+		}
+		http.ServeContent(w, req, "data.csv", time.Time{}, bytes.NewReader(content))
+	})), &requests
+}
+
+func readURL(t *testing.T, url string, opts *URLOptions) ([][]string, error) {
+	r, err := OpenURL(context.Background(), url, DialectDefault, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var records [][]string
+	for {
+		fields, err := r.ReadRecord()
+		if err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return records, err
+		}
+		records = append(records, toStrings(fields))
+	}
+}
+
+func TestOpenURL(t *testing.T) {
+	var content bytes.Buffer
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&content, "%d,\"value %d\"\n", i, i)
+	}
+	var gzipped bytes.Buffer
+	zw := gzip.NewWriter(&gzipped)
+	zw.Write(content.Bytes())
+	zw.Close()
+
+	for _, tt := range []struct {
+		Name     string
+		Content  []byte
+		Encoding string
+	}{
+		{"Plain", content.Bytes(), ""},
+		{"Gzip", gzipped.Bytes(), "gzip"},
+	} {
+		ts, requests := flakyServer(tt.Content, tt.Encoding, 2, 1000)
+		records, err := readURL(t, ts.URL, &URLOptions{MaxRetries: 2, RetryDelay: time.Millisecond})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.Name, err)
+		}
+		if len(records) != 1000 || records[999][1] != "value 999" {
+			t.Errorf("%s: got %d records", tt.Name, len(records))
+		}
+		if n := atomic.LoadInt32(requests); n != 3 {
+			t.Errorf("%s: got %d requests; want 3", tt.Name, n)
+		}
+		ts.Close()
+	}
+
+	ts, _ := flakyServer(content.Bytes(), "", 3, 0)
+	if _, err := readURL(t, ts.URL, &URLOptions{MaxRetries: 2}); err == nil {
+		t.Error("error expected when retries are exhausted")
+	}
+	ts.Close()
+
+	ts = httptest.NewServer(http.NotFoundHandler())
+	if _, err := readURL(t, ts.URL, nil); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("got %v; want 404 error", err)
+	}
+	ts.Close()
+}
+
+func TestOpenURLChanged(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		w.Header().Set("ETag", fmt.Sprintf(`"v%d"`, n))
+		content := strings.Repeat("a,b\n", 1000)
+		if n == 1 {
+			w = &truncatingWriter{w, 100}
+		}
+		http.ServeContent(w, req, "data.csv", time.Time{}, strings.NewReader(content))
+	}))
+	defer ts.Close()
+	if _, err := readURL(t, ts.URL, &URLOptions{MaxRetries: 1}); !errors.Is(err, ErrResourceChanged) {
+		t.Errorf("got %v; want %v", err, ErrResourceChanged)
+	}
+}
+
+func TestOpenURLMisappliedRange(t *testing.T) {
+	var requests int32
+	content := strings.Repeat("a,b\n", 1000)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if atomic.AddInt32(&requests, 1) == 1 {
+			http.ServeContent(&truncatingWriter{w, 100}, req, "data.csv", time.Time{}, strings.NewReader(content))
+			return
+		}
+		// the requested range is ignored: the content is resent from the start
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-%d/%d", len(content)-1, len(content)))
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte(content))
+	}))
+	defer ts.Close()
+	if _, err := readURL(t, ts.URL, &URLOptions{MaxRetries: 1}); !errors.Is(err, ErrResourceChanged) {
+		t.Errorf("got %v; want %v", err, ErrResourceChanged)
+	}
+}
+
+func TestOpenURLStalled(t *testing.T) {
+	var requests int32
+	content := strings.Repeat("a,b\n", 1000)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if atomic.AddInt32(&requests, 1) == 1 { // stalls after the first bytes
+			w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+			w.Write([]byte(content[:100]))
+			w.(http.Flusher).Flush()
+			<-req.Context().Done()
+			return
+		}
+		http.ServeContent(w, req, "data.csv", time.Time{}, strings.NewReader(content))
+	}))
+	defer ts.Close()
+	records, err := readURL(t, ts.URL, &URLOptions{MaxRetries: 1, ReadTimeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1000 {
+		t.Errorf("got %d records; want 1000", len(records))
+	}
+}
@@ -19,6 +19,7 @@ type zReadCloser struct {
 }
 
 // Zopen transparently opens gzip/bzip files (based on their extension).
+// Other files are returned as is (so that they are closed only once).
 func Zopen(filepath string) (io.ReadCloser, error) {
 	f, err := os.Open(filepath)
 	if err != nil {
@@ -36,7 +37,7 @@ func Zopen(filepath string) (io.ReadCloser, error) {
 	} else if ext == ".bz2" {
 		rd = ioutil.NopCloser(bzip2.NewReader(f))
 	} else {
-		rd = f
+		return f, nil
 	}
 	return &zReadCloser{f, rd}, nil
 }
@@ -0,0 +1,42 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+func TestZopen(t *testing.T) {
+	dir := t.TempDir()
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte("a,b\n"))
+	zw.Close()
+	files := map[string][]byte{"plain.csv": []byte("a,b\n"), "data.csv.gz": gz.Bytes()}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		rc, err := Zopen(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data, err := ioutil.ReadAll(rc); err != nil {
+			t.Fatal(err)
+		} else if string(data) != "a,b\n" {
+			t.Errorf("%s: got %q; want %q", name, data, "a,b\n")
+		}
+		if err = rc.Close(); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Notifier is the interface implemented by file system watchers (see the fsnotify build tag).
+// Watch sends something on the returned channel each time the content of dir may have changed,
+// until ctx is done.
+type Notifier interface {
+	Watch(ctx context.Context, dir string) (<-chan struct{}, error)
+}
+
+// PollNotifier is a Notifier which simply ticks at a regular interval.
+type PollNotifier time.Duration
+
+// Watch implements Notifier.
+func (p PollNotifier) Watch(ctx context.Context, dir string) (<-chan struct{}, error) {
+	c := make(chan struct{})
+	go func() {
+		defer close(c)
+		t := time.NewTicker(time.Duration(p))
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				select {
+				case c <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return c, nil
+}
+
+// WatchOptions controls WatchDir.
+type WatchOptions struct {
+	Dialect  Dialect  // used to parse all files
+	DoneDir  string   // where successfully handled files are moved (default is dir/done)
+	ErrorDir string   // where rejected files are moved, along with a ".error" file describing the error (default is dir/error)
+	Notifier Notifier // default is PollNotifier(time.Second)
+}
+
+// WatchDir handles the files matching glob dropped in dir until ctx is done.
+// Each file (compressed or not, see Zopen) is parsed with the shared dialect and passed to handler,
+// then moved to the done folder, or to the error folder when handler returns an error.
+// Hidden files (starting with a dot) are ignored: producers should write files under a hidden or
+// non-matching name and rename them when complete (see WriteFileAtomic).
+// Files already present when WatchDir is called are handled first.
+func WatchDir(ctx context.Context, dir, glob string, opts WatchOptions, handler func(path string, r *Reader) error) error {
+	if opts.DoneDir == "" {
+		opts.DoneDir = filepath.Join(dir, "done")
+	}
+	if opts.ErrorDir == "" {
+		opts.ErrorDir = filepath.Join(dir, "error")
+	}
+	if opts.Notifier == nil {
+		opts.Notifier = PollNotifier(time.Second)
+	}
+	for _, d := range []string{opts.DoneDir, opts.ErrorDir} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			return err
+		}
+	}
+	events, err := opts.Notifier.Watch(ctx, dir)
+	if err != nil {
+		return err
+	}
+	for {
+		if err = ingestDir(dir, glob, opts, handler); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case _, ok := <-events:
+			if !ok {
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+func ingestDir(dir, glob string, opts WatchOptions, handler func(path string, r *Reader) error) error {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, fi := range fis {
+		if !fi.Mode().IsRegular() || strings.HasPrefix(fi.Name(), ".") {
+			continue
+		}
+		if ok, err := filepath.Match(glob, fi.Name()); err != nil {
+			return err
+		} else if ok {
+			names = append(names, fi.Name())
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		herr := ingestFile(path, opts.Dialect, handler)
+		if herr == nil {
+			if err = os.Rename(path, filepath.Join(opts.DoneDir, name)); err != nil {
+				return err
+			}
+			continue
+		}
+		if err = os.Rename(path, filepath.Join(opts.ErrorDir, name)); err != nil {
+			return err
+		}
+		if err = ioutil.WriteFile(filepath.Join(opts.ErrorDir, name+".error"), []byte(herr.Error()+"\n"), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ingestFile(path string, d Dialect, handler func(path string, r *Reader) error) error {
+	f, err := Zopen(path)
+	if err != nil {
+		return err
+	}
+	err = handler(path, d.NewReader(f))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
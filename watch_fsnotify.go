@@ -0,0 +1,57 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build fsnotify
+// +build fsnotify
+
+package yacr
+
+import (
+	"context"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// FSNotifier is a Notifier based on fsnotify (only available with the fsnotify build tag).
+type FSNotifier struct{}
+
+// Watch implements Notifier.
+func (FSNotifier) Watch(ctx context.Context, dir string) (<-chan struct{}, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err = w.Add(dir); err != nil {
+		_ = w.Close()
+		return nil, err
+	}
+	c := make(chan struct{})
+	go func() {
+		defer close(c)
+		defer w.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if ev.Op&(fsnotify.Create|fsnotify.Rename) == 0 {
+					continue
+				}
+			case _, ok := <-w.Errors: // events may have been lost
+				if !ok {
+					return
+				}
+			}
+			select {
+			case c <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return c, nil
+}
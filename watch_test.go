@@ -0,0 +1,55 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/gwenn/yacr"
+)
+
+func TestWatchDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "a.csv"), []byte("1;2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "c.txt"), []byte("ignored\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		ioutil.WriteFile(filepath.Join(dir, "b.csv"), []byte("x\n"), 0644)
+	}()
+	count := 0
+	err := WatchDir(ctx, dir, "*.csv", WatchOptions{Dialect: Dialect{Sep: ';'}, Notifier: PollNotifier(5 * time.Millisecond)}, func(path string, r *Reader) error {
+		count++
+		if count == 2 {
+			defer cancel()
+		}
+		fields, err := r.ReadRecord()
+		if err != nil {
+			return err
+		} else if len(fields) != 2 {
+			return errors.New("two fields expected")
+		}
+		return nil
+	})
+	if err != context.Canceled {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, path := range []string{"done/a.csv", "error/b.csv", "error/b.csv.error", "c.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, path)); err != nil {
+			t.Error(err)
+		}
+	}
+}
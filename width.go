@@ -0,0 +1,99 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// WidthPolicy specifies how WidthEnforcer handles values longer than the maximum width of their column.
+type WidthPolicy int
+
+// Width policies
+const (
+	RejectWidth       WidthPolicy = iota // report an ErrTooLong error (default)
+	TruncateWidth                        // keep the first bytes (a multi-byte character may be split) and flag the column (see Truncated)
+	TruncateRuneWidth                    // keep the first bytes without splitting a character and flag the column (see Truncated)
+)
+
+// ErrTooLong is the error reported by WidthEnforcer when a value is longer than the maximum width of its column.
+var ErrTooLong = errors.New("value too long")
+
+// widthError details ErrTooLong.
+type widthError struct {
+	name   string
+	length int
+	width  int
+}
+
+func (e *widthError) Error() string {
+	return fmt.Sprintf("%s: %d bytes in column %s (max %d)", ErrTooLong, e.length, e.name, e.width)
+}
+
+func (e *widthError) Is(target error) bool {
+	return target == ErrTooLong
+}
+
+// WidthEnforcer bounds the length (in bytes) of the values of columns selected by name,
+// so that over-length values are detected while streaming instead of failing late when loaded in a database
+// with fixed-size columns (VARCHAR(n), ...).
+type WidthEnforcer struct {
+	Policy WidthPolicy
+
+	names     []string // by column index
+	widths    []int    // by column index (0 when unbounded)
+	truncated []int
+	rw        recordRewriter
+}
+
+// NewWidthEnforcer binds maximum widths (by column name) to the columns described by headers (see Reader.Headers).
+func NewWidthEnforcer(headers map[string]int, widths map[string]int, policy WidthPolicy) (*WidthEnforcer, error) {
+	e := &WidthEnforcer{Policy: policy}
+	for name, width := range widths {
+		index, ok := headers[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown field name: %s", name)
+		} else if width <= 0 {
+			return nil, fmt.Errorf("invalid width for field %s: %d", name, width)
+		}
+		for len(e.widths) < index {
+			e.widths = append(e.widths, 0)
+			e.names = append(e.names, "")
+		}
+		e.widths[index-1] = width
+		e.names[index-1] = name
+	}
+	return e, nil
+}
+
+// Transform returns the record with over-length values truncated or an error, depending on the Policy.
+// The returned fields may be overwritten by a subsequent call.
+func (e *WidthEnforcer) Transform(fields [][]byte) ([][]byte, error) {
+	e.truncated = e.truncated[:0]
+	return e.rw.rewrite(fields, func(i int, dst, field []byte) ([]byte, bool, error) {
+		if i >= len(e.widths) || e.widths[i] == 0 || len(field) <= e.widths[i] {
+			return dst, false, nil
+		}
+		width := e.widths[i]
+		switch e.Policy {
+		case TruncateWidth:
+		case TruncateRuneWidth:
+			for width > 0 && !utf8.RuneStart(field[width]) {
+				width--
+			}
+		default:
+			return dst, false, &widthError{e.names[i], len(field), width}
+		}
+		e.truncated = append(e.truncated, i+1)
+		return append(dst, field[:width]...), true, nil
+	})
+}
+
+// Truncated returns the indexes (first is 1) of the columns truncated in the last transformed record.
+func (e *WidthEnforcer) Truncated() []int {
+	return e.truncated
+}
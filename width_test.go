@@ -0,0 +1,64 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacr_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/gwenn/yacr"
+)
+
+var widthTests = []struct {
+	Name      string
+	Policy    WidthPolicy
+	Input     string
+	Output    []string
+	Truncated []int
+	Error     string
+}{
+	{Name: "Fit", Input: "abc,12", Output: []string{"abc", "12"}},
+	{Name: "Reject", Input: "abcd,12", Error: "value too long: 4 bytes in column code (max 3)"},
+	{Name: "Truncate", Policy: TruncateWidth, Input: "abcd,12345", Output: []string{"abc", "12345"}, Truncated: []int{1}},
+	{Name: "TruncateBytes", Policy: TruncateWidth, Input: "ab\u00e9,1", Output: []string{"ab\xc3", "1"}, Truncated: []int{1}},
+	{Name: "TruncateRune", Policy: TruncateRuneWidth, Input: "ab\u00e9,1", Output: []string{"ab", "1"}, Truncated: []int{1}},
+	{Name: "TruncateRuneFit", Policy: TruncateRuneWidth, Input: "a\u00e9d,1", Output: []string{"a\u00e9", "1"}, Truncated: []int{1}},
+}
+
+func TestWidthEnforcer(t *testing.T) {
+	headers := map[string]int{"code": 1, "qty": 2}
+	for _, tt := range widthTests {
+		e, err := NewWidthEnforcer(headers, map[string]int{"code": 3}, tt.Policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := DefaultReader(strings.NewReader(tt.Input))
+		fields, err := r.ReadRecord()
+		if err != nil {
+			t.Fatal(err)
+		}
+		fields, err = e.Transform(fields)
+		if tt.Error != "" {
+			if !errors.Is(err, ErrTooLong) || err.Error() != tt.Error {
+				t.Errorf("%s: got %v; want %q", tt.Name, err, tt.Error)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.Name, err)
+			continue
+		}
+		if values := toStrings(fields); !reflect.DeepEqual(values, tt.Output) {
+			t.Errorf("%s: got %q; want %q", tt.Name, values, tt.Output)
+		}
+		if truncated := e.Truncated(); len(truncated) != len(tt.Truncated) || len(truncated) > 0 && !reflect.DeepEqual(truncated, tt.Truncated) {
+			t.Errorf("%s: got %v truncated; want %v", tt.Name, truncated, tt.Truncated)
+		}
+	}
+	if _, err := NewWidthEnforcer(headers, map[string]int{"unknown": 3}, RejectWidth); err == nil {
+		t.Error("error expected for unknown column")
+	}
+}
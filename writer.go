@@ -12,6 +12,7 @@ import (
 	"io"
 	"reflect"
 	"strconv"
+	"time"
 	"unsafe"
 )
 
@@ -20,6 +21,7 @@ import (
 // Successive calls to the Write method will automatically insert the separator.
 // The EndOfRecord method tells when a line break is inserted.
 type Writer struct {
+	w      io.Writer // underlying writer
 	b      *bufio.Writer
 	sep    byte                 // values separator
 	quoted bool                 // specify if values should be quoted (when they contain a separator, a double-quote or a newline)
@@ -27,8 +29,34 @@ type Writer struct {
 	err    error                // sticky error.
 	bs     []byte               // byte slice used to write string with minimal/no alloc/copy
 	hb     *reflect.SliceHeader // header of bs
+	nb     []byte               // buffer used to normalize newlines
+	col    int                  // index (first is 0) of the next value in the current record
+	pb     []byte               // buffer used to protect text (see TextProtection)
+	fb     []byte               // buffer used to format values (see RecordBuilder)
+	nrec   int                  // records written since the last flush (see FlushRecords)
 
-	UseCRLF bool // True to use \r\n as the line terminator
+	trailer *trailer    // trailer record to be written (see EnableTrailer)
+	closers []io.Closer // resources closed by Close (see OnClose)
+
+	UseCRLF           bool // True to use \r\n as the line terminator
+	Escape            byte // when specified (not 0, typically '\\'), values are never quoted: the separator, newline and escape characters are escaped by this character (DSV style)
+	NormalizeNewlines bool // True to convert \r\n, \n and bare \r in (quoted) values to the line terminator (see UseCRLF)
+	SanitizeFormulas  bool // True to prefix values starting with '=', '+', '-', '@', tab or carriage return (except numbers) with a single quote, so that spreadsheets do not evaluate them as formulas
+
+	ForceQuotes    []int          // indexes (first is 1) of the columns whose values are always quoted in quoted mode (e.g. zip codes or IDs with leading zeros), see ForceQuoteNames
+	TextProtection TextProtection // how the (non empty) values of ProtectColumns are protected from spreadsheets conversion
+	ProtectColumns []int          // indexes (first is 1) of the columns protected by TextProtection (see ProtectNames)
+
+	FloatFormats map[int]FloatFormat // format of the float values by column index (first is 1), see FloatFormatNames (default is the shortest exact representation)
+	TimeFormats  map[int]TimeFormat  // layout and zone of the time values by column index (first is 1), see TimeFormatNames (default is RFC 3339 in the zone of each time)
+
+	Transformers []RecordTransformer // applied in order to the records written by WriteFields
+	Stats        *QuotingStats       // when not nil, updated with the fields written (quoting audit)
+	RecordRate   *RateLimiter        // when not nil, bounds the number of records written per second
+
+	FlushRecords int  // when > 0, the buffer is flushed after every FlushRecords records (latency of long-running exporters)
+	FlushBytes   int  // when > 0, the buffer is flushed at the end of a record once at least FlushBytes bytes are buffered
+	SyncOnClose  bool // True to commit the output to stable storage on Close (when the underlying writer has a Sync method, like *os.File)
 }
 
 // DefaultWriter creates a "standard" CSV writer (separator is comma and quoted mode active)
@@ -38,7 +66,19 @@ func DefaultWriter(wr io.Writer) *Writer {
 
 // NewWriter returns a new CSV writer.
 func NewWriter(w io.Writer, sep byte, quoted bool) *Writer {
-	wr := &Writer{b: bufio.NewWriter(w), sep: sep, quoted: quoted, sor: true}
+	return NewWriterSize(w, sep, quoted, 0)
+}
+
+// NewWriterSize returns a new CSV writer whose buffer has at least the specified size
+// (the default size when size <= 0).
+func NewWriterSize(w io.Writer, sep byte, quoted bool, size int) *Writer {
+	var b *bufio.Writer
+	if size > 0 {
+		b = bufio.NewWriterSize(w, size)
+	} else {
+		b = bufio.NewWriter(w)
+	}
+	wr := &Writer{w: w, b: b, sep: sep, quoted: quoted, sor: true}
 	wr.hb = (*reflect.SliceHeader)(unsafe.Pointer(&wr.bs))
 	return wr
 }
@@ -55,8 +95,29 @@ func (w *Writer) WriteRecord(values ...interface{}) bool {
 	return w.err == nil
 }
 
+// WriteFields writes one record from its raw fields.
+// It ensures that values are quoted when needed.
+// The Transformers are applied in order to the record before it is written.
+func (w *Writer) WriteFields(fields [][]byte) bool {
+	if len(w.Transformers) > 0 {
+		var err error
+		if fields, err = chain(w.Transformers).Transform(fields); err != nil {
+			w.setErr(err)
+			return false
+		}
+	}
+	for _, field := range fields {
+		if !w.Write(field) {
+			return false
+		}
+	}
+	w.EndOfRecord()
+	return w.err == nil
+}
+
 // WriteValue ensures that value is quoted when needed.
-// Value's type/kind is used to encode value to text.
+// Value's type/kind is used to encode value to text
+// (floats and times are formatted according to FloatFormats and TimeFormats).
 func (w *Writer) WriteValue(value interface{}) bool {
 	switch value := value.(type) {
 	case nil:
@@ -72,12 +133,22 @@ func (w *Writer) WriteValue(value interface{}) bool {
 	case bool:
 		return w.WriteString(strconv.FormatBool(value))
 	case float32:
-		return w.WriteString(strconv.FormatFloat(float64(value), 'f', -1, 32))
+		return w.writeFloat(float64(value), 32)
 	case float64:
-		return w.WriteString(strconv.FormatFloat(value, 'f', -1, 64))
+		return w.writeFloat(value, 64)
 	case []byte:
 		return w.Write(value)
-	case encoding.TextMarshaler: // time.Time
+	case FieldMarshaler:
+		if value, err := value.MarshalCSVField(); err != nil {
+			w.setErr(err)
+			w.Write([]byte{}) // TODO Validate: write an empty field
+			return false
+		} else {
+			return w.Write(value) // please, ignore golint
+		}
+	case time.Time:
+		return w.writeTime(value)
+	case encoding.TextMarshaler:
 		if text, err := value.MarshalText(); err != nil {
 			w.setErr(err)
 			w.Write([]byte{}) // TODO Validate: write an empty field
@@ -104,7 +175,7 @@ func (w *Writer) writeReflect(value interface{}) bool {
 	case reflect.Bool:
 		return w.WriteString(strconv.FormatBool(v.Bool()))
 	case reflect.Float32, reflect.Float64:
-		return w.WriteString(strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()))
+		return w.writeFloat(v.Float(), v.Type().Bits())
 	default:
 		w.setErr(fmt.Errorf("unsupported type: %T, %v", value, value))
 		w.Write([]byte{}) // TODO Validate: write an empty field
@@ -129,16 +200,67 @@ var (
 	ErrSeparator = errors.New("yacr.Writer: separator in value")
 )
 
+// WriteFieldsQuoted writes one record from its raw fields, quoting the fields marked as quoted
+// (and the ones that need it) so that the original quoting can be reproduced (see Reader.QuotedFields).
+func (w *Writer) WriteFieldsQuoted(fields [][]byte, quoted []bool) bool {
+	for i, field := range fields {
+		if !w.WriteQuoted(field, i < len(quoted) && quoted[i]) {
+			return false
+		}
+	}
+	w.EndOfRecord()
+	return w.err == nil
+}
+
+// WriteQuoted is like Write but value is always quoted (in quoted mode) when quoted is true (see Reader.WasQuoted).
+func (w *Writer) WriteQuoted(value []byte, quoted bool) bool {
+	return w.write(value, quoted)
+}
+
 // Write ensures that value is quoted when needed.
 func (w *Writer) Write(value []byte) bool {
+	return w.write(value, false)
+}
+
+// write ensures that value is quoted when needed or forced.
+func (w *Writer) write(value []byte, force bool) bool {
 	if w.err != nil {
 		return false
 	}
 	if !w.sor {
 		w.setErr(w.b.WriteByte(w.sep))
 	}
-	// In quoted mode, value is enclosed between quotes if it contains sep, quote or \n.
-	if w.quoted {
+	if w.SanitizeFormulas && isFormula(value) {
+		value = append([]byte{'\''}, value...)
+	}
+	if w.TextProtection != NoTextProtection && len(value) > 0 && w.inColumns(w.ProtectColumns) {
+		value = w.protectText(value)
+	}
+	if w.NormalizeNewlines {
+		value = w.normalizeNewlines(value)
+	}
+	if w.Escape != 0 {
+		last := 0
+		for i, c := range value {
+			switch c {
+			case '\r', '\n', w.sep, w.Escape:
+			default:
+				continue
+			}
+			if _, err := w.b.Write(value[last:i]); err != nil {
+				w.setErr(err)
+			}
+			w.setErr(w.b.WriteByte(w.Escape))
+			last = i
+		}
+		if _, err := w.b.Write(value[last:]); err != nil {
+			w.setErr(err)
+		}
+	} else if w.quoted { // In quoted mode, value is enclosed between quotes if it contains sep, quote or \n.
+		force = force || w.inColumns(w.ForceQuotes)
+		if force {
+			w.setErr(w.b.WriteByte('"'))
+		}
 		last := 0
 		for i, c := range value {
 			switch c {
@@ -146,7 +268,7 @@ func (w *Writer) Write(value []byte) bool {
 			default:
 				continue
 			}
-			if last == 0 {
+			if last == 0 && !force {
 				w.setErr(w.b.WriteByte('"'))
 			}
 			if _, err := w.b.Write(value[last : i+1]); err != nil {
@@ -160,9 +282,12 @@ func (w *Writer) Write(value []byte) bool {
 		if _, err := w.b.Write(value[last:]); err != nil {
 			w.setErr(err)
 		}
-		if last != 0 {
+		if last != 0 || force {
 			w.setErr(w.b.WriteByte('"'))
 		}
+		if w.Stats != nil {
+			w.Stats.audit(value, w.sep, w.col+1, last != 0 || force, force)
+		}
 	} else {
 		// check that value does not contain sep or \n
 		for _, c := range value {
@@ -181,10 +306,176 @@ func (w *Writer) Write(value []byte) bool {
 			w.setErr(err)
 		}
 	}
+	if w.Stats != nil && (w.Escape != 0 || !w.quoted) { // not quoted
+		w.Stats.audit(value, w.sep, w.col+1, false, false)
+	}
 	w.sor = false
+	w.col++
 	return w.err == nil
 }
 
+// inColumns tells if the current column is one of indexes.
+func (w *Writer) inColumns(indexes []int) bool {
+	for _, index := range indexes {
+		if index == w.col+1 {
+			return true
+		}
+	}
+	return false
+}
+
+// ForceQuoteNames adds the named columns of header to ForceQuotes.
+func (w *Writer) ForceQuoteNames(header []string, names ...string) error {
+	indexes, err := columnIndexes(header, names)
+	w.ForceQuotes = append(w.ForceQuotes, indexes...)
+	return err
+}
+
+// ProtectNames adds the named columns of header to ProtectColumns.
+func (w *Writer) ProtectNames(header []string, names ...string) error {
+	indexes, err := columnIndexes(header, names)
+	w.ProtectColumns = append(w.ProtectColumns, indexes...)
+	return err
+}
+
+// FloatFormatNames sets the format of the float values of the named columns of header (see FloatFormats).
+func (w *Writer) FloatFormatNames(header []string, f FloatFormat, names ...string) error {
+	indexes, err := columnIndexes(header, names)
+	if w.FloatFormats == nil {
+		w.FloatFormats = make(map[int]FloatFormat, len(indexes))
+	}
+	for _, index := range indexes {
+		w.FloatFormats[index] = f
+	}
+	return err
+}
+
+// writeFloat writes v with the format of the current column (see FloatFormats).
+func (w *Writer) writeFloat(v float64, bitSize int) bool {
+	f, ok := w.FloatFormats[w.col+1]
+	if !ok {
+		f = FloatFormat{Fmt: 'f', Prec: -1}
+	}
+	w.fb = f.append(w.fb[:0], v, bitSize)
+	return w.Write(w.fb)
+}
+
+// TimeFormatNames sets the layout and zone of the time values of the named columns of header (see TimeFormats).
+func (w *Writer) TimeFormatNames(header []string, f TimeFormat, names ...string) error {
+	indexes, err := columnIndexes(header, names)
+	if w.TimeFormats == nil {
+		w.TimeFormats = make(map[int]TimeFormat, len(indexes))
+	}
+	for _, index := range indexes {
+		w.TimeFormats[index] = f
+	}
+	return err
+}
+
+// writeTime writes t with the layout and zone of the current column (see TimeFormats).
+func (w *Writer) writeTime(t time.Time) bool {
+	w.fb = w.TimeFormats[w.col+1].append(w.fb[:0], t)
+	return w.Write(w.fb)
+}
+
+// columnIndexes returns the indexes (first is 1) of the named columns of header.
+func columnIndexes(header []string, names []string) ([]int, error) {
+	indexes := make([]int, 0, len(names))
+	for _, name := range names {
+		index := -1
+		for i, h := range header {
+			if h == name {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return indexes, fmt.Errorf("unknown field name: %s", name)
+		}
+		indexes = append(indexes, index+1)
+	}
+	return indexes, nil
+}
+
+// TextProtection specifies how values are protected from the conversion done by spreadsheets
+// (leading zeros of zip codes or IDs dropped, long numbers rounded, dates reformatted).
+type TextProtection int
+
+// Text protections
+const (
+	NoTextProtection      TextProtection = iota
+	FormulaTextProtection                // value written as a string formula: ="00123"
+	TabTextProtection                    // value prefixed with a tab character
+)
+
+// protectText applies TextProtection to value (see Reader.UnprotectText).
+func (w *Writer) protectText(value []byte) []byte {
+	if w.TextProtection == TabTextProtection {
+		w.pb = append(append(w.pb[:0], '\t'), value...)
+		return w.pb
+	}
+	w.pb = append(w.pb[:0], '=', '"')
+	for _, c := range value {
+		if c == '"' {
+			w.pb = append(w.pb, c)
+		}
+		w.pb = append(w.pb, c)
+	}
+	w.pb = append(w.pb, '"')
+	return w.pb
+}
+
+// normalizeNewlines converts all line terminators in value to the one of the Writer.
+func (w *Writer) normalizeNewlines(value []byte) []byte {
+	for i, c := range value {
+		switch c {
+		case '\r':
+			if !w.UseCRLF || i+1 == len(value) || value[i+1] != '\n' {
+				return w.convertNewlines(value, i)
+			}
+		case '\n':
+			if w.UseCRLF && (i == 0 || value[i-1] != '\r') {
+				return w.convertNewlines(value, i)
+			}
+		}
+	}
+	return value
+}
+
+// convertNewlines rewrites value from the first non conforming line terminator at i.
+func (w *Writer) convertNewlines(value []byte, i int) []byte {
+	w.nb = append(w.nb[:0], value[:i]...)
+	for ; i < len(value); i++ {
+		c := value[i]
+		if c == '\r' && i+1 < len(value) && value[i+1] == '\n' {
+			i++ // CRLF
+		} else if c != '\r' && c != '\n' {
+			w.nb = append(w.nb, c)
+			continue
+		}
+		if w.UseCRLF {
+			w.nb = append(w.nb, '\r')
+		}
+		w.nb = append(w.nb, '\n')
+	}
+	return w.nb
+}
+
+// isFormula tells if a spreadsheet may interpret value as a formula.
+func isFormula(value []byte) bool {
+	if len(value) == 0 {
+		return false
+	}
+	switch value[0] {
+	case '=', '@', '\t', '\r':
+		return true
+	case '+', '-':
+		isNum, _ := IsNumber(value)
+		return !isNum
+	}
+	return false
+}
+
 // EndOfRecord tells when a line break must be inserted.
 func (w *Writer) EndOfRecord() {
 	if w.UseCRLF {
@@ -192,11 +483,62 @@ func (w *Writer) EndOfRecord() {
 	}
 	w.setErr(w.b.WriteByte('\n'))
 	w.sor = true
+	w.col = 0
+	if w.trailer != nil {
+		w.trailer.records++
+	}
+	if w.RecordRate != nil {
+		w.RecordRate.Wait(1)
+	}
+	w.nrec++
+	if w.FlushRecords > 0 && w.nrec >= w.FlushRecords || w.FlushBytes > 0 && w.b.Buffered() >= w.FlushBytes {
+		w.Flush()
+	}
+}
+
+// WriteSepHint writes the "sep=X" line telling Excel which separator is used.
+// It must be called before any record (but after the BOM if any).
+func (w *Writer) WriteSepHint() bool {
+	if w.err != nil {
+		return false
+	}
+	_, err := w.b.WriteString("sep=")
+	w.setErr(err)
+	w.setErr(w.b.WriteByte(w.sep))
+	w.EndOfRecord()
+	if w.trailer != nil { // the hint is not a record (see VerifyTrailer)
+		w.trailer.records--
+	}
+	return w.err == nil
 }
 
 // Flush ensures the writer's buffer is flushed.
 func (w *Writer) Flush() {
 	w.setErr(w.b.Flush())
+	w.nrec = 0
+}
+
+// Close flushes the buffer, commits the output to stable storage when SyncOnClose is specified
+// and closes the resources owned by the Writer (see OnClose).
+// The underlying writer is not closed unless it has been registered with OnClose
+// (so that a Writer on os.Stdout can be closed safely).
+// Returns the first error that was encountered by the Writer.
+func (w *Writer) Close() error {
+	w.Flush()
+	if s, ok := w.w.(interface{ Sync() error }); ok && w.SyncOnClose && w.err == nil {
+		w.setErr(s.Sync())
+	}
+	for i := len(w.closers) - 1; i >= 0; i-- {
+		w.setErr(w.closers[i].Close())
+	}
+	w.closers = nil
+	return w.err
+}
+
+// OnClose registers c to be closed by Close.
+// Closers are closed in the reverse order of their registration (like Reader.OnClose).
+func (w *Writer) OnClose(c io.Closer) {
+	w.closers = append(w.closers, c)
 }
 
 // Err returns the first error that was encountered by the Writer.
@@ -204,6 +546,12 @@ func (w *Writer) Err() error {
 	return w.err
 }
 
+// Error reports any error that has occurred during a previous write or flush
+// (like encoding/csv.Writer.Error).
+func (w *Writer) Error() error {
+	return w.err
+}
+
 // setErr records the first error encountered.
 func (w *Writer) setErr(err error) {
 	if w.err == nil {
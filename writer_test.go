@@ -7,6 +7,10 @@ package yacr_test
 import (
 	"bytes"
 	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -116,3 +120,395 @@ func TestWriteRecord(t *testing.T) {
 		}
 	}
 }
+
+func TestWriteFileAtomic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.csv")
+	input := "a,\"b\nc\"\n\nd,e\n"
+	if err := WriteFileAtomic(path, DefaultReader(strings.NewReader(input)), Dialect{Sep: ';', Quoted: true, UseCRLF: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a;\"b\nc\"\r\nd;e\r\n"; string(content) != want {
+		t.Errorf("got %q; want %q", content, want)
+	}
+	files, err := ioutil.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("got %d file(s); want only %s", len(files), path)
+	}
+}
+
+var excelTests = []struct {
+	Dialect Dialect
+	Input   [][]string
+	Output  string
+}{
+	{Dialect: DialectExcel, Input: [][]string{{"é", "=1+2", "-1.5", "-x", "@SUM(A1)"}}, Output: "\uFEFFé,'=1+2,-1.5,'-x,'@SUM(A1)\r\n"},
+	{Dialect: DialectExcelSemicolon, Input: [][]string{{"1,5", "a;b"}}, Output: "\uFEFF1,5;\"a;b\"\r\n"},
+	{Dialect: Dialect{Sep: ';', Quoted: true, SepHint: true}, Input: [][]string{{"a", "=b"}}, Output: "sep=;\na;=b\n"},
+}
+
+func TestDialectExcel(t *testing.T) {
+	for n, tt := range excelTests {
+		b := &bytes.Buffer{}
+		w := tt.Dialect.NewWriter(b)
+		for _, row := range tt.Input {
+			writeRow(w, row)
+		}
+		w.Flush()
+		if err := w.Err(); err != nil {
+			t.Errorf("Unexpected error: %s\n", err)
+		}
+		if out := b.String(); out != tt.Output {
+			t.Errorf("#%d: out=%q want %q", n, out, tt.Output)
+		}
+	}
+}
+
+var normalizeTests = []struct {
+	UseCRLF bool
+	Input   string
+	Output  string
+}{
+	{false, "a\r\nb\rc\nd", "\"a\nb\nc\nd\"\n"},
+	{true, "a\r\nb\rc\nd", "\"a\r\nb\r\nc\r\nd\"\r\n"},
+	{false, "a\nb", "\"a\nb\"\n"},
+	{true, "a\r\n", "\"a\r\n\"\r\n"},
+	{true, "\r", "\"\r\n\"\r\n"},
+}
+
+func TestNormalizeNewlines(t *testing.T) {
+	for n, tt := range normalizeTests {
+		b := &bytes.Buffer{}
+		w := DefaultWriter(b)
+		w.UseCRLF = tt.UseCRLF
+		w.NormalizeNewlines = true
+		w.WriteString(tt.Input)
+		w.EndOfRecord()
+		w.Flush()
+		if err := w.Err(); err != nil {
+			t.Errorf("Unexpected error: %s\n", err)
+		}
+		if out := b.String(); out != tt.Output {
+			t.Errorf("#%d: out=%q want %q", n, out, tt.Output)
+		}
+	}
+}
+
+func TestSepHintRoundTrip(t *testing.T) {
+	b := &bytes.Buffer{}
+	w := Dialect{Sep: ';', Quoted: true, BOM: true, SepHint: true}.NewWriter(b)
+	writeRow(w, []string{"a", "b,c"})
+	w.Flush()
+	r := Dialect{Sep: ',', Quoted: true, SepHint: true}.NewReader(b)
+	fields, err := r.ReadRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 2 || string(fields[1]) != "b,c" || r.Sep() != ';' || !r.SepHinted() {
+		t.Errorf("got %q (sep %q)", fields, r.Sep())
+	}
+}
+
+func TestEscapeRoundTrip(t *testing.T) {
+	rows := [][]string{{"a\x01b", "c\\d", "e\r\nf", "\"g\""}, {"", "h"}}
+	b := &bytes.Buffer{}
+	w := DialectHive.NewWriter(b)
+	for _, row := range rows {
+		writeRow(w, row)
+	}
+	w.Flush()
+	if err := w.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "a\\\x01b\x01c\\\\d\x01e\\\r\\\nf\x01\"g\"\n\x01h\n"; b.String() != want {
+		t.Errorf("got %q; want %q", b.String(), want)
+	}
+	r := DialectHive.NewReader(b)
+	for _, row := range rows {
+		fields, err := r.ReadRecord()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(fields) != len(row) {
+			t.Fatalf("got %q; want %q", fields, row)
+		}
+		for i, field := range fields {
+			if string(field) != row[i] {
+				t.Errorf("got %q; want %q", field, row[i])
+			}
+		}
+	}
+}
+
+func TestForceQuotes(t *testing.T) {
+	b := &bytes.Buffer{}
+	w := DefaultWriter(b)
+	header := []string{"id", "name", "zip"}
+	if err := w.ForceQuoteNames(header, "zip", "id"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.ForceQuoteNames(header, "city"); err == nil {
+		t.Error("error expected")
+	}
+	writeRow(w, header)
+	writeRow(w, []string{"007", "a,b", "01234"})
+	writeRow(w, []string{"", "c"})
+	w.Flush()
+	if err := w.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "\"id\",name,\"zip\"\n\"007\",\"a,b\",\"01234\"\n\"\",c\n"; b.String() != want {
+		t.Errorf("got %q; want %q", b.String(), want)
+	}
+}
+
+func TestTextProtection(t *testing.T) {
+	rows := [][]string{{"zip", "name"}, {"01234", "a\"b"}, {"", "=1+2"}, {"0x\"1", "c"}}
+	for _, tt := range []struct {
+		Protection TextProtection
+		Output     string
+	}{
+		{FormulaTextProtection, "zip,\"=\"\"name\"\"\"\n01234,\"=\"\"a\"\"\"\"b\"\"\"\n,\"=\"\"'=1+2\"\"\"\n\"0x\"\"1\",\"=\"\"c\"\"\"\n"},
+		{TabTextProtection, "zip,\tname\n01234,\"\ta\"\"b\"\n,\t'=1+2\n\"0x\"\"1\",\tc\n"},
+	} {
+		b := &bytes.Buffer{}
+		w := DefaultWriter(b)
+		w.SanitizeFormulas = true
+		w.TextProtection = tt.Protection
+		if err := w.ProtectNames(rows[0], "name"); err != nil {
+			t.Fatal(err)
+		}
+		for _, row := range rows {
+			writeRow(w, row)
+		}
+		w.Flush()
+		if err := w.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != tt.Output {
+			t.Errorf("%d: got %q; want %q", tt.Protection, b.String(), tt.Output)
+		}
+		r := DefaultReader(b)
+		r.UnprotectText = true
+		if err := r.UnprotectNames(rows[0], "name"); err != nil {
+			t.Fatal(err)
+		}
+		for _, row := range rows {
+			fields, err := r.ReadRecord()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := row
+			if row[1] == "=1+2" {
+				want = []string{row[0], "'=1+2"} // sanitized
+			}
+			if !reflect.DeepEqual(toStrings(fields), want) {
+				t.Errorf("%d: got %q; want %q", tt.Protection, fields, want)
+			}
+		}
+	}
+	if got := unprotect("=\"a\"&\"b\""); got != "=\"a\"&\"b\"" {
+		t.Errorf("got %q", got)
+	}
+	r := DefaultReader(strings.NewReader("\ta,\tb\n\tc,=\"d\"\n"))
+	r.UnprotectText = true
+	r.UnprotectColumns = []int{2}
+	for _, want := range [][]string{{"\ta", "b"}, {"\tc", "d"}} {
+		fields, err := r.ReadRecord()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(toStrings(fields), want) {
+			t.Errorf("got %q; want %q (only the second column is unprotected)", fields, want)
+		}
+	}
+}
+
+func unprotect(s string) string {
+	r := DefaultReader(strings.NewReader(s))
+	r.UnprotectText = true
+	r.UnprotectColumns = []int{1}
+	r.Scan()
+	return r.Text()
+}
+
+func TestQuotingPassThrough(t *testing.T) {
+	input := "a,\"b\",,\"\"\n\"x\"\"y\",z w,\"1\"\n"
+	r := DefaultReader(strings.NewReader(input))
+	b := &bytes.Buffer{}
+	w := DefaultWriter(b)
+	for {
+		fields, err := r.ReadRecord()
+		if err != nil {
+			break
+		}
+		w.WriteFieldsQuoted(fields, r.QuotedFields())
+	}
+	w.Flush()
+	if err := w.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != input {
+		t.Errorf("got %q; want %q", b.String(), input)
+	}
+
+	r = DefaultReader(strings.NewReader("\"a\",b"))
+	var quoted []bool
+	for r.Scan() {
+		quoted = append(quoted, r.WasQuoted())
+	}
+	if !reflect.DeepEqual(quoted, []bool{true, false}) {
+		t.Errorf("got %v", quoted)
+	}
+}
+
+func TestQuotingStats(t *testing.T) {
+	b := &bytes.Buffer{}
+	w := DefaultWriter(b)
+	stats := &QuotingStats{}
+	w.Stats = stats
+	w.ForceQuotes = []int{1}
+	writeRow(w, []string{"007", "a,b", " c", "d\ne"})
+	writeRow(w, []string{"1\r\n2", "x\"y", "z ", "ok"})
+	w.Flush()
+	want := QuotingStats{Fields: 8, Quoted: 5, Forced: 1, Separator: 1, Quote: 1, Newline: 2, Space: 2, Newlines: map[int]int{1: 1, 4: 1}}
+	if !reflect.DeepEqual(*stats, want) {
+		t.Errorf("got %+v; want %+v", *stats, want)
+	}
+
+	w = NewWriter(b, '\t', false)
+	stats = &QuotingStats{}
+	w.Stats = stats
+	writeRow(w, []string{"a ", "b"})
+	if stats.Fields != 2 || stats.Quoted != 0 || stats.Space != 1 {
+		t.Errorf("unexpected stats in unquoted mode: %+v", *stats)
+	}
+}
+
+// syncCloser records the calls to Sync and Close.
+type syncCloser struct {
+	bytes.Buffer
+	synced, closed bool
+}
+
+func (s *syncCloser) Sync() error {
+	s.synced = true
+	return nil
+}
+
+func (s *syncCloser) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestFlushPolicy(t *testing.T) {
+	var b bytes.Buffer
+	w := NewWriterSize(&b, ',', true, 4096)
+	w.FlushRecords = 2
+	writeRow(w, []string{"a", "b"})
+	if b.Len() != 0 {
+		t.Errorf("unexpected flush: %q", b.String())
+	}
+	writeRow(w, []string{"c", "d"})
+	if b.String() != "a,b\nc,d\n" {
+		t.Errorf("got %q after 2 records", b.String())
+	}
+
+	b.Reset()
+	w = NewWriterSize(&b, ',', true, 4096)
+	w.FlushBytes = 6
+	writeRow(w, []string{"a"})
+	writeRow(w, []string{"b"})
+	if b.Len() != 0 {
+		t.Errorf("unexpected flush: %q", b.String())
+	}
+	writeRow(w, []string{"c"})
+	if b.String() != "a\nb\nc\n" {
+		t.Errorf("got %q after 6 bytes", b.String())
+	}
+
+	s := &syncCloser{}
+	w = NewWriter(s, ',', true)
+	w.SyncOnClose = true
+	writeRow(w, []string{"e"})
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !s.synced || s.closed || s.String() != "e\n" {
+		t.Errorf("got %q (synced: %t, closed: %t); the underlying writer must not be closed", s.String(), s.synced, s.closed)
+	}
+	s = &syncCloser{}
+	w = NewWriter(s, ',', true)
+	w.OnClose(s)
+	if err := w.Close(); err != nil || !s.closed {
+		t.Errorf("got %v (closed: %t)", err, s.closed)
+	}
+	if w.Error() != nil {
+		t.Errorf("unexpected error: %v", w.Error())
+	}
+}
+
+var floatFormatTests = []struct {
+	Format   FloatFormat
+	Value    float64
+	Expected string
+}{
+	{FloatFormat{Prec: 2}, 1.0 / 3, "0.33"},
+	{FloatFormat{Prec: 3, TrimZeros: true}, 12.5, "12.5"},
+	{FloatFormat{Prec: 3, TrimZeros: true}, 12, "12"},
+	{FloatFormat{Prec: 0}, 2.5, "2"},
+	{FloatFormat{Fmt: 'e', Prec: 3, TrimZeros: true}, 1500000, "1.5e+06"},
+	{FloatFormat{Fmt: 'g', Prec: -1}, 1e21, "1e+21"},
+	{FloatFormat{Fmt: 'f', Prec: 2, TrimZeros: true}, 100, "100"},
+}
+
+func TestFloatFormats(t *testing.T) {
+	for _, test := range floatFormatTests {
+		if got := test.Format.Format(test.Value); got != test.Expected {
+			t.Errorf("%+v: got %q; want %q", test.Format, got, test.Expected)
+		}
+	}
+
+	var b bytes.Buffer
+	w := NewWriter(&b, ',', true)
+	if err := w.FloatFormatNames([]string{"name", "price", "ratio"}, FloatFormat{Prec: 2, TrimZeros: true}, "price"); err != nil {
+		t.Fatal(err)
+	}
+	w.FloatFormats[3] = FloatFormat{Fmt: 'e', Prec: 1}
+	w.WriteRecord("x", 10.499, 0.000123)
+	w.WriteRecord(float32(0.1), float32(2.005), 1)
+	w.Flush()
+	if expected := "x,10.5,1.2e-04\n0.1,2.01,1\n"; b.String() != expected {
+		t.Errorf("got %q; want %q", b.String(), expected)
+	}
+	if err := w.FloatFormatNames([]string{"name"}, FloatFormat{}, "price"); err == nil {
+		t.Error("error expected for unknown column")
+	}
+}
+
+func TestTimeFormats(t *testing.T) {
+	cet := time.FixedZone("CET", 3600)
+	ts := time.Date(2021, 3, 4, 23, 30, 0, 0, cet)
+	var b bytes.Buffer
+	w := NewWriter(&b, ',', true)
+	if err := w.TimeFormatNames([]string{"id", "created", "day"}, TimeFormat{Location: time.UTC}, "created"); err != nil {
+		t.Fatal(err)
+	}
+	w.TimeFormats[3] = TimeFormat{Layout: "2006-01-02", Location: time.UTC}
+	w.WriteRecord(ts, ts, ts)
+	w.WriteStruct(struct {
+		ID      int
+		Created time.Time
+		Day     time.Time
+	}{1, ts, ts})
+	w.Flush()
+	if expected := "2021-03-04T23:30:00+01:00,2021-03-04T22:30:00Z,2021-03-04\n1,2021-03-04T22:30:00Z,2021-03-04\n"; b.String() != expected {
+		t.Errorf("got %q; want %q", b.String(), expected)
+	}
+}
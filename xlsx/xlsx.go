@@ -0,0 +1,363 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package xlsx exposes an XLSX worksheet as a yacr record source (or Reader),
+// so that spreadsheets can be consumed with the same API as CSV files.
+// Only cell values are extracted: styles (and so dates, which are stored as serial numbers) are ignored.
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gwenn/yacr"
+)
+
+// Sheet is a worksheet streamed record by record.
+type Sheet struct {
+	f       *os.File // only when opened by Open
+	rc      io.ReadCloser
+	d       *xml.Decoder
+	strings []string // shared strings
+	record  [][]byte
+	eof     bool
+}
+
+// Open opens the named worksheet (or the first one when name is empty) of the XLSX file at path.
+func Open(filepath string, name string) (*Sheet, error) {
+	f, err := os.Open(filepath)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	s, err := NewSheet(f, fi.Size(), name)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	s.f = f
+	return s, nil
+}
+
+// NewSheet returns the named worksheet (or the first one when name is empty) of the XLSX content in r.
+func NewSheet(r io.ReaderAt, size int64, name string) (*Sheet, error) {
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]*zip.File, len(z.File))
+	for _, f := range z.File {
+		files[f.Name] = f
+	}
+	target, err := sheetTarget(files, name)
+	if err != nil {
+		return nil, err
+	}
+	s := &Sheet{}
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		if s.strings, err = sharedStrings(f); err != nil {
+			return nil, err
+		}
+	}
+	f, ok := files[target]
+	if !ok {
+		return nil, fmt.Errorf("xlsx: missing worksheet %s", target)
+	}
+	if s.rc, err = f.Open(); err != nil {
+		return nil, err
+	}
+	s.d = xml.NewDecoder(s.rc)
+	return s, nil
+}
+
+type workbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		ID   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type relationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+func decodeFile(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
+
+// sheetTarget resolves the path of the worksheet in the archive.
+func sheetTarget(files map[string]*zip.File, name string) (string, error) {
+	f, ok := files["xl/workbook.xml"]
+	if !ok {
+		return "", errors.New("xlsx: missing workbook")
+	}
+	var wb workbook
+	if err := decodeFile(f, &wb); err != nil {
+		return "", err
+	}
+	var id string
+	for _, sheet := range wb.Sheets {
+		if name == "" || sheet.Name == name {
+			id = sheet.ID
+			break
+		}
+	}
+	if id == "" {
+		return "", fmt.Errorf("xlsx: no such worksheet: %q", name)
+	}
+	if f, ok = files["xl/_rels/workbook.xml.rels"]; !ok {
+		return "", errors.New("xlsx: missing workbook relationships")
+	}
+	var rels relationships
+	if err := decodeFile(f, &rels); err != nil {
+		return "", err
+	}
+	for _, rel := range rels.Relationships {
+		if rel.ID == id {
+			if strings.HasPrefix(rel.Target, "/") {
+				return rel.Target[1:], nil
+			}
+			return path.Join("xl", rel.Target), nil
+		}
+	}
+	return "", fmt.Errorf("xlsx: unresolved worksheet relationship: %s", id)
+}
+
+// sharedStrings loads the shared strings table (rich text runs are concatenated).
+func sharedStrings(f *zip.File) ([]string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	d := xml.NewDecoder(rc)
+	var ss []string
+	var b strings.Builder
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return ss, nil
+		} else if err != nil {
+			return nil, err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			switch tok.Name.Local {
+			case "si":
+				b.Reset()
+			case "t":
+				var t string
+				if err = d.DecodeElement(&t, &tok); err != nil {
+					return nil, err
+				}
+				b.WriteString(t)
+			case "rPh": // phonetic hints are not part of the value
+				if err = d.Skip(); err != nil {
+					return nil, err
+				}
+			}
+		case xml.EndElement:
+			if tok.Name.Local == "si" {
+				ss = append(ss, b.String())
+			}
+		}
+	}
+}
+
+// ReadRecord returns the cells of the next non-empty row or io.EOF.
+// Missing cells are returned as empty fields.
+// It implements yacr.RecordSource.
+func (s *Sheet) ReadRecord() ([][]byte, error) {
+	if s.eof {
+		return nil, io.EOF
+	}
+	for {
+		tok, err := s.d.Token()
+		if err == io.EOF {
+			s.eof = true
+			return nil, io.EOF
+		} else if err != nil {
+			return nil, err
+		}
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local == "row" {
+			if err = s.readRow(); err != nil {
+				return nil, err
+			}
+			if len(s.record) > 0 {
+				return s.record, nil
+			}
+		}
+	}
+}
+
+func (s *Sheet) readRow() error {
+	s.record = s.record[:0]
+	for {
+		tok, err := s.d.Token()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			if tok.Name.Local != "c" {
+				continue
+			}
+			var ref, typ string
+			for _, attr := range tok.Attr {
+				switch attr.Name.Local {
+				case "r":
+					ref = attr.Value
+				case "t":
+					typ = attr.Value
+				}
+			}
+			value, err := s.readCell(typ)
+			if err != nil {
+				return err
+			}
+			col, err := column(ref)
+			if err != nil {
+				return err
+			}
+			if col > len(s.record) {
+				for len(s.record) < col {
+					s.record = append(s.record, []byte{})
+				}
+			}
+			s.record = append(s.record, []byte(value))
+		case xml.EndElement:
+			if tok.Name.Local == "row" {
+				return nil
+			}
+		}
+	}
+}
+
+func (s *Sheet) readCell(typ string) (string, error) {
+	var value string
+	for {
+		tok, err := s.d.Token()
+		if err != nil {
+			return "", unexpectedEOF(err)
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			switch tok.Name.Local {
+			case "v", "t":
+				var t string
+				if err = s.d.DecodeElement(&t, &tok); err != nil {
+					return "", err
+				}
+				value += t
+			case "f", "rPh": // formula and phonetic hints are not part of the value
+				if err = s.d.Skip(); err != nil {
+					return "", err
+				}
+			}
+		case xml.EndElement:
+			if tok.Name.Local != "c" {
+				continue
+			}
+			switch typ {
+			case "s":
+				if i, err := strconv.Atoi(value); err != nil || i < 0 || i >= len(s.strings) {
+					return "", fmt.Errorf("xlsx: invalid shared string index: %q", value)
+				} else {
+					return s.strings[i], nil
+				}
+			case "b":
+				if value == "1" {
+					return "true", nil
+				}
+				return "false", nil
+			}
+			return value, nil
+		}
+	}
+}
+
+// maxColumns is the number of columns of a worksheet (the last one is XFD).
+const maxColumns = 16384
+
+// column returns the zero-based column index of a cell reference like "AB12" (-1 when unknown).
+// References past the last column (XFD) are rejected.
+func column(ref string) (int, error) {
+	col := 0
+	i := 0
+	for ; i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z'; i++ {
+		if col = col*26 + int(ref[i]-'A') + 1; col > maxColumns {
+			return 0, fmt.Errorf("xlsx: invalid cell reference: %q", ref)
+		}
+	}
+	if i == 0 {
+		return -1, nil
+	}
+	return col - 1, nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Reader returns a CSV Reader over the worksheet content, so that it can be consumed like any CSV file.
+// Records are converted in a background goroutine: the Sheet must not be read directly afterwards
+// and should only be closed once the returned Reader is exhausted or closed
+// (closing the Reader stops the goroutine).
+func (s *Sheet) Reader() *yacr.Reader {
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := yacr.Copy(yacr.DefaultWriter(pw), s)
+		pw.CloseWithError(err)
+	}()
+	r := yacr.DefaultReader(pr)
+	r.OnClose(&pipeCloser{pr, done})
+	return r
+}
+
+// pipeCloser closes the read side of the pipe and waits for the end of the conversion.
+type pipeCloser struct {
+	pr   *io.PipeReader
+	done chan struct{}
+}
+
+func (c *pipeCloser) Close() error {
+	err := c.pr.Close()
+	<-c.done
+	return err
+}
+
+// Close releases the worksheet (and the file when opened by Open).
+func (s *Sheet) Close() error {
+	err := s.rc.Close()
+	if s.f != nil {
+		if ferr := s.f.Close(); err == nil {
+			err = ferr
+		}
+	}
+	return err
+}
@@ -0,0 +1,160 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xlsx_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	. "github.com/gwenn/yacr/xlsx"
+)
+
+var parts = map[string]string{
+	"xl/workbook.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="First" sheetId="1" r:id="rId1"/><sheet name="Data" sheetId="2" r:id="rId2"/></sheets>
+</workbook>`,
+	"xl/_rels/workbook.xml.rels": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet2.xml"/>
+</Relationships>`,
+	"xl/sharedStrings.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="3" uniqueCount="3">
+<si><t>name</t></si><si><t>qty</t></si><si><r><t>a,</t></r><r><t>"b"</t></r></si>
+</sst>`,
+	"xl/worksheets/sheet1.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData/></worksheet>`,
+	"xl/worksheets/sheet2.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="inlineStr"><is><t>ok</t></is></c></row>
+<row r="3"><c r="A3" t="s"><v>2</v></c><c r="C3" t="b"><v>1</v></c></row>
+<row r="4"><c r="B4"><f>1+2</f><v>3</v></c></row>
+</sheetData></worksheet>`,
+}
+
+func xlsxFile(t *testing.T) *bytes.Reader {
+	return xlsxFileWith(t, "", "")
+}
+
+// xlsxFileWith returns parts as a zip file where the content of part is replaced by replacement.
+func xlsxFileWith(t *testing.T, part, replacement string) *bytes.Reader {
+	b := &bytes.Buffer{}
+	z := zip.NewWriter(b)
+	for name, content := range parts {
+		if name == part {
+			content = replacement
+		}
+		f, err := z.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(b.Bytes())
+}
+
+var want = [][]string{{"name", "qty", "ok"}, {`a,"b"`, "", "true"}, {"", "3"}}
+
+func TestReadRecord(t *testing.T) {
+	r := xlsxFile(t)
+	s, err := NewSheet(r, r.Size(), "Data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	var records [][]string
+	for {
+		fields, err := s.ReadRecord()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		var record []string
+		for _, field := range fields {
+			record = append(record, string(field))
+		}
+		records = append(records, record)
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("got %q; want %q", records, want)
+	}
+}
+
+func TestReader(t *testing.T) {
+	r := xlsxFile(t)
+	s, err := NewSheet(r, r.Size(), "Data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	cr := s.Reader()
+	var records [][]string
+	var record []string
+	for cr.Scan() {
+		record = append(record, cr.Text())
+		if cr.EndOfRecord() {
+			records = append(records, record)
+			record = nil
+		}
+	}
+	if err = cr.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("got %q; want %q", records, want)
+	}
+}
+
+func TestNoSuchSheet(t *testing.T) {
+	r := xlsxFile(t)
+	if _, err := NewSheet(r, r.Size(), "Missing"); err == nil {
+		t.Error("error expected")
+	}
+}
+
+func TestInvalidCellReference(t *testing.T) {
+	r := xlsxFileWith(t, "xl/worksheets/sheet2.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="XFD1"><v>1</v></c></row>
+<row r="2"><c r="ZZZZZZ2"><v>2</v></c></row>
+</sheetData></worksheet>`)
+	s, err := NewSheet(r, r.Size(), "Data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if fields, err := s.ReadRecord(); err != nil || len(fields) != 16384 {
+		t.Fatalf("got %d field(s), %v; want 16384", len(fields), err)
+	}
+	if _, err = s.ReadRecord(); err == nil || err.Error() != `xlsx: invalid cell reference: "ZZZZZZ2"` {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestReaderClose(t *testing.T) {
+	r := xlsxFile(t)
+	s, err := NewSheet(r, r.Size(), "Data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	cr := s.Reader()
+	if !cr.Scan() || cr.Text() != "name" {
+		t.Fatalf("got %q, %v", cr.Text(), cr.Err())
+	}
+	if err = cr.Close(); err != nil { // the conversion goroutine must not be left blocked
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package yacrtest is a conformance test kit for alternative implementations of the yacr Reader and Writer
+// (custom dialects, split functions, codecs or other backends):
+// tricky inputs are checked against the records yacr reads (see Cases)
+// and random records are written and read back (property-based round-trips).
+//
+//	func TestConformance(t *testing.T) {
+//		yacrtest.TestReader(t, newReader)
+//		yacrtest.TestRoundTrip(t, yacr.DialectDefault, newReader, newWriter)
+//	}
+package yacrtest
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/gwenn/yacr"
+)
+
+// RecordWriter is the writing interface checked by the kit (implemented by yacr.Writer).
+type RecordWriter interface {
+	Write(field []byte) bool
+	EndOfRecord()
+	Flush()
+	Err() error
+}
+
+// NewReader returns a reader of the records of r in the dialect d (empty lines being skipped),
+// or nil when the dialect is not supported (the case is skipped).
+type NewReader func(r io.Reader, d yacr.Dialect) yacr.RecordSource
+
+// NewWriter returns a writer to w in the dialect d.
+type NewWriter func(w io.Writer, d yacr.Dialect) RecordWriter
+
+// Case is a tricky input with the records yacr reads from it.
+type Case struct {
+	Name    string
+	Dialect yacr.Dialect
+	Input   string
+	Records [][]string // records read (before the error, if any)
+	Error   bool       // true when the input is malformed
+}
+
+var (
+	unquoted = yacr.Dialect{Sep: ','}
+	quoted   = yacr.Dialect{Sep: ',', Quoted: true}
+)
+
+// Cases are the inputs checked by TestReader.
+var Cases = []Case{
+	{"Simple", unquoted, "a,b,c\n", [][]string{{"a", "b", "c"}}, false},
+	{"CRLF", unquoted, "a,b\r\nc,d\r\n", [][]string{{"a", "b"}, {"c", "d"}}, false},
+	{"CRLFQuoted", quoted, "a,b\r\nc,\"d\"\r\n", [][]string{{"a", "b"}, {"c", "d"}}, false},
+	{"BareCR", unquoted, "a,b\rc,d\r\n", [][]string{{"a", "b\rc", "d"}}, false},
+	{"NoEOL", unquoted, "a,b,c", [][]string{{"a", "b", "c"}}, false},
+	{"Semicolon", yacr.Dialect{Sep: ';'}, "a;b;c\n", [][]string{{"a", "b", "c"}}, false},
+	{"Tab", yacr.Dialect{Sep: '\t'}, "3376027\t”S” Falls\t\"S\" Falls\t\t4.53333",
+		[][]string{{"3376027", "”S” Falls", `"S" Falls`, "", "4.53333"}}, false},
+	{"RFC4180", quoted, "#field1,field2,field3\n\"aaa\",\"bb\nb\",\"ccc\"\n\"a,a\",\"b\"\"bb\",\"ccc\"\nzzz,yyy,xxx\n",
+		[][]string{{"#field1", "field2", "field3"}, {"aaa", "bb\nb", "ccc"}, {"a,a", `b"bb`, "ccc"}, {"zzz", "yyy", "xxx"}}, false},
+	{"MultiLine", quoted, "\"two\nline\",\"one line\",\"three\nline\nfield\"",
+		[][]string{{"two\nline", "one line", "three\nline\nfield"}}, false},
+	{"EmbeddedNewline", quoted, "a,\"b\nb\",\"c\n\n\",d", [][]string{{"a", "b\nb", "c\n\n", "d"}}, false},
+	{"EscapedQuoteAndNewline", quoted, "\"a\"\"b\",\"c\"\"\r\nd\"", [][]string{{"a\"b", "c\"\r\nd"}}, false},
+	{"BlankLines", quoted, "a,b,\"c\"\n\nd,e,f\n\n", [][]string{{"a", "b", "c"}, {"d", "e", "f"}}, false},
+	{"LeadingSpace", unquoted, " a,  b,   c\n", [][]string{{" a", "  b", "   c"}}, false},
+	{"TrimSpace", yacr.Dialect{Sep: ',', Trim: true}, " a,  b,   c\n", [][]string{{"a", "b", "c"}}, false},
+	{"TrimSpaceQuoted", yacr.Dialect{Sep: ',', Quoted: true, Trim: true}, " a,b ,\" c \", d \n",
+		[][]string{{"a", "b", " c ", "d"}}, false},
+	{"Comment", yacr.Dialect{Sep: ',', Comment: '#'}, "#1,2,3\na,b,#\n#comment\nc\n# comment",
+		[][]string{{"a", "b", "#"}, {"c"}}, false},
+	{"NoComment", unquoted, "#1,2,3\na,b,c", [][]string{{"#1", "2", "3"}, {"a", "b", "c"}}, false},
+	{"StrictQuotes", quoted, `a "word","1"2",a","b`, nil, true},
+	{"LazyQuotes", yacr.Dialect{Sep: ',', Quoted: true, Lazy: true}, `a "word","1"2",a","b"`,
+		[][]string{{`a "word"`, `1"2`, `a"`, `b`}}, false},
+	{"BareDoubleQuotes", quoted, `a""b,c`, [][]string{{`a""b`, `c`}}, false},
+	{"BareQuote", quoted, `a "word","b"`, [][]string{{`a "word"`, "b"}}, false},
+	{"TrailingQuote", quoted, `"a word",b"`, [][]string{{"a word", `b"`}}, false},
+	{"ExtraneousQuote", quoted, `"a "word","b"`, nil, true},
+	{"ErrorAfterRecords", quoted, "a,b\n\"c\"d,e\n", [][]string{{"a", "b"}}, true},
+	{"FieldCount", unquoted, "a,b,c\nd,e", [][]string{{"a", "b", "c"}, {"d", "e"}}, false},
+	{"TrailingCommaEOF", unquoted, "a,b,c,", [][]string{{"a", "b", "c", ""}}, false},
+	{"TrailingCommaEOL", unquoted, "a,b,c,\n", [][]string{{"a", "b", "c", ""}}, false},
+	{"TrailingCommaSpace", yacr.Dialect{Sep: ',', Trim: true}, "a,b,c, \n", [][]string{{"a", "b", "c", ""}}, false},
+	{"NotTrailingComma", unquoted, "a,b,c, \n", [][]string{{"a", "b", "c", " "}}, false},
+	{"EmptyFields", quoted, ",,,\n\"\",\"\",\"\",\"\"\n", [][]string{{"", "", "", ""}, {"", "", "", ""}}, false},
+	{"Escape", yacr.DialectHive, "a\\\x01b\x01c\\\nd\\\\\n", [][]string{{"a\x01b", "c\nd\\"}}, false},
+	{"Unicode", quoted, "\u00e9t\u00e9,\"\u65e5\u672c\"\n", [][]string{{"\u00e9t\u00e9", "\u65e5\u672c"}}, false},
+}
+
+// TestReader checks that the readers returned by newReader read Cases like yacr does.
+func TestReader(t *testing.T, newReader NewReader) {
+	for _, c := range Cases {
+		t.Run(c.Name, func(t *testing.T) {
+			r := newReader(strings.NewReader(c.Input), c.Dialect)
+			if r == nil {
+				t.Skip("unsupported dialect")
+			}
+			records, err := readAll(r)
+			if !reflect.DeepEqual(records, c.Records) {
+				t.Errorf("input %q: got records %q; want %q", c.Input, records, c.Records)
+			}
+			if c.Error && err == nil {
+				t.Errorf("input %q: error expected", c.Input)
+			} else if !c.Error && err != nil {
+				t.Errorf("input %q: unexpected error: %s", c.Input, err)
+			}
+		})
+	}
+}
+
+// readAll returns the records read until the end of the input or the first error (io.EOF excepted).
+func readAll(r yacr.RecordSource) ([][]string, error) {
+	var records [][]string
+	for {
+		fields, err := r.ReadRecord()
+		if err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return records, err
+		}
+		record := make([]string, len(fields))
+		for i, field := range fields {
+			record[i] = string(field)
+		}
+		records = append(records, record)
+	}
+}
+
+// TestRoundTrip checks that random records (see RandomRecords) written by newWriter in the dialect d
+// are read back unchanged by newReader.
+// The dialect must be quoted (or escaped) so that any value can be written.
+func TestRoundTrip(t *testing.T, d yacr.Dialect, newReader NewReader, newWriter NewWriter) {
+	roundTrip := func(records [][]string) bool {
+		var b bytes.Buffer
+		w := newWriter(&b, d)
+		for _, record := range records {
+			for _, field := range record {
+				w.Write([]byte(field))
+			}
+			w.EndOfRecord()
+		}
+		w.Flush()
+		if err := w.Err(); err != nil {
+			t.Errorf("records %q: %s", records, err)
+			return false
+		}
+		r := newReader(bytes.NewReader(b.Bytes()), d)
+		if r == nil {
+			t.Fatal("unsupported dialect")
+		}
+		read, err := readAll(r)
+		if err != nil {
+			t.Errorf("records %q written as %q: %s", records, b.String(), err)
+			return false
+		} else if !reflect.DeepEqual(read, records) {
+			t.Errorf("records %q written as %q: got %q", records, b.String(), read)
+			return false
+		}
+		return true
+	}
+	config := &quick.Config{
+		Values: func(args []reflect.Value, r *rand.Rand) {
+			args[0] = reflect.ValueOf(RandomRecords(r, d, 1+r.Intn(10)))
+		},
+	}
+	if err := quick.Check(roundTrip, config); err != nil && !t.Failed() {
+		t.Error(err)
+	}
+}
+
+// RandomRecords returns n random records whose values mix special characters of the dialect d
+// (separator, quotes, escape, newlines...), spaces and non-ASCII characters.
+// A record made of a single empty value (written as an empty line, which is skipped) is never returned.
+// Spaces are not generated when d trims values, nor the comment character.
+func RandomRecords(r *rand.Rand, d yacr.Dialect, n int) [][]string {
+	alphabet := []string{"a", "b", "0", string(d.Sep), `"`, "\n", "\r", "\r\n", "\u00e9", "\u65e5", ",", ";", "\t"}
+	if d.Escape != 0 {
+		alphabet = append(alphabet, string(d.Escape))
+	}
+	if !d.Trim {
+		alphabet = append(alphabet, " ")
+	}
+	records := make([][]string, n)
+	for i := range records {
+		record := make([]string, 1+r.Intn(5))
+		for j := range record {
+			var value strings.Builder
+			for k := r.Intn(6); k > 0; k-- {
+				c := alphabet[r.Intn(len(alphabet))]
+				if d.Trim && c == "\t" || d.Comment != 0 && c == string(d.Comment) {
+					continue
+				}
+				value.WriteString(c)
+			}
+			record[j] = value.String()
+		}
+		if len(record) == 1 && record[0] == "" {
+			record[0] = "x"
+		}
+		records[i] = record
+	}
+	return records
+}
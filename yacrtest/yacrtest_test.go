@@ -0,0 +1,37 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yacrtest_test
+
+import (
+	"io"
+	"testing"
+
+	"github.com/gwenn/yacr"
+	. "github.com/gwenn/yacr/yacrtest"
+)
+
+func newReader(r io.Reader, d yacr.Dialect) yacr.RecordSource {
+	return d.NewReader(r)
+}
+
+func newWriter(w io.Writer, d yacr.Dialect) RecordWriter {
+	return d.NewWriter(w)
+}
+
+func TestReaderConformance(t *testing.T) {
+	TestReader(t, newReader)
+}
+
+func TestRoundTripConformance(t *testing.T) {
+	dialects := []yacr.Dialect{
+		yacr.DialectDefault,
+		yacr.DialectHive,
+		{Sep: ';', Quoted: true, UseCRLF: true},
+		{Sep: '\t', Quoted: true, Trim: true, Comment: '#'},
+	}
+	for _, d := range dialects {
+		TestRoundTrip(t, d, newReader, newWriter)
+	}
+}